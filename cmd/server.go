@@ -9,6 +9,7 @@ import (
 	"github.com/sunkaimr/data-loom/internal/middlewares"
 	"github.com/sunkaimr/data-loom/internal/models"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/leader"
 	"github.com/sunkaimr/data-loom/internal/pkg/logger"
 	"github.com/sunkaimr/data-loom/internal/pkg/mysql"
 	"github.com/sunkaimr/data-loom/internal/router"
@@ -61,17 +62,28 @@ func start() {
 	SetupSignalHandler(ctx)
 
 	services.Cfg.ReloadConfig(middlewares.NewGinContext(log, db))
+	go job.StartReloadConfigJob(ctx)
 	go startHttpServer(ctx)
 	time.Sleep(time.Second * 1)
 
-	go job.StartTaskJob(ctx)
-	go job.StartOthersJob(ctx)
+	// 多副本部署时只有主副本运行定时任务，所有副本都提供API服务
+	if configs.C.Job.LeaderLease > 0 {
+		elector := leader.NewElector(leader.JobLeaseName, time.Duration(configs.C.Job.LeaderLease)*time.Second)
+		go elector.Run(ctx, startJobs)
+	} else {
+		go startJobs(ctx)
+	}
 
 	time.Sleep(time.Second * 3)
 	ctx.Wg.Wait()
 	ctx.Log.Info("main exited")
 }
 
+func startJobs(ctx *common.Context) {
+	go job.StartTaskJob(ctx)
+	go job.StartOthersJob(ctx)
+}
+
 func startHttpServer(ctx *common.Context) {
 	server := &http.Server{
 		Addr:    ":" + configs.C.Server.Port,
@@ -19,6 +19,10 @@ type Config struct {
 type Job struct {
 	PolicyCron string `yaml:"policyCron"`
 	TaskCron   string `yaml:"taskCron"`
+	// 选主租约时长单位秒，多副本部署时只有持有租约的副本运行定时任务，0表示不选主
+	LeaderLease int `yaml:"leaderLease"`
+	// 策略推荐的执行时间窗口，如["01:00:00", "05:00:00"]，为空时使用01:00:00-05:00:00
+	RecommendExecuteWindow []string `yaml:"recommendExecuteWindow"`
 }
 
 type Jwt struct {
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sunkaimr/data-loom/internal/models"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
 )
 
 type ClusterDriver interface {
@@ -23,7 +24,25 @@ type ClusterDriver interface {
 	TablesHasPrimaryKey(*gin.Context, string, []string /*database []tables*/) (bool, common.ServiceCode, error)
 
 	// SQLExplain 检查归档条件合法性
-	SQLExplain(*gin.Context, string, string, string, string /*database table columns conditions*/) (int64, common.ServiceCode, error)
+	SQLExplain(*gin.Context, string, string, string, string /*database table columns conditions*/) (*mysql.ExplainInfo, common.ServiceCode, error)
+
+	// GetTableIndexes 查询表的索引
+	GetTableIndexes(*gin.Context, string, string /*database table*/) ([]mysql.IndexSchema, common.ServiceCode, error)
+
+	// GetTableColumns 查询表的列
+	GetTableColumns(*gin.Context, string, string /*database table*/) ([]mysql.ColumnSchema, common.ServiceCode, error)
+
+	// GetTablePartitions 查询表的分区
+	GetTablePartitions(*gin.Context, string, string /*database table*/) ([]mysql.PartitionSchema, common.ServiceCode, error)
+
+	// GetExchangeState 查询分区交换到归档表前双方的当前状态
+	GetExchangeState(*gin.Context, string, string, string, string /*database table partition archive*/) (*mysql.ExchangeState, common.ServiceCode, error)
+
+	// GetColumnDataAge 统计时间列早于N天的数据行数
+	GetColumnDataAge(*gin.Context, string, string, string, []int /*database table column days*/) (*mysql.ColumnDataAge, common.ServiceCode, error)
+
+	// GetColumnMax 查询满足条件的数据中列的最大值
+	GetColumnMax(*gin.Context, string, string, string, string /*database table column conditions*/) (string, common.ServiceCode, error)
 
 	// GetClusterBigTables 统计集群的大表
 	GetClusterBigTables(*gin.Context, int /* 只抓取表大于10G以上的 */) (any, common.ServiceCode, error)
@@ -39,6 +58,15 @@ type ClusterDriver interface {
 
 	// GetDiskUsed 获取磁盘使用空间
 	GetDiskUsed(ctx *gin.Context) (int, error)
+
+	// GetLoad 获取集群的负载情况
+	GetLoad(*gin.Context) (int, int, int64, error) /*threads_running active_trx questions*/
+
+	// DiscoverTopology 通过服务地址发现集群的主库和从库
+	DiscoverTopology(*gin.Context) (string, string, []string, error) /*primary_uuid primary_addr replicas*/
+
+	// GetTablesSchema 获取集群所有表的结构元数据
+	GetTablesSchema(*gin.Context, []string /*exclude databases*/) ([]mysql.TableSchema, common.ServiceCode, error)
 }
 
 type ClusterSynchronizer interface {
@@ -180,21 +180,21 @@ func (c *ClusterMysql) TablesHasPrimaryKey(ctx *gin.Context, database string, ta
 	return true, common.CodeOK, nil
 }
 
-func (c *ClusterMysql) SQLExplain(ctx *gin.Context, database, table, columns, conditions string) (int64, common.ServiceCode, error) {
+func (c *ClusterMysql) SQLExplain(ctx *gin.Context, database, table, columns, conditions string) (*mysql.ExplainInfo, common.ServiceCode, error) {
 	log, _ := common.ExtractContext(ctx)
 
 	_, _, _, _, _, err := c.ClusterInfo(ctx)
 	if err != nil {
 		err = errors.Join(ExtractingClusterInfoErr, err)
 		log.Error(err)
-		return 0, common.CodeClusterExtractingErr, err
+		return nil, common.CodeClusterExtractingErr, err
 	}
 
 	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, database)
 	if err != nil {
 		err = fmt.Errorf("new mysql connector failed, %s", err)
 		log.Error(err)
-		return 0, common.CodeConnConnectMysql, err
+		return nil, common.CodeConnConnectMysql, err
 	}
 	defer func() {
 		_ = con.Close()
@@ -204,23 +204,197 @@ func (c *ClusterMysql) SQLExplain(ctx *gin.Context, database, table, columns, co
 	if err != nil {
 		err = fmt.Errorf("build select sql failed, %s", err)
 		log.Error(err)
-		return 0, common.CodePolicyConditionsErr, err
+		return nil, common.CodePolicyConditionsErr, err
 	}
 
 	explain, err := con.Explain(sql)
 	if err != nil {
 		err = fmt.Errorf("explain %s failed, %s", sql, err)
 		log.Error(err)
-		return 0, common.CodePolicyConditionsErr, err
+		return nil, common.CodePolicyConditionsErr, err
 	}
 
-	var affectRows int64
-	for _, v := range explain.ExplainRows {
-		if v.Rows > affectRows {
-			affectRows = v.Rows
-		}
+	return explain, common.CodeOK, nil
+}
+
+// GetTableIndexes 查询表的索引
+func (c *ClusterMysql) GetTableIndexes(ctx *gin.Context, database, table string) ([]mysql.IndexSchema, common.ServiceCode, error) {
+	log, _ := common.ExtractContext(ctx)
+
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		err = errors.Join(ExtractingClusterInfoErr, err)
+		log.Error(err)
+		return nil, common.CodeClusterExtractingErr, err
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		err = fmt.Errorf("new mysql connector failed, %s", err)
+		log.Error(err)
+		return nil, common.CodeConnConnectMysql, err
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	indexes, err := con.TableIndexes(database, table)
+	if err != nil {
+		err = fmt.Errorf("query table(%s.%s) indexes failed, %s", database, table, err)
+		log.Error(err)
+		return nil, common.CodeSourceQueryTableErr, err
+	}
+	return indexes, common.CodeOK, nil
+}
+
+// GetTableColumns 查询表的列
+func (c *ClusterMysql) GetTableColumns(ctx *gin.Context, database, table string) ([]mysql.ColumnSchema, common.ServiceCode, error) {
+	log, _ := common.ExtractContext(ctx)
+
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		err = errors.Join(ExtractingClusterInfoErr, err)
+		log.Error(err)
+		return nil, common.CodeClusterExtractingErr, err
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		err = fmt.Errorf("new mysql connector failed, %s", err)
+		log.Error(err)
+		return nil, common.CodeConnConnectMysql, err
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	res, err := con.TableColumns(database, table)
+	if err != nil {
+		err = fmt.Errorf("query table(%s.%s) columns failed, %s", database, table, err)
+		log.Error(err)
+		return nil, common.CodeSourceQueryTableErr, err
+	}
+	return res, common.CodeOK, nil
+}
+
+// GetColumnDataAge 统计时间列早于N天的数据行数
+func (c *ClusterMysql) GetColumnDataAge(ctx *gin.Context, database, table, column string, days []int) (*mysql.ColumnDataAge, common.ServiceCode, error) {
+	log, _ := common.ExtractContext(ctx)
+
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		err = errors.Join(ExtractingClusterInfoErr, err)
+		log.Error(err)
+		return nil, common.CodeClusterExtractingErr, err
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		err = fmt.Errorf("new mysql connector failed, %s", err)
+		log.Error(err)
+		return nil, common.CodeConnConnectMysql, err
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	res, err := con.ColumnDataAge(database, table, column, days)
+	if err != nil {
+		err = fmt.Errorf("query table(%s.%s) column(%s) data age failed, %s", database, table, column, err)
+		log.Error(err)
+		return nil, common.CodeSourceQueryTableErr, err
+	}
+	return res, common.CodeOK, nil
+}
+
+// GetColumnMax 查询满足条件的数据中列的最大值，没有数据时返回空
+func (c *ClusterMysql) GetColumnMax(ctx *gin.Context, database, table, column, conditions string) (string, common.ServiceCode, error) {
+	log, _ := common.ExtractContext(ctx)
+
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		err = errors.Join(ExtractingClusterInfoErr, err)
+		log.Error(err)
+		return "", common.CodeClusterExtractingErr, err
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		err = fmt.Errorf("new mysql connector failed, %s", err)
+		log.Error(err)
+		return "", common.CodeConnConnectMysql, err
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	res, err := con.ColumnMax(database, table, column, conditions)
+	if err != nil {
+		err = fmt.Errorf("query table(%s.%s) column(%s) max value failed, %s", database, table, column, err)
+		log.Error(err)
+		return "", common.CodeSourceQueryTableErr, err
+	}
+	return res, common.CodeOK, nil
+}
+
+// GetTablePartitions 查询表的分区
+func (c *ClusterMysql) GetTablePartitions(ctx *gin.Context, database, table string) ([]mysql.PartitionSchema, common.ServiceCode, error) {
+	log, _ := common.ExtractContext(ctx)
+
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		err = errors.Join(ExtractingClusterInfoErr, err)
+		log.Error(err)
+		return nil, common.CodeClusterExtractingErr, err
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		err = fmt.Errorf("new mysql connector failed, %s", err)
+		log.Error(err)
+		return nil, common.CodeConnConnectMysql, err
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	res, err := con.TablePartitions(database, table)
+	if err != nil {
+		err = fmt.Errorf("query table(%s.%s) partitions failed, %s", database, table, err)
+		log.Error(err)
+		return nil, common.CodeSourceQueryTableErr, err
+	}
+	return res, common.CodeOK, nil
+}
+
+// GetExchangeState 查询分区交换到归档表前双方的当前状态
+func (c *ClusterMysql) GetExchangeState(ctx *gin.Context, database, table, partition, archive string) (*mysql.ExchangeState, common.ServiceCode, error) {
+	log, _ := common.ExtractContext(ctx)
+
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		err = errors.Join(ExtractingClusterInfoErr, err)
+		log.Error(err)
+		return nil, common.CodeClusterExtractingErr, err
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		err = fmt.Errorf("new mysql connector failed, %s", err)
+		log.Error(err)
+		return nil, common.CodeConnConnectMysql, err
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	res, err := con.ExchangeState(database, table, partition, archive)
+	if err != nil {
+		err = fmt.Errorf("query table(%s.%s) partition(%s) exchange state with archive(%s) failed, %s", database, table, partition, archive, err)
+		log.Error(err)
+		return nil, common.CodeSourceQueryTableErr, err
 	}
-	return affectRows, common.CodeOK, nil
+	return res, common.CodeOK, nil
 }
 
 func (c *ClusterMysql) GetTablesSize(ctx *gin.Context, database string, tables []string) (int, common.ServiceCode, error) {
@@ -399,3 +573,128 @@ func (c *ClusterMysql) GetDiskUsed(ctx *gin.Context) (int, error) {
 	//return int(disk), err
 	return 0, nil
 }
+
+// GetLoad 获取集群的负载情况：正在运行的线程数、活跃事务数、累计的Questions，QPS由调用方根据两次采样的Questions计算
+func (c *ClusterMysql) GetLoad(ctx *gin.Context) (int, int, int64, error) {
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		return 0, 0, 0, errors.Join(ExtractingClusterInfoErr, err)
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("new mysql connector failed, %s", err)
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	threadsRunning, err := con.GlobalStatus("Threads_running")
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("get global status Threads_running failed, %s", err)
+	}
+
+	activeTrx, err := con.ActiveTrxNum()
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("get active trx num failed, %s", err)
+	}
+
+	questions, err := con.GlobalStatus("Questions")
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("get global status Questions failed, %s", err)
+	}
+
+	return int(threadsRunning), activeTrx, questions, nil
+}
+
+// DiscoverTopology 连接服务地址，根据read_only和复制关系识别主库和从库
+func (c *ClusterMysql) DiscoverTopology(ctx *gin.Context) (string, string, []string, error) {
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		return "", "", nil, errors.Join(ExtractingClusterInfoErr, err)
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("new mysql connector failed, %s", err)
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	readOnly, err := con.ReadOnly()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("query read_only from %s failed, %s", c.ServiceAddr, err)
+	}
+
+	// 服务地址指向的是从库，则顺着复制关系找到主库
+	primaryCon := con
+	if readOnly {
+		source, err := con.ReplicaSource()
+		if err != nil {
+			return "", "", nil, fmt.Errorf("query replica status from %s failed, %s", c.ServiceAddr, err)
+		}
+		if source == "" {
+			return "", "", nil, fmt.Errorf("%s is read only but not a replica", c.ServiceAddr)
+		}
+
+		host, port, err := utils.ParseAddr(source, "", 3306)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("parse source addr(%s) failed, %s", source, err)
+		}
+		primaryCon, err = mysql.NewMysqlConnect(host, strconv.Itoa(port), c.user, c.passwd, "")
+		if err != nil {
+			return "", "", nil, fmt.Errorf("new mysql connector failed, %s", err)
+		}
+		defer func() {
+			_ = primaryCon.Close()
+		}()
+	}
+
+	uuid, err := primaryCon.ServerUUID()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("query primary server_uuid failed, %s", err)
+	}
+
+	primary, err := primaryCon.InstanceAddr()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("query primary addr failed, %s", err)
+	}
+
+	replicas, err := primaryCon.ShowReplicas()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("query replicas from primary(%s) failed, %s", primary, err)
+	}
+
+	return uuid, primary, replicas, nil
+}
+
+// GetTablesSchema 获取集群所有表的结构元数据
+func (c *ClusterMysql) GetTablesSchema(ctx *gin.Context, excludeDatabases []string) ([]mysql.TableSchema, common.ServiceCode, error) {
+	log, _ := common.ExtractContext(ctx)
+
+	_, _, _, _, _, err := c.ClusterInfo(ctx)
+	if err != nil {
+		err = errors.Join(ExtractingClusterInfoErr, err)
+		log.Error(err)
+		return nil, common.CodeClusterExtractingErr, err
+	}
+
+	con, err := mysql.NewMysqlConnect(c.host, strconv.Itoa(c.port), c.user, c.passwd, "")
+	if err != nil {
+		err = fmt.Errorf("new mysql connector failed, %s", err)
+		log.Error(err)
+		return nil, common.CodeConnConnectMysql, err
+	}
+	defer func() {
+		_ = con.Close()
+	}()
+
+	schemas, err := con.TablesSchema(excludeDatabases)
+	if err != nil {
+		err = fmt.Errorf("query tables schema failed, %s", err)
+		log.Error(err)
+		return nil, common.CodeSourceQueryTableErr, err
+	}
+	return schemas, common.CodeOK, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
 )
 
 var UnSupportClusterType = errors.New("unsupported cluster type")
@@ -34,8 +35,32 @@ func (c *ClusterUnknown) TablesHasPrimaryKey(_ *gin.Context, _ string, _ []strin
 	return false, common.CodeServerErr, UnSupportClusterType
 }
 
-func (c *ClusterUnknown) SQLExplain(_ *gin.Context, _ string, _ string, _ string, _ string /*database table columns conditions*/) (int64, common.ServiceCode, error) {
-	return 0, common.CodeServerErr, UnSupportClusterType
+func (c *ClusterUnknown) SQLExplain(_ *gin.Context, _ string, _ string, _ string, _ string /*database table columns conditions*/) (*mysql.ExplainInfo, common.ServiceCode, error) {
+	return nil, common.CodeServerErr, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) GetTableIndexes(_ *gin.Context, _ string, _ string) ([]mysql.IndexSchema, common.ServiceCode, error) {
+	return nil, common.CodeServerErr, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) GetTableColumns(_ *gin.Context, _ string, _ string) ([]mysql.ColumnSchema, common.ServiceCode, error) {
+	return nil, common.CodeServerErr, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) GetColumnDataAge(_ *gin.Context, _, _, _ string, _ []int) (*mysql.ColumnDataAge, common.ServiceCode, error) {
+	return nil, common.CodeServerErr, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) GetColumnMax(_ *gin.Context, _, _, _, _ string) (string, common.ServiceCode, error) {
+	return "", common.CodeServerErr, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) GetTablePartitions(_ *gin.Context, _ string, _ string) ([]mysql.PartitionSchema, common.ServiceCode, error) {
+	return nil, common.CodeServerErr, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) GetExchangeState(_ *gin.Context, _, _, _, _ string) (*mysql.ExchangeState, common.ServiceCode, error) {
+	return nil, common.CodeServerErr, UnSupportClusterType
 }
 
 func (c *ClusterUnknown) GetFreeDisk(_ *gin.Context) (int, error) {
@@ -54,6 +79,18 @@ func (c *ClusterUnknown) GetDiskUsed(_ *gin.Context) (int, error) {
 	return 0, UnSupportClusterType
 }
 
+func (c *ClusterUnknown) GetLoad(_ *gin.Context) (int, int, int64, error) {
+	return 0, 0, 0, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) DiscoverTopology(_ *gin.Context) (string, string, []string, error) {
+	return "", "", nil, UnSupportClusterType
+}
+
+func (c *ClusterUnknown) GetTablesSchema(_ *gin.Context, _ []string) ([]mysql.TableSchema, common.ServiceCode, error) {
+	return nil, common.CodeServerErr, UnSupportClusterType
+}
+
 func (c *ClusterUnknown) GetClusterBigTables(*gin.Context, int) (any, common.ServiceCode, error) {
 	return nil, common.CodeServerErr, UnSupportClusterType
 }
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+	"net/http"
+)
+
+type BlackoutController struct{}
+
+// CreateBlackout	创建封网日历
+// @Router			/blackout [post]
+// @Description		创建封网日历
+// @Tags			封网日历
+// @Param			blackout		body		services.BlackoutService	true	"封网日历"
+// @Success			200				{object}	common.Response{data=services.BlackoutService}
+// @Failure			500				{object}	common.Response
+func (c *BlackoutController) CreateBlackout(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.BlackoutService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	// 参数校验
+	if ok, res, err := req.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", req, err)
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+
+	res, err := req.CreateBlackout(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// UpdateBlackout	更新封网日历
+// @Router			/blackout [put]
+// @Description		更新封网日历
+// @Tags			封网日历
+// @Param			blackout	body		services.BlackoutService	true	"封网日历"
+// @Success			200			{object}	common.Response{data=services.BlackoutService}
+// @Failure			500			{object}	common.Response
+func (c *BlackoutController) UpdateBlackout(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.BlackoutService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid Blackout.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.UpdateBlackout(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// DeleteBlackout	删除封网日历
+// @Router			/blackout [delete]
+// @Description		删除封网日历
+// @Tags			封网日历
+// @Param			blackout	body		services.BlackoutService	true	"封网日历"
+// @Success			200			{object}	common.Response
+// @Failure			500			{object}	common.Response
+func (c *BlackoutController) DeleteBlackout(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+	req := &services.BlackoutService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid Blackout.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.DeleteBlackout(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res})
+}
+
+// QueryBlackout  	查询封网日历
+// @Router			/blackout [get]
+// @Description		查询封网日历
+// @Tags			封网日历
+// @Param   		page			query		int			false  	"page"
+// @Param   		pageSize		query		int     	false  	"pageSize"
+// @Param   		id				query		uint     	false  	"封网日历ID"
+// @Param   		name			query		string     	false  	"封网名称"
+// @Param   		scope			query		string     	false  	"生效范围"			Enums(global, bu, cluster)
+// @Param   		scope_value		query		string     	false  	"生效的BU或集群ID"
+// @Param   		recurrence		query		string     	false  	"重复周期"			Enums(none, daily, weekly, monthly, yearly)
+// @Param   		creator			query		string     	false  	"创建人"
+// @Success			200		{object}	common.Response{data=services.BlackoutService}
+// @Failure			500		{object}	common.Response
+func (c *BlackoutController) QueryBlackout(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	queryMap := make(map[string]string, 5)
+	queryMap["name"] = ctx.Query("name")
+	queryMap["scope"] = ctx.Query("scope")
+	queryMap["scope_value"] = ctx.Query("scope_value")
+	queryMap["recurrence"] = ctx.Query("recurrence")
+	queryMap["creator"] = ctx.Query("creator")
+
+	blackout := services.BlackoutService{Model: services.Model{ID: id}}
+	data, res, err := blackout.QueryBlackout(ctx, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: data})
+}
+
+// ImportBlackout	从iCal文件导入封网日历
+// @Router			/blackout/import [post]
+// @Description		从iCal文件导入节假日等封网日历，UID相同的事件会更新已导入的封网日历
+// @Tags			封网日历
+// @Accept			multipart/form-data
+// @Param			file			formData	file		true	"iCal文件"
+// @Param			scope			formData	string		false	"生效范围"	Enums(global, bu, cluster)
+// @Param			scope_value		formData	string		false	"生效的BU或集群ID"
+// @Success			200				{object}	common.Response{data=services.BlackoutImportService}
+// @Failure			500				{object}	common.Response
+func (c *BlackoutController) ImportBlackout(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBlackoutICalErr, Error: err.Error()})
+		return
+	}
+	defer f.Close()
+
+	req := &services.BlackoutImportService{
+		Scope:      common.BlackoutScopeType(ctx.PostForm("scope")),
+		ScopeValue: ctx.PostForm("scope_value"),
+	}
+	res, err := req.ImportICal(ctx, f)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
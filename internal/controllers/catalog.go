@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+)
+
+type CatalogController struct{}
+
+// QueryCatalogTable	查询表结构快照
+// @Router				/cluster/catalog [get]
+// @Description			查询表结构快照
+// @Tags				集群管理
+// @Param   			page			query		int			false  	"page"
+// @Param   			pageSize		query		int     	false  	"pageSize"
+// @Param   			id				query		uint     	false  	"ID"
+// @Param   			bu				query		string     	false  	"bu"
+// @Param   			cluster_id		query		string     	false  	"集群ID"
+// @Param   			cluster_name	query		string     	false  	"集群名称"
+// @Param   			database		query		string     	false  	"库名"
+// @Param   			table			query		string     	false  	"表名"
+// @Param   			table_comment	query		string     	false  	"表注释"
+// @Success				200		{object}	common.Response{data=services.CatalogTableService}
+// @Failure				500		{object}	common.Response
+func (c *CatalogController) QueryCatalogTable(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	queryMap := make(map[string]string, 10)
+	queryMap["bu"] = ctx.Query("bu")
+	queryMap["cluster_id"] = ctx.Query("cluster_id")
+	queryMap["cluster_name"] = ctx.Query("cluster_name")
+	queryMap["`database`"] = ctx.Query("database")
+	queryMap["`table`"] = ctx.Query("table")
+	queryMap["table_comment"] = ctx.Query("table_comment")
+
+	svc := services.CatalogTableService{Model: services.Model{ID: id}}
+	data, code, err := svc.QueryCatalogTable(ctx, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Data: data})
+	return
+}
+
+// QuerySchemaChange	查询表结构变更历史
+// @Router				/cluster/catalog/change [get]
+// @Description			查询表结构变更历史
+// @Tags				集群管理
+// @Param   			page			query		int			false  	"page"
+// @Param   			pageSize		query		int     	false  	"pageSize"
+// @Param   			id				query		uint     	false  	"ID"
+// @Param   			cluster_id		query		string     	false  	"集群ID"
+// @Param   			database		query		string     	false  	"库名"
+// @Param   			table			query		string     	false  	"表名"
+// @Param   			change_type		query		string     	false  	"变更类型 新建表:create, 修改表:alter, 删除表:drop"
+// @Success				200		{object}	common.Response{data=services.CatalogSchemaChangeService}
+// @Failure				500		{object}	common.Response
+func (c *CatalogController) QuerySchemaChange(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	queryMap := make(map[string]string, 10)
+	queryMap["cluster_id"] = ctx.Query("cluster_id")
+	queryMap["`database`"] = ctx.Query("database")
+	queryMap["`table`"] = ctx.Query("table")
+	queryMap["change_type"] = ctx.Query("change_type")
+
+	svc := services.CatalogSchemaChangeService{ID: id}
+	data, code, err := svc.QuerySchemaChange(ctx, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Data: data})
+	return
+}
+
+// RefreshCatalog		刷新所有集群的表结构快照
+// @Router				/cluster/catalog/refresh [get]
+// @Description			刷新所有集群的表结构快照
+// @Tags				集群管理
+// @Success				200		{object}	common.Response
+// @Failure				500		{object}	common.Response
+func (c *CatalogController) RefreshCatalog(ctx *gin.Context) {
+	if !services.RefreshCatalogLock.TryLock() {
+		code := common.CodeClusterCatalogTaskExisted
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: "there is already a task refreshing the catalog"})
+		return
+	}
+	services.RefreshCatalogLock.Unlock()
+
+	go func() {
+		_, _ = (&services.CatalogTableService{}).RefreshCatalog(ctx.Copy())
+	}()
+
+	ctx.JSON(common.ServiceCode2HttpCode(common.CodeClusterCatalogTaskRunning), common.Response{ServiceCode: common.CodeClusterCatalogTaskRunning})
+	return
+}
@@ -35,7 +35,9 @@ func (c *ConfigController) GetConfig(ctx *gin.Context) {
 // @Failure			500		{object}	common.Response
 func (c *ConfigController) UpdateConfig(ctx *gin.Context) {
 	log, _ := common.ExtractContext(ctx)
-	req := &services.ConfigService{}
+	req := &services.ConfigService{
+		WebhookRetries: common.InvalidInt,
+	}
 	if err := ctx.ShouldBindJSON(req); err != nil {
 		log.Error(err)
 		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
@@ -55,6 +57,7 @@ func (c *ConfigController) UpdateConfig(ctx *gin.Context) {
 // @Description		通知测试
 // @Tags			管理员
 // @Param   		user				query		string			false  	"user"
+// @Param   		notice				query		string			false  	"指定测试的通知方式，为空时使用当前配置的通知方式" Enums(email,webhook,dingtalk,feishu,wecom)
 // @Success			200		{object}	common.Response
 // @Failure			500		{object}	common.Response
 func (c *ConfigController) NoticeTest(ctx *gin.Context) {
@@ -66,7 +69,17 @@ func (c *ConfigController) NoticeTest(ctx *gin.Context) {
 
 	(&services.ConfigService{}).ReloadConfig(ctx)
 
-	err := notice.NewDriver(services.Cfg.ServiceToModel()).Test(nil, user)
+	noticeType := common.NoticeType(ctx.Query("notice"))
+	if noticeType == "" {
+		noticeType = services.Cfg.Notice
+	} else if !common.CheckNoticeType(noticeType) {
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeConfigNoticeTypeErr})
+		return
+	}
+
+	log, db := common.ExtractContext(ctx)
+	user = notice.TestUser(common.NewContext().WithDB(db).WithLog(log), noticeType, user)
+	err := notice.NewDriverByType(services.Cfg.ServiceToModel(), noticeType).Test(nil, user)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeConfigNoticeErr, Error: err.Error()})
 		return
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+	"net/http"
+)
+
+type NotifyRouteController struct{}
+
+// CreateNotifyRoute	创建通知路由规则
+// @Router				/manage/notice/route [post]
+// @Description			创建通知路由规则，任务按BU、集群、治理方式、任务状态匹配后发送到指定的通知渠道
+// @Tags				通知路由
+// @Param				route	body		services.NotifyRouteService	true	"通知路由规则"
+// @Success				200		{object}	common.Response{data=services.NotifyRouteService}
+// @Failure				500		{object}	common.Response
+func (c *NotifyRouteController) CreateNotifyRoute(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.NotifyRouteService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	// 参数校验
+	if ok, res, err := req.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", req, err)
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+
+	res, err := req.CreateNotifyRoute(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// UpdateNotifyRoute	更新通知路由规则
+// @Router				/manage/notice/route [put]
+// @Description			更新通知路由规则，未传的匹配条件和渠道保持不变，传空数组表示清空
+// @Tags				通知路由
+// @Param				route	body		services.NotifyRouteService	true	"通知路由规则"
+// @Success				200		{object}	common.Response{data=services.NotifyRouteService}
+// @Failure				500		{object}	common.Response
+func (c *NotifyRouteController) UpdateNotifyRoute(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.NotifyRouteService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid NotifyRoute.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.UpdateNotifyRoute(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// DeleteNotifyRoute	删除通知路由规则
+// @Router				/manage/notice/route [delete]
+// @Description			删除通知路由规则
+// @Tags				通知路由
+// @Param				route	body		services.NotifyRouteService	true	"通知路由规则"
+// @Success				200		{object}	common.Response
+// @Failure				500		{object}	common.Response
+func (c *NotifyRouteController) DeleteNotifyRoute(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+	req := &services.NotifyRouteService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid NotifyRoute.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.DeleteNotifyRoute(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res})
+}
+
+// QueryNotifyRoute  	查询通知路由规则
+// @Router				/manage/notice/route [get]
+// @Description			查询通知路由规则
+// @Tags				通知路由
+// @Param   			page			query		int			false  	"page"
+// @Param   			pageSize		query		int     	false  	"pageSize"
+// @Param   			id				query		uint     	false  	"规则ID"
+// @Param   			name			query		string     	false  	"规则名称"
+// @Param   			creator			query		string     	false  	"创建人"
+// @Success				200		{object}	common.Response{data=services.NotifyRouteService}
+// @Failure				500		{object}	common.Response
+func (c *NotifyRouteController) QueryNotifyRoute(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	queryMap := make(map[string]string, 2)
+	queryMap["name"] = ctx.Query("name")
+	queryMap["creator"] = ctx.Query("creator")
+
+	route := services.NotifyRouteService{Model: services.Model{ID: id}}
+	data, res, err := route.QueryNotifyRoute(ctx, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: data})
+}
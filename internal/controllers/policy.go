@@ -57,9 +57,12 @@ func (c *PolicyController) UpdatePolicy(ctx *gin.Context) {
 		Model: services.Model{
 			ID: common.InvalidUint,
 		},
-		SrcID:  common.InvalidUint,
-		DestID: common.InvalidUint,
-		Day:    common.InvalidInt,
+		SrcID:      common.InvalidUint,
+		DestID:     common.InvalidUint,
+		Day:        common.InvalidInt,
+		ThrottleID: common.InvalidUint,
+
+		PartitionPrecreate: common.InvalidInt,
 	}
 	if err := ctx.ShouldBindJSON(req); err != nil {
 		log.Error(err)
@@ -84,6 +87,60 @@ func (c *PolicyController) UpdatePolicy(ctx *gin.Context) {
 	return
 }
 
+// ExplainPolicy		分析策略治理条件
+// @Router				/policy/explain [post]
+// @Description			保存策略前分析治理条件的执行计划，检查能否使用索引及预估扫描行数并给出优化建议
+// @Tags				策略
+// @Param				PolicyExplain	body		services.PolicyExplainService	true	"PolicyExplain"
+// @Success				200				{object}	common.Response{data=services.PolicyExplainService}
+// @Failure				500				{object}	common.Response
+func (c *PolicyController) ExplainPolicy(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.PolicyExplainService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	res, err := req.Explain(ctx)
+	if err != nil {
+		log.Errorf("explain policy condition(%s) failed, %s", req.Condition, err)
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+	return
+}
+
+// BackfillPolicy		补录错过的执行周期
+// @Router				/policy/backfill [post]
+// @Description			服务停止或策略暂停期间错过的执行周期不会自动补执行，为指定日期范围内错过的周期生成补录任务，治理条件中的NOW()等按补录周期的时间计算
+// @Tags				策略
+// @Param				PolicyBackfill	body		services.BackfillService	true	"PolicyBackfill"
+// @Success				200				{object}	common.Response{data=services.BackfillService}
+// @Failure				500				{object}	common.Response
+func (c *PolicyController) BackfillPolicy(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.BackfillService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	res, err := req.Backfill(ctx)
+	if err != nil {
+		log.Errorf("backfill policy(%v) from %s to %s failed, %s", req.PolicyID, req.StartDate, req.EndDate, err)
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+	return
+}
+
 // QueryPolicy			查询策略
 // @Router				/policy [get]
 // @Description			查询策略
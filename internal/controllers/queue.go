@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+	"net/http"
+)
+
+type QueueController struct{}
+
+// QueryQueueItem  	查询队列中待处理的策略和任务
+// @Router			/manage/queue [get]
+// @Description		查询持久化队列中待处理、处理中和处理失败的策略和任务
+// @Tags			队列管理
+// @Param   		page			query		int			false  	"page"
+// @Param   		pageSize		query		int     	false  	"pageSize"
+// @Param   		id				query		uint     	false  	"队列元素ID"
+// @Param   		queue			query		string     	false  	"队列类型"		Enums(policy, task)
+// @Param   		status			query		string     	false  	"状态"			Enums(pending, processing, failed)
+// @Param   		item_id			query		uint     	false  	"策略或任务ID"
+// @Param   		policy_id		query		uint     	false  	"策略ID"
+// @Success			200		{object}	common.Response{data=services.QueueItemService}
+// @Failure			500		{object}	common.Response
+func (c *QueueController) QueryQueueItem(ctx *gin.Context) {
+	req := services.QueueItemService{
+		Model:    services.Model{ID: common.ParsingQueryUintID(ctx.Query("id"))},
+		Queue:    common.QueueNameType(ctx.Query("queue")),
+		Status:   common.QueueItemStatusType(ctx.Query("status")),
+		ItemID:   common.ParsingQueryUintID(ctx.Query("item_id")),
+		PolicyID: common.ParsingQueryUintID(ctx.Query("policy_id")),
+	}
+
+	data, res, err := req.QueryQueueItem(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: data})
+}
+
+// RequeueQueueItem	重新入队列
+// @Router			/manage/queue/requeue [put]
+// @Description		将处理失败或卡住的队列元素重新置为等待处理
+// @Tags			队列管理
+// @Param			item		body		services.QueueItemService	true	"队列元素"
+// @Success			200			{object}	common.Response
+// @Failure			500			{object}	common.Response
+func (c *QueueController) RequeueQueueItem(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.QueueItemService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid QueueItem.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.RequeueQueueItem(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res})
+}
+
+// DropQueueItem	丢弃队列元素
+// @Router			/manage/queue [delete]
+// @Description		从队列中丢弃待处理的策略或任务
+// @Tags			队列管理
+// @Param			item		body		services.QueueItemService	true	"队列元素"
+// @Success			200			{object}	common.Response
+// @Failure			500			{object}	common.Response
+func (c *QueueController) DropQueueItem(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.QueueItemService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid QueueItem.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.DropQueueItem(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res})
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+	"net/http"
+)
+
+type QuotaController struct{}
+
+// CreateQuota		创建并发配额
+// @Router			/manage/quota [post]
+// @Description		创建BU或集群的并发配额
+// @Tags			并发配额
+// @Param			quota		body		services.QuotaService	true	"并发配额"
+// @Success			200			{object}	common.Response{data=services.QuotaService}
+// @Failure			500			{object}	common.Response
+func (c *QuotaController) CreateQuota(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.QuotaService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	// 参数校验
+	if ok, res, err := req.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", req, err)
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+
+	res, err := req.CreateQuota(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// UpdateQuota		更新并发配额
+// @Router			/manage/quota [put]
+// @Description		更新并发配额
+// @Tags			并发配额
+// @Param			quota		body		services.QuotaService	true	"并发配额"
+// @Success			200			{object}	common.Response{data=services.QuotaService}
+// @Failure			500			{object}	common.Response
+func (c *QuotaController) UpdateQuota(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.QuotaService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+		MaxParallel: common.InvalidInt,
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid Quota.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.UpdateQuota(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// DeleteQuota		删除并发配额
+// @Router			/manage/quota [delete]
+// @Description		删除并发配额
+// @Tags			并发配额
+// @Param			quota		body		services.QuotaService	true	"并发配额"
+// @Success			200			{object}	common.Response
+// @Failure			500			{object}	common.Response
+func (c *QuotaController) DeleteQuota(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+	req := &services.QuotaService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid Quota.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.DeleteQuota(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res})
+}
+
+// QueryQuota  		查询并发配额
+// @Router			/manage/quota [get]
+// @Description		查询并发配额
+// @Tags			并发配额
+// @Param   		page			query		int			false  	"page"
+// @Param   		pageSize		query		int     	false  	"pageSize"
+// @Param   		id				query		uint     	false  	"配额ID"
+// @Param   		name			query		string     	false  	"配额名称"
+// @Param   		scope			query		string     	false  	"生效范围"			Enums(bu, cluster)
+// @Param   		scope_value		query		string     	false  	"生效的BU或集群ID"
+// @Param   		creator			query		string     	false  	"创建人"
+// @Success			200		{object}	common.Response{data=services.QuotaService}
+// @Failure			500		{object}	common.Response
+func (c *QuotaController) QueryQuota(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	queryMap := make(map[string]string, 4)
+	queryMap["name"] = ctx.Query("name")
+	queryMap["scope"] = ctx.Query("scope")
+	queryMap["scope_value"] = ctx.Query("scope_value")
+	queryMap["creator"] = ctx.Query("creator")
+
+	quota := services.QuotaService{Model: services.Model{ID: id}}
+	data, res, err := quota.QueryQuota(ctx, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: data})
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+	"net/http"
+)
+
+type PolicyRecommendController struct{}
+
+// QueryPolicyRecommend	查询策略推荐
+// @Router				/policy/recommend [get]
+// @Description			查询没有策略覆盖的大表的策略推荐
+// @Tags				策略
+// @Param   			page			query		int			false  	"page"
+// @Param   			pageSize		query		int     	false  	"pageSize"
+// @Param   			id				query		uint     	false  	"ID"
+// @Param   			bu				query		string     	false  	"bu"
+// @Param   			cluster_id		query		string     	false  	"集群ID"
+// @Param   			cluster_name	query		string     	false  	"集群名称"
+// @Param   			database		query		string     	false  	"库名"
+// @Param   			table			query		string     	false  	"表名"
+// @Param   			status			query		string     	false  	"状态 待处理:pending, 已采纳:accepted, 已忽略:dismissed"
+// @Success				200		{object}	common.Response{data=services.PolicyRecommendService}
+// @Failure				500		{object}	common.Response
+func (c *PolicyRecommendController) QueryPolicyRecommend(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	queryMap := make(map[string]string, 10)
+	queryMap["bu"] = ctx.Query("bu")
+	queryMap["cluster_id"] = ctx.Query("cluster_id")
+	queryMap["cluster_name"] = ctx.Query("cluster_name")
+	queryMap["`database`"] = ctx.Query("database")
+	queryMap["`table`"] = ctx.Query("table")
+	queryMap["status"] = ctx.Query("status")
+
+	svc := services.PolicyRecommendService{Model: services.Model{ID: id}}
+	data, code, err := svc.QueryPolicyRecommend(ctx, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Data: data})
+	return
+}
+
+// RefreshPolicyRecommend	刷新策略推荐
+// @Router					/policy/recommend/refresh [get]
+// @Description				根据最近一次大表统计为没有策略覆盖的大表生成策略推荐
+// @Tags					策略
+// @Success					200		{object}	common.Response
+// @Failure					500		{object}	common.Response
+func (c *PolicyRecommendController) RefreshPolicyRecommend(ctx *gin.Context) {
+	if !services.RefreshPolicyRecommendLock.TryLock() {
+		code := common.CodePolicyRecommendExisted
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: "there is already a task refreshing the policy recommend"})
+		return
+	}
+	services.RefreshPolicyRecommendLock.Unlock()
+
+	go func() {
+		_, _ = (&services.PolicyRecommendService{}).RefreshPolicyRecommend(ctx.Copy())
+	}()
+
+	ctx.JSON(common.ServiceCode2HttpCode(common.CodePolicyRecommendRunning), common.Response{ServiceCode: common.CodePolicyRecommendRunning})
+	return
+}
+
+// AcceptPolicyRecommend	采纳策略推荐
+// @Router					/policy/recommend/accept [post]
+// @Description				采纳策略推荐，自动创建源端和策略，未填写的字段使用推荐的值
+// @Tags					策略
+// @Param					Accept		body		services.PolicyRecommendAcceptService	true	"Accept"
+// @Success					200			{object}	common.Response{data=services.PolicyService}
+// @Failure					500			{object}	common.Response
+func (c *PolicyRecommendController) AcceptPolicyRecommend(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.PolicyRecommendAcceptService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	data, code, err := req.AcceptPolicyRecommend(ctx)
+	if err != nil {
+		log.Errorf("accept policy recommend(%v) failed, %s", req.ID, err)
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Data: data})
+	return
+}
+
+// DismissPolicyRecommend	忽略策略推荐
+// @Router					/policy/recommend/dismiss [post]
+// @Description				忽略策略推荐，需填写忽略原因
+// @Tags					策略
+// @Param					Dismiss		body		services.PolicyRecommendDismissService	true	"Dismiss"
+// @Success					200			{object}	common.Response
+// @Failure					500			{object}	common.Response
+func (c *PolicyRecommendController) DismissPolicyRecommend(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.PolicyRecommendDismissService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	code, err := req.DismissPolicyRecommend(ctx)
+	if err != nil {
+		log.Errorf("dismiss policy recommend(%v) failed, %s", req.ID, err)
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code})
+	return
+}
@@ -128,6 +128,8 @@ func (c *SourceController) DeleteSource(ctx *gin.Context) {
 // @Param   		cluster_id		query		string     	false  	"cluster_id"
 // @Param   		database_name	query		string     	false  	"数据库"
 // @Param   		tables_name		query		string     	false  	"表名字"
+// @Param   		tables_pattern	query		string     	false  	"表名匹配规则"
+// @Param   		database_pattern	query		string     	false  	"库名匹配规则"
 // @Success			200		{object}	common.Response{data=services.SourceService}
 // @Failure			500		{object}	common.Response
 func (c *SourceController) QuerySource(ctx *gin.Context) {
@@ -142,6 +144,8 @@ func (c *SourceController) QuerySource(ctx *gin.Context) {
 	queryMap["cluster_id"] = ctx.Query("cluster_id")
 	queryMap["database_name"] = ctx.Query("database_name")
 	queryMap["tables_name"] = ctx.Query("tables_name")
+	queryMap["tables_pattern"] = ctx.Query("tables_pattern")
+	queryMap["database_pattern"] = ctx.Query("database_pattern")
 	queryMap["columns"] = ctx.Query("columns")
 
 	source := services.SourceService{Model: services.Model{ID: id}}
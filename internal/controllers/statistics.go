@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
 	"github.com/sunkaimr/data-loom/internal/services"
+	"net/http"
 )
 
 type TaskStatisticController struct{}
@@ -54,6 +55,33 @@ func (c *TaskStatisticController) TaskExecPlan(ctx *gin.Context) {
 	return
 }
 
+// TaskExecSimulate		模拟任务执行计划
+// @Router				/task/plan/simulate [post]
+// @Description			按假设的最大并发数、冲突级别或策略修改，模拟未来N天任务的准入和执行，预测每个任务的开始和结束时间以及无法执行的任务，不会修改任何配置、策略和任务
+// @Tags				任务统计
+// @Param				TaskSimulate	body		services.SimulateService	true	"TaskSimulate"
+// @Success				200				{object}	common.Response{data=services.SimulateService}
+// @Failure				500				{object}	common.Response
+func (c *TaskStatisticController) TaskExecSimulate(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.SimulateService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	res, err := req.Simulate(ctx)
+	if err != nil {
+		log.Errorf("simulate task exec plan failed, %s", err)
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+	return
+}
+
 // TaskStatisticGroupByTable	查询表维度统计信息
 // @Router						/task/statistic/table [get]
 // @Description					查询表维度统计信息
@@ -451,3 +479,114 @@ func (c *ClusterStatisticController) ClusterStatisticGroupByDate(ctx *gin.Contex
 	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Data: data})
 	return
 }
+
+// ClusterForecastGroupByTable	预测表维度的容量增长
+// @Router						/cluster/forecast/table [get]
+// @Description					根据历史统计信息拟合表的增长趋势，预测未来容量及达到阈值的日期，并标记快速增长但没有策略的表
+// @Tags						统计信息
+// @Param   					start_date			query		string     	false  	"参与拟合的历史数据开始日期"
+// @Param   					end_date			query		string     	false  	"参与拟合的历史数据结束日期"
+// @Param   					days				query		string     	false  	"预测未来多少天的容量，默认90天"
+// @Param   					threshold			query		string     	false  	"容量阈值(GB)"
+// @Param   					fast_growth_rate	query		string     	false  	"月增长率超过该值(%)视为快速增长，默认10"
+// @Param   					bu					query		string     	false  	"BU"
+// @Param   					cluster_id			query		string     	false  	"集群ID"
+// @Param   					cluster_name		query		string     	false  	"集群名称"
+// @Param   					database			query		string     	false  	"库名"
+// @Param   					table				query		string     	false  	"表名"
+// @Success						200					{object}	common.Response{data=services.ClusterForecast}
+// @Failure						500					{object}	common.Response
+func (c *ClusterStatisticController) ClusterForecastGroupByTable(ctx *gin.Context) {
+	queryMap := make(map[string]string, 10)
+	queryMap["bu"] = ctx.Query("bu")
+	queryMap["cluster_id"] = ctx.Query("cluster_id")
+	queryMap["cluster_name"] = ctx.Query("cluster_name")
+	queryMap["`database`"] = ctx.Query("database")
+	queryMap["`table`"] = ctx.Query("table")
+
+	clusterForecast(ctx, "table", queryMap)
+}
+
+// ClusterForecastGroupByDatabase	预测库维度的容量增长
+// @Router							/cluster/forecast/database [get]
+// @Description						根据历史统计信息拟合库的增长趋势，预测未来容量及达到阈值的日期
+// @Tags							统计信息
+// @Param   						start_date			query		string     	false  	"参与拟合的历史数据开始日期"
+// @Param   						end_date			query		string     	false  	"参与拟合的历史数据结束日期"
+// @Param   						days				query		string     	false  	"预测未来多少天的容量，默认90天"
+// @Param   						threshold			query		string     	false  	"容量阈值(GB)"
+// @Param   						fast_growth_rate	query		string     	false  	"月增长率超过该值(%)视为快速增长，默认10"
+// @Param   						bu					query		string     	false  	"BU"
+// @Param   						cluster_id			query		string     	false  	"集群ID"
+// @Param   						cluster_name		query		string     	false  	"集群名称"
+// @Param   						database			query		string     	false  	"库名"
+// @Success							200					{object}	common.Response{data=services.ClusterForecast}
+// @Failure							500					{object}	common.Response
+func (c *ClusterStatisticController) ClusterForecastGroupByDatabase(ctx *gin.Context) {
+	queryMap := make(map[string]string, 10)
+	queryMap["bu"] = ctx.Query("bu")
+	queryMap["cluster_id"] = ctx.Query("cluster_id")
+	queryMap["cluster_name"] = ctx.Query("cluster_name")
+	queryMap["`database`"] = ctx.Query("database")
+
+	clusterForecast(ctx, "database", queryMap)
+}
+
+// ClusterForecastGroupByCluster	预测集群维度的容量增长
+// @Router							/cluster/forecast/cluster [get]
+// @Description						根据历史统计信息拟合集群大表的增长趋势，预测未来容量、达到阈值的日期及磁盘使用率达到阈值的日期
+// @Tags							统计信息
+// @Param   						start_date			query		string     	false  	"参与拟合的历史数据开始日期"
+// @Param   						end_date			query		string     	false  	"参与拟合的历史数据结束日期"
+// @Param   						days				query		string     	false  	"预测未来多少天的容量，默认90天"
+// @Param   						threshold			query		string     	false  	"容量阈值(GB)"
+// @Param   						fast_growth_rate	query		string     	false  	"月增长率超过该值(%)视为快速增长，默认10"
+// @Param   						bu					query		string     	false  	"BU"
+// @Param   						cluster_id			query		string     	false  	"集群ID"
+// @Param   						cluster_name		query		string     	false  	"集群名称"
+// @Success							200					{object}	common.Response{data=services.ClusterForecast}
+// @Failure							500					{object}	common.Response
+func (c *ClusterStatisticController) ClusterForecastGroupByCluster(ctx *gin.Context) {
+	queryMap := make(map[string]string, 10)
+	queryMap["bu"] = ctx.Query("bu")
+	queryMap["cluster_id"] = ctx.Query("cluster_id")
+	queryMap["cluster_name"] = ctx.Query("cluster_name")
+
+	clusterForecast(ctx, "cluster", queryMap)
+}
+
+// ClusterForecastGroupByBu			预测bu维度的容量增长
+// @Router							/cluster/forecast/bu [get]
+// @Description						根据历史统计信息拟合BU大表的增长趋势，预测未来容量及达到阈值的日期
+// @Tags							统计信息
+// @Param   						start_date			query		string     	false  	"参与拟合的历史数据开始日期"
+// @Param   						end_date			query		string     	false  	"参与拟合的历史数据结束日期"
+// @Param   						days				query		string     	false  	"预测未来多少天的容量，默认90天"
+// @Param   						threshold			query		string     	false  	"容量阈值(GB)"
+// @Param   						fast_growth_rate	query		string     	false  	"月增长率超过该值(%)视为快速增长，默认10"
+// @Param   						bu					query		string     	false  	"BU"
+// @Success							200					{object}	common.Response{data=services.ClusterForecast}
+// @Failure							500					{object}	common.Response
+func (c *ClusterStatisticController) ClusterForecastGroupByBu(ctx *gin.Context) {
+	queryMap := make(map[string]string, 10)
+	queryMap["bu"] = ctx.Query("bu")
+
+	clusterForecast(ctx, "bu", queryMap)
+}
+
+func clusterForecast(ctx *gin.Context, groupBy string, queryMap map[string]string) {
+	svc := services.ClusterForecast{
+		StartDate:      ctx.Query("start_date"),
+		EndDate:        ctx.Query("end_date"),
+		Days:           ctx.Query("days"),
+		Threshold:      ctx.Query("threshold"),
+		FastGrowthRate: ctx.Query("fast_growth_rate"),
+	}
+	data, code, err := svc.ClusterForecastDetail(ctx, groupBy, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Data: data})
+	return
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+)
+
+type SubTaskController struct{}
+
+// QuerySubTask		查询任务按库拆分的子任务
+// @Router				/task/subtask [get]
+// @Description			查询源端包含多个分库时任务按库拆分的子任务及各库的执行结果
+// @Tags				任务
+// @Param   			page			query		int			false  	"page"
+// @Param   			pageSize		query		int     	false  	"pageSize"
+// @Param   			task_id			query		uint     	false  	"任务ID"
+// @Success				200		{object}	common.Response{data=services.SubTaskService}
+// @Failure				500		{object}	common.Response
+func (c *SubTaskController) QuerySubTask(ctx *gin.Context) {
+	subTask := services.SubTaskService{
+		TaskID: common.ParsingQueryUintID(ctx.Query("task_id")),
+	}
+	data, code, err := subTask.QuerySubTask(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(code), common.Response{ServiceCode: code, Data: data})
+	return
+}
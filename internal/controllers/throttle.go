@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/services"
+	"net/http"
+)
+
+type ThrottleController struct{}
+
+// CreateThrottle	创建限流配置
+// @Router			/manage/throttle [post]
+// @Description		创建限流配置，策略引用后删除和归档时按该配置限流
+// @Tags			限流配置
+// @Param			throttle	body		services.ThrottleService	true	"限流配置"
+// @Success			200			{object}	common.Response{data=services.ThrottleService}
+// @Failure			500			{object}	common.Response
+func (c *ThrottleController) CreateThrottle(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.ThrottleService{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr, Error: err.Error()})
+		return
+	}
+
+	// 参数校验
+	if ok, res, err := req.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", req, err)
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+
+	res, err := req.CreateThrottle(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// UpdateThrottle	更新限流配置
+// @Router			/manage/throttle [put]
+// @Description		更新限流配置，引用该配置执行中的任务会在工作流下次查询限流参数时生效
+// @Tags			限流配置
+// @Param			throttle	body		services.ThrottleService	true	"限流配置"
+// @Success			200			{object}	common.Response{data=services.ThrottleService}
+// @Failure			500			{object}	common.Response
+func (c *ThrottleController) UpdateThrottle(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.ThrottleService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+		SleepMs:          common.InvalidInt,
+		MaxRowsPerSecond: common.InvalidInt,
+		MaxTxnBytes:      common.InvalidInt64,
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid Throttle.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.UpdateThrottle(ctx)
+	if res.Code != common.CodeOK.Code {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: req})
+}
+
+// DeleteThrottle	删除限流配置
+// @Router			/manage/throttle [delete]
+// @Description		删除限流配置，被策略或未结束的任务引用时不能删除
+// @Tags			限流配置
+// @Param			throttle	body		services.ThrottleService	true	"限流配置"
+// @Success			200			{object}	common.Response
+// @Failure			500			{object}	common.Response
+func (c *ThrottleController) DeleteThrottle(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+	req := &services.ThrottleService{
+		Model: services.Model{
+			ID: common.InvalidUint,
+		},
+	}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.ID) {
+		log.Errorf("invalid Throttle.id(%d)", req.ID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	res, err := req.DeleteThrottle(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res})
+}
+
+// QueryThrottle  	查询限流配置
+// @Router			/manage/throttle [get]
+// @Description		查询限流配置
+// @Tags			限流配置
+// @Param   		page			query		int			false  	"page"
+// @Param   		pageSize		query		int     	false  	"pageSize"
+// @Param   		id				query		uint     	false  	"限流配置ID"
+// @Param   		name			query		string     	false  	"限流配置名称"
+// @Param   		creator			query		string     	false  	"创建人"
+// @Success			200		{object}	common.Response{data=services.ThrottleService}
+// @Failure			500		{object}	common.Response
+func (c *ThrottleController) QueryThrottle(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	queryMap := make(map[string]string, 2)
+	queryMap["name"] = ctx.Query("name")
+	queryMap["creator"] = ctx.Query("creator")
+
+	throttle := services.ThrottleService{Model: services.Model{ID: id}}
+	data, res, err := throttle.QueryThrottle(ctx, queryMap)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: data})
+}
+
+// UpdateTaskThrottle	调整任务的限流配置
+// @Router				/manage/throttle/task [put]
+// @Description			调整未结束任务的限流配置，执行中的任务在工作流下次查询限流参数时生效
+// @Tags				限流配置
+// @Param				throttle	body		services.TaskThrottleService	true	"任务的限流配置"
+// @Success				200			{object}	common.Response{data=types.Throttle}
+// @Failure				500			{object}	common.Response
+func (c *ThrottleController) UpdateTaskThrottle(ctx *gin.Context) {
+	log, _ := common.ExtractContext(ctx)
+
+	req := &services.TaskThrottleService{TaskID: common.InvalidUint}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeBindErr})
+		return
+	}
+
+	if common.InvalidUintID(req.TaskID) {
+		log.Errorf("invalid Task.id(%d)", req.TaskID)
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	data, res, err := req.UpdateTaskThrottle(ctx)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: data})
+}
+
+// QueryTaskThrottle	查询任务当前生效的限流参数
+// @Router				/task/throttle [get]
+// @Description			查询任务当前生效的限流参数，工作流执行过程中定时查询
+// @Tags				任务
+// @Param   			id			query		uint     	true  	"任务ID"
+// @Success				200			{object}	common.Response{data=types.Throttle}
+// @Failure				500			{object}	common.Response
+func (c *ThrottleController) QueryTaskThrottle(ctx *gin.Context) {
+	id := common.ParsingQueryUintID(ctx.Query("id"))
+	if common.InvalidUintID(id) {
+		ctx.JSON(http.StatusBadRequest, common.Response{ServiceCode: common.CodeInvalidID})
+		return
+	}
+
+	data, res, err := services.QueryTaskThrottle(ctx, id)
+	if err != nil {
+		ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Error: err.Error()})
+		return
+	}
+	ctx.JSON(common.ServiceCode2HttpCode(res), common.Response{ServiceCode: res, Data: data})
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/sunkaimr/data-loom/internal/job/workflow"
 	"github.com/sunkaimr/data-loom/internal/middlewares"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/leader"
 	"github.com/sunkaimr/data-loom/internal/services"
 	"time"
 )
@@ -19,8 +20,11 @@ func StartOthersJob(ctx *common.Context) {
 	cleanWorkFlowEntryID := cron.EntryID(0)
 	syncClusterEntryID := cron.EntryID(0)
 	grabClusterTableSizeEntryID := cron.EntryID(0)
+	refreshCatalogEntryID := cron.EntryID(0)
+	refreshPolicyRecommendEntryID := cron.EntryID(0)
+	escalateTaskNoticeEntryID := cron.EntryID(0)
 
-	c := cron.New()
+	c := cron.New(cron.WithChain(leader.FenceJob(ctx)))
 
 	// 定期清理工作流
 	cleanWorkFlowEntryID, err = c.AddFunc(
@@ -74,6 +78,57 @@ func StartOthersJob(ctx *common.Context) {
 		log.Fatalf("add cron job(0 5 1 * *) for GrabClusterTableSize failed %v", err)
 	}
 
+	// 定期对集群的表结构做快照
+	refreshCatalogEntryID, err = c.AddFunc(
+		"30 5 * * *", /* 每天早上5点30分快照一次 */
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running RefreshCatalog")
+			RefreshCatalog(ctx)
+			log.Debugf("running RefreshCatalog done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run RefreshCatalog at %s", c.Entry(refreshCatalogEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(30 5 * * *) for RefreshCatalog failed %v", err)
+	}
+
+	// 大表统计完成后为没有策略覆盖的大表生成策略推荐
+	refreshPolicyRecommendEntryID, err = c.AddFunc(
+		"0 9 1 * *", /* 每月1号早上9点推荐一次 */
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running RefreshPolicyRecommend")
+			RefreshPolicyRecommend(ctx)
+			log.Debugf("running RefreshPolicyRecommend done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run RefreshPolicyRecommend at %s", c.Entry(refreshPolicyRecommendEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(0 9 1 * *) for RefreshPolicyRecommend failed %v", err)
+	}
+
+	// 任务失败超过通知路由规则设置的时长仍未处理时升级通知
+	escalateTaskNoticeEntryID, err = c.AddFunc(
+		"*/10 * * * *",
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running EscalateTaskNotice")
+			services.EscalateTaskNotice(ctx)
+			log.Debugf("running EscalateTaskNotice done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run EscalateTaskNotice at %s", c.Entry(escalateTaskNoticeEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(*/10 * * * *) for EscalateTaskNotice failed %v", err)
+	}
+
 	c.Start()
 
 	<-ctx.Context.Done()
@@ -88,6 +143,9 @@ func SyncClusterJob(ctx *common.Context) {
 	ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
 	cluster := &services.ClusterService{}
 	_, _ = cluster.SyncCluster(ginCtx)
+
+	// 主从切换后写库和读库地址会失效，需重新发现集群拓扑
+	_, _ = cluster.RefreshClusterTopology(ginCtx)
 }
 
 // GrabClusterTableSize 定期抓取集群各表的大小
@@ -97,3 +155,53 @@ func GrabClusterTableSize(ctx *common.Context) {
 
 	_, _ = (&services.ClusterService{}).RefreshClusterBigTables(middlewares.NewGinContext(ctx.Log, ctx.DB))
 }
+
+// RefreshCatalog 定期对集群的表结构做快照
+func RefreshCatalog(ctx *common.Context) {
+	ctx.Wg.Add(1)
+	defer ctx.Wg.Done()
+
+	_, _ = (&services.CatalogTableService{}).RefreshCatalog(middlewares.NewGinContext(ctx.Log, ctx.DB))
+}
+
+// RefreshPolicyRecommend 定期为没有策略覆盖的大表生成策略推荐
+func RefreshPolicyRecommend(ctx *common.Context) {
+	ctx.Wg.Add(1)
+	defer ctx.Wg.Done()
+
+	_, _ = (&services.PolicyRecommendService{}).RefreshPolicyRecommend(middlewares.NewGinContext(ctx.Log, ctx.DB))
+}
+
+// StartReloadConfigJob 定期从数据库重新加载配置，所有副本都需要运行，API服务使用的配置在其他副本修改后也能及时生效
+func StartReloadConfigJob(ctx *common.Context) {
+	ctx.Wg.Add(1)
+	defer ctx.Wg.Done()
+	log := ctx.Log
+
+	reloadConfigEntryID := cron.EntryID(0)
+
+	c := cron.New()
+
+	var err error
+	reloadConfigEntryID, err = c.AddFunc(
+		"@every 1m",
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running ReloadConfig")
+			services.Cfg.ReloadConfig(middlewares.NewGinContext(ctx.Log, ctx.DB))
+			log.Debugf("running ReloadConfig done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run ReloadConfig at %s", c.Entry(reloadConfigEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(@every 1m) for ReloadConfig failed %v", err)
+	}
+
+	c.Start()
+
+	<-ctx.Context.Done()
+	c.Stop()
+	log.Info("shutdown reload config job")
+}
@@ -1,7 +1,6 @@
 package job
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/robfig/cron/v3"
@@ -11,13 +10,14 @@ import (
 	"github.com/sunkaimr/data-loom/internal/middlewares"
 	"github.com/sunkaimr/data-loom/internal/models"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/leader"
 	. "github.com/sunkaimr/data-loom/internal/pkg/queue"
 	"github.com/sunkaimr/data-loom/internal/services"
 	workflow1 "github.com/sunkaimr/data-loom/internal/workflow"
 	"github.com/sunkaimr/data-loom/pkg/utils"
 	"gorm.io/gorm"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,7 +25,6 @@ import (
 func StartTaskJob(ctx *common.Context) {
 	ctx.Wg.Add(1)
 	defer ctx.Wg.Done()
-	var err error
 	log := ctx.Log
 
 	go PolicyHandle(ctx)
@@ -37,9 +36,14 @@ func StartTaskJob(ctx *common.Context) {
 	runWorkflowEntryID := cron.EntryID(0)
 	checkWorkflowTimeoutEntryID := cron.EntryID(0)
 	checkDiskUsageEntryID := cron.EntryID(0)
+	checkSourceLoadEntryID := cron.EntryID(0)
+	checkSourcePrimaryEntryID := cron.EntryID(0)
+	checkExecWindowPauseEntryID := cron.EntryID(0)
+	checkBlackoutStopEntryID := cron.EntryID(0)
 
-	c := cron.New()
+	c := cron.New(cron.WithChain(leader.FenceJob(ctx)))
 
+	var err error
 	// 一天运行一次：运行策略调度任务，将策略实例化为任务
 	policyEntryID, err = c.AddFunc(
 		configs.C.Job.PolicyCron,
@@ -142,6 +146,74 @@ func StartTaskJob(ctx *common.Context) {
 		log.Fatalf("add cron job(@every 10s) for RunTaskWorkFlow failed %v", err)
 	}
 
+	// 检查源端负载，连续多次超过设定值自动停止工作流
+	checkSourceLoadEntryID, err = c.AddFunc(
+		"@every 10s",
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running CheckSourceLoad")
+			CheckSourceLoad(ctx)
+			log.Debugf("running CheckSourceLoad done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run CheckSourceLoad at %s", c.Entry(checkSourceLoadEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(@every 10s) for CheckSourceLoad failed %v", err)
+	}
+
+	// 检查源端主库是否发生切换，切换后停止正在执行的工作流
+	checkSourcePrimaryEntryID, err = c.AddFunc(
+		"@every 1m",
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running CheckSourcePrimary")
+			CheckSourcePrimary(ctx)
+			log.Debugf("running CheckSourcePrimary done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run CheckSourcePrimary at %s", c.Entry(checkSourcePrimaryEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(@every 1m) for CheckSourcePrimary failed %v", err)
+	}
+
+	// 执行窗口外需要暂停的任务超出执行窗口后停止工作流，等待下一个执行窗口继续执行
+	checkExecWindowPauseEntryID, err = c.AddFunc(
+		"@every 1m",
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running CheckExecWindowPause")
+			CheckExecWindowPause(ctx)
+			log.Debugf("running CheckExecWindowPause done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run CheckExecWindowPause at %s", c.Entry(checkExecWindowPauseEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(@every 1m) for CheckExecWindowPause failed %v", err)
+	}
+
+	// 执行中的任务进入封网期间后停止工作流，等待封网结束后继续执行
+	checkBlackoutStopEntryID, err = c.AddFunc(
+		"@every 1m",
+		func() {
+			ctx.Wg.Add(1)
+			defer ctx.Wg.Done()
+
+			sTime := time.Now()
+			log.Debugf("running CheckBlackoutStop")
+			CheckBlackoutStop(ctx)
+			log.Debugf("running CheckBlackoutStop done, cost:%v", time.Now().Sub(sTime))
+			log.Debugf("next run CheckBlackoutStop at %s", c.Entry(checkBlackoutStopEntryID).Next.Format(time.DateTime))
+		})
+	if err != nil {
+		log.Fatalf("add cron job(@every 1m) for CheckBlackoutStop failed %v", err)
+	}
+
 	c.Start()
 	log.Debugf("next run CreateOrUpdateTaskByAllPolicies at %s", c.Entry(policyEntryID).Next.Format(time.DateTime))
 	log.Debugf("next run CheckScheduledTask at %s", c.Entry(taskEntryID).Next.Format(time.DateTime))
@@ -176,6 +248,9 @@ func PolicyHandle(ctx *common.Context) {
 			newCtx.SetData(HandleID, handle.HandleID)
 			newCtx.Log.With(ProcDequeue).Infof("policy(%v) out of queue", handle.ID)
 			AttemptCreateOrUpdateTask(newCtx, v.ID)
+			if err := PolicyQueue.Done(v, nil); err != nil {
+				newCtx.Log.Errorf("mark policy(%v) queue item done failed, %s", v.ID, err)
+			}
 		}
 	}
 }
@@ -212,6 +287,13 @@ func TaskHandle(ctx *common.Context) {
 			} else {
 				newCtx.Log.With(ProcSupplemented).Infof("supplementary task(%v) information", v.ID)
 			}
+			// 只有处理异常时才将队列元素标记为处理失败，检查未通过的任务由RecheckSupplementFailedTask重新入队列
+			if errors.As(err, &supplementNotPassedError{}) {
+				err = nil
+			}
+			if err = TaskQueue.Done(v, err); err != nil {
+				newCtx.Log.Errorf("mark task(%v) queue item done failed, %s", v.ID, err)
+			}
 		}
 	}
 }
@@ -281,8 +363,12 @@ func RunTaskWorkFlow(ctx *common.Context) {
 	admissionFunc := []admission{
 		TaskExecDateAdmission,     // 达到执行日期
 		TaskInExecWindowAdmission, // 任务是否在执行窗口
+		TaskBlackoutAdmission,     // 是否处于封网期间
+		TaskDependencyAdmission,   // 依赖的策略在本周期的任务是否执行成功
 		TaskParallelAdmission,     // 最大并发校验
+		TaskQuotaAdmission,        // BU和集群的并发配额校验
 		TaskConflictAdmission,     // 判断和当前任务的源集群是否冲突
+		TaskSourceLoadAdmission,   // 源端集群负载是否过高
 	}
 
 	var filterTasks []models.Task
@@ -314,7 +400,10 @@ func RunTaskWorkFlow(ctx *common.Context) {
 				log.Errorf("update models.Task(ID=%v) from db failed, %s", task.ID, err)
 			}
 
-			if task.TaskReason != tasks[i].TaskReason || task.TaskStatus != tasks[i].TaskStatus {
+			if task.TaskStatus == common.TaskStatusSkipped {
+				services.CreateTaskChangeLog(ctx, &task, common.SystemUserName,
+					fmt.Sprintf(common.TaskChangeLogDependencySkipped, task.TaskDetail))
+			} else if task.TaskReason != tasks[i].TaskReason || task.TaskStatus != tasks[i].TaskStatus {
 				services.CreateTaskChangeLog(ctx, &task, common.SystemUserName,
 					fmt.Sprintf(common.TaskChangeLogWaitingExec, task.TaskReason, task.TaskDetail))
 			}
@@ -324,39 +413,8 @@ func RunTaskWorkFlow(ctx *common.Context) {
 		filterTasks = append(filterTasks, tasks[i])
 	}
 
-	// 排序
-	sort.Slice(filterTasks, func(i, j int) bool {
-		// 执行窗口
-		res, err := compareExecWindows(filterTasks[i].ExecuteWindow.String(), filterTasks[j].ExecuteWindow.String())
-		if err != nil {
-			log.Errorf("compare exec windows task1:(%v) task2:(%v) failed, %s", filterTasks[i].ExecuteWindow.String(), filterTasks[j].ExecuteWindow.String(), err)
-			return false
-		}
-		switch {
-		case res < 0:
-			return true
-		case res > 0:
-			return false
-		}
-
-		// 任务创建时间
-		createTime1 := filterTasks[i].CreatedAt.Format(time.DateOnly)
-		createTime2 := filterTasks[j].CreatedAt.Format(time.DateOnly)
-		switch {
-		case createTime1 < createTime2:
-			return true
-		case createTime1 > createTime2:
-			return false
-		}
-
-		// 等待执行 > 检查失败
-		if filterTasks[i].TaskStatus == common.TaskStatusWaiting && filterTasks[j].TaskStatus == common.TaskStatusWaiting {
-			if filterTasks[i].ID < filterTasks[j].ID {
-				return true
-			}
-		}
-		return false
-	})
+	// 按优先级、超过计划开始时间的时长、执行窗口等排序
+	services.SortTasksByAdmission(filterTasks, time.Now())
 
 	for i, task := range filterTasks {
 		handle := &TaskQueueHandle{ID: task.ID, PolicyID: task.PolicyID, HandleID: utils.RandStr(20)}
@@ -367,6 +425,12 @@ func RunTaskWorkFlow(ctx *common.Context) {
 			WithDB(ctx.DB)
 		log := newCtx.Log
 
+		// 提交工作流前校验本副本仍是主，失去租约后不再提交
+		if err := leader.Check(ctx); err != nil {
+			log.Warnf("stop running task workflow, %s", err)
+			return
+		}
+
 		// 再次进行最大并发检验
 		executingTask, err := GetExecutingTaskNum(ctx)
 		if err != nil {
@@ -391,9 +455,14 @@ func RunTaskWorkFlow(ctx *common.Context) {
 			continue
 		}
 
-		// 再次进行任务冲突检验
-		ok, code, err := TaskConflictAdmission(newCtx, &task)
-		if !ok || err != nil {
+		// 再次进行并发配额和任务冲突检验
+		pass := true
+		for _, f := range []admission{TaskQuotaAdmission, TaskConflictAdmission} {
+			ok, code, err := f(newCtx, &task)
+			if ok && err == nil {
+				continue
+			}
+			pass = false
 			log.Error(err)
 			task.TaskReason = code.Message
 			task.TaskDetail = err.Error()
@@ -406,6 +475,9 @@ func RunTaskWorkFlow(ctx *common.Context) {
 				services.CreateTaskChangeLog(ctx, &task, common.SystemUserName,
 					fmt.Sprintf(common.TaskChangeLogWaitingExec, task.TaskReason, task.TaskDetail))
 			}
+			break
+		}
+		if !pass {
 			continue
 		}
 
@@ -446,8 +518,9 @@ func JudgeCouldCreateTask(ctx *common.Context, policyID uint) (bool, error) {
 		return false, nil
 	}
 
-	err = ctx.DB.Model(models.Task{}).Where("policy_id=? AND task_status NOT IN (?)",
-		policyID, common.TaskStatusHasFinished).Count(&count).Error
+	// 补录任务独立于策略的正常排期
+	err = ctx.DB.Model(models.Task{}).Where("policy_id=? AND backfill =? AND task_status NOT IN (?)",
+		policyID, false, common.TaskStatusHasFinished).Count(&count).Error
 	if err != nil {
 		return false, fmt.Errorf("query models.Task(policy_id=%v AND task_status NOT IN (%v)) from db failed, %s",
 			policyID, common.TaskStatusHasFinished, err)
@@ -457,8 +530,8 @@ func JudgeCouldCreateTask(ctx *common.Context, policyID uint) (bool, error) {
 
 func JudgeCouldUpdateTask(ctx *common.Context, policyID uint) (bool, error) {
 	var count int64
-	err := ctx.DB.Model(models.Task{}).Where("policy_id =? AND task_status IN (?)",
-		policyID, common.TaskStatusCanUpdate).Count(&count).Error
+	err := ctx.DB.Model(models.Task{}).Where("policy_id =? AND backfill =? AND task_status IN (?)",
+		policyID, false, common.TaskStatusCanUpdate).Count(&count).Error
 	if err != nil {
 		return false, fmt.Errorf("query models.Task(policy_id=%v AND task_status IN (%v)) from db failed, %s",
 			policyID, common.TaskStatusCanUpdate, err)
@@ -466,27 +539,44 @@ func JudgeCouldUpdateTask(ctx *common.Context, policyID uint) (bool, error) {
 	return count != 0, nil
 }
 
-// 是否错过本月的执行日期
-func missedExecDateAndWin(policy *models.Policy) (bool, error) {
+// 是否错过本月的执行日期，now为源端集群所在时区的当前时间
+func missedExecDateAndWin(policy *models.Policy, now time.Time) (bool, error) {
 	switch policy.Period {
 	case common.PeriodMonthly, common.PeriodQuarterly, common.PeriodSixMonths, common.PeriodYearly:
-		if time.Now().Day() > policy.Day {
+		if now.Day() > policy.Day {
 			return true, nil
-		} else if time.Now().Day() == policy.Day {
+		} else if now.Day() == policy.Day {
 			// 判断是否错过当天执行窗口
-			if b, err := timeInExecWindows(time.Now().Format(time.DateOnly), time.Now(), policy.ExecuteWindow); err != nil {
+			if b, err := timeInExecWindows(now.Format(time.DateOnly), now, policy.ExecuteWindow, policy.ExecuteWindows); err != nil {
 				return false, fmt.Errorf("judge time(%s) in exec windows(%s) failed, %s",
-					time.Now().Format(time.DateTime), string(policy.ExecuteWindow), err)
+					now.Format(time.DateTime), string(policy.ExecuteWindow), err)
 			} else if b > 0 {
 				// 已经错过了执行窗口
 				return true, nil
 			}
 		}
+	case common.PeriodCron:
+		schedule, err := common.ParseCronExpr(policy.CronExpr)
+		if err != nil {
+			return false, fmt.Errorf("parse policy(%v) cron_expr(%s) failed, %s", policy.ID, policy.CronExpr, err)
+		}
+		// 今天不满足cron表达式
+		if !schedule.Match(now) {
+			return true, nil
+		}
+		// 判断是否错过当天执行窗口
+		if b, err := timeInExecWindows(now.Format(time.DateOnly), now, policy.ExecuteWindow, policy.ExecuteWindows); err != nil {
+			return false, fmt.Errorf("judge time(%s) in exec windows(%s) failed, %s",
+				now.Format(time.DateTime), string(policy.ExecuteWindow), err)
+		} else if b > 0 {
+			// 已经错过了执行窗口
+			return true, nil
+		}
 	default:
 		// 判断是否错过当天执行窗口
-		if b, err := timeInExecWindows(time.Now().Format(time.DateOnly), time.Now(), policy.ExecuteWindow); err != nil {
+		if b, err := timeInExecWindows(now.Format(time.DateOnly), now, policy.ExecuteWindow, policy.ExecuteWindows); err != nil {
 			return false, fmt.Errorf("judge time(%s) in exec windows(%s) failed, %s",
-				time.Now().Format(time.DateTime), string(policy.ExecuteWindow), err)
+				now.Format(time.DateTime), string(policy.ExecuteWindow), err)
 		} else if b > 0 {
 			// 已经错过了执行窗口
 			return true, nil
@@ -512,12 +602,23 @@ func CreateTask(ctx *common.Context, policy *models.Policy) (*models.Task, error
 		return task, fmt.Errorf("query models.Source(id=%v) from db failed, %s", policy.SrcID, err)
 	}
 
+	// 执行日期和执行窗口按源端集群所在时区计算
+	tz, err := services.ClusterTimezone(ctx.DB, source.ClusterID)
+	if err != nil {
+		return task, err
+	}
+	now := common.NowIn(tz)
+	task.Timezone = tz
+
 	// 如果lastExecDate.IsZero()代表此前已经执行过任务(一次性任务除外)
 	// 此时会根据上次任务的ExecDate日期向后延长一个周期
 	if !lastExecDate.IsZero() && policy.Period != common.PeriodOnce {
-		executeTime := policyNextScheduleTime(policy, lastExecDate)
-		task.ExecuteDate = formatExecDate(policy, executeTime)
-		task = generateEmptyTaskByPolicy(task, policy, source)
+		executeTime, err := services.PolicyNextScheduleTime(policy, lastExecDate)
+		if err != nil {
+			return task, err
+		}
+		task.ExecuteDate = services.FormatExecDate(policy, executeTime)
+		task = services.GenerateTaskByPolicy(task, policy, source)
 		return task, nil
 	}
 
@@ -527,19 +628,20 @@ func CreateTask(ctx *common.Context, policy *models.Policy) (*models.Task, error
 	//    a): day > 当前日期 顺延一个周期
 	//    b): 已经错过了执行窗口 顺延一个周期
 	//    c): day == 当前日期 && 没有错过执行窗口 直接设置当天执行
-	missedExecWin, err := missedExecDateAndWin(policy)
+	missedExecWin, err := missedExecDateAndWin(policy, now)
 	if err != nil {
 		return task, fmt.Errorf("judge policy missed ExecDate and ExecWindow failed, %s", err)
 	}
 
-	executeTime := time.Time{}
+	executeTime := now
 	if missedExecWin {
-		executeTime = policyNextScheduleTime(policy, time.Now())
-	} else {
-		executeTime = time.Now()
+		executeTime, err = services.PolicyNextScheduleTime(policy, now)
+		if err != nil {
+			return task, err
+		}
 	}
-	task.ExecuteDate = formatExecDate(policy, executeTime)
-	task = generateEmptyTaskByPolicy(task, policy, source)
+	task.ExecuteDate = services.FormatExecDate(policy, executeTime)
+	task = services.GenerateTaskByPolicy(task, policy, source)
 
 	return task, nil
 }
@@ -551,6 +653,13 @@ func calculateTaskExecDate(ctx *common.Context, policy *models.Policy, task *mod
 	lastExecDate := time.Time{}
 	newExecDate := ""
 
+	// 执行日期和执行窗口按源端集群所在时区计算
+	tz, err := services.ClusterTimezone(ctx.DB, task.SrcClusterID)
+	if err != nil {
+		return false, err
+	}
+	now := common.NowIn(tz)
+
 	// 对于一次性任务上一次任务的执行日期不应该影响本次任务的执行日期，所以上一次执行日期给空
 	if policy.Period != common.PeriodOnce {
 		// 拿到策略上一次任务的计划执行日期
@@ -562,27 +671,32 @@ func calculateTaskExecDate(ctx *common.Context, policy *models.Policy, task *mod
 
 	if !lastExecDate.IsZero() {
 		// 根据策略的上次期望执行日期计算下一次的预期执行日期
-		newExecDate = formatExecDate(policy, policyNextScheduleTime(policy, lastExecDate))
+		executeTime, err := services.PolicyNextScheduleTime(policy, lastExecDate)
+		if err != nil {
+			return false, err
+		}
+		newExecDate = services.FormatExecDate(policy, executeTime)
 	} else {
 		// 如果策略还没有执行过任务此时返回lastExecDate.IsZero()对于这种情况重新计算执行日期
-		missedExecWin, err := missedExecDateAndWin(policy)
+		missedExecWin, err := missedExecDateAndWin(policy, now)
 		if err != nil {
 			return false, fmt.Errorf("judge policy missed ExecDate and ExecWindow failed, %s", err)
 		}
-		executeTime := time.Time{}
+		executeTime := now
 		if missedExecWin {
-			executeTime = policyNextScheduleTime(policy, time.Now())
-		} else {
-			executeTime = time.Now()
+			executeTime, err = services.PolicyNextScheduleTime(policy, now)
+			if err != nil {
+				return false, err
+			}
 		}
-		newExecDate = formatExecDate(policy, executeTime)
+		newExecDate = services.FormatExecDate(policy, executeTime)
 	}
 
 	// 如果已经错过了执行窗口往后再延一个周期
 	missedExecWin := false
-	if b, err := timeInExecWindows(newExecDate, time.Now(), policy.ExecuteWindow); err != nil {
+	if b, err := timeInExecWindows(newExecDate, now, policy.ExecuteWindow, policy.ExecuteWindows); err != nil {
 		return false, fmt.Errorf("judge execDate(%s) time(%s) in exec windows(%s) failed, %s",
-			newExecDate, time.Now().Format(time.DateTime), string(policy.ExecuteWindow), err)
+			newExecDate, now.Format(time.DateTime), string(policy.ExecuteWindow), err)
 	} else if b > 0 {
 		// 已经错过了执行窗口
 		missedExecWin = true
@@ -592,16 +706,23 @@ func calculateTaskExecDate(ctx *common.Context, policy *models.Policy, task *mod
 	if missedExecWin {
 		// 方式一：顺延2个周期
 		//nextExecTime, _ := time.ParseInLocation(time.DateOnly, newExecDate, time.Now().Location())
-		//newExecDate = formatExecDate(policy, policyNextScheduleTime(&policy, nextExecTime))
+		//newExecDate = services.FormatExecDate(policy, services.PolicyNextScheduleTime(&policy, nextExecTime))
 
 		// 方式二：以当前年月为基准顺延个周期
-		newExecDate = formatExecDate(policy, policyNextScheduleTime(policy, time.Now()))
+		executeTime, err := services.PolicyNextScheduleTime(policy, now)
+		if err != nil {
+			return false, err
+		}
+		newExecDate = services.FormatExecDate(policy, executeTime)
 	}
 
-	if task.ExecuteDate != newExecDate || string(task.ExecuteWindow) != string(policy.ExecuteWindow) {
+	if task.ExecuteDate != newExecDate || string(task.ExecuteWindow) != string(policy.ExecuteWindow) ||
+		string(task.ExecuteWindows) != string(policy.ExecuteWindows) || task.Timezone != tz {
 		task.TaskStatus = common.TaskStatusScheduled
 		task.ExecuteDate = newExecDate
+		task.Timezone = tz
 		task.ExecuteWindow = policy.ExecuteWindow
+		task.ExecuteWindows = policy.ExecuteWindows
 		return true, nil
 	}
 	return false, nil
@@ -613,9 +734,17 @@ func calculateNeedUpdateTaskInfo(_ *common.Context, policy *models.Policy, task
 		policy.Pause != task.Pause ||
 		policy.RebuildFlag != task.RebuildFlag ||
 		policy.CleaningSpeed != task.CleaningSpeed ||
+		policy.ThrottleID != task.ThrottleID ||
 		policy.RetainSrcData != task.RetainSrcData ||
+		policy.PartitionColumn != task.PartitionColumn ||
+		policy.PartitionInterval != task.PartitionInterval ||
+		policy.PartitionRetainDays != task.PartitionRetainDays ||
+		policy.PartitionPrecreate != task.PartitionPrecreate ||
+		policy.PartitionAction != task.PartitionAction ||
+		policy.WatermarkColumn != task.WatermarkColumn ||
 		policy.ArchiveScope != task.SrcColumns ||
 		policy.NotifyPolicy != task.NotifyPolicy ||
+		policy.Priority != task.Priority ||
 		string(policy.Relevant) != string(task.Relevant) {
 
 		// 更新对应任务字段
@@ -623,211 +752,167 @@ func calculateNeedUpdateTaskInfo(_ *common.Context, policy *models.Policy, task
 		task.Pause = policy.Pause
 		task.RebuildFlag = policy.RebuildFlag
 		task.CleaningSpeed = policy.CleaningSpeed
+		task.ThrottleID = policy.ThrottleID
 		task.RetainSrcData = policy.RetainSrcData
+		task.PartitionColumn = policy.PartitionColumn
+		task.PartitionInterval = policy.PartitionInterval
+		task.PartitionRetainDays = policy.PartitionRetainDays
+		task.PartitionPrecreate = policy.PartitionPrecreate
+		task.PartitionAction = policy.PartitionAction
+		task.WatermarkColumn = policy.WatermarkColumn
 		task.SrcColumns = policy.ArchiveScope
 		task.NotifyPolicy = policy.NotifyPolicy
+		task.Priority = policy.Priority
 		task.Relevant = policy.Relevant
 		return true, nil
 	}
 	return false, nil
 }
 
-func generateEmptyTaskByPolicy(task *models.Task, policy *models.Policy, source *models.Source) *models.Task {
-	task.Creator = policy.Creator
-	task.Name = fmt.Sprintf("%s-%s", common.PeriodCN[policy.Period], policy.Name)
-	task.Enable = policy.Enable
-	task.PolicyID = policy.ID
-	task.ExecuteWindow = policy.ExecuteWindow
-
-	task.TaskStartTime = time.UnixMilli(0)
-	task.TaskEndTime = time.UnixMilli(0)
-	task.Pause = policy.Pause
-	task.RebuildFlag = policy.RebuildFlag
-	task.TaskStatus = common.TaskStatusScheduled
-	task.TaskReason = ""
-	task.TaskDetail = ""
-
-	// 源端信息
-	task.SrcID = policy.SrcID
-	task.SrcName = source.Name
-	task.SrcBu = source.Bu
-	task.SrcClusterID = source.ClusterID
-	task.SrcClusterName = source.ClusterName
-	task.SrcDatabaseName = source.DatabaseName
-	task.SrcTablesName = source.TablesName
-	task.SrcColumns = policy.ArchiveScope
-
-	// 数据清理方式
-	task.Govern = policy.Govern
-	task.Condition = policy.Condition
-	task.RetainSrcData = policy.RetainSrcData
-	task.CleaningSpeed = policy.CleaningSpeed
-
-	task.Relevant = policy.Relevant
-	task.NotifyPolicy = policy.NotifyPolicy
-	return task
-}
-
-func policyNextScheduleTime(policy *models.Policy, lastExecDate time.Time) time.Time /*仅精确到天*/ {
-	if lastExecDate.IsZero() {
-		return time.Now()
-	}
-	switch policy.Period {
-	case common.PeriodDay:
-		return lastExecDate.Add(time.Hour * 24)
-	case common.PeriodTwoDay:
-		return lastExecDate.Add(time.Hour * 24 * 2)
-	case common.PeriodWeekly:
-		return lastExecDate.Add(time.Hour * 24 * 7)
-	case common.PeriodTwoWeeks:
-		return lastExecDate.Add(time.Hour * 24 * 14)
-	case common.PeriodOnce:
-		return lastExecDate.Add(time.Hour * 24)
-	case common.PeriodMonthly:
-		return lastExecDate.AddDate(0, 1, 0)
-	case common.PeriodQuarterly:
-		return lastExecDate.AddDate(0, 3, 0)
-	case common.PeriodSixMonths:
-		return lastExecDate.AddDate(0, 6, 0)
-	case common.PeriodYearly:
-		return lastExecDate.AddDate(1, 0, 0)
-	default:
-		return time.Now()
+func timeInExecWindows(execDate string, execTime time.Time, execWindow, execWindows []byte) (int, error) {
+	windows, err := common.ParseExecWindows(execWindow, execWindows)
+	if err != nil {
+		return 0, err
 	}
+	return services.ExecWindowPosition(windows, execDate, execTime)
 }
 
-func timeInExecWindows(execDate string, execTime time.Time, execWindow []byte) (int, error) {
-	// 没有到执行日期
-	if execDate > execTime.Format(time.DateOnly) {
-		return -1, nil
-	}
-
-	var executeWin []string
-	err := json.Unmarshal(execWindow, &executeWin)
+func TaskInExecWindowAdmission(_ *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
+	// 判断任务是否在执行窗口 && 剩余窗口是否支持任务执行完毕
+	windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows)
 	if err != nil {
-		return 0, fmt.Errorf("unmarshal execWindow(%s) failed, %s", string(execWindow), err)
-	}
-	// 防止panic
-	if len(executeWin) < 2 {
-		executeWin = append(executeWin, "")
+		return false, common.CodeServerErr, fmt.Errorf("judge task(%v) in exec windows %s failed, %s", task.Name, string(task.ExecuteWindow), err)
 	}
+	return services.ExecWindowAdmission(windows, task, common.NowIn(task.Timezone))
+}
 
-	startTime, err := time.ParseInLocation(time.DateTime, execTime.Format("2006-01-02 ")+executeWin[0], time.Now().Location())
+// TaskBlackoutAdmission 处于全局、任务所属BU或源端集群的封网期间时禁止执行
+func TaskBlackoutAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
+	blackouts, err := services.QueryEffectiveBlackouts(ctx.DB, task.SrcBu, task.SrcClusterID)
 	if err != nil {
-		return 0, fmt.Errorf("parse execWindow(%s) failed, %s", string(execWindow), err)
+		return false, common.CodeServerErr, err
 	}
-	endTime, err := time.ParseInLocation(time.DateTime, execTime.Format("2006-01-02 ")+executeWin[1], time.Now().Location())
+	windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows)
 	if err != nil {
-		return 0, fmt.Errorf("parse execWindow(%s) failed, %s", string(execWindow), err)
-	}
-
-	// 情况一：结束时间大于开始时间：没有跨天，比如：02:00:00 - 06:00:00
-
-	// 情况二：结束时间小于等于开始时间： 跨天了，比如：22:00:00 - 05:00:00
-	if executeWin[1] <= executeWin[0] {
-		if execDate <= execTime.Format(time.DateOnly) {
-			endTime = endTime.Add(time.Hour * 24)
-		} else {
-			startTime = startTime.Add(time.Hour * -24)
-		}
+		return false, common.CodeServerErr, fmt.Errorf("parse task(%v) exec windows failed, %s", task.ID, err)
 	}
 
-	if execTime.Before(startTime) {
-		// 在执行窗口之前
-		return -1, nil
-	} else if execTime.After(endTime) {
-		// 在执行窗口之后
-		return 1, nil
-	} else {
-		// 刚好在执行窗口
-		return 0, nil
-	}
+	ok, _, code, err := services.BlackoutAdmission(blackouts, windows, task, common.NowIn(task.Timezone))
+	return ok, code, err
 }
 
-func execWindowsToTime(execWindow []byte) ([2]time.Time, error) {
-	var executeWinTime [2]time.Time
+// TaskDependencyAdmission 依赖的策略在本周期的任务执行成功后才允许执行。
+// 本周期指本策略上一个任务的执行日期(不含)到本任务的执行日期(含)，依赖的策略在本周期没有任务且不会再生成任务时不做限制。
+// 依赖的策略任务执行失败时根据策略的depend_fail_action处理：阻塞等待、跳过本周期任务或通知后继续执行
+func TaskDependencyAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
+	db := ctx.DB
 
-	var executeWinStr []string
-	err := json.Unmarshal(execWindow, &executeWinStr)
+	var policy models.Policy
+	err := db.Model(models.Policy{}).Select("id, depends_on, depend_fail_action").Where("id =?", task.PolicyID).First(&policy).Error
 	if err != nil {
-		return executeWinTime, fmt.Errorf("unmarshal execWindow(%s) failed, %s", string(execWindow), err)
+		return false, common.CodeServerErr, fmt.Errorf("query models.Policy(id=%v) from db failed, %s", task.PolicyID, err)
 	}
-	// 防止panic
-	if len(executeWinStr) < 2 {
-		executeWinStr = append(executeWinStr, "")
+	dependsOn := services.ParsePolicyDependsOn(policy.DependsOn)
+	if len(dependsOn) == 0 {
+		return true, common.CodeOK, nil
 	}
 
-	startTime, err := time.ParseInLocation(time.TimeOnly, executeWinStr[0], time.Now().Location())
-	if err != nil {
-		return executeWinTime, fmt.Errorf("parse execWindow(%s) failed, %s", string(execWindow), err)
-	}
-	endTime, err := time.ParseInLocation(time.TimeOnly, executeWinStr[1], time.Now().Location())
+	// 本策略上一个任务的执行日期，补录任务不参与依赖判断
+	var lastTasks []models.Task
+	err = db.Model(models.Task{}).Select("execute_date").
+		Where("policy_id =? AND id !=? AND backfill =? AND task_status IN (?)", task.PolicyID, task.ID, false, common.TaskStatusExecTimeImmutable).
+		Order("execute_date desc").Limit(1).Find(&lastTasks).Error
 	if err != nil {
-		return executeWinTime, fmt.Errorf("parse execWindow(%s) failed, %s", string(execWindow), err)
+		return false, common.CodeServerErr, fmt.Errorf("query last models.Task(policy_id=%v) from db failed, %s", task.PolicyID, err)
 	}
-
-	// 跨天了 endTime < startTime
-	if endTime.Before(startTime) {
-		endTime = endTime.Add(time.Hour * 24)
+	lastExecDate := ""
+	if len(lastTasks) != 0 {
+		lastExecDate = lastTasks[0].ExecuteDate
 	}
-	executeWinTime[0] = startTime
-	executeWinTime[1] = endTime
-	return executeWinTime, nil
-}
 
-// 比较execWin1和execWin2的时间先后
-// -1：execWin1早于execWin2
-// 0：execWin1相同execWin2
-// 1：execWin1晚于execWin2
-func compareExecWindows(execWin1, execWin2 string) (int, error) {
-	execTime1, err1 := execWindowsToTime([]byte(execWin1))
-	if err1 != nil {
-		return 0, fmt.Errorf("compare exec windows failed, %s", err1)
-	}
-	execTime2, err2 := execWindowsToTime([]byte(execWin2))
-	if err2 != nil {
-		return 0, fmt.Errorf("compare exec windows failed, %s", err2)
-	}
+	var failed []string
+	for _, id := range dependsOn {
+		var upstream []models.Policy
+		err = db.Model(models.Policy{}).Select("id, enable, period, day, cron_expr").Where("id =?", id).Find(&upstream).Error
+		if err != nil {
+			return false, common.CodeServerErr, fmt.Errorf("query models.Policy(id=%v) from db failed, %s", id, err)
+		}
+		// 依赖的策略已删除或未生效时不做限制
+		if len(upstream) == 0 || !upstream[0].Enable {
+			continue
+		}
 
-	if execTime1[0] == execTime2[0] {
-		return 0, nil
-	}
+		var upTasks []models.Task
+		err = db.Model(models.Task{}).Select("id, task_status").
+			Where("policy_id =? AND backfill =? AND execute_date >? AND execute_date <=?", id, false, lastExecDate, task.ExecuteDate).
+			Order("id desc").Find(&upTasks).Error
+		if err != nil {
+			return false, common.CodeServerErr, fmt.Errorf("query models.Task(policy_id=%v) from db failed, %s", id, err)
+		}
+		if len(upTasks) == 0 {
+			// 依赖的策略本周期的任务尚未生成时需等待其生成并执行成功
+			upLastExecDate, err := GetPolicyActualLastExecDate(ctx, id)
+			if err != nil {
+				return false, common.CodeServerErr, err
+			}
+			scheduled, err := services.DependencyScheduledBefore(&upstream[0], upLastExecDate, task.ExecuteDate)
+			if err != nil {
+				return false, common.CodeServerErr, fmt.Errorf("calculate policy(%v) next schedule time failed, %s", id, err)
+			}
+			if scheduled {
+				return false, common.CodeTaskDependencyNotReady,
+					fmt.Errorf("depends on policy(%v) whose task on or before %s has not been created", id, task.ExecuteDate)
+			}
+			continue
+		}
 
-	if execTime1[0].Before(execTime2[0]) {
-		return -1, nil
-	}
-	return 1, nil
-}
+		success := false
+		for _, t := range upTasks {
+			if t.TaskStatus == common.TaskStatusSuccess {
+				success = true
+				break
+			}
+		}
+		if success {
+			continue
+		}
 
-func formatExecDate(policy *models.Policy, executeTime time.Time) string {
-	switch policy.Period {
-	case common.PeriodMonthly, common.PeriodQuarterly, common.PeriodSixMonths, common.PeriodYearly:
-		maxDay := utils.DaysInMonth(executeTime.Year(), int(executeTime.Month()))
-		if policy.Day > maxDay {
-			return fmt.Sprintf("%d-%02d-%02d", executeTime.Year(), executeTime.Month(), maxDay)
+		latest := upTasks[0]
+		if !utils.ElementExist(latest.TaskStatus, common.TaskStatusHasFinished) {
+			return false, common.CodeTaskDependencyNotReady,
+				fmt.Errorf("depends on policy(%v) task(%v) which is %s", id, latest.ID, latest.TaskStatus)
 		}
-		return fmt.Sprintf("%d-%02d-%02d", executeTime.Year(), executeTime.Month(), policy.Day)
-	default:
-		return executeTime.Format(time.DateOnly)
+		failed = append(failed, fmt.Sprintf("policy(%v) task(%v) %s", id, latest.ID, latest.TaskStatus))
 	}
-}
 
-func TaskInExecWindowAdmission(_ *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
-	// 判断任务是否在执行窗口 && 剩余窗口是否支持任务执行完毕
-	inWin, err := timeInExecWindows(task.ExecuteDate, time.Now(), task.ExecuteWindow)
-	if err != nil {
-		return false, common.CodeServerErr, fmt.Errorf("judge task(%v) in exec windows %s failed, %s", task.Name, string(task.ExecuteWindow), err)
+	if len(failed) == 0 {
+		return true, common.CodeOK, nil
 	}
 
-	switch inWin {
-	case -1:
-		// 在执行窗口之前
-		return false, common.CodeTaskNotReachedExecWin, fmt.Errorf("task has not yet reached the execution time window%s", string(task.ExecuteWindow))
-	case 1:
-		// 在执行窗口之后
-		return false, common.CodeTaskMissedExecWin, fmt.Errorf("task missed exec windows%s", string(task.ExecuteWindow))
+	detail := fmt.Sprintf("depends on %s", strings.Join(failed, ", "))
+	switch policy.DependFailAction {
+	case common.DependFailActionSkip:
+		task.TaskStatus = common.TaskStatusSkipped
+		return false, common.CodeTaskDependencyFailed, fmt.Errorf("skip task, %s", detail)
+	case common.DependFailActionNotify:
+		// 仅通知一次，通知后继续执行
+		content := fmt.Sprintf(common.TaskChangeLogDependencyNotify, detail)
+		var count int64
+		err = db.Model(models.TaskChangeLog{}).Where("task_id =? AND content =?", task.ID, content).Count(&count).Error
+		if err != nil {
+			return false, common.CodeServerErr, fmt.Errorf("query models.TaskChangeLog(task_id=%v) from db failed, %s", task.ID, err)
+		}
+		if count == 0 {
+			services.CreateTaskChangeLog(ctx, task, common.SystemUserName, content)
+			notifyTask := *task
+			notifyTask.TaskReason = common.CodeTaskDependencyFailed.Message
+			notifyTask.TaskDetail = detail
+			services.SendTaskNotice(ctx, &notifyTask)
+		}
+		return true, common.CodeOK, nil
+	default:
+		return false, common.CodeTaskDependencyFailed, fmt.Errorf("task blocked, %s", detail)
 	}
-	return true, common.CodeOK, nil
 }
 
 func CheckSource(ctx *common.Context, source *models.Source) (bool, common.ServiceCode, error) {
@@ -875,6 +960,164 @@ func CheckSource(ctx *common.Context, source *models.Source) (bool, common.Servi
 	return true, common.CodeOK, nil
 }
 
+// ResolveTaskSourceDatabases 按源端的库名匹配规则重新匹配源库，匹配结果变化时记录任务变更日志
+func ResolveTaskSourceDatabases(ctx *common.Context, source *models.Source, task *models.Task) ([]string, common.ServiceCode, error) {
+	ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
+
+	clusterSvc, err := services.GetClusterServiceByClusterID(ginCtx, source.ClusterID)
+	if err != nil {
+		return nil, common.CodeServerErr, fmt.Errorf("get cluster_id(%s) failed, %s", source.ClusterID, err)
+	}
+
+	databases, code, err := services.ResolveSourceDatabases(ginCtx, clusterSvc, source.DatabasePatternType, source.DatabasePattern)
+	if err != nil {
+		return nil, code, err
+	}
+
+	databasesName := strings.Join(databases, ",")
+	if databasesName != source.DatabasesName {
+		oldDatabases := strings.Split(source.DatabasesName, ",")
+		added := utils.RemoveSubSlices(databases, oldDatabases)
+		removed := utils.RemoveSubSlices(oldDatabases, databases)
+		services.CreateTaskChangeLog(ctx, task, common.SystemUserName,
+			fmt.Sprintf(common.TaskChangeLogSourceDatabasesChanged, strings.Join(added, ","), strings.Join(removed, ",")))
+
+		err = ctx.DB.Model(source).Updates(map[string]any{"database_name": databases[0], "databases_name": databasesName}).Error
+		if err != nil {
+			return nil, common.CodeServerErr, fmt.Errorf("update models.Source(%v) databases_name failed, %s", source.ID, err)
+		}
+		source.DatabaseName = databases[0]
+		source.DatabasesName = databasesName
+	}
+	task.SrcDatabaseName = source.DatabaseName
+	return databases, common.CodeOK, nil
+}
+
+// ResolveTaskSourceTables 按源端的表名匹配规则重新匹配源表并记录到任务上，匹配结果变化或有表因不符合分表命名规则被跳过时记录任务变更日志
+func ResolveTaskSourceTables(ctx *common.Context, source *models.Source, task *models.Task) (common.ServiceCode, error) {
+	ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
+
+	clusterSvc, err := services.GetClusterServiceByClusterID(ginCtx, source.ClusterID)
+	if err != nil {
+		return common.CodeServerErr, fmt.Errorf("get cluster_id(%s) failed, %s", source.ClusterID, err)
+	}
+
+	tables, skipped, code, err := services.ResolveSourceTables(ginCtx, clusterSvc, source.DatabaseName, source.TablesPatternType, source.TablesPattern)
+	if err != nil {
+		return code, err
+	}
+	if len(skipped) != 0 {
+		services.CreateTaskChangeLog(ctx, task, common.SystemUserName,
+			fmt.Sprintf(common.TaskChangeLogSourceTablesSkipped, strings.Join(skipped, ",")))
+	}
+	return updateSourceTables(ctx, source, task, tables)
+}
+
+// updateSourceTables 将匹配到的源表记录到数据源和任务上，匹配结果变化时记录任务变更日志
+func updateSourceTables(ctx *common.Context, source *models.Source, task *models.Task, tables []string) (common.ServiceCode, error) {
+	tablesName := strings.Join(tables, ",")
+	if tablesName != source.TablesName {
+		oldTables := strings.Split(source.TablesName, ",")
+		added := utils.RemoveSubSlices(tables, oldTables)
+		removed := utils.RemoveSubSlices(oldTables, tables)
+		services.CreateTaskChangeLog(ctx, task, common.SystemUserName,
+			fmt.Sprintf(common.TaskChangeLogSourceTablesChanged, strings.Join(added, ","), strings.Join(removed, ",")))
+
+		err := ctx.DB.Model(source).Update("tables_name", tablesName).Error
+		if err != nil {
+			return common.CodeServerErr, fmt.Errorf("update models.Source(%v) tables_name failed, %s", source.ID, err)
+		}
+		source.TablesName = tablesName
+	}
+	task.SrcTablesName = tablesName
+	return common.CodeOK, nil
+}
+
+// CheckSourceDatabases 源端包含多个分库时逐个库匹配源表，并校验库表、主键、治理条件及目标表名，
+// 校验未通过的库标记为补充信息失败不参与执行。源表以第一个校验通过的库为准，所有库都未通过时返回第一个库的失败原因
+func CheckSourceDatabases(ctx *common.Context, source *models.Source, policy *models.Policy, task *models.Task, databases []string) ([]models.SubTask, common.ServiceCode, error) {
+	var (
+		subTasks  = make([]models.SubTask, 0, len(databases))
+		passed    = -1
+		firstCode common.ServiceCode
+		firstErr  error
+	)
+	for _, database := range databases {
+		dbSource := *source
+		dbSource.DatabaseName = database
+		subTask := models.SubTask{DatabaseName: database, TaskStatus: common.TaskStatusWaiting}
+		code, err := checkSourceDatabase(ctx, &dbSource, policy, task)
+		if err != nil {
+			err = fmt.Errorf("check source(%s) database(%s) failed, %s", source.Name, database, err)
+			ctx.Log.Error(err)
+			subTask.TaskStatus = common.TaskStatusSupplementFailed
+			subTask.TaskReason = code.Message
+			subTask.TaskDetail = err.Error()
+			if firstErr == nil {
+				firstCode, firstErr = code, err
+			}
+		} else if passed < 0 {
+			passed = len(subTasks)
+		}
+		subTask.TablesName = dbSource.TablesName
+		subTasks = append(subTasks, subTask)
+	}
+	if passed < 0 {
+		return nil, firstCode, firstErr
+	}
+
+	if source.TablesPattern != "" {
+		code, err := updateSourceTables(ctx, source, task, strings.Split(subTasks[passed].TablesName, ","))
+		if err != nil {
+			return nil, code, err
+		}
+	}
+	return subTasks, common.CodeOK, nil
+}
+
+// checkSourceDatabase 按表名匹配规则匹配源端某个库的表，并校验该库的库表、主键、治理条件及目标表名
+func checkSourceDatabase(ctx *common.Context, source *models.Source, policy *models.Policy, task *models.Task) (common.ServiceCode, error) {
+	if source.TablesPattern != "" {
+		ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
+		clusterSvc, err := services.GetClusterServiceByClusterID(ginCtx, source.ClusterID)
+		if err != nil {
+			return common.CodeServerErr, fmt.Errorf("get cluster_id(%s) failed, %s", source.ClusterID, err)
+		}
+
+		tables, skipped, code, err := services.ResolveSourceTables(ginCtx, clusterSvc, source.DatabaseName, source.TablesPatternType, source.TablesPattern)
+		if err != nil {
+			return code, fmt.Errorf("resolve tables_pattern(%s) failed, %s", source.TablesPattern, err)
+		}
+		if len(skipped) != 0 {
+			for i := range skipped {
+				skipped[i] = source.DatabaseName + "." + skipped[i]
+			}
+			services.CreateTaskChangeLog(ctx, task, common.SystemUserName,
+				fmt.Sprintf(common.TaskChangeLogSourceTablesSkipped, strings.Join(skipped, ",")))
+		}
+		source.TablesName = strings.Join(tables, ",")
+	}
+
+	ok, code, err := CheckSource(ctx, source)
+	if !ok {
+		return code, err
+	}
+
+	ok, code, err = CheckPolicyCondition(ctx, source, policy)
+	if !ok {
+		return code, fmt.Errorf("check policy.condition(%s) failed, %s", policy.Condition, err)
+	}
+
+	// 归档时各库的源表需能生成目标表名
+	if policy.Govern == common.GovernTypeArchive {
+		_, err = common.GenerateDestTableName(source.TablesName, "")
+		if err != nil {
+			return common.CodeTaskGenDestTabNameErr, fmt.Errorf("generate dest table name failed, %s", err)
+		}
+	}
+	return common.CodeOK, nil
+}
+
 func CheckDestination(ctx *common.Context, destModel *models.Destination) (bool, common.ServiceCode, error) {
 	ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
 
@@ -911,109 +1154,113 @@ func GetMaxParallel() int {
 
 // TaskExecDateAdmission 达到执行日期
 func TaskExecDateAdmission(_ *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
-	if task.ExecuteDate <= time.Now().Format(time.DateOnly) {
-		return true, common.CodeOK, nil
-	}
-	return false, common.CodeTaskExecDateNotReached, fmt.Errorf("task.ExecuteDate(%s) > now(%v)",
-		task.ExecuteDate, time.Now().Format(time.DateOnly))
+	return services.ExecDateAdmission(task, common.NowIn(task.Timezone))
 }
 
 // TaskParallelAdmission 任务最大并发度检查
 func TaskParallelAdmission(ctx *common.Context, _ *models.Task) (bool, common.ServiceCode, error) {
-	// 最大并发判断
-	var count int64
-	err := ctx.DB.Model(models.Task{}).Where("task_status IN (?)", common.TaskStatusExecuting).Count(&count).Error
+	count, err := GetExecutingTaskNum(ctx)
 	if err != nil {
-		return false, common.CodeServerErr,
-			fmt.Errorf("query models.Task(task_status IN (%v)) from db failed, %s", common.TaskStatusExecuting, err)
-	}
-
-	if int(count) >= GetMaxParallel() {
-		return false, common.CodeTaskParallelUpperLimit,
-			fmt.Errorf("current running task num(%v) has reached the maximum parallel limit(%v)", count, GetMaxParallel())
+		return false, common.CodeServerErr, err
 	}
-	return true, common.CodeOK, nil
+	return services.ParallelAdmission(count, GetMaxParallel())
 }
 
-func TaskConflictAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
-	switch services.Cfg.TaskConflictLevel {
-	case common.TaskConflictLevelCluster:
-		return TaskSrcClusterConflictAdmission(ctx, task)
-	case common.TaskConflictLevelDatabase:
-		return TaskSrcDatabaseConflictAdmission(ctx, task)
-	case common.TaskConflictLevelTable:
-		return TaskSrcTableConflictAdmission(ctx, task)
-	}
-	return false, common.CodeConfigConflictLevelErr, fmt.Errorf("not support task conflict level: %s", services.Cfg.TaskConflictLevel)
-}
+// TaskQuotaAdmission BU和集群的并发配额检查，开启公平分配时还需检查BU是否超出按权重分得的并发份额
+func TaskQuotaAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
+	quotas := []struct {
+		scope  common.QuotaScopeType
+		value  string
+		column string
+	}{
+		{common.QuotaScopeBu, task.SrcBu, "src_bu"},
+		{common.QuotaScopeCluster, task.SrcClusterID, "src_cluster_id"},
+	}
+	for _, q := range quotas {
+		quota, err := services.EnabledQuota(ctx.DB, q.scope, q.value)
+		if err != nil {
+			return false, common.CodeServerErr, err
+		}
+		if quota == nil || quota.MaxParallel <= 0 {
+			continue
+		}
 
-func TaskSrcClusterConflictAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
-	// 拿到正在执行的任务列表，判断和当前任务的源是否冲突
-	clusterIds, err := GetExecutingTaskClusterID(ctx)
-	if err != nil {
-		return false, common.CodeServerErr, fmt.Errorf("get executing task src cluster failed, %s", err)
+		var count int64
+		err = ctx.DB.Model(models.Task{}).Where("task_status IN (?) AND "+q.column+" =?", common.TaskStatusExecuting, q.value).
+			Count(&count).Error
+		if err != nil {
+			return false, common.CodeServerErr, fmt.Errorf("query models.Task(task_status IN (%v) AND %s=%s) from db failed, %s",
+				common.TaskStatusExecuting, q.column, q.value, err)
+		}
+		if ok, code, err := services.QuotaAdmission(quota, int(count)); !ok {
+			return ok, code, err
+		}
 	}
 
-	m := make(map[string]int, len(clusterIds))
-	m[task.SrcClusterID] = 1
-	for _, k := range clusterIds {
-		m[k] = m[k] + 1
-		if m[k] > services.Cfg.TaskConflictMax {
-			return false, common.CodeTaskSrcClusterConflict,
-				fmt.Errorf("source cluster(%v) reached the max num(%v) executing task", task.SrcClusterID, services.Cfg.TaskConflictMax)
-		}
+	if !services.Cfg.TaskFairShare {
+		return true, common.CodeOK, nil
 	}
-	return true, common.CodeOK, nil
+	return TaskFairShareAdmission(ctx, task)
 }
 
-func TaskSrcDatabaseConflictAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
-	// 拿到正在执行的任务列表，判断和当前任务的源是否冲突
-	databases, err := GetExecutingTaskDatabases(ctx)
-	if err != nil {
-		return false, common.CodeServerErr, fmt.Errorf("get executing task src database failed, %s", err)
+// TaskFairShareAdmission 按BU权重公平分配任务并发数，查询各BU执行中和因并发不足排队的任务数后按权重判断
+func TaskFairShareAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
+	type buCount struct {
+		SrcBu string
+		Count int
 	}
 
-	m := make(map[string]int, len(databases))
-	m[task.SrcClusterID+"/"+task.SrcDatabaseName] = 1
-	for _, k := range databases {
-		m[k] = m[k] + 1
-		if m[k] > services.Cfg.TaskConflictMax {
-			return false, common.CodeTaskSrcDatabaseConflict, fmt.Errorf("source database(%v) reached the max num(%v) executing task",
-				task.SrcClusterID+"/"+task.SrcDatabaseName, services.Cfg.TaskConflictMax)
-		}
+	// 各BU执行中的任务数
+	var executing []buCount
+	err := ctx.DB.Model(models.Task{}).Select("src_bu, COUNT(*) AS count").
+		Where("task_status IN (?)", common.TaskStatusExecuting).Group("src_bu").Scan(&executing).Error
+	if err != nil {
+		return false, common.CodeServerErr, fmt.Errorf("query models.Task(task_status IN (%v)) group by src_bu from db failed, %s",
+			common.TaskStatusExecuting, err)
 	}
-	return true, common.CodeOK, nil
-}
 
-func TaskSrcTableConflictAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
-	// 拿到正在执行的任务列表，判断和当前任务的源是否冲突
-	tables1, err := GetExecutingTaskTables(ctx)
+	// 各BU因并发不足而排队的任务数
+	var waiting []buCount
+	err = ctx.DB.Model(models.Task{}).Select("src_bu, COUNT(*) AS count").
+		Where("enable =? AND task_status IN (?) AND task_reason IN (?) AND id !=?", true, common.TaskStatusCanExec,
+			[]string{common.CodeTaskParallelUpperLimit.Message, common.CodeTaskFairShareLimit.Message}, task.ID).
+		Group("src_bu").Scan(&waiting).Error
 	if err != nil {
-		return false, common.CodeServerErr, fmt.Errorf("get executing task src table failed, %s", err)
+		return false, common.CodeServerErr, fmt.Errorf("query models.Task(task_status IN (%v)) group by src_bu from db failed, %s",
+			common.TaskStatusCanExec, err)
 	}
 
-	_, baseName, err := common.CheckSameShardingTables(task.SrcTablesName)
+	weights, err := services.BuQuotaWeights(ctx.DB)
 	if err != nil {
-		return false, common.CodeServerErr, fmt.Errorf("get executing task src same sharding tables failed, %s", err)
+		return false, common.CodeServerErr, err
 	}
 
-	m := make(map[string]int, len(tables1))
-	m[task.SrcClusterID+"/"+task.SrcDatabaseName+"/"+baseName] = 1
-	for _, k := range tables1 {
-		m[k] = m[k] + 1
-		if m[k] > services.Cfg.TaskConflictMax {
-			return false, common.CodeTaskSrcTableConflict, fmt.Errorf("source table(%v) reached the max num(%v) executing task",
-				task.SrcClusterID+"/"+task.SrcDatabaseName, services.Cfg.TaskConflictMax)
-		}
+	executingMap := make(map[string]int, len(executing))
+	waitingMap := make(map[string]int, len(waiting))
+	for _, c := range executing {
+		executingMap[c.SrcBu] = c.Count
 	}
+	for _, c := range waiting {
+		waitingMap[c.SrcBu] = c.Count
+	}
+	return services.FairShareAdmission(task.SrcBu, GetMaxParallel(), weights, executingMap, waitingMap)
+}
 
-	return true, common.CodeOK, nil
+// TaskConflictAdmission 按任务冲突级别检查同一源端集群、库或表执行中的任务数
+func TaskConflictAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
+	// 拿到正在执行的任务列表，判断和当前任务的源是否冲突
+	var tasks []*models.Task
+	err := ctx.DB.Model(models.Task{}).Where("task_status IN (?)", common.TaskStatusExecuting).Find(&tasks).Error
+	if err != nil {
+		return false, common.CodeServerErr, fmt.Errorf("query models.Task(task_status IN (%v)) from db failed, %s", common.TaskStatusExecuting, err)
+	}
+	return services.ConflictAdmission(services.Cfg.TaskConflictLevel, services.Cfg.TaskConflictMax, task, tasks)
 }
 
 func GetPolicyActualLastExecDate(ctx *common.Context, policyID uint) (time.Time, error) {
 	execDate := time.Time{}
 	var task models.Task
-	err := ctx.DB.Model(models.Task{}).Where("policy_id =? AND task_status IN (?)", policyID, common.TaskStatusExecTimeImmutable).Last(&task).Error
+	err := ctx.DB.Model(models.Task{}).Where("policy_id =? AND backfill =? AND task_status IN (?)", policyID, false, common.TaskStatusExecTimeImmutable).Last(&task).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return execDate, nil
@@ -1038,52 +1285,9 @@ func GetExecutingTaskNum(ctx *common.Context) (int, error) {
 	return int(executingTask), nil
 }
 
-func GetExecutingTaskClusterID(ctx *common.Context) ([]string, error) {
-	var tasks []models.Task
-	err := ctx.DB.Model(models.Task{}).Where("task_status IN (?)", common.TaskStatusExecuting).Find(&tasks).Error
-	if err != nil {
-		return nil, fmt.Errorf("query models.Task(task_status IN (%v)) from db failed, %s", common.TaskStatusExecuting, err)
-	}
-
-	clusters := make([]string, 0, len(tasks))
-	for _, task := range tasks {
-		clusters = append(clusters, task.SrcClusterID)
-	}
-
-	return clusters, nil
-}
-
-func GetExecutingTaskDatabases(ctx *common.Context) ([]string, error) {
-	var tasks []models.Task
-	err := ctx.DB.Model(models.Task{}).Where("task_status IN (?)", common.TaskStatusExecuting).Find(&tasks).Error
-	if err != nil {
-		return nil, fmt.Errorf("query models.Task(task_status IN (%v)) from db failed, %s", common.TaskStatusExecuting, err)
-	}
-
-	databases := make([]string, 0, len(tasks))
-	for _, task := range tasks {
-		databases = append(databases, task.SrcClusterID+"/"+task.SrcDatabaseName)
-	}
-
-	return databases, nil
-}
-
-func GetExecutingTaskTables(ctx *common.Context) ([]string, error) {
-	var tasks []models.Task
-	err := ctx.DB.Model(models.Task{}).Where("task_status IN (?)", common.TaskStatusExecuting).Find(&tasks).Error
-	if err != nil {
-		return nil, fmt.Errorf("query models.Task(task_status IN (%v)) from db failed, %s", common.TaskStatusExecuting, err)
-	}
-
-	tables := make([]string, 0, len(tasks)*2)
-	for _, ts := range tasks {
-		_, baseName, err := common.CheckSameShardingTables(ts.SrcTablesName)
-		if err != nil {
-			return nil, fmt.Errorf("check same sharding tables failed, %s", err)
-		}
-		tables = append(tables, ts.SrcClusterID+"/"+ts.SrcDatabaseName+"/"+baseName)
-	}
-	return tables, nil
+// supplementNotPassedError 任务未到检查时间或检查未通过，结果已记录在任务上并会被定时重新检查，不属于队列元素处理失败
+type supplementNotPassedError struct {
+	error
 }
 
 // SupplementaryTaskInformation 补充任务信息
@@ -1098,16 +1302,27 @@ func SupplementaryTaskInformation(ctx *common.Context, taskID uint) error {
 	task := &models.Task{}
 	err := db.Model(models.Task{}).Where("id =?", taskID).First(&task).Error
 	if err != nil {
+		// 任务已被删除无需处理
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("models.Task(id=%v) not exist, skip supplementary", taskID)
+			return nil
+		}
 		err = fmt.Errorf("query models.Task(id=%v) from db failed, %s", taskID, err)
 		log.Error(err)
 		return err
 	}
 
+	// 队列中的元素可能被重复处理(如服务重启后恢复)，已进入执行状态的任务不能再次补充信息
+	if !utils.ElementExist(task.TaskStatus, common.TaskStatusCanUpdate) {
+		log.Warnf("task(%v) status is %s, skip supplementary", task.Name, task.TaskStatus)
+		return nil
+	}
+
 	// 任务未到执行的前一天
-	if !common.JudgeTaskCouldCheckBeforeExec(task.ExecuteDate) {
+	if !common.JudgeTaskCouldCheckBeforeExec(task.ExecuteDate, task.Timezone) {
 		err = fmt.Errorf("judge task(%v) could check exec, execution time(%s) not reached", task.Name, task.ExecuteDate)
-		log.Error(err)
-		return err
+		log.Warn(err)
+		return supplementNotPassedError{err}
 	}
 
 	var policy models.Policy
@@ -1148,11 +1363,27 @@ func SupplementaryTaskInformation(ctx *common.Context, taskID uint) error {
 	// 数据清理方式
 	task.Govern = policy.Govern
 	task.Condition = policy.Condition
+	if task.Backfill {
+		// 补录任务的条件需按补录周期的历史时间计算
+		task.Condition, err = services.BackfillCondition(&policy, task)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+	}
 	task.RetainSrcData = policy.RetainSrcData
 	task.CleaningSpeed = policy.CleaningSpeed
+	task.ThrottleID = policy.ThrottleID
+	task.PartitionColumn = policy.PartitionColumn
+	task.PartitionInterval = policy.PartitionInterval
+	task.PartitionRetainDays = policy.PartitionRetainDays
+	task.PartitionPrecreate = policy.PartitionPrecreate
+	task.PartitionAction = policy.PartitionAction
+	task.WatermarkColumn = policy.WatermarkColumn
 
 	task.Relevant = policy.Relevant
 	task.NotifyPolicy = policy.NotifyPolicy
+	task.Priority = policy.Priority
 
 	defer func() {
 		if err = db.Save(task).Error; err != nil {
@@ -1172,25 +1403,71 @@ func SupplementaryTaskInformation(ctx *common.Context, taskID uint) error {
 		}
 	}()
 
-	ok, code, err := CheckSource(ctx, source)
-	if !ok {
-		err = fmt.Errorf("check source(%s) failed, %s", source.Name, err)
-		log.Error(err)
-		task.TaskStatus = common.TaskStatusSupplementFailed
-		task.TaskReason = code.Message
-		task.TaskDetail = err.Error()
-		return err
+	// 设置了库名匹配规则时重新匹配源库，新增的分库自动纳入治理
+	var databases []string
+	if source.DatabasePattern != "" {
+		var code common.ServiceCode
+		databases, code, err = ResolveTaskSourceDatabases(ctx, source, task)
+		if err != nil {
+			err = fmt.Errorf("resolve source(%s) database_pattern(%s) failed, %s", source.Name, source.DatabasePattern, err)
+			log.Error(err)
+			task.TaskStatus = common.TaskStatusSupplementFailed
+			task.TaskReason = code.Message
+			task.TaskDetail = err.Error()
+			return supplementNotPassedError{err}
+		}
 	}
 
-	// 校验where条件合法性
-	ok, code, err = CheckPolicyCondition(ctx, source, &policy)
-	if !ok {
-		err = fmt.Errorf("check policy.condition(%s) failed, %s", policy.Condition, err)
-		log.Error(err)
-		task.TaskStatus = common.TaskStatusSupplementFailed
-		task.TaskReason = code.Message
-		task.TaskDetail = err.Error()
-		return err
+	var (
+		subTasks []models.SubTask
+		code     common.ServiceCode
+		ok       bool
+	)
+	if len(databases) != 0 {
+		// 源端包含多个分库时逐个库校验，校验未通过的库不参与执行
+		subTasks, code, err = CheckSourceDatabases(ctx, source, &policy, task, databases)
+		if err != nil {
+			err = fmt.Errorf("check source(%s) databases(%s) failed, %s", source.Name, strings.Join(databases, ","), err)
+			log.Error(err)
+			task.TaskStatus = common.TaskStatusSupplementFailed
+			task.TaskReason = code.Message
+			task.TaskDetail = err.Error()
+			return supplementNotPassedError{err}
+		}
+	} else {
+		// 设置了表名匹配规则时重新匹配源表，新增的分表自动纳入治理
+		if source.TablesPattern != "" {
+			code, err = ResolveTaskSourceTables(ctx, source, task)
+			if err != nil {
+				err = fmt.Errorf("resolve source(%s) tables_pattern(%s) failed, %s", source.Name, source.TablesPattern, err)
+				log.Error(err)
+				task.TaskStatus = common.TaskStatusSupplementFailed
+				task.TaskReason = code.Message
+				task.TaskDetail = err.Error()
+				return supplementNotPassedError{err}
+			}
+		}
+
+		ok, code, err = CheckSource(ctx, source)
+		if !ok {
+			err = fmt.Errorf("check source(%s) failed, %s", source.Name, err)
+			log.Error(err)
+			task.TaskStatus = common.TaskStatusSupplementFailed
+			task.TaskReason = code.Message
+			task.TaskDetail = err.Error()
+			return supplementNotPassedError{err}
+		}
+
+		// 校验where条件合法性
+		ok, code, err = CheckPolicyCondition(ctx, source, &policy)
+		if !ok {
+			err = fmt.Errorf("check policy.condition(%s) failed, %s", policy.Condition, err)
+			log.Error(err)
+			task.TaskStatus = common.TaskStatusSupplementFailed
+			task.TaskReason = code.Message
+			task.TaskDetail = err.Error()
+			return supplementNotPassedError{err}
+		}
 	}
 
 	// 只有归档需要校验目标端信息
@@ -1203,7 +1480,7 @@ func SupplementaryTaskInformation(ctx *common.Context, taskID uint) error {
 			task.TaskStatus = common.TaskStatusSupplementFailed
 			task.TaskReason = code.Message
 			task.TaskDetail = err.Error()
-			return err
+			return supplementNotPassedError{err}
 		}
 		ok, code, err = CheckDestination(ctx, dest)
 		if !ok {
@@ -1212,7 +1489,7 @@ func SupplementaryTaskInformation(ctx *common.Context, taskID uint) error {
 			task.TaskStatus = common.TaskStatusSupplementFailed
 			task.TaskReason = code.Message
 			task.TaskDetail = err.Error()
-			return err
+			return supplementNotPassedError{err}
 		}
 
 		// 若目标端库名为空，则默认和源库保持一致
@@ -1228,7 +1505,7 @@ func SupplementaryTaskInformation(ctx *common.Context, taskID uint) error {
 			task.TaskStatus = common.TaskStatusSupplementFailed
 			task.TaskReason = common.CodeTaskGenDestTabNameErr.Message
 			task.TaskDetail = err.Error()
-			return err
+			return supplementNotPassedError{err}
 		}
 
 		// TODO 创建目标库和目标表
@@ -1242,6 +1519,20 @@ func SupplementaryTaskInformation(ctx *common.Context, taskID uint) error {
 		task.DestDatabaseName = dest.DatabaseName
 		task.DestTableName = dest.TableName
 	}
+
+	// 源端包含多个分库时按库拆分为子任务依次执行
+	task.SrcDatabasesName = ""
+	if len(subTasks) != 0 {
+		err = services.CreateSubTasks(ctx, task, subTasks)
+		if err != nil {
+			err = fmt.Errorf("create task(%v) sub tasks failed, %s", task.ID, err)
+			log.Error(err)
+			task.TaskStatus = common.TaskStatusSupplementFailed
+			task.TaskReason = common.CodeServerErr.Message
+			task.TaskDetail = err.Error()
+			return supplementNotPassedError{err}
+		}
+	}
 	return nil
 }
 
@@ -1258,7 +1549,7 @@ func CheckScheduledTask(ctx *common.Context) {
 	for _, task := range tasks {
 		//	提前一天检查任务是否满足执行条件，以便于人可以提前介入修复异常
 		// 任务不是一天后执行先不用检查
-		if !common.JudgeTaskCouldCheckBeforeExec(task.ExecuteDate) {
+		if !common.JudgeTaskCouldCheckBeforeExec(task.ExecuteDate, task.Timezone) {
 			log.Debugf("judge task(%v) could common before exec, execution time(%s) not reached", task.Name, task.ExecuteDate)
 			continue
 		}
@@ -1409,7 +1700,7 @@ func AttemptCreateOrUpdateTask(ctx *common.Context, policyID uint) {
 		}
 	}
 
-	if !common.JudgeTaskCouldCheckBeforeExec(task.ExecuteDate) {
+	if !common.JudgeTaskCouldCheckBeforeExec(task.ExecuteDate, task.Timezone) {
 		err = fmt.Errorf("judge task(%v) could exec check, execution time(%s) not reached", task.Name, task.ExecuteDate)
 		log.Info(err)
 		return
@@ -1433,7 +1724,7 @@ func deleteRedundantTasks(ctx *common.Context, policyID uint) (*models.Task, err
 	log, db := ctx.Log, ctx.DB
 
 	var tasks []models.Task
-	err := db.Model(models.Task{}).Where("policy_id =? AND task_status IN (?)", policyID, common.TaskStatusCanUpdate).Find(&tasks).Order("id desc").Error
+	err := db.Model(models.Task{}).Where("policy_id =? AND backfill =? AND task_status IN (?)", policyID, false, common.TaskStatusCanUpdate).Find(&tasks).Order("id desc").Error
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, fmt.Errorf("query models.Task(policy_id=%v AND task_status IN (%v)) from db failed, %s", policyID, common.TaskStatusCanUpdate, err)
 	}
@@ -1517,3 +1808,316 @@ func CheckDiskUsage(ctx *common.Context) {
 		}
 	}
 }
+
+// sourceLoadMaxGap 源端负载采样每10s一次，与上次采样间隔超过该值时上次的采样已过期，
+// 不再参与计算QPS，连续超过阈值的次数也重新计数
+const sourceLoadMaxGap = 30 * time.Second
+
+// sourceLoadQPSInterval 没有可用的上次采样时，间隔该时长再采样一次计算QPS
+const sourceLoadQPSInterval = time.Second
+
+// sourceLoad 源端集群最近一次的负载采样
+type sourceLoad struct {
+	SampleTime     time.Time
+	ThreadsRunning int
+	ActiveTrx      int
+	Questions      int64
+	QPS            int    // 根据与上次采样的Questions差值计算，无法计算时为-1
+	Overload       bool   // 是否超过阈值
+	Detail         string // 超过阈值的详情
+	Continuous     int    // 连续超过阈值的次数
+}
+
+var (
+	sourceLoadMutex sync.Mutex
+	sourceLoads     = make(map[string]*sourceLoad)
+)
+
+// sampleSourceLoad 采集源端集群负载，maxAge内采集过的直接使用上次的采样结果
+func sampleSourceLoad(ctx *common.Context, clusterID string, maxAge time.Duration) (*sourceLoad, error) {
+	sourceLoadMutex.Lock()
+	last, ok := sourceLoads[clusterID]
+	sourceLoadMutex.Unlock()
+	if ok && time.Now().Sub(last.SampleTime) < maxAge {
+		return last, nil
+	}
+
+	ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
+	clusterSvc, err := services.GetClusterServiceByClusterID(ginCtx, clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("get cluster(%s) failed, %s", clusterID, err)
+	}
+
+	driver := services.NewClusterDriver(clusterSvc)
+	threadsRunning, activeTrx, questions, err := driver.GetLoad(ginCtx)
+	if err != nil {
+		return nil, fmt.Errorf("get cluster(%s) load failed, %s", clusterID, err)
+	}
+
+	now := time.Now()
+	fresh := ok && now.Sub(last.SampleTime) <= sourceLoadMaxGap
+	var prevTime time.Time
+	var prevQuestions int64
+	if fresh {
+		prevTime, prevQuestions = last.SampleTime, last.Questions
+	} else if services.Cfg.SourceStatusDetectQPS > 0 {
+		// 没有可用的上次采样时间隔1s再采样一次，避免首次采样时无法判断QPS
+		prevTime, prevQuestions = now, questions
+		time.Sleep(sourceLoadQPSInterval)
+		threadsRunning, activeTrx, questions, err = driver.GetLoad(ginCtx)
+		if err != nil {
+			return nil, fmt.Errorf("get cluster(%s) load failed, %s", clusterID, err)
+		}
+		now = time.Now()
+	}
+
+	load := &sourceLoad{SampleTime: now, ThreadsRunning: threadsRunning, ActiveTrx: activeTrx, Questions: questions, QPS: -1}
+	if !prevTime.IsZero() && questions >= prevQuestions && now.After(prevTime) {
+		load.QPS = int(float64(questions-prevQuestions) / now.Sub(prevTime).Seconds())
+	}
+	qps := load.QPS
+	var details []string
+	if services.Cfg.SourceStatusDetectThreadsRunning > 0 && threadsRunning > services.Cfg.SourceStatusDetectThreadsRunning {
+		details = append(details, fmt.Sprintf("threads_running(%d) > threshold(%d)", threadsRunning, services.Cfg.SourceStatusDetectThreadsRunning))
+	}
+	if services.Cfg.SourceStatusDetectActiveTrx > 0 && activeTrx > services.Cfg.SourceStatusDetectActiveTrx {
+		details = append(details, fmt.Sprintf("active_trx(%d) > threshold(%d)", activeTrx, services.Cfg.SourceStatusDetectActiveTrx))
+	}
+	if services.Cfg.SourceStatusDetectQPS > 0 && qps >= 0 && qps > services.Cfg.SourceStatusDetectQPS {
+		details = append(details, fmt.Sprintf("qps(%d) > threshold(%d)", qps, services.Cfg.SourceStatusDetectQPS))
+	}
+	if len(details) != 0 {
+		load.Overload = true
+		load.Detail = fmt.Sprintf("cluster(%s) current %s", clusterID, strings.Join(details, ", "))
+	}
+
+	sourceLoadMutex.Lock()
+	if load.Overload {
+		load.Continuous = 1
+		if fresh && last.Overload {
+			load.Continuous = last.Continuous + 1
+		}
+	}
+	sourceLoads[clusterID] = load
+	sourceLoadMutex.Unlock()
+	return load, nil
+}
+
+// TaskSourceLoadAdmission 源端集群负载超过阈值时推迟执行，采集负载失败时不阻塞执行
+func TaskSourceLoadAdmission(ctx *common.Context, task *models.Task) (bool, common.ServiceCode, error) {
+	if !services.Cfg.SourceStatusDetect {
+		return true, common.CodeOK, nil
+	}
+
+	load, err := sampleSourceLoad(ctx, task.SrcClusterID, 10*time.Second)
+	if err != nil {
+		ctx.Log.Warnf("sample task(%v) source load failed, skip source load admission, %s", task.ID, err)
+		return true, common.CodeOK, nil
+	}
+
+	if load.Overload {
+		return false, common.CodeTaskSrcClusterBusy, errors.New(load.Detail)
+	}
+	return true, common.CodeOK, nil
+}
+
+// CheckSourceLoad 定时检查源端负载，连续多次超过阈值时停止工作流，任务回到等待执行状态，负载恢复后继续执行
+func CheckSourceLoad(ctx *common.Context) {
+	log, db := ctx.Log, ctx.DB
+
+	if !services.Cfg.SourceStatusDetect {
+		log.Infof("source status detect is disabled")
+		return
+	}
+
+	var tasks []models.Task
+	err := db.Model(models.Task{}).Where("task_status =?", common.TaskStatusExecuting).Find(&tasks).Error
+	if err != nil {
+		log.Errorf("query models.Task(task_status=%v) from db failed, %s", common.TaskStatusExecuting, err)
+		return
+	}
+
+	// 同一集群只采样一次
+	loads := make(map[string]*sourceLoad, len(tasks))
+	for _, task := range tasks {
+		load, ok := loads[task.SrcClusterID]
+		if !ok {
+			load, err = sampleSourceLoad(ctx, task.SrcClusterID, 0)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			loads[task.SrcClusterID] = load
+		}
+
+		if !load.Overload {
+			continue
+		}
+
+		log.Info(load.Detail)
+		if load.Continuous < services.Cfg.SourceStatusDetectSamples {
+			// 只在开始连续超过阈值时记录一次
+			if load.Continuous == 1 {
+				services.CreateTaskChangeLog(ctx, &task, common.SystemUserName,
+					fmt.Sprintf(common.TaskChangeLogSourceBusy, load.Continuous, services.Cfg.SourceStatusDetectSamples, load.Detail))
+			}
+			continue
+		}
+
+		err = workflow1.NewDriver(configs.C.WorkFlow.Driver).StopWorkFlow(ctx, task.WorkFlow)
+		if err != nil {
+			log.Errorf("stop task(%v) workflow(%s) failed, %s", task.ID, task.WorkFlow, err)
+			continue
+		}
+		log.Infof("stop task(%v) workflow(%s) success", task.ID, task.WorkFlow)
+
+		msg := fmt.Sprintf("%s, %d consecutive samples", load.Detail, load.Continuous)
+		task.TaskStatus = common.TaskStatusWaiting
+		task.TaskReason = common.CodeTaskSrcClusterBusy.Message
+		task.TaskDetail = msg
+		err = db.Save(&task).Error
+		if err != nil {
+			log.Errorf("update models.Task(ID=%v) from db failed, %s", task.ID, err)
+			continue
+		}
+		services.CreateTaskChangeLog(ctx, &task, common.SystemUserName, fmt.Sprintf(common.TaskChangeLogStopWorkFlowBusy, msg))
+	}
+}
+
+// CheckSourcePrimary 定时检查执行中任务的源端主库，主库发生切换时停止工作流
+func CheckSourcePrimary(ctx *common.Context) {
+	log, db := ctx.Log, ctx.DB
+
+	var tasks []models.Task
+	err := db.Model(models.Task{}).
+		Where("task_status =? AND (src_primary_uuid <> '' OR src_primary_addr <> '')", common.TaskStatusExecuting).
+		Find(&tasks).Error
+	if err != nil {
+		log.Errorf("query models.Task(task_status=%v) from db failed, %s", common.TaskStatusExecuting, err)
+		return
+	}
+
+	// 同一集群只发现一次
+	ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
+	primaries := make(map[string][2]string, len(tasks))
+	for _, task := range tasks {
+		primary, ok := primaries[task.SrcClusterID]
+		if !ok {
+			clusterSvc, err := services.GetClusterServiceByClusterID(ginCtx, task.SrcClusterID)
+			if err != nil {
+				log.Errorf("get cluster(%s) failed, %s", task.SrcClusterID, err)
+				continue
+			}
+
+			primary[0], primary[1], err = services.UpdateClusterTopology(ginCtx, clusterSvc)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			primaries[task.SrcClusterID] = primary
+		}
+
+		if services.SamePrimary(task.SrcPrimaryUUID, task.SrcPrimaryAddr, primary[0], primary[1]) {
+			continue
+		}
+
+		msg := fmt.Sprintf("cluster(%s) primary changed from %s(%s) to %s(%s)",
+			task.SrcClusterID, task.SrcPrimaryAddr, task.SrcPrimaryUUID, primary[1], primary[0])
+		log.Info(msg)
+		err = workflow1.NewDriver(configs.C.WorkFlow.Driver).StopWorkFlow(ctx, task.WorkFlow)
+		if err != nil {
+			log.Errorf("stop task(%v) workflow(%s) failed, %s", task.ID, task.WorkFlow, err)
+		} else {
+			log.Infof("stop task(%v) workflow(%s) success", task.ID, task.WorkFlow)
+			services.CreateTaskChangeLog(ctx, &task, common.SystemUserName, fmt.Sprintf(common.TaskChangeLogPrimaryChanged, msg))
+		}
+	}
+}
+
+// CheckExecWindowPause 执行窗口外需要暂停的任务超出所有执行窗口时停止工作流，任务回到等待执行状态在下一个执行窗口继续执行
+func CheckExecWindowPause(ctx *common.Context) {
+	log, db := ctx.Log, ctx.DB
+
+	var tasks []models.Task
+	err := db.Model(models.Task{}).Where("task_status =? AND pause =?", common.TaskStatusExecuting, true).Find(&tasks).Error
+	if err != nil {
+		log.Errorf("query models.Task(task_status=%v AND pause=%v) from db failed, %s", common.TaskStatusExecuting, true, err)
+		return
+	}
+
+	for _, task := range tasks {
+		now := common.NowIn(task.Timezone)
+		inWin, err := timeInExecWindows(task.ExecuteDate, now, task.ExecuteWindow, task.ExecuteWindows)
+		if err != nil {
+			log.Errorf("judge task(%v) in exec windows failed, %s", task.ID, err)
+			continue
+		}
+		if inWin == 0 {
+			continue
+		}
+
+		msg := fmt.Sprintf("task(%v) is out of exec windows at %s(%s)", task.ID, now.Format(time.DateTime), now.Location())
+		log.Info(msg)
+		err = workflow1.NewDriver(configs.C.WorkFlow.Driver).StopWorkFlow(ctx, task.WorkFlow)
+		if err != nil {
+			log.Errorf("stop task(%v) workflow(%s) failed, %s", task.ID, task.WorkFlow, err)
+			continue
+		}
+		log.Infof("stop task(%v) workflow(%s) success", task.ID, task.WorkFlow)
+
+		task.TaskStatus = common.TaskStatusWaiting
+		task.TaskReason = common.CodeTaskPausedOutOfExecWin.Message
+		task.TaskDetail = msg
+		err = db.Save(&task).Error
+		if err != nil {
+			log.Errorf("update models.Task(ID=%v) from db failed, %s", task.ID, err)
+			continue
+		}
+		services.CreateTaskChangeLog(ctx, &task, common.SystemUserName, fmt.Sprintf(common.TaskChangeLogPausedOutOfExecWin, msg))
+	}
+}
+
+// CheckBlackoutStop 执行中的任务进入封网期间时停止工作流，任务回到等待执行状态，封网结束后在执行窗口内继续执行
+func CheckBlackoutStop(ctx *common.Context) {
+	log, db := ctx.Log, ctx.DB
+
+	var tasks []models.Task
+	err := db.Model(models.Task{}).Where("task_status =?", common.TaskStatusExecuting).Find(&tasks).Error
+	if err != nil {
+		log.Errorf("query models.Task(task_status=%v) from db failed, %s", common.TaskStatusExecuting, err)
+		return
+	}
+
+	for _, task := range tasks {
+		blackouts, err := services.QueryEffectiveBlackouts(db, task.SrcBu, task.SrcClusterID)
+		if err != nil {
+			log.Errorf("query task(%v) effective blackouts failed, %s", task.ID, err)
+			continue
+		}
+
+		now := time.Now()
+		b, start, end, ok := services.MatchBlackout(blackouts, now, now.Add(time.Second))
+		if !ok {
+			continue
+		}
+
+		msg := fmt.Sprintf("task(%v) is in blackout(%s) from %s to %s", task.ID, b.Name, start.Format(time.DateTime), end.Format(time.DateTime))
+		log.Info(msg)
+		err = workflow1.NewDriver(configs.C.WorkFlow.Driver).StopWorkFlow(ctx, task.WorkFlow)
+		if err != nil {
+			log.Errorf("stop task(%v) workflow(%s) failed, %s", task.ID, task.WorkFlow, err)
+			continue
+		}
+		log.Infof("stop task(%v) workflow(%s) success", task.ID, task.WorkFlow)
+
+		task.TaskStatus = common.TaskStatusWaiting
+		task.TaskReason = common.CodeTaskInBlackout.Message
+		task.TaskDetail = msg
+		err = db.Save(&task).Error
+		if err != nil {
+			log.Errorf("update models.Task(ID=%v) from db failed, %s", task.ID, err)
+			continue
+		}
+		services.CreateTaskChangeLog(ctx, &task, common.SystemUserName, fmt.Sprintf(common.TaskChangeLogStopWorkFlowBlackout, msg))
+	}
+}
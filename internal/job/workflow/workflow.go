@@ -3,6 +3,7 @@ package workflow
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sunkaimr/data-loom/configs"
 	. "github.com/sunkaimr/data-loom/internal/job/status"
@@ -13,6 +14,7 @@ import (
 	"github.com/sunkaimr/data-loom/internal/services"
 	"github.com/sunkaimr/data-loom/internal/workflow"
 	"github.com/sunkaimr/data-loom/internal/workflow/types"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
 	"github.com/sunkaimr/data-loom/pkg/utils"
 	"strconv"
 	"strings"
@@ -47,6 +49,31 @@ func CleanWorkFlow(ctx *common.Context) {
 			db.Model(models.Task{}).Where("id =?", task.ID).Update("work_flow", "")
 		}
 	}
+
+	// 分库子任务的工作流
+	var subTasks []models.SubTask
+	err = db.Model(models.SubTask{}).Select("id, work_flow").
+		Where("task_end_time < ? AND task_status IN (?) AND work_flow != ''",
+			time.Now().Add(-24*time.Hour*time.Duration(services.Cfg.WorkflowRetentionDays)).Format(time.DateTime),
+			common.TaskStatusHasFinished).
+		Find(&subTasks).Error
+	if err != nil {
+		log.Errorf("query CleanWorkFlow sub task failed, %s", err)
+		return
+	}
+
+	for _, subTask := range subTasks {
+		err = workflow.NewDriver(configs.C.WorkFlow.Driver).DeleteWorkFlow(ctx, subTask.WorkFlow)
+		if err != nil {
+			log.Errorf("delete sub task(%v) workflow(%s) failed, %s", subTask.ID, subTask.WorkFlow, err)
+		} else {
+			log.Infof("delete sub task(%v) workflow(%s) success", subTask.ID, subTask.WorkFlow)
+		}
+
+		if err == nil || strings.Contains(err.Error(), "not found") {
+			db.Model(models.SubTask{}).Where("id =?", subTask.ID).Update("work_flow", "")
+		}
+	}
 }
 
 func CallTaskWorkFlowCheck(ctx *common.Context, task *models.Task) error {
@@ -146,6 +173,35 @@ func CallTaskWorkFlow(ctx *common.Context, task *models.Task) error {
 	}
 	task.TaskStartTime = time.Now()
 
+	// 记录开始执行时的主库，执行过程中主库发生切换需要终止任务。发现失败时不记录，执行过程中不做主库切换检查
+	task.SrcPrimaryUUID, task.SrcPrimaryAddr, err = services.UpdateClusterTopology(ginCtx, clusterSvc)
+	if err != nil {
+		log.Warnf("%s, skip primary switchover check for task(%v)", err, task.ID)
+		task.SrcPrimaryUUID, task.SrcPrimaryAddr = "", ""
+	}
+
+	// 增量治理计算本次治理的水位范围
+	var throttle types.Throttle
+	if task.Govern == common.GovernTypeDelete || task.Govern == common.GovernTypeArchive {
+		code, err = services.BoundTaskWatermark(ginCtx, clusterSvc, task)
+		if err != nil {
+			err = fmt.Errorf("bound task(%v) watermark failed, %s", task.ID, err)
+			log.Error(err)
+			task.TaskReason = code.Message
+			task.TaskDetail = err.Error()
+			return err
+		}
+
+		// 删除和归档按限流配置控制清理速度
+		throttle, err = services.TaskThrottle(db, task)
+		if err != nil {
+			log.Error(err)
+			task.TaskReason = common.CodeServerErr.Message
+			task.TaskDetail = err.Error()
+			return err
+		}
+	}
+
 	// 调用工作流
 	switch task.Govern {
 	case common.GovernTypeTruncate:
@@ -153,13 +209,25 @@ func CallTaskWorkFlow(ctx *common.Context, task *models.Task) error {
 			BuildTruncateDataPara(task, addr, port, user, passwd))
 	case common.GovernTypeDelete:
 		task.WorkFlow, err = workflow.NewDriver(configs.C.WorkFlow.Driver).DeleteData(ctx,
-			BuildDeleteDataPara(task, addr, port, user, passwd))
+			BuildDeleteDataPara(task, throttle, addr, port, user, passwd))
 	case common.GovernTypeArchive:
 		task.WorkFlow, err = workflow.NewDriver(configs.C.WorkFlow.Driver).ArchiveData(ctx,
-			BuildArchivePara(task, addr, port, user, passwd))
+			BuildArchivePara(task, throttle, addr, port, user, passwd))
 	case common.GovernTypeRebuild:
 		task.WorkFlow, err = workflow.NewDriver(configs.C.WorkFlow.Driver).RebuildTables(ctx,
 			BuildRebuildDataPara(task, addr, port, user, passwd))
+	case common.GovernTypePartition:
+		var para *types.PartitionParaStruct
+		para, code, err = BuildPartitionPara(ginCtx, clusterSvc, task, addr, port, user, passwd)
+		if err != nil {
+			err = fmt.Errorf("build task(%v) partition statements failed, %s", task.ID, err)
+			log.Error(err)
+			task.TaskStatus = common.TaskStatusExecFailed
+			task.TaskReason = code.Message
+			task.TaskDetail = err.Error()
+			return err
+		}
+		task.WorkFlow, err = workflow.NewDriver(configs.C.WorkFlow.Driver).PartitionData(ctx, para)
 	default:
 		err = fmt.Errorf("unsupported task govern(%s)", task.Govern)
 		log.Error(err)
@@ -182,6 +250,11 @@ func CallTaskWorkFlow(ctx *common.Context, task *models.Task) error {
 	task.TaskDetail = ""
 	task.TaskStartTime = time.Now()
 
+	// 源端包含多个分库时标记当前库的子任务开始执行
+	if err = services.StartSubTask(ctx, task); err != nil {
+		log.Warn(err)
+	}
+
 	return nil
 }
 
@@ -201,29 +274,49 @@ func BuildTruncateDataPara(task *models.Task, host, port, user, passwd string) *
 	}
 }
 
-func BuildDeleteDataPara(task *models.Task, host, port, user, passwd string) *types.DeleteParaStruct {
+func BuildDeleteDataPara(task *models.Task, throttle types.Throttle, host, port, user, passwd string) *types.DeleteParaStruct {
 	var execWin []string
 	_ = json.Unmarshal(task.ExecuteWindow, &execWin)
 	if len(execWin) < 2 {
 		execWin = append(execWin, "", "")
 	}
 
+	// 多个执行窗口时传递当前所在的执行窗口，执行窗口按任务所在时区计算后转换为服务所在时区传递
+	if windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows); err == nil {
+		now := common.NowIn(task.Timezone)
+		ranges, _ := common.ActiveExecWindowRanges(windows, task.ExecuteDate, now)
+		for _, r := range ranges {
+			if !now.Before(r.Start) && !now.After(r.End) {
+				execWin = []string{
+					r.Start.In(time.Now().Location()).Format(time.TimeOnly),
+					r.End.In(time.Now().Location()).Format(time.TimeOnly),
+				}
+				break
+			}
+		}
+	}
+
 	return &types.DeleteParaStruct{
-		TaskID:      task.ID,
-		StartTime:   execWin[0],
-		EndTime:     execWin[1],
-		Host:        host,
-		Port:        port,
-		User:        user,
-		Password:    passwd,
-		Database:    task.SrcDatabaseName,
-		Tables:      task.SrcTablesName,
-		Condition:   task.Condition,
-		RebuildFlag: task.RebuildFlag,
-		FreeDisk:    strconv.Itoa(task.SrcClusterFreeDisk),
+		TaskID:          task.ID,
+		StartTime:       execWin[0],
+		EndTime:         execWin[1],
+		Host:            host,
+		Port:            port,
+		User:            user,
+		Password:        passwd,
+		Database:        task.SrcDatabaseName,
+		Tables:          task.SrcTablesName,
+		Condition:       services.WatermarkCondition(task),
+		RebuildFlag:     task.RebuildFlag,
+		FreeDisk:        strconv.Itoa(task.SrcClusterFreeDisk),
+		CleaningSpeed:   task.CleaningSpeed,
+		Throttle:        throttle,
+		WatermarkColumn: task.WatermarkColumn,
+		WatermarkHigh:   task.WatermarkHigh,
 		Callback: types.Callback{
-			URL:   generateCallbackUrl(),
-			Token: generateToken(task.ID),
+			URL:         generateCallbackUrl(),
+			Token:       generateToken(task.ID),
+			ThrottleURL: generateThrottleUrl(task.ID),
 		},
 	}
 }
@@ -245,21 +338,69 @@ func BuildRebuildDataPara(task *models.Task, host, port, user, passwd string) *t
 	}
 }
 
-func BuildArchivePara(task *models.Task, host, port, user, passwd string) *types.ArchiveParaStruct {
+func BuildArchivePara(task *models.Task, throttle types.Throttle, host, port, user, passwd string) *types.ArchiveParaStruct {
 	return &types.ArchiveParaStruct{
-		TaskID:   task.ID,
-		Host:     host,
-		Port:     port,
-		User:     user,
-		Password: passwd,
-		Database: task.SrcDatabaseName,
-		Tables:   task.SrcTablesName,
-		FreeDisk: strconv.Itoa(task.SrcClusterFreeDisk),
+		TaskID:          task.ID,
+		Host:            host,
+		Port:            port,
+		User:            user,
+		Password:        passwd,
+		Database:        task.SrcDatabaseName,
+		Tables:          task.SrcTablesName,
+		Condition:       services.WatermarkCondition(task),
+		FreeDisk:        strconv.Itoa(task.SrcClusterFreeDisk),
+		CleaningSpeed:   task.CleaningSpeed,
+		Throttle:        throttle,
+		WatermarkColumn: task.WatermarkColumn,
+		WatermarkHigh:   task.WatermarkHigh,
+		Callback: types.Callback{
+			URL:         generateCallbackUrl(),
+			Token:       generateToken(task.ID),
+			ThrottleURL: generateThrottleUrl(task.ID),
+		},
+	}
+}
+
+// BuildPartitionPara 根据当前的分区信息生成需要执行的分区维护SQL
+func BuildPartitionPara(ctx *gin.Context, clusterSvc *services.ClusterService, task *models.Task, host, port, user, passwd string) (*types.PartitionParaStruct, common.ServiceCode, error) {
+	var statements []string
+	for _, table := range strings.Split(task.SrcTablesName, ",") {
+		partitions, code, err := services.NewClusterDriver(clusterSvc).GetTablePartitions(ctx, task.SrcDatabaseName, table)
+		if err != nil {
+			return nil, code, err
+		}
+
+		// 查询分区和归档表的当前状态，任务重试时跳过上次已经完成的交换步骤
+		code = common.CodePolicyNotPartitioned
+		exchangeState := func(partition, archive string) (*mysql.ExchangeState, error) {
+			state, c, e := services.NewClusterDriver(clusterSvc).GetExchangeState(ctx, task.SrcDatabaseName, table, partition, archive)
+			if e != nil {
+				code = c
+			}
+			return state, e
+		}
+		plan, err := services.PlanPartitions(table, task.PartitionColumn, partitions, task.PartitionInterval,
+			task.PartitionRetainDays, task.PartitionPrecreate, task.PartitionAction, time.Now(), exchangeState)
+		if err != nil {
+			return nil, code, err
+		}
+		statements = append(statements, plan.Statements...)
+	}
+
+	return &types.PartitionParaStruct{
+		TaskID:     task.ID,
+		Host:       host,
+		Port:       port,
+		User:       user,
+		Password:   passwd,
+		Database:   task.SrcDatabaseName,
+		Tables:     task.SrcTablesName,
+		Statements: strings.Join(statements, ";\n"),
 		Callback: types.Callback{
 			URL:   generateCallbackUrl(),
 			Token: generateToken(task.ID),
 		},
-	}
+	}, common.CodeOK, nil
 }
 
 // 为更新task任务状态单独生成一个token
@@ -280,6 +421,10 @@ func generateCallbackUrl() string {
 	return configs.C.Server.ExternalAddr + "/data-loom/api/v1/task/result"
 }
 
+func generateThrottleUrl(taskID uint) string {
+	return fmt.Sprintf("%s/data-loom/api/v1/task/throttle?id=%d", configs.C.Server.ExternalAddr, taskID)
+}
+
 // CheckWorkFlowTimeout 定时同步任务和workflow的状态
 func CheckWorkFlowTimeout(ctx *common.Context) {
 	log, db := ctx.Log, ctx.DB
@@ -355,12 +500,12 @@ func CheckWorkFlowTimeout(ctx *common.Context) {
 
 	update:
 		// 更新任务状态
-		err = updateTaskStatus(newCtx, &task)
+		next, err := updateTaskStatus(newCtx, &task)
 		if err != nil {
 			log.Errorf("update task(%v) status(%v) failed, %s", task.ID, task.TaskStatus, err)
 		}
-		// 实例化下一次任务
-		if needScheduleNext {
+		// 实例化下一次任务，源端包含多个分库且还有未执行的库时继续执行当前任务
+		if needScheduleNext && !next {
 			handleID, ok := newCtx.GetData(HandleID)
 			if !ok {
 				handleID = utils.RandStr(20)
@@ -376,29 +521,39 @@ func CheckWorkFlowTimeout(ctx *common.Context) {
 	}
 }
 
-func updateTaskStatus(ctx *common.Context, task *models.Task) error {
+func updateTaskStatus(ctx *common.Context, task *models.Task) (bool /* 是否继续执行下一个库 */, error) {
 	ginCtx := middlewares.NewGinContext(ctx.Log, ctx.DB)
 	task.TaskEndTime = time.Now()
 	task.TaskDuration = int(task.TaskEndTime.Sub(task.TaskStartTime).Seconds())
 
 	clusterSvc, err := services.GetClusterServiceByClusterID(ginCtx, task.SrcClusterID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	tableSize, _, err := services.NewClusterDriver(clusterSvc).GetTablesSize(ginCtx, task.SrcDatabaseName, strings.Split(task.SrcTablesName, ","))
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	task.TaskResultSize = task.SrcClusterSumTableSize - tableSize
 	task.TaskResultSize = utils.Ternary[int](task.TaskResultSize > 0, task.TaskResultSize, 0)
 
+	next, err := services.FinishSubTask(ctx, task)
+	if err != nil {
+		return false, err
+	}
+
 	err = ctx.DB.Save(&task).Error
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	services.CreateTaskChangeLog(ctx, task, common.SystemUserName, common.TaskChangeLogWorkFlowFinished)
-	return nil
+	if !next {
+		if err = services.AdvancePolicyWatermark(ctx, task); err != nil {
+			ctx.Log.Error(err)
+		}
+	}
+	return next, nil
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"time"
+)
+
+// Blackout 封网日历，封网期间禁止执行治理任务
+type Blackout struct {
+	Model
+
+	Name        string                        `json:"name" gorm:"type:varchar(1024);not null;comment:封网名称"`
+	Description string                        `json:"description" gorm:"type:longtext;default '';comment:说明"`
+	Enable      bool                          `json:"enable" gorm:"type:int(4);comment:是否生效"`
+	Scope       common.BlackoutScopeType      `json:"scope" gorm:"type:varchar(64);not null;comment:生效范围"`
+	ScopeValue  string                        `json:"scope_value" gorm:"type:varchar(128);index:blackout_scope_idx;comment:生效的BU或集群ID"`
+	StartTime   time.Time                     `json:"start_time" gorm:"type:DATETIME;comment:开始时间"`
+	EndTime     time.Time                     `json:"end_time" gorm:"type:DATETIME;comment:结束时间"`
+	Recurrence  common.BlackoutRecurrenceType `json:"recurrence" gorm:"type:varchar(64);comment:重复周期"`
+	RecurUntil  time.Time                     `json:"recur_until" gorm:"type:DATETIME;comment:重复截止时间"`
+	ICalUID     string                        `json:"ical_uid" gorm:"type:varchar(512);index:blackout_ical_uid_idx;comment:从iCal导入时的事件UID"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"time"
+)
+
+// CatalogTable 源端表结构元数据快照
+type CatalogTable struct {
+	Model
+	Bu           string    `json:"bu" gorm:"type:varchar(64);comment:bu"`
+	ClusterID    string    `json:"cluster_id" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:集群ID"`
+	ClusterName  string    `json:"cluster_name" gorm:"type:varchar(64);comment:集群名称"`
+	Database     string    `json:"database" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:库名"`
+	Table        string    `json:"table" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:表名"`
+	Engine       string    `json:"engine" gorm:"type:varchar(64);comment:存储引擎"`
+	TableRows    int64     `json:"table_rows" gorm:"type:bigint;comment:估算行数"`
+	DataLength   int64     `json:"data_length" gorm:"type:bigint;comment:数据大小(字节)"`
+	IndexLength  int64     `json:"index_length" gorm:"type:bigint;comment:索引大小(字节)"`
+	DataFree     int64     `json:"data_free" gorm:"type:bigint;comment:碎片空间大小(字节)"`
+	TableComment string    `json:"table_comment" gorm:"type:varchar(2048);comment:表注释"`
+	PrimaryKey   string    `json:"primary_key" gorm:"type:varchar(1024);comment:主键列,多列以逗号分隔"`
+	Columns      JSON      `json:"columns" gorm:"type:json;comment:列信息"`
+	Indexes      JSON      `json:"indexes" gorm:"type:json;comment:索引信息"`
+	SchemaHash   string    `json:"schema_hash" gorm:"type:varchar(64);comment:表结构摘要,用于判断表结构是否变化"`
+	SnapshotAt   time.Time `json:"snapshot_at" gorm:"type:DATETIME;comment:快照时间"`
+}
+
+// CatalogSchemaChange 源端表结构变更历史
+type CatalogSchemaChange struct {
+	Model
+	ClusterID  string                  `json:"cluster_id" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:集群ID"`
+	Database   string                  `json:"database" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:库名"`
+	Table      string                  `json:"table" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:表名"`
+	ChangeType common.SchemaChangeType `json:"change_type" gorm:"type:varchar(64);comment:变更类型：新建表，修改表，删除表"`
+	Detail     string                  `json:"detail" gorm:"type:longtext;comment:变更详情"`
+	OldColumns JSON                    `json:"old_columns" gorm:"type:json;comment:变更前的列信息"`
+	NewColumns JSON                    `json:"new_columns" gorm:"type:json;comment:变更后的列信息"`
+	OldIndexes JSON                    `json:"old_indexes" gorm:"type:json;comment:变更前的索引信息"`
+	NewIndexes JSON                    `json:"new_indexes" gorm:"type:json;comment:变更后的索引信息"`
+	ChangedAt  time.Time               `json:"changed_at" gorm:"type:DATETIME;index:idx_changed_at;comment:发现变更的时间"`
+}
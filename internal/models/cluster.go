@@ -1,6 +1,9 @@
 package models
 
-import "github.com/sunkaimr/data-loom/internal/pkg/common"
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"time"
+)
 
 type Cluster struct {
 	Model
@@ -9,11 +12,16 @@ type Cluster struct {
 	Description string                       `json:"description" gorm:"type:longtext;comment:说明"`
 	Bu          string                       `json:"bu" gorm:"type:varchar(64);comment:bu"`
 	Env         string                       `json:"env" gorm:"type:varchar(64);comment:环境"`
+	Timezone    string                       `json:"timezone" gorm:"type:varchar(64);comment:时区(如Asia/Shanghai)，为空时使用服务所在时区"`
 	ImportFrom  common.ClusterImportFromType `json:"import_from" gorm:"type:varchar(64);comment:添加方式：自定义添加、从资源中心或其他地方导入"`
 	ClusterType common.ClusterType           `json:"cluster_type" gorm:"type:varchar(64);comment:集群类型（mysql, 其他的）"`
 	ServiceAddr string                       `json:"service_addr" gorm:"type:varchar(1024);comment:服务地址(vip) ip:port"`
 	WriteAddr   string                       `json:"write_addr" gorm:"type:varchar(1024);comment:写库地址 ip:port"`
 	ReadAddr    string                       `json:"read_addr" gorm:"type:varchar(1024);comment:读库地址 ip:port"`
+	PrimaryUUID string                       `json:"primary_uuid" gorm:"type:varchar(64);comment:自动发现的主库server_uuid"`
+	PrimaryAddr string                       `json:"primary_addr" gorm:"type:varchar(1024);comment:自动发现的主库地址 report_host或hostname:port"`
+	Replicas    string                       `json:"replicas" gorm:"type:varchar(4096);comment:自动发现的从库地址列表 ip:port,ip:port"`
+	TopologyAt  time.Time                    `json:"topology_at" gorm:"type:DATETIME;comment:最近一次发现集群拓扑的时间"`
 	UserName    string                       `json:"user_name" gorm:"type:varchar(1024);comment:用户名"`
 	Password    string                       `json:"password" gorm:"type:varchar(1024);comment:密码"`
 }
@@ -29,4 +37,5 @@ type ClusterStatistics struct {
 	Table       string `json:"table" gorm:"type:varchar(64);comment:表名"`
 	TableSize   int    `json:"table_size" gorm:"type:int;comment:表大小(GB)"`
 	Policies    string `json:"policies" gorm:"type:longtext;comment:对应的策略"` // 不包含重建表的策略
+	SrcID       uint   `json:"src_id" gorm:"type:int;comment:所属的多分库源端ID"`   // 表属于包含多个分库的源端时按源端汇总
 }
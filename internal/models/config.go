@@ -19,16 +19,37 @@ type Config struct {
 	TaskConflictLevel     TaskConflictLevelType `json:"task_conflict_level" gorm:"type:varchar(20);comment:任务冲突级别：源端集群级别，库级别，表级别"`
 	TaskConflictMax       int                   `json:"task_conflict_max" gorm:"type:int(4);comment:每个级别最多允许运行几个任务"`
 	WorkflowRetentionDays int                   `json:"workflow_retention_days" gorm:"type:int(4);comment:任务的工作流保留天数"`
+	TaskPriorityAging     int                   `json:"task_priority_aging" gorm:"type:int(4);default:1440;comment:任务优先级老化周期,等待执行每超过多少分钟优先级提升1,0表示不提升"`
+	TaskFairShare         bool                  `json:"task_fair_share" gorm:"type:int(4);comment:开启按BU权重公平分配任务并发数"`
 
 	ThanosUrl string `json:"thanos_url" gorm:"type:varchar(1024);comment:监控指标地址"`
 
 	SourceStatusDetect          bool `json:"source_status_detect" gorm:"type:int(4);comment:开启源端库运行状态检测"`
 	SourceStatusDetectDiskUsage int  `json:"source_status_detect_disk_usage" gorm:"type:int(4);comment:源端磁盘使用率"`
 
+	SourceStatusDetectThreadsRunning int `json:"source_status_detect_threads_running" gorm:"type:int(4);comment:源端正在运行的线程数阈值,0表示不检测"`
+	SourceStatusDetectActiveTrx      int `json:"source_status_detect_active_trx" gorm:"type:int(4);comment:源端活跃事务数阈值,0表示不检测"`
+	SourceStatusDetectQPS            int `json:"source_status_detect_qps" gorm:"type:int(4);comment:源端QPS阈值,0表示不检测"`
+	SourceStatusDetectSamples        int `json:"source_status_detect_samples" gorm:"type:int(4);comment:源端负载连续超过阈值多少次后暂停执行"`
+
+	PolicyConditionMaxScanRows int  `json:"policy_condition_max_scan_rows" gorm:"type:int(11);comment:策略治理条件预估扫描行数阈值,0表示不检测"`
+	PolicyConditionStrict      bool `json:"policy_condition_strict" gorm:"type:int(4);comment:治理条件无法使用索引或扫描行数超过阈值时拒绝保存策略,否则仅告警"`
+
 	Notice                  NoticeType `json:"notice" gorm:"type:varchar(255);comment:通知方式"`
 	EmailHost               string     `json:"email_host" gorm:"type:varchar(1024);comment:邮件服务器地址"`
 	EmailPort               int        `json:"email_port" gorm:"type:int(4);comment:邮件服务器端口"`
 	EmailUsername           string     `json:"email_username" gorm:"type:varchar(1024);comment:用户名"`
 	EmailPassword           string     `json:"email_password" gorm:"type:varchar(1024);comment:密码"`
 	EmailInsecureSkipVerify bool       `json:"email_insecure_skip_verify" gorm:"type:int(4);comment:不检验服务端证书"`
+
+	WebhookURL      string `json:"webhook_url" gorm:"type:varchar(1024);comment:Webhook通知地址"`
+	WebhookSecret   string `json:"webhook_secret" gorm:"type:varchar(1024);comment:Webhook签名密钥"`
+	WebhookTemplate string `json:"webhook_template" gorm:"type:longtext;comment:Webhook请求体模板,为空时使用默认格式"`
+	WebhookRetries  int    `json:"webhook_retries" gorm:"type:int(4);default:3;comment:Webhook发送失败后的重试次数"`
+
+	DingTalkWebhook string `json:"dingtalk_webhook" gorm:"type:varchar(1024);comment:钉钉群机器人地址"`
+	DingTalkSecret  string `json:"dingtalk_secret" gorm:"type:varchar(1024);comment:钉钉群机器人加签密钥"`
+	FeishuWebhook   string `json:"feishu_webhook" gorm:"type:varchar(1024);comment:飞书群机器人地址"`
+	FeishuSecret    string `json:"feishu_secret" gorm:"type:varchar(1024);comment:飞书群机器人签名校验密钥"`
+	WeComWebhook    string `json:"wecom_webhook" gorm:"type:varchar(1024);comment:企业微信群机器人地址"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// LeaderLease 选主租约，多副本部署时只有持有租约的副本运行定时任务
+type LeaderLease struct {
+	ID         uint      `json:"id" gorm:"primary_key;AUTO_INCREMENT;comment:ID"`
+	Name       string    `json:"name" gorm:"type:varchar(64);uniqueIndex:leader_lease_name_idx;not null;comment:租约名称"`
+	Holder     string    `json:"holder" gorm:"type:varchar(255);comment:租约持有者"`
+	AcquiredAt time.Time `json:"acquired_at" gorm:"type:DATETIME;comment:获得租约的时间"`
+	RenewedAt  time.Time `json:"renewed_at" gorm:"type:DATETIME;comment:最近一次续约的时间"`
+	ExpiredAt  time.Time `json:"expired_at" gorm:"type:DATETIME;comment:租约过期时间"`
+	Term       uint64    `json:"term" gorm:"type:bigint unsigned;default:0;comment:任期，每次获得租约时加1，用作防护令牌"`
+}
@@ -31,8 +31,19 @@ func UpdateModels() {
 		&Source{},
 		&Cluster{},
 		&ClusterStatistics{},
+		&CatalogTable{},
+		&CatalogSchemaChange{},
+		&PolicyRecommend{},
 		&Task{},
 		&TaskChangeLog{},
+		&SubTask{},
+		&Blackout{},
+		&Quota{},
+		&Throttle{},
+		&NotifyRoute{},
+		&NotifyEscalation{},
+		&QueueItem{},
+		&LeaderLease{},
 		&Config{},
 	)
 	if err != nil {
@@ -65,9 +76,14 @@ func UpdateModels() {
 		c.TaskConflictLevel = common.TaskConflictLevelCluster
 		c.TaskConflictMax = 1
 		c.WorkflowRetentionDays = 30 // 工作流记录默认保留30天
+		c.TaskPriorityAging = 1440   // 任务等待执行每超过1天优先级提升1
+		c.TaskFairShare = false
 
 		c.SourceStatusDetect = false
 		c.SourceStatusDetectDiskUsage = 95 // 磁盘使用率大于95%自动停止工作流
+		c.SourceStatusDetectSamples = 3    // 负载连续3次超过阈值自动暂停执行
+		c.PolicyConditionMaxScanRows = 10000000
+		c.PolicyConditionStrict = false
 
 		mysql.DB.Where("id=1").Save(c)
 		l.Log.Warnf("use defaule config(%+v)", c)
@@ -81,11 +97,14 @@ func (j *JSON) Scan(value interface{}) error {
 		*j = nil
 		return nil
 	}
-	s, ok := value.([]byte)
-	if !ok {
+	switch s := value.(type) {
+	case []byte:
+		*j = append((*j)[0:0], s...)
+	case string:
+		*j = append((*j)[0:0], s...)
+	default:
 		return errors.New("invalid scan source")
 	}
-	*j = append((*j)[0:0], s...)
 	return nil
 }
 
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// NotifyRoute 通知路由规则，任务按BU、集群、治理方式、任务状态匹配后发送到指定的通知渠道，
+// 任务失败超过指定时长仍未处理时升级通知
+type NotifyRoute struct {
+	Model
+
+	Name             string `json:"name" gorm:"type:varchar(1024);not null;comment:规则名称"`
+	Description      string `json:"description" gorm:"type:longtext;default '';comment:说明"`
+	Enable           bool   `json:"enable" gorm:"type:int(4);comment:是否生效"`
+	Bus              JSON   `json:"bus" gorm:"type:json;comment:匹配的BU,为空表示不限"`
+	ClusterIDs       JSON   `json:"cluster_ids" gorm:"type:json;comment:匹配的集群ID,为空表示不限"`
+	Governs          JSON   `json:"governs" gorm:"type:json;comment:匹配的治理方式,为空表示不限"`
+	TaskStatuses     JSON   `json:"task_statuses" gorm:"type:json;comment:匹配的任务状态,为空表示不限"`
+	Channels         JSON   `json:"channels" gorm:"type:json;comment:通知渠道"`
+	EscalateHours    int    `json:"escalate_hours" gorm:"type:int(11);comment:任务失败超过该小时数后升级通知,0表示不升级"`
+	EscalateChannels JSON   `json:"escalate_channels" gorm:"type:json;comment:升级通知的渠道,为空时使用通知渠道"`
+	EscalateRelevant JSON   `json:"escalate_relevant" gorm:"type:json;comment:升级通知额外通知的人"`
+}
+
+// NotifyEscalation 升级通知记录，同一个任务的同一次失败每条规则只升级一次
+type NotifyEscalation struct {
+	ID       uint      `json:"id" gorm:"primary_key;AUTO_INCREMENT;comment:ID"`
+	TaskID   uint      `json:"task_id" gorm:"type:int;uniqueIndex:notify_escalation_idx;comment:任务ID"`
+	RouteID  uint      `json:"route_id" gorm:"type:int;uniqueIndex:notify_escalation_idx;comment:通知路由规则ID"`
+	FailedAt time.Time `json:"failed_at" gorm:"type:DATETIME;uniqueIndex:notify_escalation_idx;comment:任务进入失败状态的时间"`
+	Time     time.Time `json:"time" gorm:"type:DATETIME;comment:升级通知的时间"`
+}
@@ -8,16 +8,20 @@ type Policy struct {
 	Model
 
 	// 归档策略元数据信息
-	Name          string                   `json:"name" gorm:"type:varchar(1024);not null;comment:策略名称"`
-	Description   string                   `json:"description" gorm:"type:longtext;default '';comment:说明"`
-	Bu            string                   `json:"bu" gorm:"type:varchar(64);comment:资产BU"`
-	Enable        bool                     `json:"enable" gorm:"type:int(4);comment:是否生效"`
-	Period        common.PeriodType        `json:"period" gorm:"type:varchar(64);not null;comment:执行周期"`
-	Day           int                      `json:"day" gorm:"type:int(4);comment:期望执行日期"`
-	ExecuteWindow JSON                     `json:"execute_window" gorm:"type:json;comment:执行窗口"`
-	Pause         bool                     `json:"pause" gorm:"type:int(4);comment:执行窗口外是否需要暂停执行"`
-	RebuildFlag   bool                     `json:"rebuild_flag" gorm:"type:int(4);comment:执行窗口外是否重建表(仅在治理方式是删除时有效)"`
-	CleaningSpeed common.CleaningSpeedType `json:"cleaning_speed" gorm:"type:varchar(64);comment:清理速度"`
+	Name           string                   `json:"name" gorm:"type:varchar(1024);not null;comment:策略名称"`
+	Description    string                   `json:"description" gorm:"type:longtext;default '';comment:说明"`
+	Bu             string                   `json:"bu" gorm:"type:varchar(64);comment:资产BU"`
+	Enable         bool                     `json:"enable" gorm:"type:int(4);comment:是否生效"`
+	Period         common.PeriodType        `json:"period" gorm:"type:varchar(64);not null;comment:执行周期"`
+	Day            int                      `json:"day" gorm:"type:int(4);comment:期望执行日期"`
+	CronExpr       string                   `json:"cron_expr" gorm:"type:varchar(256);comment:cron表达式(执行周期为cron时有效)"`
+	ExecuteWindow  JSON                     `json:"execute_window" gorm:"type:json;comment:执行窗口"`
+	ExecuteWindows JSON                     `json:"execute_windows" gorm:"type:json;comment:多执行窗口(按星期生效)"`
+	Pause          bool                     `json:"pause" gorm:"type:int(4);comment:执行窗口外是否需要暂停执行"`
+	RebuildFlag    bool                     `json:"rebuild_flag" gorm:"type:int(4);comment:执行窗口外是否重建表(仅在治理方式是删除时有效)"`
+	CleaningSpeed  common.CleaningSpeedType `json:"cleaning_speed" gorm:"type:varchar(64);comment:清理速度"`
+	Priority       int                      `json:"priority" gorm:"type:int(4);default:5;comment:优先级(1-10)，数值越大越优先执行"`
+	ThrottleID     uint                     `json:"throttle_id" gorm:"type:int;comment:限流配置ID，为空时按清理速度使用内置的限流配置"`
 
 	// 源端信息
 	SrcID uint `json:"src_id" gorm:"type:int;index:src_idx;comment:源端ID"`
@@ -28,9 +32,24 @@ type Policy struct {
 	ArchiveScope  string            `json:"archive_scope" gorm:"type:longtext;comment:数据归档范围，归档那些列（仅归档涉及）"`
 	RetainSrcData bool              `json:"retain_src_data" gorm:"type:int(4);comment:归档时否保留源表数据"`
 
+	// 分区治理（仅分区治理涉及）
+	PartitionColumn     string                       `json:"partition_column" gorm:"type:varchar(64);comment:分区列"`
+	PartitionInterval   common.PartitionIntervalType `json:"partition_interval" gorm:"type:varchar(64);comment:分区粒度"`
+	PartitionRetainDays int                          `json:"partition_retain_days" gorm:"type:int;comment:保留最近多少天的分区"`
+	PartitionPrecreate  int                          `json:"partition_precreate" gorm:"type:int;comment:预创建未来多少个周期的分区"`
+	PartitionAction     common.PartitionActionType   `json:"partition_action" gorm:"type:varchar(64);comment:过期分区处理方式"`
+
+	// 增量治理（仅删除和归档涉及）
+	WatermarkColumn string `json:"watermark_column" gorm:"type:varchar(64);comment:增量治理的水位列(主键或时间列)，为空表示不启用"`
+	Watermark       string `json:"watermark" gorm:"type:varchar(256);comment:已处理到的水位"`
+
 	// 目标端信息
 	DestID uint `json:"dest_id" gorm:"type:int;index:dest_idx;comment:目标端ID"`
 
+	// 策略依赖
+	DependsOn        JSON                        `json:"depends_on" gorm:"type:json;comment:依赖的策略ID列表"`
+	DependFailAction common.DependFailActionType `json:"depend_fail_action" gorm:"type:varchar(64);comment:依赖的策略任务执行失败时的处理方式"`
+
 	// 结果通知
 	Relevant     JSON                    `json:"relevant" gorm:"type:json;comment:关注人"`
 	NotifyPolicy common.NotifyPolicyType `json:"notify_policy" gorm:"type:varchar(64);not null;comment:通知策略"`
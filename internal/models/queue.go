@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"time"
+)
+
+// QueueItem 持久化的策略、任务待处理队列，服务重启后可继续处理
+type QueueItem struct {
+	Model
+
+	Queue       common.QueueNameType       `json:"queue" gorm:"type:varchar(32);index:queue_item_idx;comment:队列类型"`
+	ItemID      uint                       `json:"item_id" gorm:"type:int(11);index:queue_item_idx;comment:策略或任务ID"`
+	PolicyID    uint                       `json:"policy_id" gorm:"type:int(11);comment:策略ID"`
+	HandleID    string                     `json:"handle_id" gorm:"type:varchar(64);comment:处理ID"`
+	Status      common.QueueItemStatusType `json:"status" gorm:"type:varchar(32);index:queue_status_idx;comment:状态"`
+	Attempts    int                        `json:"attempts" gorm:"type:int(11);comment:已处理次数"`
+	LeaseOwner  string                     `json:"lease_owner" gorm:"type:varchar(255);comment:租约持有者"`
+	LeaseExpiry time.Time                  `json:"lease_expiry" gorm:"type:DATETIME;comment:租约过期时间"`
+	LastError   string                     `json:"last_error" gorm:"type:longtext;comment:最近一次处理失败的原因"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+// Quota 并发配额，限制同一BU或同一集群同时执行的任务数
+type Quota struct {
+	Model
+
+	Name        string                `json:"name" gorm:"type:varchar(1024);not null;comment:配额名称"`
+	Description string                `json:"description" gorm:"type:longtext;default '';comment:说明"`
+	Enable      bool                  `json:"enable" gorm:"type:int(4);comment:是否生效"`
+	Scope       common.QuotaScopeType `json:"scope" gorm:"type:varchar(64);not null;comment:生效范围"`
+	ScopeValue  string                `json:"scope_value" gorm:"type:varchar(128);index:quota_scope_idx;comment:生效的BU或集群ID"`
+	MaxParallel int                   `json:"max_parallel" gorm:"type:int(11);comment:最多同时执行的任务数,0表示不限制"`
+	Weight      int                   `json:"weight" gorm:"type:int(11);default:1;comment:公平分配并发时BU的权重"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+// PolicyRecommend 未被策略覆盖的大表的策略推荐
+type PolicyRecommend struct {
+	Model
+	Bu          string `json:"bu" gorm:"type:varchar(64);comment:bu"`
+	ClusterID   string `json:"cluster_id" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:集群ID"`
+	ClusterName string `json:"cluster_name" gorm:"type:varchar(64);comment:集群名称"`
+	Database    string `json:"database" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:库名"`
+	Table       string `json:"table" gorm:"type:varchar(64);index:idx_cluster_table;not null;comment:表名"`
+	TableSize   int    `json:"table_size" gorm:"type:int;comment:表大小(GB)"`
+
+	// 推荐依据
+	Column     string `json:"column" gorm:"type:varchar(64);comment:治理条件使用的列"`
+	ColumnType string `json:"column_type" gorm:"type:varchar(64);comment:列类型"`
+	IndexName  string `json:"index_name" gorm:"type:varchar(64);comment:列上的索引"`
+	DataAge    JSON   `json:"data_age" gorm:"type:json;comment:数据年龄分布"`
+	Reason     string `json:"reason" gorm:"type:longtext;comment:推荐理由"`
+
+	// 推荐的策略草稿
+	Govern        common.GovernType        `json:"govern" gorm:"type:varchar(64);comment:数据治理方式"`
+	Condition     string                   `json:"condition" gorm:"type:longtext;comment:数据治理条件"`
+	RetainDays    int                      `json:"retain_days" gorm:"type:int;comment:保留最近多少天的数据"`
+	Period        common.PeriodType        `json:"period" gorm:"type:varchar(64);comment:执行周期"`
+	Day           int                      `json:"day" gorm:"type:int(4);comment:期望执行日期"`
+	ExecuteWindow JSON                     `json:"execute_window" gorm:"type:json;comment:执行窗口"`
+	CleaningSpeed common.CleaningSpeedType `json:"cleaning_speed" gorm:"type:varchar(64);comment:清理速度"`
+
+	// 处理结果
+	Status        common.RecommendStatusType `json:"status" gorm:"type:varchar(64);index:idx_status;comment:状态"`
+	DismissReason string                     `json:"dismiss_reason" gorm:"type:longtext;comment:忽略原因"`
+	SrcID         uint                       `json:"src_id" gorm:"type:int;comment:采纳后对应的源端ID"`
+	PolicyID      uint                       `json:"policy_id" gorm:"type:int;comment:采纳后创建的策略ID"`
+}
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
 type Source struct {
 	Model
 
@@ -11,4 +15,13 @@ type Source struct {
 	ClusterID    string `json:"cluster_id" gorm:"type:varchar(128);not null;index:cluster_idx;comment:集群ID"`
 	DatabaseName string `json:"database_name" gorm:"type:varchar(128);index:database_name_idx;comment:源库名"`
 	TablesName   string `json:"tables_name" gorm:"type:longtext;comment:源表名"`
+
+	// 源表名匹配规则，不为空时在补充任务信息时重新匹配源表
+	TablesPattern     string                  `json:"tables_pattern" gorm:"type:varchar(1024);comment:源表名匹配规则"`
+	TablesPatternType common.TablePatternType `json:"tables_pattern_type" gorm:"type:varchar(64);comment:源表名匹配规则类型"`
+
+	// 源库名匹配规则，不为空时源端包含多个分库，DatabaseName为第一个匹配到的库
+	DatabasePattern     string                  `json:"database_pattern" gorm:"type:varchar(1024);comment:源库名匹配规则"`
+	DatabasePatternType common.TablePatternType `json:"database_pattern_type" gorm:"type:varchar(64);comment:源库名匹配规则类型"`
+	DatabasesName       string                  `json:"databases_name" gorm:"type:longtext;comment:匹配到的源库名"`
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"time"
+)
+
+// SubTask 源端包含多个分库时，任务按库拆分的子任务，依次执行
+type SubTask struct {
+	ID                 uint                  `json:"id" gorm:"primary_key;AUTO_INCREMENT;comment:ID"`
+	TaskID             uint                  `json:"task_id" gorm:"type:int;index:task_id_idx;comment:任务ID"`
+	DatabaseName       string                `json:"database_name" gorm:"type:varchar(128);comment:源库名"`
+	TablesName         string                `json:"tables_name" gorm:"type:longtext;comment:源表名"`
+	TaskStatus         common.TaskStatusType `json:"task_status" gorm:"type:varchar(64);comment:子任务状态"`
+	TaskReason         string                `json:"task_reason" gorm:"type:longtext;comment:子任务失败原因"`
+	TaskDetail         string                `json:"task_detail" gorm:"type:longtext;comment:子任务失败详情"`
+	WorkFlow           string                `json:"workflow" gorm:"type:varchar(1024);comment:工作流"`
+	TaskStartTime      time.Time             `json:"task_start_time" gorm:"type:DATETIME;comment:开始执行时间"`
+	TaskEndTime        time.Time             `json:"task_end_time" gorm:"type:DATETIME;comment:执行结束时间"`
+	TaskResultQuantity int                   `json:"task_result_quantity" gorm:"type:int;comment:治理数据量"`
+	TaskResultSize     int                   `json:"task_result_size" gorm:"type:int;comment:治理数据容量(MB)"`
+}
@@ -9,14 +9,19 @@ type Task struct {
 	Model
 
 	// 归档任务元数据信息
-	Name          string `json:"name" gorm:"type:varchar(1024);not null;comment:任务名称"`
-	Description   string `json:"description" gorm:"type:longtext;default '';comment:说明"`
-	Enable        bool   `json:"enable" gorm:"type:int(4);comment:是否生效"`
-	PolicyID      uint   `json:"policy_id" gorm:"type:int;index:policy_id_idx;comment:策略ID"`
-	ExecuteWindow JSON   `json:"execute_window" gorm:"type:json;comment:执行窗口"`
-	ExecuteDate   string `json:"execute_date" gorm:"varchar(20);index:execute_date_idx;comment:计划执行日期"`
-	Pause         bool   `json:"pause" gorm:"type:int(4);comment:执行窗口外是否需要暂停执行"`
-	RebuildFlag   bool   `json:"rebuild_flag" gorm:"type:int(4);comment:执行窗口外是否重建表(仅在治理方式是删除时有效)"`
+	Name            string `json:"name" gorm:"type:varchar(1024);not null;comment:任务名称"`
+	Description     string `json:"description" gorm:"type:longtext;default '';comment:说明"`
+	Enable          bool   `json:"enable" gorm:"type:int(4);comment:是否生效"`
+	PolicyID        uint   `json:"policy_id" gorm:"type:int;index:policy_id_idx;comment:策略ID"`
+	ExecuteWindow   JSON   `json:"execute_window" gorm:"type:json;comment:执行窗口"`
+	ExecuteWindows  JSON   `json:"execute_windows" gorm:"type:json;comment:多执行窗口(按星期生效)"`
+	ExecuteDate     string `json:"execute_date" gorm:"varchar(20);index:execute_date_idx;comment:计划执行日期"`
+	Timezone        string `json:"timezone" gorm:"type:varchar(64);comment:执行日期和执行窗口所在时区(取自源端集群)"`
+	Priority        int    `json:"priority" gorm:"type:int(4);default:5;comment:优先级(1-10)，数值越大越优先执行"`
+	Pause           bool   `json:"pause" gorm:"type:int(4);comment:执行窗口外是否需要暂停执行"`
+	RebuildFlag     bool   `json:"rebuild_flag" gorm:"type:int(4);comment:执行窗口外是否重建表(仅在治理方式是删除时有效)"`
+	Backfill        bool   `json:"backfill" gorm:"type:int(4);default:0;comment:是否为补录任务"`
+	BackfillPeriods string `json:"backfill_periods" gorm:"type:longtext;comment:补录的执行周期，多个以逗号分隔"`
 
 	// 源端信息
 	SrcID                  uint   `json:"src_id" gorm:"type:int;index:src_id;comment:任务ID"`
@@ -25,10 +30,13 @@ type Task struct {
 	SrcClusterName         string `json:"src_cluster_name" gorm:"type:varchar(128);not null;index:src_cluster_name_idx;comment:集群名称"`
 	SrcClusterID           string `json:"src_cluster_id" gorm:"type:varchar(128);not null;index:src_cluster_id_idx;comment:集群ID"`
 	SrcDatabaseName        string `json:"src_database_name" gorm:"type:varchar(128);comment:源库名"`
+	SrcDatabasesName       string `json:"src_databases_name" gorm:"type:longtext;comment:源端包含多个分库时的全部库名"`
 	SrcTablesName          string `json:"src_tables_name" gorm:"type:longtext;comment:源表名"`
 	SrcColumns             string `json:"src_columns" gorm:"type:longtext;comment:列名"`
 	SrcClusterFreeDisk     int    `json:"src_cluster_free_disk" gorm:"type:int;comment:磁盘剩余空间"`
 	SrcClusterSumTableSize int    `json:"src_cluster_sum_table_size" gorm:"type:int;comment:清理前表大小"`
+	SrcPrimaryAddr         string `json:"src_primary_addr" gorm:"type:varchar(1024);comment:开始执行时源端的主库地址"`
+	SrcPrimaryUUID         string `json:"src_primary_uuid" gorm:"type:varchar(64);comment:开始执行时源端主库的server_uuid"`
 
 	// 目标端信息
 	DestID           uint               `json:"dest_id" gorm:"type:int;index:dest_id;comment:目标端ID"`
@@ -44,6 +52,20 @@ type Task struct {
 	Condition     string                   `json:"condition" gorm:"type:longtext;comment:数据治理条件"`
 	RetainSrcData bool                     `json:"retain_src_data" gorm:"type:int(4);comment:归档时否保留源表数据"`
 	CleaningSpeed common.CleaningSpeedType `json:"cleaning_speed" gorm:"type:varchar(64);comment:清理速度"`
+	ThrottleID    uint                     `json:"throttle_id" gorm:"type:int;comment:限流配置ID，为空时按清理速度使用内置的限流配置"`
+
+	// 分区治理（仅分区治理涉及）
+	PartitionColumn     string                       `json:"partition_column" gorm:"type:varchar(64);comment:分区列"`
+	PartitionInterval   common.PartitionIntervalType `json:"partition_interval" gorm:"type:varchar(64);comment:分区粒度"`
+	PartitionRetainDays int                          `json:"partition_retain_days" gorm:"type:int;comment:保留最近多少天的分区"`
+	PartitionPrecreate  int                          `json:"partition_precreate" gorm:"type:int;comment:预创建未来多少个周期的分区"`
+	PartitionAction     common.PartitionActionType   `json:"partition_action" gorm:"type:varchar(64);comment:过期分区处理方式"`
+
+	// 增量治理（仅删除和归档涉及）
+	WatermarkColumn string `json:"watermark_column" gorm:"type:varchar(64);comment:增量治理的水位列"`
+	WatermarkLow    string `json:"watermark_low" gorm:"type:varchar(256);comment:本次治理的水位下界(不含)"`
+	WatermarkHigh   string `json:"watermark_high" gorm:"type:varchar(256);comment:本次治理的水位上界(含)"`
+	Watermark       string `json:"watermark" gorm:"type:varchar(256);comment:工作流上报的已处理到的水位"`
 
 	// 通知策略
 	Relevant     JSON                    `json:"relevant" gorm:"type:json;comment:关注人"`
@@ -0,0 +1,13 @@
+package models
+
+// Throttle 限流配置，控制删除和归档时每批处理的行数、批次间隔、每秒处理的行数及单个事务的大小
+type Throttle struct {
+	Model
+
+	Name             string `json:"name" gorm:"type:varchar(1024);not null;comment:限流配置名称"`
+	Description      string `json:"description" gorm:"type:longtext;default '';comment:说明"`
+	ChunkSize        int    `json:"chunk_size" gorm:"type:int(11);comment:每批处理的行数"`
+	SleepMs          int    `json:"sleep_ms" gorm:"type:int(11);comment:批次之间休眠的毫秒数"`
+	MaxRowsPerSecond int    `json:"max_rows_per_second" gorm:"type:int(11);comment:每秒最多处理的行数,0表示不限制"`
+	MaxTxnBytes      int64  `json:"max_txn_bytes" gorm:"type:bigint;comment:单个事务最多处理的字节数,0表示不限制"`
+}
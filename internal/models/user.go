@@ -8,6 +8,7 @@ type User struct {
 	Password  string    `json:"password" gorm:"type:varchar(150);not null"`
 	RealName  string    `json:"real_name" gorm:"type:varchar(50);"`
 	Email     string    `json:"email" gorm:"type:varchar(50);"`
+	ChatID    string    `json:"chat_id" gorm:"type:varchar(128);comment:聊天工具(钉钉、飞书、企业微信)中的用户ID,用于群机器人通知时@提醒"`
 	IsLdap    uint      `json:"is_ldap" gorm:"type:tinyint(2) not null default 0"`
 	LastLogin time.Time `json:"last_login" gorm:"type:DATETIME;"`
 }
@@ -1,23 +1,66 @@
 package message
 
+import (
+	"fmt"
+	"github.com/sunkaimr/data-loom/configs"
+)
+
 type Message struct {
-	TaskID             uint
-	TaskName           string
-	SrcClusterName     string
-	SrcDatabaseName    string
-	SrcTablesName      string
-	Govern             string
-	Condition          string
-	TaskStartTime      string
-	TaskEndTime        string
-	TaskDuration       string
-	TaskStatus         string
-	TaskResultQuantity int
-	TaskResultSize     int
-	TaskReason         string
-	Relevant           []string
+	TaskID             uint     `json:"task_id"`
+	TaskName           string   `json:"task_name"`
+	PolicyID           uint     `json:"policy_id"`
+	SrcBu              string   `json:"src_bu"`
+	SrcClusterID       string   `json:"src_cluster_id"`
+	SrcClusterName     string   `json:"src_cluster_name"`
+	SrcDatabaseName    string   `json:"src_database_name"`
+	SrcTablesName      string   `json:"src_tables_name"`
+	GovernCode         string   `json:"govern_code"` // 治理方式的原始值，如：delete
+	Govern             string   `json:"govern"`
+	Condition          string   `json:"condition"`
+	TaskStartTime      string   `json:"task_start_time"`
+	TaskEndTime        string   `json:"task_end_time"`
+	Timezone           string   `json:"timezone"` // 任务开始和结束时间所在时区
+	TaskDuration       string   `json:"task_duration"`
+	TaskStatusCode     string   `json:"task_status_code"` // 任务状态的原始值，如：failed
+	TaskStatus         string   `json:"task_status"`
+	TaskResultQuantity int      `json:"task_result_quantity"`
+	TaskResultSize     int      `json:"task_result_size"`
+	TaskReason         string   `json:"task_reason"`
+	Relevant           []string `json:"relevant"`
+	Mentions           []string `json:"mentions"` // 关注人在聊天工具中的用户ID，群机器人通知时@提醒
+
+	HomeURL         string `json:"home_url"`
+	TaskURL         string `json:"task_url"`
+	TaskStatusColor string `json:"-"` // 失败的提示颜色：#F33， 成功的颜色：008000
+}
+
+// Example 通知测试时使用的示例消息
+func Example(testUser string) *Message {
+	return &Message{
+		TaskID:             123,
+		TaskName:           "这是一个任务通知示例",
+		PolicyID:           1,
+		SrcBu:              "bu",
+		SrcClusterID:       "cluster-id",
+		SrcClusterName:     "cluster-name",
+		SrcDatabaseName:    "database",
+		SrcTablesName:      "table",
+		GovernCode:         "delete",
+		Govern:             "删除数据",
+		Condition:          "id < 10000",
+		TaskStartTime:      "2024-01-01 00:00:00",
+		TaskEndTime:        "2024-01-01 01:00:00",
+		Timezone:           "Asia/Shanghai",
+		TaskDuration:       "01:00:00",
+		TaskStatusCode:     "success",
+		TaskStatus:         "执行成功",
+		TaskResultQuantity: 123,
+		TaskResultSize:     456,
+		TaskReason:         "",
+		Relevant:           []string{testUser},
+		Mentions:           []string{testUser},
 
-	HomeURL         string
-	TaskURL         string
-	TaskStatusColor string // 失败的提示颜色：#F33， 成功的颜色：008000
+		HomeURL: configs.C.Server.ExternalAddr,
+		TaskURL: fmt.Sprintf("%s/#/task/detail?task_id=", configs.C.Server.ExternalAddr),
+	}
 }
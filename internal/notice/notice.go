@@ -8,7 +8,9 @@ import (
 	. "github.com/sunkaimr/data-loom/internal/notice/message"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
 	"github.com/sunkaimr/data-loom/pkg/email"
+	"github.com/sunkaimr/data-loom/pkg/robot"
 	"github.com/sunkaimr/data-loom/pkg/utils"
+	"github.com/sunkaimr/data-loom/pkg/webhook"
 	"strings"
 	"time"
 )
@@ -22,7 +24,12 @@ type Notice interface {
 }
 
 func NewDriver(c *models.Config) Notice {
-	switch c.Notice {
+	return NewDriverByType(c, c.Notice)
+}
+
+// NewDriverByType 按指定的通知方式创建通知驱动，用于测试未启用的通知方式
+func NewDriverByType(c *models.Config, t common.NoticeType) Notice {
+	switch t {
 	case common.NoticeTypeEmail:
 		return &email.Email{
 			Host:               c.EmailHost,
@@ -31,6 +38,19 @@ func NewDriver(c *models.Config) Notice {
 			Password:           c.EmailPassword,
 			InsecureSkipVerify: c.EmailInsecureSkipVerify,
 		}
+	case common.NoticeTypeWebhook:
+		return &webhook.Webhook{
+			URL:      c.WebhookURL,
+			Secret:   c.WebhookSecret,
+			Template: c.WebhookTemplate,
+			Retries:  c.WebhookRetries,
+		}
+	case common.NoticeTypeDingTalk:
+		return &robot.DingTalk{Webhook: c.DingTalkWebhook, Secret: c.DingTalkSecret}
+	case common.NoticeTypeFeishu:
+		return &robot.Feishu{Webhook: c.FeishuWebhook, Secret: c.FeishuSecret}
+	case common.NoticeTypeWeCom:
+		return &robot.WeCom{Webhook: c.WeComWebhook}
 	default:
 		return &email.Email{
 			Host:               c.EmailHost,
@@ -42,19 +62,45 @@ func NewDriver(c *models.Config) Notice {
 	}
 }
 
+// TestUser 群机器人通知测试时将用户名、姓名或邮箱转换为聊天工具中的用户ID，找不到时原样使用
+func TestUser(ctx *common.Context, t common.NoticeType, user string) string {
+	if !common.IsChatNoticeType(t) {
+		return user
+	}
+
+	var users []models.User
+	err := ctx.DB.Model(models.User{}).Where("username =? OR real_name =? OR email =?", user, user, user).Limit(1).Find(&users).Error
+	if err != nil {
+		ctx.Log.Errorf("query models.User(username=%v OR real_name=%v OR email=%v) failed, %s", user, user, user, err)
+		return user
+	}
+	if len(users) == 0 || users[0].ChatID == "" {
+		return user
+	}
+	return users[0].ChatID
+}
+
 func GenerateMessage(ctx *common.Context, task *models.Task) *Message {
 	log, db := ctx.Log, ctx.DB
 
+	// 任务时间按源端集群所在时区展示
+	loc := common.LoadLocation(task.Timezone)
 	msg := &Message{
 		TaskID:             task.ID,
 		TaskName:           task.Name,
+		PolicyID:           task.PolicyID,
+		SrcBu:              task.SrcBu,
+		SrcClusterID:       task.SrcClusterID,
 		SrcClusterName:     task.SrcClusterName,
 		SrcDatabaseName:    task.SrcDatabaseName,
+		GovernCode:         string(task.Govern),
 		Govern:             common.GovernCN[task.Govern],
 		Condition:          task.Condition,
-		TaskStartTime:      task.TaskStartTime.Format(time.DateTime),
-		TaskEndTime:        task.TaskEndTime.Format(time.DateTime),
+		TaskStartTime:      task.TaskStartTime.In(loc).Format(time.DateTime),
+		TaskEndTime:        task.TaskEndTime.In(loc).Format(time.DateTime),
+		Timezone:           loc.String(),
 		TaskDuration:       utils.HumanFormatTimeSeconds(task.TaskDuration),
+		TaskStatusCode:     string(task.TaskStatus),
 		TaskStatus:         common.TaskStatusCN[task.TaskStatus],
 		TaskResultQuantity: task.TaskResultQuantity,
 		TaskResultSize:     task.TaskResultSize,
@@ -67,7 +113,8 @@ func GenerateMessage(ctx *common.Context, task *models.Task) *Message {
 	if task.TaskStatus == common.TaskStatusSupplementFailed ||
 		task.TaskStatus == common.TaskStatusExecCheckFailed ||
 		task.TaskStatus == common.TaskStatusExecFailed ||
-		task.TaskStatus == common.TaskStatusTimeout {
+		task.TaskStatus == common.TaskStatusTimeout ||
+		task.TaskStatus == common.TaskStatusSkipped {
 		msg.TaskStatusColor = "#F33"
 	}
 
@@ -81,17 +128,26 @@ func GenerateMessage(ctx *common.Context, task *models.Task) *Message {
 	var relevant []string
 	_ = json.Unmarshal(task.Relevant, &relevant)
 	for i, v := range relevant {
-		if v == "" || utils.IsMail(v) {
+		if v == "" {
 			continue
 		}
 
-		var u models.User
-		err := db.Model(models.User{}).Where("username =? OR real_name =?", v, v).First(&u).Error
+		// 邮箱地址直接使用，同时查找对应用户在聊天工具中的用户ID
+		var users []models.User
+		err := db.Model(models.User{}).Where("username =? OR real_name =? OR email =?", v, v, v).Limit(1).Find(&users).Error
 		if err != nil {
-			log.Errorf("query models.User(username=%v OR real_name=%v) failed, %s", v, v, err)
+			log.Errorf("query models.User(username=%v OR real_name=%v OR email=%v) failed, %s", v, v, v, err)
+			continue
+		}
+		if len(users) == 0 {
 			continue
 		}
-		relevant[i] = u.Email
+		if !utils.IsMail(v) {
+			relevant[i] = users[0].Email
+		}
+		if users[0].ChatID != "" {
+			msg.Mentions = append(msg.Mentions, users[0].ChatID)
+		}
 	}
 	msg.Relevant = relevant
 	return msg
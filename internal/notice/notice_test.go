@@ -0,0 +1,54 @@
+package notice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sunkaimr/data-loom/configs"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/logger"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql/mysqltest"
+)
+
+func TestGenerateMessageMentions(t *testing.T) {
+	old := configs.C
+	configs.C = &configs.Config{}
+	defer func() { configs.C = old }()
+
+	db := mysqltest.Open(t, &models.User{})
+	users := []models.User{
+		{Username: "zhangsan", RealName: "张三", Email: "zhangsan@example.com", ChatID: "ding_zs"},
+		{Username: "lisi", RealName: "李四", Email: "lisi@example.com", ChatID: "ding_ls"},
+		{Username: "wangwu", RealName: "王五", Email: "wangwu@example.com"},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := common.NewContext().WithLog(logger.Log).WithDB(db)
+
+	tests := []struct {
+		name         string
+		relevant     string
+		wantRelevant []string
+		wantMentions []string
+	}{
+		{"username", `["zhangsan"]`, []string{"zhangsan@example.com"}, []string{"ding_zs"}},
+		{"real name and email", `["李四", "zhangsan@example.com"]`, []string{"lisi@example.com", "zhangsan@example.com"}, []string{"ding_ls", "ding_zs"}},
+		{"user without chat id", `["wangwu"]`, []string{"wangwu@example.com"}, nil},
+		{"unknown user", `["nobody", "other@example.com"]`, []string{"nobody", "other@example.com"}, nil},
+		{"no relevant", `[]`, []string{}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := &models.Task{Name: "task-1", Relevant: models.JSON(test.relevant)}
+			msg := GenerateMessage(ctx, task)
+			if !reflect.DeepEqual(msg.Relevant, test.wantRelevant) {
+				t.Fatalf("GenerateMessage() got relevant %v, want %v", msg.Relevant, test.wantRelevant)
+			}
+			if !reflect.DeepEqual(msg.Mentions, test.wantMentions) {
+				t.Fatalf("GenerateMessage() got mentions %v, want %v", msg.Mentions, test.wantMentions)
+			}
+		})
+	}
+}
@@ -46,6 +46,11 @@ var (
 	CodeSourceQueryDatabaseErr   = ServiceCode{5000201, "查询库列表失败，请联系管理员处理"}
 	CodeSourceQueryTableErr      = ServiceCode{5000202, "查询表列表失败，请联系管理员处理"}
 	CodeSourceQueryTableSizeErr  = ServiceCode{5000203, "查询表大小失败，请联系管理员处理"}
+
+	CodeSourceTablePatternErr      = ServiceCode{4000211, "源端表名匹配规则不合法"}
+	CodeSourceTablePatternEmpty    = ServiceCode{4000212, "源端表名匹配规则没有匹配到表"}
+	CodeSourceDatabasePatternErr   = ServiceCode{4000213, "源端库名匹配规则不合法"}
+	CodeSourceDatabasePatternEmpty = ServiceCode{4000214, "源端库名匹配规则没有匹配到库"}
 )
 
 // 归档库连接信息模块错误码范围: 4xx3xx - 5xx3xx
@@ -107,6 +112,27 @@ var (
 	CodePolicyNeedConditions   = ServiceCode{4000511, "删除数据时必须指定删除条件"}
 	CodePolicyNotExist         = ServiceCode{4040501, "策略不存在"}
 	CodePolicyNameConflict     = ServiceCode{4090501, "策略名字已存在"}
+
+	CodePolicyRecommendRunning  = ServiceCode{2000501, "策略推荐任务开始运行，可能需要几分钟时间"}
+	CodePolicyConditionReject   = ServiceCode{4000514, "策略的治理条件无法使用索引或扫描行数超过阈值"}
+	CodePolicyRecommendHandled  = ServiceCode{4000515, "策略推荐已被处理"}
+	CodePolicyRecommendExisted  = ServiceCode{4000516, "已有策略推荐任务正在运行，稍后再试"}
+	CodePolicyDismissReasonErr  = ServiceCode{4000517, "忽略策略推荐时必须填写原因"}
+	CodePolicyPartitionErr      = ServiceCode{4000518, "分区治理参数不合法"}
+	CodePolicyNotPartitioned    = ServiceCode{4000519, "源端表不是按指定列RANGE分区的表"}
+	CodePolicyCronExprErr       = ServiceCode{4000520, "cron表达式不合法"}
+	CodePolicyDependencyErr     = ServiceCode{4000521, "依赖的策略不合法"}
+	CodePolicyDependencyCycle   = ServiceCode{4000522, "策略之间的依赖存在环"}
+	CodePolicyDependFailErr     = ServiceCode{4000523, "依赖失败时的处理方式不合法"}
+	CodePolicyDepended          = ServiceCode{4000524, "策略被其他策略依赖，请先解除依赖"}
+	CodePolicyPriorityErr       = ServiceCode{4000525, "优先级不合法，取值范围1-10"}
+	CodePolicyBackfillDateErr   = ServiceCode{4000526, "补录的日期范围不合法"}
+	CodePolicyBackfillPeriodErr = ServiceCode{4000527, "一次性策略不支持补录"}
+	CodePolicyBackfillDisabled  = ServiceCode{4000528, "策略未生效，不能补录"}
+	CodePolicyBackfillTooMany   = ServiceCode{4000529, "补录的周期过多，请缩小日期范围"}
+	CodePolicyWatermarkErr      = ServiceCode{4000530, "增量治理的水位列不合法"}
+	CodePolicyThrottleErr       = ServiceCode{4000531, "限流配置不存在"}
+	CodePolicyRecommendNotExist = ServiceCode{4040502, "策略推荐不存在"}
 )
 
 // 任务模块错误码范围: 4xx6xx - 5xx6xx
@@ -123,10 +149,22 @@ var (
 	CodeTaskSrcTableConflict    = ServiceCode{4000609, "同一表执行中的任务达到上限"}
 	CodeTaskStatusErr           = ServiceCode{4000610, "任务状态不合法"}
 	CodeTaskExecDateNotReached  = ServiceCode{4000611, "任务未到执行日期"}
+	CodeTaskSrcClusterBusy      = ServiceCode{4000612, "源端集群负载过高"}
+	CodeTaskInBlackout          = ServiceCode{4000613, "处于封网期间禁止执行"}
+	CodeTaskPausedOutOfExecWin  = ServiceCode{4000614, "超出执行窗口已暂停，等待下一个执行窗口继续执行"}
+	CodeTaskDependencyNotReady  = ServiceCode{4000615, "依赖的策略任务尚未执行成功"}
+	CodeTaskDependencyFailed    = ServiceCode{4000616, "依赖的策略任务执行失败"}
+	CodeTaskPriorityErr         = ServiceCode{4000617, "优先级不合法，取值范围1-10"}
+	CodeTaskBuQuotaLimit        = ServiceCode{4000618, "BU执行中的任务达到配额上限"}
+	CodeTaskClusterQuotaLimit   = ServiceCode{4000619, "集群执行中的任务达到配额上限"}
+	CodeTaskFairShareLimit      = ServiceCode{4000620, "BU执行中的任务超过公平分配的并发份额"}
+	CodeTaskSimulateDaysErr     = ServiceCode{4000621, "模拟的天数不合法，取值范围1-31"}
+	CodeTaskPrevPeriodNotDone   = ServiceCode{4000622, "同一策略上一个周期的任务尚未执行完"}
 	CodeTaskStatusUpdateDenied  = ServiceCode{4030601, "权限不足无法更新任务结果"}
 	CodeTaskNotExist            = ServiceCode{4040601, "任务不存在"}
 	CodeTaskGenDestTabNameErr   = ServiceCode{5000601, "生成归档库表名失败"}
 	CodeTaskParallelUpperLimit  = ServiceCode{5000602, "达到任务并发上限"}
+	CodeTaskWatermarkErr        = ServiceCode{5000603, "计算增量治理的水位上界失败"}
 )
 
 // 工作流模块错误码范围: 4xx7xx - 5xx7xx
@@ -141,6 +179,7 @@ var (
 // 集群管理模块错误码范围: 4xx8xx - 5xx8xx
 var (
 	CodeClusterCollectedTaskRunning   = ServiceCode{2000801, "收集大表任务开始运行，可能需要几分钟时间"}
+	CodeClusterCatalogTaskRunning     = ServiceCode{2000802, "表结构快照任务开始运行，可能需要几分钟时间"}
 	CodeClusterNameErr                = ServiceCode{4000801, "集群名字不合法"}
 	CodeClusterIDErr                  = ServiceCode{4000802, "集群ID不合法"}
 	CodeClusterNameAndIDErr           = ServiceCode{4000803, "集群名字和ID不能同时为空"}
@@ -152,6 +191,8 @@ var (
 	CodeClusterSyncImmutable          = ServiceCode{4000809, "从外部同步的集群信息不支持修改"}
 	CodeClusterUsing                  = ServiceCode{4000810, "集群已被源端使用，请先删除对应源端"}
 	CodeClusterCollectedTaskExisted   = ServiceCode{4000811, "已有收集大表任务正在运行，稍后再试"}
+	CodeClusterCatalogTaskExisted     = ServiceCode{4000812, "已有表结构快照任务正在运行，稍后再试"}
+	CodeClusterTimezoneErr            = ServiceCode{4000813, "集群时区不合法"}
 	CodeClusterNotExist               = ServiceCode{4040801, "集群不存在"}
 	CodeClusterExisted                = ServiceCode{4090801, "集群名字或ID已存在"}
 	CodeClusterFreeDiskErr            = ServiceCode{5000801, "获取源端剩余磁盘空间失败，请联系管理员处理"}
@@ -164,4 +205,57 @@ var (
 	CodeConfigConflictLevelErr = ServiceCode{4000901, "任务冲突级别配置不合法"}
 	CodeConfigNoticeUserErr    = ServiceCode{4000902, "通知测试用户不能为空"}
 	CodeConfigNoticeErr        = ServiceCode{4000903, "通知测试失败"}
+	CodeConfigPriorityAgingErr = ServiceCode{4000904, "优先级老化周期配置不合法"}
+	CodeConfigNoticeTypeErr    = ServiceCode{4000905, "通知方式不合法"}
+	CodeConfigWebhookErr       = ServiceCode{4000906, "Webhook通知配置不合法"}
+	CodeConfigRobotErr         = ServiceCode{4000907, "群机器人通知配置不合法"}
+)
+
+// 封网日历模块错误码范围: 4xx10xx - 5xx10xx
+var (
+	CodeBlackoutNameErr       = ServiceCode{4001001, "封网名称不合法"}
+	CodeBlackoutScopeErr      = ServiceCode{4001002, "封网生效范围不合法"}
+	CodeBlackoutTimeErr       = ServiceCode{4001003, "封网时间范围不合法"}
+	CodeBlackoutRecurrenceErr = ServiceCode{4001004, "封网重复周期不合法"}
+	CodeBlackoutICalErr       = ServiceCode{4001005, "iCal文件解析失败"}
+	CodeBlackoutNotExist      = ServiceCode{4041001, "封网日历不存在"}
+	CodeBlackoutNameConflict  = ServiceCode{4091001, "封网名称已存在"}
+)
+
+// 并发配额模块错误码范围: 4xx11xx - 5xx11xx
+var (
+	CodeQuotaNameErr        = ServiceCode{4001101, "配额名称不合法"}
+	CodeQuotaScopeErr       = ServiceCode{4001102, "配额生效范围不合法"}
+	CodeQuotaMaxParallelErr = ServiceCode{4001103, "配额并发数不合法"}
+	CodeQuotaWeightErr      = ServiceCode{4001104, "配额权重不合法"}
+	CodeQuotaNotExist       = ServiceCode{4041101, "配额不存在"}
+	CodeQuotaNameConflict   = ServiceCode{4091101, "配额名称已存在"}
+	CodeQuotaScopeConflict  = ServiceCode{4091102, "该BU或集群已存在配额"}
+)
+
+// 持久化队列模块错误码范围: 4xx12xx - 5xx12xx
+var (
+	CodeQueueNameErr      = ServiceCode{4001201, "队列类型不合法"}
+	CodeQueueStatusErr    = ServiceCode{4001202, "队列元素状态不合法"}
+	CodeQueueItemNotExist = ServiceCode{4041201, "队列元素不存在"}
+)
+
+// 限流配置模块错误码范围: 4xx13xx - 5xx13xx
+var (
+	CodeThrottleNameErr      = ServiceCode{4001301, "限流配置名称不合法"}
+	CodeThrottleValueErr     = ServiceCode{4001302, "限流参数不合法"}
+	CodeThrottleTaskFinished = ServiceCode{4001303, "任务已执行结束，不能调整限流配置"}
+	CodeThrottleNotExist     = ServiceCode{4041301, "限流配置不存在"}
+	CodeThrottleNameConflict = ServiceCode{4091301, "限流配置名称已存在"}
+	CodeThrottleUsing        = ServiceCode{4091302, "限流配置正在被策略或任务使用"}
+)
+
+// 通知路由模块错误码范围: 4xx14xx - 5xx14xx
+var (
+	CodeNotifyRouteNameErr      = ServiceCode{4001401, "通知路由规则名称不合法"}
+	CodeNotifyRouteMatchErr     = ServiceCode{4001402, "通知路由匹配条件不合法"}
+	CodeNotifyRouteChannelErr   = ServiceCode{4001403, "通知渠道不合法"}
+	CodeNotifyRouteEscalateErr  = ServiceCode{4001404, "升级通知配置不合法"}
+	CodeNotifyRouteNotExist     = ServiceCode{4041401, "通知路由规则不存在"}
+	CodeNotifyRouteNameConflict = ServiceCode{4091401, "通知路由规则名称已存在"}
 )
@@ -6,13 +6,28 @@ import (
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"net/http"
+	"os"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// InstanceName 当前进程的实例标识：主机名/进程号/启动时间，用于队列租约和选主。
+// 容器重启后主机名和进程号可能不变，加上启动时间保证每个进程的标识不同
+var InstanceName = instanceName()
+
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s/%d/%x", host, os.Getpid(), time.Now().UnixNano())
+}
+
 type Context struct {
 	Context context.Context
 	Wg      *sync.WaitGroup
@@ -100,7 +115,7 @@ func ParsingQueryUintID(id string) uint {
 func CheckPeriod(s PeriodType) bool {
 	switch s {
 	case PeriodOnce, PeriodMonthly, PeriodQuarterly, PeriodSixMonths, PeriodYearly,
-		PeriodDay, PeriodTwoDay, PeriodWeekly, PeriodTwoWeeks:
+		PeriodDay, PeriodTwoDay, PeriodWeekly, PeriodTwoWeeks, PeriodCron:
 		return true
 	default:
 		return false
@@ -109,8 +124,8 @@ func CheckPeriod(s PeriodType) bool {
 
 func CheckPolicyDay(p PeriodType, day int) bool {
 	switch p {
-	case PeriodOnce, PeriodDay, PeriodTwoDay, PeriodWeekly, PeriodTwoWeeks:
-		// 执行周期小于月度的day不影响
+	case PeriodOnce, PeriodDay, PeriodTwoDay, PeriodWeekly, PeriodTwoWeeks, PeriodCron:
+		// 执行周期小于月度及按cron表达式执行的day不影响
 		return true
 	case PeriodMonthly, PeriodQuarterly, PeriodSixMonths, PeriodYearly:
 		if day >= 1 && day <= 31 {
@@ -122,6 +137,20 @@ func CheckPolicyDay(p PeriodType, day int) bool {
 	return false
 }
 
+func CheckNoticeType(s NoticeType) bool {
+	switch s {
+	case NoticeTypeEmail, NoticeTypeWebhook, NoticeTypeDingTalk, NoticeTypeFeishu, NoticeTypeWeCom:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsChatNoticeType 是否为群机器人通知，群机器人通过聊天工具中的用户ID@提醒用户
+func IsChatNoticeType(s NoticeType) bool {
+	return s == NoticeTypeDingTalk || s == NoticeTypeFeishu || s == NoticeTypeWeCom
+}
+
 func CheckCleaningSpeed(s CleaningSpeedType) bool {
 	switch s {
 	case CleaningSpeedSteady, CleaningSpeedBalanced, CleaningSpeed:
@@ -133,7 +162,220 @@ func CheckCleaningSpeed(s CleaningSpeedType) bool {
 
 func CheckGovernType(s GovernType) bool {
 	switch s {
-	case GovernTypeTruncate, GovernTypeDelete, GovernTypeBackupDelete, GovernTypeArchive, GovernTypeRebuild:
+	case GovernTypeTruncate, GovernTypeDelete, GovernTypeBackupDelete, GovernTypeArchive, GovernTypeRebuild, GovernTypePartition:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckPartitionInterval(s PartitionIntervalType) bool {
+	switch s {
+	case PartitionIntervalDay, PartitionIntervalWeek, PartitionIntervalMonth, PartitionIntervalYear:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckPartitionAction(s PartitionActionType) bool {
+	switch s {
+	case PartitionActionDrop, PartitionActionExchange:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckTablePatternType(s TablePatternType) bool {
+	switch s {
+	case TablePatternGlob, TablePatternRegex:
+		return true
+	default:
+		return false
+	}
+}
+
+// MatchNamePattern 从names中筛选出符合匹配规则的库名或表名，按匹配规则自身的顺序返回：
+// 通配符有多个时按匹配到的通配符的先后排序，同一个通配符或正则表达式匹配到的名字按自然顺序排序(如 t_2 在 t_10 之前)
+func MatchNamePattern(patternType TablePatternType, pattern string, names []string) ([]string, error) {
+	var match func(string) int
+	switch patternType {
+	case TablePatternGlob:
+		globs := strings.Split(pattern, ",")
+		for _, g := range globs {
+			if _, err := path.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob pattern(%s), %s", g, err)
+			}
+		}
+		match = func(name string) int {
+			for i, g := range globs {
+				if ok, _ := path.Match(g, name); ok {
+					return i
+				}
+			}
+			return -1
+		}
+	case TablePatternRegex:
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern(%s), %s", pattern, err)
+		}
+		match = func(name string) int {
+			if re.MatchString(name) {
+				return 0
+			}
+			return -1
+		}
+	default:
+		return nil, fmt.Errorf("unsupported pattern type(%s)", patternType)
+	}
+
+	matched := make([]string, 0, len(names))
+	order := make(map[string]int, len(names))
+	for _, name := range names {
+		if i := match(name); i >= 0 {
+			matched = append(matched, name)
+			order[name] = i
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if order[matched[i]] != order[matched[j]] {
+			return order[matched[i]] < order[matched[j]]
+		}
+		return NaturalLess(matched[i], matched[j])
+	})
+	return matched, nil
+}
+
+// NaturalLess 按自然顺序比较名字，名字中连续的数字按数值大小比较
+func NaturalLess(a, b string) bool {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	for a != "" && b != "" {
+		if !isDigit(a[0]) || !isDigit(b[0]) {
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		i, j := 0, 0
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		na, nb := strings.TrimLeft(a[:i], "0"), strings.TrimLeft(b[:j], "0")
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+		if na != nb {
+			return na < nb
+		}
+		a, b = a[i:], b[j:]
+	}
+	return len(a) < len(b)
+}
+
+var shardingTableRe = regexp.MustCompile(`^(.+)_\d+$`)
+
+// PickShardingTables 从匹配规则匹配到的表中选出同一个分表(表名_编号)的表，匹配到多个分表时选表最多的一个，
+// 返回保留的表和跳过的表，顺序与tables一致；只匹配到一张表时不要求分表的命名规则
+func PickShardingTables(tables []string) ([]string, []string, error) {
+	if len(tables) <= 1 {
+		return tables, nil, nil
+	}
+
+	count := make(map[string]int)
+	baseName := ""
+	for _, t := range tables {
+		match := shardingTableRe.FindStringSubmatch(t)
+		if len(match) != 2 {
+			continue
+		}
+		count[match[1]]++
+		if c := count[match[1]]; c > count[baseName] || (c == count[baseName] && match[1] < baseName) {
+			baseName = match[1]
+		}
+	}
+	if baseName == "" {
+		return nil, nil, fmt.Errorf("none of tables(%s) conform to the sharding table naming rules", strings.Join(tables, ","))
+	}
+
+	var kept, skipped []string
+	for _, t := range tables {
+		if match := shardingTableRe.FindStringSubmatch(t); len(match) == 2 && match[1] == baseName {
+			kept = append(kept, t)
+		} else {
+			skipped = append(skipped, t)
+		}
+	}
+	return kept, skipped, nil
+}
+
+// SortShardingTables 按分表编号对表排序
+func SortShardingTables(tables []string, baseName string) {
+	sort.Slice(tables, func(i, j int) bool {
+		num1, _ := strconv.Atoi(strings.TrimPrefix(tables[i], baseName+"_"))
+		num2, _ := strconv.Atoi(strings.TrimPrefix(tables[j], baseName+"_"))
+		return num1 < num2
+	})
+}
+
+func CheckQueueName(s QueueNameType) bool {
+	switch s {
+	case QueueNamePolicy, QueueNameTask:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckQueueItemStatus(s QueueItemStatusType) bool {
+	switch s {
+	case QueueItemPending, QueueItemProcessing, QueueItemFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckQuotaScope(s QuotaScopeType) bool {
+	switch s {
+	case QuotaScopeBu, QuotaScopeCluster:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckBlackoutScope(s BlackoutScopeType) bool {
+	switch s {
+	case BlackoutScopeGlobal, BlackoutScopeBu, BlackoutScopeCluster:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckBlackoutRecurrence(s BlackoutRecurrenceType) bool {
+	switch s {
+	case BlackoutRecurrenceNone, BlackoutRecurrenceDaily, BlackoutRecurrenceWeekly, BlackoutRecurrenceMonthly, BlackoutRecurrenceYearly:
+		return true
+	default:
+		return false
+	}
+}
+
+func CheckPriority(p int) bool {
+	return p >= PriorityMin && p <= PriorityMax
+}
+
+func CheckDependFailAction(s DependFailActionType) bool {
+	switch s {
+	case DependFailActionBlock, DependFailActionSkip, DependFailActionNotify:
 		return true
 	default:
 		return false
@@ -152,7 +394,7 @@ func CheckNotifyPolicyType(s NotifyPolicyType) bool {
 func CheckTaskStatusType(s TaskStatusType) bool {
 	switch s {
 	case TaskStatusScheduled, TaskStatusSupplementFailed, TaskStatusWaiting, TaskStatusExecCheckFailed,
-		TaskStatusExecuting, TaskStatusSuccess, TaskStatusExecFailed, TaskStatusTimeout:
+		TaskStatusExecuting, TaskStatusSuccess, TaskStatusExecFailed, TaskStatusTimeout, TaskStatusSkipped:
 		return true
 	default:
 		return false
@@ -212,8 +454,8 @@ func GenerateDestTableName(srcTabName, destTabName string) (string, error) {
 }
 
 // JudgeTaskCouldCheckBeforeExec 提前一天检查任务是否满足执行条件，以便于人可以提前介入修复异常
-func JudgeTaskCouldCheckBeforeExec(execDate string) bool {
-	if time.Now().Add(time.Hour*24).Format(time.DateOnly) >= execDate {
+func JudgeTaskCouldCheckBeforeExec(execDate, tz string) bool {
+	if NowIn(tz).Add(time.Hour*24).Format(time.DateOnly) >= execDate {
 		return true
 	}
 	return false
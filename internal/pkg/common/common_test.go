@@ -0,0 +1,143 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchNamePattern(t *testing.T) {
+	tables := []string{"order_10", "order_2", "order_1", "order_history", "order_bak", "user", "order_2_bak"}
+	tests := []struct {
+		name        string
+		patternType TablePatternType
+		pattern     string
+		exclude     string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:        "glob natural order",
+			patternType: TablePatternGlob,
+			pattern:     "order_*",
+			want:        []string{"order_1", "order_2", "order_2_bak", "order_10", "order_bak", "order_history"},
+		},
+		{
+			name:        "multiple globs in pattern order",
+			patternType: TablePatternGlob,
+			pattern:     "user,order_?",
+			want:        []string{"user", "order_1", "order_2"},
+		},
+		{
+			name:        "regex",
+			patternType: TablePatternRegex,
+			pattern:     `order_\d+`,
+			want:        []string{"order_1", "order_2", "order_10"},
+		},
+		{
+			name:        "regex matches whole name",
+			patternType: TablePatternRegex,
+			pattern:     `order_2`,
+			want:        []string{"order_2"},
+		},
+		{
+			name:        "excluded tables",
+			patternType: TablePatternGlob,
+			pattern:     "order_*",
+			exclude:     "order_bak,db1.order_history,db2.order_1",
+			want:        []string{"order_1", "order_2", "order_2_bak", "order_10"},
+		},
+		{
+			name:        "zero matches",
+			patternType: TablePatternRegex,
+			pattern:     `orders_\d+`,
+			want:        []string{},
+		},
+		{name: "invalid glob", patternType: TablePatternGlob, pattern: "order_[", wantErr: true},
+		{name: "invalid regex", patternType: TablePatternRegex, pattern: "order_(", wantErr: true},
+		{name: "unsupported type", patternType: "like", pattern: "order_%", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := ExcludeTablesFilter("db1", tables, strings.Split(test.exclude, ","))
+			got, err := MatchNamePattern(test.patternType, test.pattern, names)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("MatchNamePattern() got err %v, want err %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("MatchNamePattern() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"t_2", "t_10", true},
+		{"t_10", "t_2", false},
+		{"t_02", "t_10", true},
+		{"db_1_9", "db_1_10", true},
+		{"a", "b", true},
+		{"t_1", "t_1_bak", true},
+		{"t_1", "t_1", false},
+		{"t_9", "t_a", true},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"<"+test.b, func(t *testing.T) {
+			if got := NaturalLess(test.a, test.b); got != test.want {
+				t.Fatalf("NaturalLess(%s, %s) got %v, want %v", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPickShardingTables(t *testing.T) {
+	tests := []struct {
+		name        string
+		tables      []string
+		wantKept    []string
+		wantSkipped []string
+		wantErr     bool
+	}{
+		{"single table", []string{"order_history"}, []string{"order_history"}, nil, false},
+		{"sharding tables", []string{"order_1", "order_2"}, []string{"order_1", "order_2"}, nil, false},
+		{
+			name:        "skip tables not sharding",
+			tables:      []string{"order_1", "order_2", "order_bak", "order_history"},
+			wantKept:    []string{"order_1", "order_2"},
+			wantSkipped: []string{"order_bak", "order_history"},
+		},
+		{
+			name:        "largest sharding group kept",
+			tables:      []string{"order_1", "order_1_1", "order_1_2", "order_2"},
+			wantKept:    []string{"order_1", "order_2"},
+			wantSkipped: []string{"order_1_1", "order_1_2"},
+		},
+		{
+			name:        "same size group by base name",
+			tables:      []string{"b_1", "a_1"},
+			wantKept:    []string{"a_1"},
+			wantSkipped: []string{"b_1"},
+		},
+		{name: "no sharding tables", tables: []string{"order_bak", "order_history"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept, skipped, err := PickShardingTables(test.tables)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("PickShardingTables() got err %v, want err %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(kept, test.wantKept) || !reflect.DeepEqual(skipped, test.wantSkipped) {
+				t.Fatalf("PickShardingTables() got %v %v, want %v %v", kept, skipped, test.wantKept, test.wantSkipped)
+			}
+			if err == nil && len(kept) > 1 {
+				if ok, _, err := CheckSameShardingTables(strings.Join(kept, ",")); !ok || err != nil {
+					t.Fatalf("CheckSameShardingTables(%v) got %v %v, want sharding tables", kept, ok, err)
+				}
+			}
+		})
+	}
+}
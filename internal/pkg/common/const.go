@@ -4,6 +4,7 @@ import "math"
 
 const InvalidUint uint = math.MaxUint
 const InvalidInt int = math.MaxInt
+const InvalidInt64 int64 = math.MaxInt64
 
 const (
 	RequestID = "RequestID"
@@ -33,10 +34,43 @@ const (
 	ClusterTypeOther ClusterType = ""
 )
 
+// SchemaChangeType 表结构变更类型
+type SchemaChangeType string
+
+const (
+	SchemaChangeCreate SchemaChangeType = "create" // 新建表
+	SchemaChangeAlter  SchemaChangeType = "alter"  // 修改表
+	SchemaChangeDrop   SchemaChangeType = "drop"   // 删除表
+)
+
+// ExplainResultType 治理条件执行计划检查结果
+type ExplainResultType string
+
+const (
+	ExplainResultPass   ExplainResultType = "pass"   // 通过
+	ExplainResultWarn   ExplainResultType = "warn"   // 告警
+	ExplainResultReject ExplainResultType = "reject" // 拒绝
+)
+
+// RecommendStatusType 策略推荐的状态
+type RecommendStatusType string
+
+const (
+	RecommendStatusPending   RecommendStatusType = "pending"   // 待处理
+	RecommendStatusAccepted  RecommendStatusType = "accepted"  // 已采纳
+	RecommendStatusDismissed RecommendStatusType = "dismissed" // 已忽略
+)
+
 type NoticeType string
 
 const (
-	NoticeTypeEmail NoticeType = "email"
+	NoticeTypeEmail   NoticeType = "email"
+	NoticeTypeWebhook NoticeType = "webhook"
+
+	// 群机器人通知
+	NoticeTypeDingTalk NoticeType = "dingtalk"
+	NoticeTypeFeishu   NoticeType = "feishu"
+	NoticeTypeWeCom    NoticeType = "wecom"
 )
 
 // StorageType 归档存贮介质
@@ -68,6 +102,7 @@ const (
 	PeriodQuarterly PeriodType = "quarterly"  // 每季度一次
 	PeriodSixMonths PeriodType = "six-months" // 每半年一次
 	PeriodYearly    PeriodType = "yearly"     // 每年一次
+	PeriodCron      PeriodType = "cron"       // 按cron表达式执行
 )
 
 var PeriodCN = map[PeriodType]string{
@@ -80,6 +115,7 @@ var PeriodCN = map[PeriodType]string{
 	PeriodQuarterly: "季度任务",
 	PeriodSixMonths: "半年度任务",
 	PeriodYearly:    "年度任务",
+	PeriodCron:      "定时任务",
 }
 
 // CleaningSpeedType 清理速速
@@ -92,6 +128,14 @@ const (
 	CleaningSpeedDefault                    = CleaningSpeedBalanced
 )
 
+// TablePatternType 源端表名匹配规则类型
+type TablePatternType string
+
+const (
+	TablePatternGlob  TablePatternType = "glob"  // 通配符，如 order_*，多个规则用逗号分隔
+	TablePatternRegex TablePatternType = "regex" // 正则表达式，如 ^order_\d+$
+)
+
 // GovernType 清理类型
 type GovernType string
 
@@ -101,6 +145,7 @@ const (
 	GovernTypeBackupDelete GovernType = "backup-delete" // 备份后删除
 	GovernTypeArchive      GovernType = "archive"       // 归档数据
 	GovernTypeRebuild      GovernType = "rebuild"       // 重建表
+	GovernTypePartition    GovernType = "partition"     // 分区治理
 )
 
 var GovernCN = map[GovernType]string{
@@ -109,8 +154,90 @@ var GovernCN = map[GovernType]string{
 	GovernTypeBackupDelete: "备份后删除",
 	GovernTypeArchive:      "归档数据",
 	GovernTypeRebuild:      "重建表",
+	GovernTypePartition:    "分区治理",
 }
 
+// PartitionIntervalType 分区粒度
+type PartitionIntervalType string
+
+const (
+	PartitionIntervalDay   PartitionIntervalType = "day"   // 按天分区
+	PartitionIntervalWeek  PartitionIntervalType = "week"  // 按周分区
+	PartitionIntervalMonth PartitionIntervalType = "month" // 按月分区
+	PartitionIntervalYear  PartitionIntervalType = "year"  // 按年分区
+)
+
+// PartitionActionType 过期分区的处理方式
+type PartitionActionType string
+
+const (
+	PartitionActionDrop     PartitionActionType = "drop"     // 直接删除分区
+	PartitionActionExchange PartitionActionType = "exchange" // 将分区交换到归档表后再删除分区
+)
+
+// QueueNameType 持久化队列的类型
+type QueueNameType string
+
+const (
+	QueueNamePolicy QueueNameType = "policy" // 待处理的策略队列
+	QueueNameTask   QueueNameType = "task"   // 待补充信息的任务队列
+)
+
+// QueueItemStatusType 持久化队列中元素的状态
+type QueueItemStatusType string
+
+const (
+	QueueItemPending    QueueItemStatusType = "pending"    // 等待处理
+	QueueItemProcessing QueueItemStatusType = "processing" // 处理中
+	QueueItemFailed     QueueItemStatusType = "failed"     // 处理失败
+)
+
+// QuotaScopeType 并发配额的生效范围
+type QuotaScopeType string
+
+const (
+	QuotaScopeBu      QuotaScopeType = "bu"      // 限制指定BU
+	QuotaScopeCluster QuotaScopeType = "cluster" // 限制指定集群
+)
+
+// BlackoutScopeType 封网日历的生效范围
+type BlackoutScopeType string
+
+const (
+	BlackoutScopeGlobal  BlackoutScopeType = "global"  // 全局生效
+	BlackoutScopeBu      BlackoutScopeType = "bu"      // 指定BU生效
+	BlackoutScopeCluster BlackoutScopeType = "cluster" // 指定集群生效
+)
+
+// BlackoutRecurrenceType 封网日历的重复周期
+type BlackoutRecurrenceType string
+
+const (
+	BlackoutRecurrenceNone    BlackoutRecurrenceType = "none"    // 不重复
+	BlackoutRecurrenceDaily   BlackoutRecurrenceType = "daily"   // 每天重复
+	BlackoutRecurrenceWeekly  BlackoutRecurrenceType = "weekly"  // 每周重复
+	BlackoutRecurrenceMonthly BlackoutRecurrenceType = "monthly" // 每月重复
+	BlackoutRecurrenceYearly  BlackoutRecurrenceType = "yearly"  // 每年重复
+)
+
+// 任务优先级，数值越大越优先执行
+const (
+	PriorityMin     = 1
+	PriorityMax     = 10
+	PriorityDefault = 5
+)
+
+// DependFailActionType 依赖的策略在本周期的任务执行失败时的处理方式
+type DependFailActionType string
+
+const (
+	DependFailActionBlock  DependFailActionType = "block"  // 阻塞等待，直到依赖的策略在本周期有任务执行成功
+	DependFailActionSkip   DependFailActionType = "skip"   // 跳过本周期的任务
+	DependFailActionNotify DependFailActionType = "notify" // 通知关注人后继续执行
+
+	DependFailActionDefault = DependFailActionBlock
+)
+
 // NotifyPolicyType 通知策略
 type NotifyPolicyType string
 
@@ -143,6 +270,7 @@ const (
 	TaskStatusSuccess          TaskStatusType = "success"           // 执行成功
 	TaskStatusExecFailed       TaskStatusType = "failed"            // 执行失败
 	TaskStatusTimeout          TaskStatusType = "timeout"           // 执行超时
+	TaskStatusSkipped          TaskStatusType = "skipped"           // 已跳过
 )
 
 var TaskStatusCN = map[TaskStatusType]string{
@@ -154,13 +282,14 @@ var TaskStatusCN = map[TaskStatusType]string{
 	TaskStatusSuccess:          "执行成功",
 	TaskStatusExecFailed:       "执行失败",
 	TaskStatusTimeout:          "执行超时",
+	TaskStatusSkipped:          "已跳过",
 }
 
-// TaskStatusHasFinished 任务已经结束(执行成功,执行失败,执行超时)
-var TaskStatusHasFinished = []TaskStatusType{TaskStatusSuccess, TaskStatusExecFailed, TaskStatusTimeout}
+// TaskStatusHasFinished 任务已经结束(执行成功,执行失败,执行超时,已跳过)
+var TaskStatusHasFinished = []TaskStatusType{TaskStatusSuccess, TaskStatusExecFailed, TaskStatusTimeout, TaskStatusSkipped}
 
 // TaskStatusExecTimeImmutable 任务已经开始或结束, 任务的执行时间不会再更改了
-var TaskStatusExecTimeImmutable = []TaskStatusType{TaskStatusExecuting, TaskStatusSuccess, TaskStatusExecFailed, TaskStatusTimeout}
+var TaskStatusExecTimeImmutable = []TaskStatusType{TaskStatusExecuting, TaskStatusSuccess, TaskStatusExecFailed, TaskStatusTimeout, TaskStatusSkipped}
 
 // TaskStatusCanExec 可以调用工作流的任务
 var TaskStatusCanExec = []TaskStatusType{TaskStatusWaiting, TaskStatusExecCheckFailed}
@@ -168,18 +297,39 @@ var TaskStatusCanExec = []TaskStatusType{TaskStatusWaiting, TaskStatusExecCheckF
 // TaskStatusCanUpdate 以下状态的任务还没有进入执行状态可以更改
 var TaskStatusCanUpdate = []TaskStatusType{TaskStatusScheduled, TaskStatusSupplementFailed, TaskStatusWaiting, TaskStatusExecCheckFailed}
 
+// TaskStatusFailed 任务执行失败需要人工处理，超过一定时长仍未处理时按通知路由规则升级通知
+var TaskStatusFailed = []TaskStatusType{TaskStatusSupplementFailed, TaskStatusExecCheckFailed, TaskStatusExecFailed, TaskStatusTimeout}
+
 // TaskStatusNotFinish 任务未完成的不可删除
 var TaskStatusNotFinish = []TaskStatusType{TaskStatusScheduled, TaskStatusSupplementFailed, TaskStatusWaiting, TaskStatusExecCheckFailed, TaskStatusExecuting}
 
 const (
-	TaskChangeLogCreate              = "创建任务"
-	TaskChangeLogUpdateByPolicy      = "策略发生变动，同步更新任务，执行日期：%s 执行窗口：%s"
-	TaskChangeLogUpdate              = "任务发生变动，执行日期：%s 执行窗口：%s"
-	TaskChangeLogSupplementSuccess   = "补充任务信息"
-	TaskChangeLogSupplementFailed    = "补充任务信息失败，原因：%s，详情：%s"
-	TaskChangeLogWaitingExec         = "任务等待执行，原因：%s，详情：%s"
-	TaskChangeLogCallWorkFlowSuccess = "调用工作流"
-	TaskChangeLogCallWorkFlowFailed  = "调用工作流失败，原因：%s，详情：%s"
-	TaskChangeLogStopWorkFlow        = "停止工作流，原因：源端磁盘空间不足，详情：%s"
-	TaskChangeLogWorkFlowFinished    = "工作流结束"
+	TaskChangeLogCreate                  = "创建任务"
+	TaskChangeLogUpdateByPolicy          = "策略发生变动，同步更新任务，执行日期：%s 执行窗口：%s"
+	TaskChangeLogUpdate                  = "任务发生变动，执行日期：%s 执行窗口：%s"
+	TaskChangeLogSupplementSuccess       = "补充任务信息"
+	TaskChangeLogSupplementFailed        = "补充任务信息失败，原因：%s，详情：%s"
+	TaskChangeLogWaitingExec             = "任务等待执行，原因：%s，详情：%s"
+	TaskChangeLogCallWorkFlowSuccess     = "调用工作流"
+	TaskChangeLogCallWorkFlowFailed      = "调用工作流失败，原因：%s，详情：%s"
+	TaskChangeLogStopWorkFlow            = "停止工作流，原因：源端磁盘空间不足，详情：%s"
+	TaskChangeLogSourceBusy              = "源端负载超过阈值(%d/%d)，详情：%s"
+	TaskChangeLogStopWorkFlowBusy        = "停止工作流，原因：源端负载过高暂停执行，负载恢复后继续执行，详情：%s"
+	TaskChangeLogPrimaryChanged          = "停止工作流，原因：源端主库发生切换，详情：%s"
+	TaskChangeLogPausedOutOfExecWin      = "停止工作流，原因：超出执行窗口暂停执行，详情：%s"
+	TaskChangeLogStopWorkFlowBlackout    = "停止工作流，原因：进入封网期间，详情：%s"
+	TaskChangeLogWorkFlowFinished        = "工作流结束"
+	TaskChangeLogSourceTablesChanged     = "源表匹配结果发生变化，新增：%s，移除：%s"
+	TaskChangeLogSourceTablesSkipped     = "源表匹配规则匹配到的表不符合分表命名规则，已跳过：%s"
+	TaskChangeLogSourceDatabasesChanged  = "源库匹配结果发生变化，新增：%s，移除：%s"
+	TaskChangeLogSubTaskCreate           = "按库拆分为%d个子任务：%s"
+	TaskChangeLogSubTaskNext             = "库%s执行完成，继续执行下一个库%s"
+	TaskChangeLogSubTaskSupplementFailed = "库%s校验未通过，跳过该库的治理，原因：%s，详情：%s"
+	TaskChangeLogDependencySkipped       = "跳过本周期任务，原因：依赖的策略任务执行失败，详情：%s"
+	TaskChangeLogDependencyNotify        = "依赖的策略任务执行失败，通知后继续执行，详情：%s"
+	TaskChangeLogBackfill                = "补录错过的执行周期：%s"
+	TaskChangeLogWatermarkBound          = "增量治理范围：%s > %s AND %s <= %s"
+	TaskChangeLogWatermarkAdvance        = "增量治理水位推进到：%s"
+	TaskChangeLogThrottle                = "调整限流配置为：%s"
+	TaskChangeLogNotifyEscalate          = "任务%s超过%d小时未处理，按通知路由规则(%s)升级通知"
 )
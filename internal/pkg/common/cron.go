@@ -0,0 +1,125 @@
+package common
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMaxSearchDays 计算下一个执行日期时最多向后查找的天数
+const cronMaxSearchDays = 366 * 5
+
+var cronLastWeekdayRe = regexp.MustCompile(`^([0-7])L$`)
+
+// cronDescriptors 支持的预定义cron描述符，执行周期仅精确到天，不支持@hourly和@every
+var cronDescriptors = map[string]bool{
+	"@daily":    true,
+	"@weekly":   true,
+	"@monthly":  true,
+	"@yearly":   true,
+	"@annually": true,
+}
+
+// CronSchedule 策略的cron执行周期，仅精确到天，具体的执行时间由执行窗口决定。
+// 在标准的5位cron表达式(分 时 日 月 周)基础上支持:
+//   - 日字段为L: 每月最后一天
+//   - 周字段为nL: 每月最后一个周n，如 0L 表示每月最后一个周日，多个用逗号分隔
+type CronSchedule struct {
+	schedule     cron.Schedule
+	lastDay      bool
+	lastWeekdays map[time.Weekday]bool
+}
+
+// ParseCronExpr 解析并校验cron表达式
+func ParseCronExpr(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("cron expression empty")
+	}
+
+	s := &CronSchedule{}
+	if strings.HasPrefix(expr, "@") {
+		if !cronDescriptors[strings.ToLower(expr)] {
+			return nil, fmt.Errorf("cron expression(%s) not support, only support @daily @weekly @monthly @yearly @annually", expr)
+		}
+		expr = strings.ToLower(expr)
+	} else {
+		fields := strings.Fields(expr)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("cron expression(%s) should have 5 fields(minute hour day month weekday)", expr)
+		}
+
+		if strings.EqualFold(fields[2], "L") {
+			s.lastDay = true
+			fields[2] = "*"
+		}
+
+		if strings.Contains(strings.ToUpper(fields[4]), "L") {
+			s.lastWeekdays = make(map[time.Weekday]bool)
+			for _, w := range strings.Split(strings.ToUpper(fields[4]), ",") {
+				match := cronLastWeekdayRe.FindStringSubmatch(w)
+				if len(match) != 2 {
+					return nil, fmt.Errorf("invalid weekday(%s) in cron expression(%s), only support nL with others", w, expr)
+				}
+				n, _ := strconv.Atoi(match[1])
+				s.lastWeekdays[time.Weekday(n%7)] = true
+			}
+			fields[4] = "*"
+		}
+
+		if s.lastDay && s.lastWeekdays != nil {
+			return nil, fmt.Errorf("cron expression(%s) can not use L in both day and weekday", expr)
+		}
+
+		// 执行时间由执行窗口决定，分和时固定为0
+		fields[0], fields[1] = "0", "0"
+		expr = strings.Join(fields, " ")
+	}
+
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parse cron expression(%s) failed, %s", expr, err)
+	}
+	s.schedule = schedule
+
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression(%s) will never be triggered", expr)
+	}
+	return s, nil
+}
+
+// Match 判断给定日期是否满足cron表达式
+func (s *CronSchedule) Match(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	next := s.schedule.Next(day.Add(-time.Second))
+	if next.Year() != day.Year() || next.YearDay() != day.YearDay() {
+		return false
+	}
+
+	if s.lastDay && day.AddDate(0, 0, 1).Month() == day.Month() {
+		return false
+	}
+	if s.lastWeekdays != nil && (!s.lastWeekdays[day.Weekday()] || day.AddDate(0, 0, 7).Month() == day.Month()) {
+		return false
+	}
+	return true
+}
+
+// Next 返回t之后(不含t当天)第一个满足cron表达式的日期，找不到时返回零值
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 1; i <= cronMaxSearchDays; i++ {
+		if d := day.AddDate(0, 0, i); s.Match(d) {
+			return d
+		}
+	}
+	return time.Time{}
+}
+
+func CheckCronExpr(expr string) bool {
+	_, err := ParseCronExpr(expr)
+	return err == nil
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{"standard", "0 0 1 * *", false},
+		{"descriptor", "@monthly", false},
+		{"descriptor upper case", "@Yearly", false},
+		{"every descriptor", "@every 36h", true},
+		{"hourly descriptor", "@hourly", true},
+		{"midnight descriptor", "@midnight", true},
+		{"unknown descriptor", "@reboot", true},
+		{"last day", "0 0 L * *", false},
+		{"last weekdays", "0 0 * * 5L,0L", false},
+		{"empty", " ", true},
+		{"too few fields", "0 0 *", true},
+		{"invalid field", "0 0 x * *", true},
+		{"invalid last weekday", "0 0 * * 8L", true},
+		{"last weekday mixed with weekday", "0 0 * * 1,5L", true},
+		{"last in both day and weekday", "0 0 L * 5L", true},
+		{"never triggered", "0 0 31 2 *", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseCronExpr(test.expr)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseCronExpr(%s) got err %v, want err %v", test.expr, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{"first day of month", "0 0 1 * *", "2024-01-15", "2024-02-01"},
+		{"not include from day", "0 0 * * 1", "2024-01-15", "2024-01-22"},
+		{"minute and hour ignored", "30 5 * * *", "2024-01-15", "2024-01-16"},
+		{"descriptor", "@monthly", "2024-01-15", "2024-02-01"},
+		{"daily descriptor", "@daily", "2024-01-15", "2024-01-16"},
+		{"weekly descriptor", "@weekly", "2024-01-15", "2024-01-21"},
+		{"annually descriptor", "@annually", "2024-01-15", "2025-01-01"},
+		{"last day", "0 0 L * *", "2024-01-15", "2024-01-31"},
+		{"last day of leap february", "0 0 L * *", "2024-01-31", "2024-02-29"},
+		{"last sunday", "0 0 * * 0L", "2024-01-15", "2024-01-28"},
+		{"last friday or monday", "0 0 * * 5L,1L", "2024-01-15", "2024-01-26"},
+		{"leap day", "0 0 29 2 *", "2024-03-01", "2028-02-29"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseCronExpr(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, _ := time.ParseInLocation(time.DateOnly, test.from, time.Local)
+			if got := s.Next(from).Format(time.DateOnly); got != test.want {
+				t.Fatalf("Next(%s) got %s, want %s", test.from, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// CheckTimezone 校验时区名称(IANA格式，如: Asia/Shanghai)，为空表示使用服务所在时区
+func CheckTimezone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("invalid timezone(%s), it should look like: Asia/Shanghai, %s", tz, err)
+	}
+	return nil
+}
+
+// LoadLocation 加载时区，为空或不合法时使用服务所在时区
+func LoadLocation(tz string) *time.Location {
+	if tz == "" {
+		return time.Now().Location()
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Now().Location()
+	}
+	return loc
+}
+
+// NowIn 返回指定时区的当前时间
+func NowIn(tz string) time.Time {
+	return time.Now().In(LoadLocation(tz))
+}
+
+// FormatInZone 将服务所在时区的时间字符串(2006-01-02 15:04:05)转换为指定时区的时间字符串
+func FormatInZone(s, tz string) string {
+	t, err := time.ParseInLocation(time.DateTime, s, time.Now().Location())
+	if err != nil {
+		return ""
+	}
+	return t.In(LoadLocation(tz)).Format(time.DateTime)
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+// setLocal 将服务所在时区设置为loc，测试结束后恢复
+func setLocal(t *testing.T, loc *time.Location) {
+	old := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = old })
+}
+
+func TestCheckTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		tz      string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"utc", "UTC", false},
+		{"iana", "Asia/Shanghai", false},
+		{"offset", "+08:00", true},
+		{"unknown", "Mars/Olympus", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckTimezone(test.tz); (err != nil) != test.wantErr {
+				t.Fatalf("CheckTimezone(%s) got err %v, want err %v", test.tz, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	setLocal(t, time.FixedZone("server", -5*3600))
+	tests := []struct {
+		name string
+		tz   string
+		want string
+	}{
+		{"empty uses server timezone", "", "server"},
+		{"invalid uses server timezone", "Mars/Olympus", "server"},
+		{"iana", "Asia/Shanghai", "Asia/Shanghai"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := LoadLocation(test.tz).String(); got != test.want {
+				t.Fatalf("LoadLocation(%s) got %s, want %s", test.tz, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatInZone(t *testing.T) {
+	setLocal(t, time.UTC)
+	tests := []struct {
+		name string
+		s    string
+		tz   string
+		want string
+	}{
+		{"same timezone", "2024-01-01 10:00:00", "", "2024-01-01 10:00:00"},
+		{"east to next day", "2024-01-01 20:00:00", "Asia/Shanghai", "2024-01-02 04:00:00"},
+		{"west to previous day", "2024-01-01 02:00:00", "America/New_York", "2023-12-31 21:00:00"},
+		{"daylight saving time", "2024-07-01 02:00:00", "America/New_York", "2024-06-30 22:00:00"},
+		{"invalid time", "2024-01-01", "Asia/Shanghai", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FormatInZone(test.s, test.tz); got != test.want {
+				t.Fatalf("FormatInZone(%s, %s) got %s, want %s", test.s, test.tz, got, test.want)
+			}
+		})
+	}
+}
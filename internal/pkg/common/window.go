@@ -0,0 +1,137 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ExecWindow 执行窗口，结束时间小于等于开始时间时表示跨天
+type ExecWindow struct {
+	Start    string `json:"start"`    // 开始时间 如: 01:00:00
+	End      string `json:"end"`      // 结束时间 如: 05:00:00
+	Weekdays []int  `json:"weekdays"` // 生效的星期(0:周日, 1:周一 ... 6:周六)，为空时每天生效，跨天的窗口以开始时间所在的星期为准
+}
+
+// ExecWindowRange 执行窗口在某一天的具体时间段
+type ExecWindowRange struct {
+	Window ExecWindow
+	Start  time.Time
+	End    time.Time
+}
+
+// CheckExecWindows 校验执行窗口并格式化时间和星期
+func CheckExecWindows(windows []ExecWindow) ([]ExecWindow, error) {
+	res := make([]ExecWindow, 0, len(windows))
+	for _, w := range windows {
+		s, err1 := time.Parse(time.TimeOnly, w.Start)
+		e, err2 := time.Parse(time.TimeOnly, w.End)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("validate execute_windows(%+v) not pass, start and end should look like: 03:00:00", w)
+		}
+
+		weekdays := make([]int, 0, len(w.Weekdays))
+		seen := make(map[int]bool, len(w.Weekdays))
+		for _, d := range w.Weekdays {
+			if d < 0 || d > 6 {
+				return nil, fmt.Errorf("validate execute_windows(%+v) not pass, weekday should in (0-6)", w)
+			}
+			if !seen[d] {
+				seen[d] = true
+				weekdays = append(weekdays, d)
+			}
+		}
+		sort.Ints(weekdays)
+		res = append(res, ExecWindow{Start: s.Format(time.TimeOnly), End: e.Format(time.TimeOnly), Weekdays: weekdays})
+	}
+	return res, nil
+}
+
+// ParseExecWindows 解析任务或策略的执行窗口，未配置多执行窗口时使用单个执行窗口["03:00:00", "05:00:00"]且每天生效
+func ParseExecWindows(execWindow, execWindows []byte) ([]ExecWindow, error) {
+	var windows []ExecWindow
+	if len(execWindows) != 0 && string(execWindows) != "null" {
+		err := json.Unmarshal(execWindows, &windows)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal execWindows(%s) failed, %s", string(execWindows), err)
+		}
+	}
+	if len(windows) != 0 {
+		return windows, nil
+	}
+
+	var executeWin []string
+	err := json.Unmarshal(execWindow, &executeWin)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal execWindow(%s) failed, %s", string(execWindow), err)
+	}
+	if len(executeWin) < 2 {
+		return nil, fmt.Errorf("parse execWindow(%s) failed, it should look like: [\"03:00:00\", \"05:00:00\"]", string(execWindow))
+	}
+	return []ExecWindow{{Start: executeWin[0], End: executeWin[1]}}, nil
+}
+
+// Applies 执行窗口在指定日期是否生效
+func (w *ExecWindow) Applies(day time.Time) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if time.Weekday(d) == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecWindowRanges 返回在指定日期开始的执行时间段，按开始时间排序
+func ExecWindowRanges(windows []ExecWindow, day time.Time) ([]ExecWindowRange, error) {
+	date := day.Format("2006-01-02 ")
+	ranges := make([]ExecWindowRange, 0, len(windows))
+	for _, w := range windows {
+		if !w.Applies(day) {
+			continue
+		}
+		start, err1 := time.ParseInLocation(time.DateTime, date+w.Start, day.Location())
+		end, err2 := time.ParseInLocation(time.DateTime, date+w.End, day.Location())
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("parse execWindow(%s - %s) failed", w.Start, w.End)
+		}
+		// 跨天了，比如：22:00:00 - 05:00:00
+		if w.End <= w.Start {
+			end = end.Add(time.Hour * 24)
+		}
+		ranges = append(ranges, ExecWindowRange{Window: w, Start: start, End: end})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start.Before(ranges[j].Start) })
+	return ranges, nil
+}
+
+// FirstExecWindow 返回最早开始的执行窗口，用于任务排序和展示
+func FirstExecWindow(windows []ExecWindow) ExecWindow {
+	first := ExecWindow{}
+	for i, w := range windows {
+		if i == 0 || w.Start < first.Start {
+			first = w
+		}
+	}
+	return first
+}
+
+// ActiveExecWindowRanges 返回t当天开始的执行时间段以及前一天开始的跨天执行时间段，早于执行日期开始的时间段不包含在内
+func ActiveExecWindowRanges(windows []ExecWindow, execDate string, t time.Time) ([]ExecWindowRange, error) {
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	var ranges []ExecWindowRange
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		if day.Format(time.DateOnly) < execDate {
+			continue
+		}
+		r, err := ExecWindowRanges(windows, day)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r...)
+	}
+	return ranges, nil
+}
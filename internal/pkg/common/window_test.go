@@ -0,0 +1,145 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseExecWindows(t *testing.T) {
+	tests := []struct {
+		name        string
+		execWindow  string
+		execWindows string
+		want        []ExecWindow
+		wantErr     bool
+	}{
+		{
+			name:       "single window",
+			execWindow: `["03:00:00", "05:00:00"]`,
+			want:       []ExecWindow{{Start: "03:00:00", End: "05:00:00"}},
+		},
+		{
+			name:        "multiple windows first",
+			execWindow:  `["03:00:00", "05:00:00"]`,
+			execWindows: `[{"start":"01:00:00","end":"02:00:00","weekdays":[6,0]},{"start":"22:00:00","end":"04:00:00"}]`,
+			want: []ExecWindow{
+				{Start: "01:00:00", End: "02:00:00", Weekdays: []int{6, 0}},
+				{Start: "22:00:00", End: "04:00:00"},
+			},
+		},
+		{
+			name:        "null windows",
+			execWindow:  `["03:00:00", "05:00:00"]`,
+			execWindows: "null",
+			want:        []ExecWindow{{Start: "03:00:00", End: "05:00:00"}},
+		},
+		{
+			name:        "empty windows",
+			execWindow:  `["03:00:00", "05:00:00"]`,
+			execWindows: "[]",
+			want:        []ExecWindow{{Start: "03:00:00", End: "05:00:00"}},
+		},
+		{name: "window missing end", execWindow: `["03:00:00"]`, wantErr: true},
+		{name: "invalid window", execWindow: `03:00:00`, wantErr: true},
+		{name: "invalid windows", execWindow: `["03:00:00", "05:00:00"]`, execWindows: `{}`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseExecWindows([]byte(test.execWindow), []byte(test.execWindows))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseExecWindows() got err %v, want err %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ParseExecWindows() got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckExecWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []ExecWindow
+		want    []ExecWindow
+		wantErr bool
+	}{
+		{
+			name:    "format time and weekdays",
+			windows: []ExecWindow{{Start: "1:00:00", End: "05:00:00", Weekdays: []int{6, 0, 6}}},
+			want:    []ExecWindow{{Start: "01:00:00", End: "05:00:00", Weekdays: []int{0, 6}}},
+		},
+		{name: "invalid time", windows: []ExecWindow{{Start: "25:00:00", End: "05:00:00"}}, wantErr: true},
+		{name: "invalid weekday", windows: []ExecWindow{{Start: "01:00:00", End: "05:00:00", Weekdays: []int{7}}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CheckExecWindows(test.windows)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("CheckExecWindows() got err %v, want err %v", err, test.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("CheckExecWindows() got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestActiveExecWindowRanges(t *testing.T) {
+	// 2024-01-01是周一
+	windows := []ExecWindow{
+		{Start: "22:00:00", End: "02:00:00"},
+		{Start: "10:00:00", End: "12:00:00", Weekdays: []int{1}},
+	}
+	tests := []struct {
+		name     string
+		execDate string
+		t        string
+		want     []string
+	}{
+		{
+			name:     "cross day window of yesterday",
+			execDate: "2024-01-02",
+			t:        "2024-01-03 01:00:00",
+			want:     []string{"2024-01-02 22:00:00~2024-01-03 02:00:00", "2024-01-03 22:00:00~2024-01-04 02:00:00"},
+		},
+		{
+			name:     "weekday window",
+			execDate: "2024-01-01",
+			t:        "2024-01-01 11:00:00",
+			want:     []string{"2024-01-01 10:00:00~2024-01-01 12:00:00", "2024-01-01 22:00:00~2024-01-02 02:00:00"},
+		},
+		{
+			name:     "yesterday before exec date",
+			execDate: "2024-01-02",
+			t:        "2024-01-02 01:00:00",
+			want:     []string{"2024-01-02 22:00:00~2024-01-03 02:00:00"},
+		},
+		{
+			name:     "monday window started yesterday",
+			execDate: "2024-01-01",
+			t:        "2024-01-02 11:00:00",
+			want: []string{
+				"2024-01-01 10:00:00~2024-01-01 12:00:00", "2024-01-01 22:00:00~2024-01-02 02:00:00",
+				"2024-01-02 22:00:00~2024-01-03 02:00:00",
+			},
+		},
+		{name: "exec date not reached", execDate: "2024-01-05", t: "2024-01-02 01:00:00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now, _ := time.ParseInLocation(time.DateTime, test.t, time.Local)
+			ranges, err := ActiveExecWindowRanges(windows, test.execDate, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range ranges {
+				got = append(got, r.Start.Format(time.DateTime)+"~"+r.End.Format(time.DateTime))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ActiveExecWindowRanges() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// JobLeaseName 运行定时任务的选主租约名称
+const JobLeaseName = "job"
+
+// fenceKey 主副本运行上下文中保存防护令牌的key
+const fenceKey = "leader_fence"
+
+// fence 防护令牌，定时任务执行前校验本副本仍持有该任期的租约
+type fence struct {
+	elector *Elector
+	term    uint64
+}
+
+// Elector 基于数据库租约的选主，租约的过期时间以数据库时间为准，避免各副本时钟不一致
+type Elector struct {
+	Name     string        // 租约名称
+	Identity string        // 当前副本的标识
+	Lease    time.Duration // 租约时长
+}
+
+// 续约间隔为租约时长的1/3，续约时间精确到秒，租约时长不能少于3秒
+const minLease = 3 * time.Second
+
+// dbTime 返回数据库当前时间加上d的SQL表达式，租约的时间都以数据库时间为准，测试时可替换为固定的时钟
+var dbTime = func(d time.Duration) clause.Expr {
+	if d == 0 {
+		return gorm.Expr("NOW()")
+	}
+	return gorm.Expr("DATE_ADD(NOW(), INTERVAL ? SECOND)", int(d.Seconds()))
+}
+
+func NewElector(name string, lease time.Duration) *Elector {
+	return &Elector{
+		Name:     name,
+		Identity: common.InstanceName,
+		Lease:    max(lease, minLease),
+	}
+}
+
+// Run 竞选成为主后调用run，失去租约时取消run的上下文并等待其退出后重新竞选；
+// 服务退出时等待run退出后主动释放租约，以便其他副本尽快接管
+func (e *Elector) Run(ctx *common.Context, run func(ctx *common.Context)) {
+	ctx.Wg.Add(1)
+	defer ctx.Wg.Done()
+	log, db := ctx.Log, ctx.DB
+
+	var term *common.Context
+	var termID uint64
+	var leaseReady bool
+	var lastRenew time.Time
+	stepDown := func() {
+		if term == nil {
+			return
+		}
+		term.Cancel()
+		term.Wg.Wait()
+		term = nil
+	}
+
+	retry := e.Lease / 3
+	tick := time.NewTicker(retry)
+	defer tick.Stop()
+	for {
+		if !leaseReady {
+			// 租约记录初始化失败时无法竞选，持续重试直到成功
+			if err := e.ensureLease(db); err != nil {
+				log.Errorf("init leader lease(%s) failed, retry after %s, %s", e.Name, retry, err)
+			} else {
+				leaseReady = true
+			}
+		}
+
+		if term == nil && leaseReady {
+			ok, t, err := e.acquire(db)
+			if err != nil {
+				log.Errorf("acquire leader lease(%s) failed, %s", e.Name, err)
+			} else if ok {
+				lastRenew, termID = time.Now(), t
+				log.Infof("%s became leader of %s, term %d", e.Identity, e.Name, termID)
+
+				termCtx, cancel := context.WithCancel(ctx.Context)
+				term = common.NewContext().WithContext(termCtx).WithCancel(cancel).WithLog(log).WithDB(db).
+					SetData(fenceKey, &fence{elector: e, term: termID})
+				go run(term)
+			}
+		} else if term != nil {
+			ok, err := e.renew(db, termID)
+			switch {
+			case err == nil && ok:
+				lastRenew = time.Now()
+			case err == nil && !ok:
+				log.Warnf("%s lost leader lease(%s), stepping down", e.Identity, e.Name)
+				stepDown()
+			case time.Since(lastRenew) > e.Lease-retry:
+				// 无法续约且租约即将过期，需在其他副本接管前退出
+				log.Errorf("renew leader lease(%s) failed, %s, stepping down", e.Name, err)
+				stepDown()
+			default:
+				log.Errorf("renew leader lease(%s) failed, %s", e.Name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Context.Done():
+			if term != nil {
+				stepDown()
+				if err := e.release(db); err != nil {
+					log.Errorf("release leader lease(%s) failed, %s", e.Name, err)
+				} else {
+					log.Infof("%s released leader lease(%s)", e.Identity, e.Name)
+				}
+			}
+			log.Info("shutdown leader elector")
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+func (e *Elector) ensureLease(db *gorm.DB) error {
+	lease := &models.LeaderLease{
+		Name:       e.Name,
+		AcquiredAt: time.UnixMilli(0),
+		RenewedAt:  time.UnixMilli(0),
+		ExpiredAt:  time.UnixMilli(0),
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(lease).Error
+}
+
+// acquire 租约已过期或本副本持有时获得租约，任期加1后返回新的任期
+func (e *Elector) acquire(db *gorm.DB) (bool, uint64, error) {
+	res := db.Model(&models.LeaderLease{}).
+		Where("name =? AND (holder =? OR holder ='' OR expired_at < ?)", e.Name, e.Identity, dbTime(0)).
+		Updates(map[string]any{
+			"holder":      e.Identity,
+			"term":        gorm.Expr("term + 1"),
+			"acquired_at": dbTime(0),
+			"renewed_at":  dbTime(0),
+			"expired_at":  dbTime(e.Lease),
+		})
+	if res.Error != nil {
+		return false, 0, fmt.Errorf("update models.LeaderLease(name=%s) from db failed, %s", e.Name, res.Error)
+	}
+	if res.RowsAffected != 1 {
+		return false, 0, nil
+	}
+
+	lease := &models.LeaderLease{}
+	err := db.Model(lease).Where("name =? AND holder =?", e.Name, e.Identity).First(lease).Error
+	if err != nil {
+		return false, 0, fmt.Errorf("query models.LeaderLease(name=%s) from db failed, %s", e.Name, err)
+	}
+	return true, lease.Term, nil
+}
+
+func (e *Elector) renew(db *gorm.DB, term uint64) (bool, error) {
+	res := db.Model(&models.LeaderLease{}).
+		Where("name =? AND holder =? AND term =?", e.Name, e.Identity, term).
+		Updates(map[string]any{
+			"renewed_at": dbTime(0),
+			"expired_at": dbTime(e.Lease),
+		})
+	if res.Error != nil {
+		return false, fmt.Errorf("update models.LeaderLease(name=%s) from db failed, %s", e.Name, res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (e *Elector) release(db *gorm.DB) error {
+	return db.Model(&models.LeaderLease{}).
+		Where("name =? AND holder =?", e.Name, e.Identity).
+		Updates(map[string]any{
+			"holder":     "",
+			"expired_at": time.UnixMilli(0),
+		}).Error
+}
+
+// holding 本副本是否仍持有指定任期的租约且未过期
+func (e *Elector) holding(db *gorm.DB, term uint64) (bool, error) {
+	var count int64
+	err := db.Model(&models.LeaderLease{}).
+		Where("name =? AND holder =? AND term =? AND expired_at > ?", e.Name, e.Identity, term, dbTime(0)).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("query models.LeaderLease(name=%s) from db failed, %s", e.Name, err)
+	}
+	return count == 1, nil
+}
+
+// Check 执行有副作用的操作前校验本副本仍是主：任期已结束或租约已被其他副本接管时返回错误，未启用选主时不做校验
+func Check(ctx *common.Context) error {
+	if err := ctx.Context.Err(); err != nil {
+		return fmt.Errorf("leader term ended, %s", err)
+	}
+	v, ok := ctx.GetData(fenceKey)
+	if !ok {
+		return nil
+	}
+	f := v.(*fence)
+	ok, err := f.elector.holding(ctx.DB, f.term)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s no longer holds leader lease(%s) of term %d", f.elector.Identity, f.elector.Name, f.term)
+	}
+	return nil
+}
+
+// FenceJob 定时任务执行前校验本副本仍是主，不是主时跳过本次执行，避免旧的主副本在退出前与新的主副本同时执行
+func FenceJob(ctx *common.Context) cron.JobWrapper {
+	return func(j cron.Job) cron.Job {
+		return cron.FuncJob(func() {
+			if err := Check(ctx); err != nil {
+				ctx.Log.Warnf("skip cron job, %s", err)
+				return
+			}
+			j.Run()
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/logger"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql/mysqltest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// setClock 将数据库时间替换为可调整的时钟，返回调整时钟的函数
+func setClock(t *testing.T) func(d time.Duration) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	now := start
+	old := dbTime
+	dbTime = func(d time.Duration) clause.Expr {
+		return gorm.Expr("?", now.Add(d))
+	}
+	t.Cleanup(func() { dbTime = old })
+	return func(d time.Duration) { now = start.Add(d) }
+}
+
+// newLease 创建租约记录，租约时长为30秒
+func newLease(t *testing.T) (*gorm.DB, *Elector, *Elector) {
+	db := mysqltest.Open(t, &models.LeaderLease{})
+	a := &Elector{Name: JobLeaseName, Identity: "a", Lease: 30 * time.Second}
+	b := &Elector{Name: JobLeaseName, Identity: "b", Lease: 30 * time.Second}
+	if err := a.ensureLease(db); err != nil {
+		t.Fatal(err)
+	}
+	// 重复初始化不影响已有的租约
+	if err := b.ensureLease(db); err != nil {
+		t.Fatal(err)
+	}
+	return db, a, b
+}
+
+func TestElectorLease(t *testing.T) {
+	setAt := setClock(t)
+	db, a, b := newLease(t)
+
+	steps := []struct {
+		name     string
+		at       time.Duration
+		elector  *Elector
+		op       string
+		term     uint64
+		want     bool
+		wantTerm uint64
+	}{
+		{name: "a acquires first term", elector: a, op: "acquire", want: true, wantTerm: 1},
+		{name: "b can not acquire held lease", elector: b, op: "acquire"},
+		{name: "a renews", at: 10 * time.Second, elector: a, op: "renew", term: 1, want: true},
+		{name: "renewed lease not expired", at: 35 * time.Second, elector: b, op: "acquire"},
+		{name: "a holds renewed lease", at: 35 * time.Second, elector: a, op: "holding", term: 1, want: true},
+		{name: "a lost lease on expiry", at: 41 * time.Second, elector: a, op: "holding", term: 1},
+		{name: "b acquires expired lease with next term", at: 41 * time.Second, elector: b, op: "acquire", want: true, wantTerm: 2},
+		{name: "a can not renew stale term", at: 41 * time.Second, elector: a, op: "renew", term: 1},
+		{name: "a can not acquire lease of b", at: 41 * time.Second, elector: a, op: "acquire"},
+		{name: "b holds its term", at: 42 * time.Second, elector: b, op: "holding", term: 2, want: true},
+		{name: "b releases", at: 42 * time.Second, elector: b, op: "release", want: true},
+		{name: "a acquires released lease", at: 42 * time.Second, elector: a, op: "acquire", want: true, wantTerm: 3},
+		{name: "a acquires again when restarted", at: 43 * time.Second, elector: a, op: "acquire", want: true, wantTerm: 4},
+		{name: "a does not hold old term", at: 43 * time.Second, elector: a, op: "holding", term: 3},
+	}
+	for _, step := range steps {
+		setAt(step.at)
+		var ok bool
+		var term uint64
+		var err error
+		switch step.op {
+		case "acquire":
+			ok, term, err = step.elector.acquire(db)
+		case "renew":
+			ok, err = step.elector.renew(db, step.term)
+		case "holding":
+			ok, err = step.elector.holding(db, step.term)
+		case "release":
+			err = step.elector.release(db)
+			ok = err == nil
+		}
+		if err != nil {
+			t.Fatalf("%s: %s got err %s", step.name, step.op, err)
+		}
+		if ok != step.want || term != step.wantTerm {
+			t.Fatalf("%s: %s got %v term %d, want %v term %d", step.name, step.op, ok, term, step.want, step.wantTerm)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		cancel  bool
+		wantErr bool
+	}{
+		{"leader election disabled", false, false},
+		{"term ended", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancel {
+				cancel()
+			}
+			ctx := common.NewContext().WithContext(c).WithCancel(cancel)
+			if err := Check(ctx); (err != nil) != test.wantErr {
+				t.Fatalf("Check() got err %v, want err %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFenceJob(t *testing.T) {
+	tests := []struct {
+		name     string
+		at       time.Duration
+		takeover bool // 租约过期后b是否已接管
+		fence    func(a, b *Elector) *fence
+		wantRun  bool
+	}{
+		{"leader runs job", 10 * time.Second, false, func(a, b *Elector) *fence { return &fence{elector: a, term: 1} }, true},
+		{"expired leader skips job", 31 * time.Second, false, func(a, b *Elector) *fence { return &fence{elector: a, term: 1} }, false},
+		{"stale term skips job", 31 * time.Second, true, func(a, b *Elector) *fence { return &fence{elector: a, term: 1} }, false},
+		{"new leader runs job", 31 * time.Second, true, func(a, b *Elector) *fence { return &fence{elector: b, term: 2} }, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setAt := setClock(t)
+			db, a, b := newLease(t)
+			if ok, _, err := a.acquire(db); !ok || err != nil {
+				t.Fatalf("a acquire got %v %v", ok, err)
+			}
+			setAt(test.at)
+			if test.takeover {
+				if ok, term, err := b.acquire(db); !ok || term != 2 || err != nil {
+					t.Fatalf("b acquire got %v %d %v", ok, term, err)
+				}
+			}
+
+			c, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ctx := common.NewContext().WithContext(c).WithCancel(cancel).WithLog(logger.Log).WithDB(db).
+				SetData(fenceKey, test.fence(a, b))
+
+			run := false
+			FenceJob(ctx)(cron.FuncJob(func() { run = true })).Run()
+			if run != test.wantRun {
+				t.Fatalf("FenceJob() got run %v, want %v", run, test.wantRun)
+			}
+			if err := Check(ctx); (err == nil) != test.wantRun {
+				t.Fatalf("Check() got err %v, want run %v", err, test.wantRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Package mysqltest 为单元测试提供基于内存sqlite的mysql.DB，不依赖真实的MySQL
+package mysqltest
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql"
+	"gorm.io/gorm"
+	glog "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Open 创建内存数据库并迁移给定的表结构，替换mysql.DB，测试结束后恢复
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:                 glog.Discard,
+		SkipDefaultTransaction: true,
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("open sqlite failed, %s", err)
+	}
+
+	// 内存数据库每个连接都是独立的库，只保留一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql db failed, %s", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err = db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate %T failed, %s", models, err)
+	}
+
+	old := mysql.DB
+	mysql.DB = db
+	t.Cleanup(func() {
+		mysql.DB = old
+		_ = sqlDB.Close()
+	})
+	return db
+}
@@ -1,33 +1,50 @@
 package queue
 
 import (
-	"github.com/eapache/queue"
+	"fmt"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/logger"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	// LeaseDuration 出队列后的租约时长，超过租约仍未处理完的元素可被重新出队列
+	LeaseDuration = 10 * time.Minute
+	// MaxAttempts 同一元素最多出队列的次数，超过后标记为处理失败，防止异常的元素反复导致服务崩溃
+	MaxAttempts = 5
 )
 
 type Queue[T PolicyQueueHandle | TaskQueueHandle] struct {
-	queue *queue.Queue
+	name common.QueueNameType
 }
 
 // PolicyQueue 存放待处理policy队列
 var PolicyQueue = &Queue[PolicyQueueHandle]{
-	queue: queue.New(),
+	name: common.QueueNamePolicy,
 }
 
+// TaskQueue 存放待补充信息的task队列
 var TaskQueue = &Queue[TaskQueueHandle]{
-	queue: queue.New(),
+	name: common.QueueNameTask,
 }
 
 type PolicyQueueHandle struct {
 	ID       uint   `json:"id"`
 	HandleID string `json:"handle_id"`
+	ItemID   uint   `json:"-"` // 持久化队列中的元素ID
 }
 
 type TaskQueueHandle struct {
 	ID       uint   `json:"id"`
 	PolicyID uint   `json:"policy_id"`
 	HandleID string `json:"handle_id"`
+	ItemID   uint   `json:"-"` // 持久化队列中的元素ID
 }
 
+// Push 入队列，队列中已有相同ID的元素等待处理时不再入队列；处理失败的元素会被重新置为等待处理
 func (c *Queue[T]) Push(item T) (ok bool) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -35,62 +52,174 @@ func (c *Queue[T]) Push(item T) (ok bool) {
 		}
 	}()
 
-	equal := func(v1, v2 any) bool {
-		switch o := v1.(type) {
-		case PolicyQueueHandle:
-			return o.ID == v2.(PolicyQueueHandle).ID
-		case TaskQueueHandle:
-			return o.ID == v2.(TaskQueueHandle).ID
-		}
+	elem := c.toModel(item)
+
+	existed := &models.QueueItem{}
+	err := mysql.DB.Model(existed).
+		Where("queue =? AND item_id =? AND status IN (?)", c.name, elem.ItemID,
+			[]common.QueueItemStatusType{common.QueueItemPending, common.QueueItemFailed}).
+		Order("id").Limit(1).Find(existed).Error
+	if err != nil {
 		return false
 	}
 
-	for i := 0; ; i++ {
-		if i >= c.queue.Length() {
-			break
-		}
-
-		elem := c.queue.Get(i)
-
-		if equal(item, elem) {
-			return false
-		}
+	switch existed.Status {
+	case common.QueueItemPending:
+		return false
+	case common.QueueItemFailed:
+		err = mysql.DB.Model(existed).Where("id =? AND status =?", existed.ID, common.QueueItemFailed).
+			Updates(map[string]any{
+				"status":    common.QueueItemPending,
+				"handle_id": elem.HandleID,
+				"policy_id": elem.PolicyID,
+				"attempts":  0,
+			}).Error
+		return err == nil
 	}
-	c.queue.Add(item)
-	return true
+
+	elem.ID = 0
+	return mysql.DB.Create(elem).Error == nil
 }
 
+// Pop 出队列，取出最早等待处理或租约已过期的元素并持有租约，处理完后需调用Done
 func (c *Queue[T]) Pop() (T, bool) {
 	var ret T
-	if c.queue.Length() == 0 {
+
+	now := time.Now()
+	var items []models.QueueItem
+	err := mysql.DB.Model(&models.QueueItem{}).
+		Where("queue =? AND (status =? OR (status =? AND lease_expiry <?))",
+			c.name, common.QueueItemPending, common.QueueItemProcessing, now).
+		Order("id").Limit(10).Find(&items).Error
+	if err != nil {
 		return ret, false
 	}
-	elem := c.queue.Remove()
-	if v, ok := elem.(T); ok {
-		return v, true
+
+	for _, item := range items {
+		// 多次出队列仍未处理完，可能每次处理都会导致服务异常退出
+		if item.Attempts >= MaxAttempts {
+			err = mysql.DB.Model(&item).Where("id =? AND status =? AND attempts =?", item.ID, item.Status, item.Attempts).
+				Updates(map[string]any{
+					"status":     common.QueueItemFailed,
+					"last_error": fmt.Sprintf("dequeued %d times but not done, lease owner: %s", item.Attempts, item.LeaseOwner),
+				}).Error
+			if err != nil {
+				logger.Log.Errorf("mark %s(%d) of queue(%s) failed after %d attempts failed, %s", c.name, item.ItemID, c.name, item.Attempts, err)
+			}
+			continue
+		}
+
+		// 通过状态和次数做乐观锁，避免多个实例同时取到同一个元素
+		res := mysql.DB.Model(&item).Where("id =? AND status =? AND attempts =?", item.ID, item.Status, item.Attempts).
+			Updates(map[string]any{
+				"status":       common.QueueItemProcessing,
+				"attempts":     item.Attempts + 1,
+				"lease_owner":  common.InstanceName,
+				"lease_expiry": now.Add(LeaseDuration),
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		return c.toHandle(&item), true
 	}
 	return ret, false
 }
 
+// Done 元素处理结束，处理成功时从队列中移除，失败时保留失败原因以便管理员重新入队列或丢弃
+func (c *Queue[T]) Done(item T, err error) error {
+	elem := c.toModel(item)
+	if elem.ID == 0 {
+		return fmt.Errorf("%s(%d) is not dequeued from queue(%s)", c.name, elem.ItemID, c.name)
+	}
+
+	db := mysql.DB.Model(&models.QueueItem{}).Where("id =? AND status =? AND lease_owner =?",
+		elem.ID, common.QueueItemProcessing, common.InstanceName)
+	if err == nil {
+		return db.Unscoped().Delete(&models.QueueItem{}).Error
+	}
+	return db.Updates(map[string]any{
+		"status":     common.QueueItemFailed,
+		"last_error": err.Error(),
+	}).Error
+}
+
+// List 列出等待处理的元素
 func (c *Queue[T]) List() (ret []T) {
-	if c.queue.Length() == 0 {
+	var items []models.QueueItem
+	err := mysql.DB.Model(&models.QueueItem{}).Where("queue =? AND status =?", c.name, common.QueueItemPending).
+		Order("id").Find(&items).Error
+	if err != nil {
 		return ret
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			ret = []T{}
-		}
-	}()
+	for i := range items {
+		ret = append(ret, c.toHandle(&items[i]))
+	}
+	return ret
+}
 
-	for i := 0; ; i++ {
-		if i >= c.queue.Length() {
-			break
-		}
+// RequeueItem 将队列中的元素重新置为等待处理
+func RequeueItem(db *gorm.DB, id uint) error {
+	item := &models.QueueItem{}
+	err := db.Model(item).First(item, "id =?", id).Error
+	if err != nil {
+		return err
+	}
 
-		elem := c.queue.Get(i)
+	var count int64
+	err = db.Model(item).Where("queue =? AND item_id =? AND status =? AND id !=?",
+		item.Queue, item.ItemID, common.QueueItemPending, item.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	// 已有相同的元素等待处理，直接丢弃该元素
+	if count != 0 {
+		return db.Unscoped().Delete(&models.QueueItem{}, "id =?", item.ID).Error
+	}
 
-		ret = append(ret, elem.(T))
+	return db.Model(item).Where("id =?", item.ID).Updates(map[string]any{
+		"status":       common.QueueItemPending,
+		"attempts":     0,
+		"lease_owner":  "",
+		"lease_expiry": time.UnixMilli(0),
+	}).Error
+}
+
+// DropItem 从队列中丢弃元素
+func DropItem(db *gorm.DB, id uint) error {
+	res := db.Unscoped().Delete(&models.QueueItem{}, "id =?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (c *Queue[T]) toModel(item T) *models.QueueItem {
+	m := &models.QueueItem{
+		Queue:       c.name,
+		Status:      common.QueueItemPending,
+		LeaseExpiry: time.UnixMilli(0),
+	}
+	m.Creator = common.SystemUserName
+	switch v := any(item).(type) {
+	case PolicyQueueHandle:
+		m.ID, m.ItemID, m.HandleID = v.ItemID, v.ID, v.HandleID
+	case TaskQueueHandle:
+		m.ID, m.ItemID, m.PolicyID, m.HandleID = v.ItemID, v.ID, v.PolicyID, v.HandleID
+	}
+	return m
+}
+
+func (c *Queue[T]) toHandle(m *models.QueueItem) T {
+	var ret T
+	switch any(ret).(type) {
+	case PolicyQueueHandle:
+		ret = any(PolicyQueueHandle{ID: m.ItemID, HandleID: m.HandleID, ItemID: m.ID}).(T)
+	case TaskQueueHandle:
+		ret = any(TaskQueueHandle{ID: m.ItemID, PolicyID: m.PolicyID, HandleID: m.HandleID, ItemID: m.ID}).(T)
 	}
 	return ret
 }
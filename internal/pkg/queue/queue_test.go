@@ -0,0 +1,188 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql/mysqltest"
+	"gorm.io/gorm"
+)
+
+// newItem 在队列中直接插入一个元素
+func newItem(t *testing.T, itemID uint, status common.QueueItemStatusType, attempts int, expiry time.Time) *models.QueueItem {
+	t.Helper()
+	item := &models.QueueItem{
+		Queue:       common.QueueNameTask,
+		ItemID:      itemID,
+		Status:      status,
+		Attempts:    attempts,
+		LeaseOwner:  "other",
+		LeaseExpiry: expiry,
+		LastError:   "failed before",
+	}
+	item.Creator = common.SystemUserName
+	if err := mysql.DB.Create(item).Error; err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func queueItems(t *testing.T) []models.QueueItem {
+	t.Helper()
+	var items []models.QueueItem
+	if err := mysql.DB.Order("id").Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func TestPush(t *testing.T) {
+	tests := []struct {
+		name         string
+		existed      common.QueueItemStatusType
+		attempts     int
+		wantOK       bool
+		wantItems    int
+		wantStatus   common.QueueItemStatusType
+		wantAttempts int
+	}{
+		{"new item", "", 0, true, 1, common.QueueItemPending, 0},
+		{"pending deduplicated", common.QueueItemPending, 0, false, 1, common.QueueItemPending, 0},
+		{"processing pushed again", common.QueueItemProcessing, 1, true, 2, common.QueueItemPending, 0},
+		{"failed revived", common.QueueItemFailed, 3, true, 1, common.QueueItemPending, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mysqltest.Open(t, &models.QueueItem{})
+			if test.existed != "" {
+				newItem(t, 1, test.existed, test.attempts, time.Now().Add(LeaseDuration))
+			}
+
+			if ok := TaskQueue.Push(TaskQueueHandle{ID: 1, PolicyID: 2, HandleID: "h"}); ok != test.wantOK {
+				t.Fatalf("Push() got %v, want %v", ok, test.wantOK)
+			}
+			items := queueItems(t)
+			if len(items) != test.wantItems {
+				t.Fatalf("Push() got %d items, want %d", len(items), test.wantItems)
+			}
+			last := items[len(items)-1]
+			if last.Status != test.wantStatus || last.Attempts != test.wantAttempts {
+				t.Fatalf("Push() got item status %s attempts %d, want %s %d", last.Status, last.Attempts, test.wantStatus, test.wantAttempts)
+			}
+			if test.existed == common.QueueItemFailed && (last.HandleID != "h" || last.PolicyID != 2) {
+				t.Fatalf("Push() revived item got handle %s policy %d, want h 2", last.HandleID, last.PolicyID)
+			}
+		})
+	}
+}
+
+func TestPop(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		status       common.QueueItemStatusType
+		attempts     int
+		expiry       time.Time
+		wantOK       bool
+		wantStatus   common.QueueItemStatusType
+		wantAttempts int
+	}{
+		{"pending", common.QueueItemPending, 0, time.UnixMilli(0), true, common.QueueItemProcessing, 1},
+		{"lease expired", common.QueueItemProcessing, 1, now.Add(-time.Second), true, common.QueueItemProcessing, 2},
+		{"lease held", common.QueueItemProcessing, 1, now.Add(LeaseDuration), false, common.QueueItemProcessing, 1},
+		{"failed not popped", common.QueueItemFailed, 1, time.UnixMilli(0), false, common.QueueItemFailed, 1},
+		{"max attempts", common.QueueItemProcessing, MaxAttempts, now.Add(-time.Second), false, common.QueueItemFailed, MaxAttempts},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mysqltest.Open(t, &models.QueueItem{})
+			item := newItem(t, 1, test.status, test.attempts, test.expiry)
+
+			handle, ok := TaskQueue.Pop()
+			if ok != test.wantOK {
+				t.Fatalf("Pop() got %v, want %v", ok, test.wantOK)
+			}
+			if ok && (handle.ID != 1 || handle.ItemID != item.ID) {
+				t.Fatalf("Pop() got %+v, want item %d", handle, item.ID)
+			}
+			got := queueItems(t)[0]
+			if got.Status != test.wantStatus || got.Attempts != test.wantAttempts {
+				t.Fatalf("Pop() got item status %s attempts %d, want %s %d", got.Status, got.Attempts, test.wantStatus, test.wantAttempts)
+			}
+			if ok && (got.LeaseOwner != common.InstanceName || !got.LeaseExpiry.After(now)) {
+				t.Fatalf("Pop() got lease %s %v, want %s after %v", got.LeaseOwner, got.LeaseExpiry, common.InstanceName, now)
+			}
+		})
+	}
+}
+
+// TestPopOptimisticLock 查询到元素后被其他实例抢先取走时，不能再取到同一个元素
+func TestPopOptimisticLock(t *testing.T) {
+	db := mysqltest.Open(t, &models.QueueItem{})
+	first := newItem(t, 1, common.QueueItemPending, 0, time.UnixMilli(0))
+	second := newItem(t, 2, common.QueueItemPending, 0, time.UnixMilli(0))
+
+	// 在第一次更新前模拟其他实例取走第一个元素
+	stolen := false
+	err := db.Callback().Update().Before("gorm:update").Register("test:steal", func(tx *gorm.DB) {
+		if stolen {
+			return
+		}
+		stolen = true
+		err := tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE queue_item SET status =?, attempts =?, lease_owner =?, lease_expiry =? WHERE id =?",
+			common.QueueItemProcessing, 1, "other", time.Now().Add(LeaseDuration), first.ID).Error
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle, ok := TaskQueue.Pop()
+	if !ok || handle.ItemID != second.ID {
+		t.Fatalf("Pop() got %+v %v, want item %d", handle, ok, second.ID)
+	}
+	items := queueItems(t)
+	if items[0].LeaseOwner != "other" || items[0].Attempts != 1 {
+		t.Fatalf("Pop() overwrote lease of other instance, got %s attempts %d", items[0].LeaseOwner, items[0].Attempts)
+	}
+	if _, ok = TaskQueue.Pop(); ok {
+		t.Fatal("Pop() got item, want queue empty")
+	}
+}
+
+func TestDone(t *testing.T) {
+	tests := []struct {
+		name       string
+		owner      string
+		err        error
+		wantItems  int
+		wantStatus common.QueueItemStatusType
+	}{
+		{"success removed", common.InstanceName, nil, 0, ""},
+		{"failure kept", common.InstanceName, gorm.ErrInvalidData, 1, common.QueueItemFailed},
+		{"lease lost", "other", nil, 1, common.QueueItemProcessing},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mysqltest.Open(t, &models.QueueItem{})
+			item := newItem(t, 1, common.QueueItemProcessing, 1, time.Now().Add(LeaseDuration))
+			mysql.DB.Model(item).Update("lease_owner", test.owner)
+
+			if err := TaskQueue.Done(TaskQueueHandle{ID: 1, ItemID: item.ID}, test.err); err != nil {
+				t.Fatal(err)
+			}
+			items := queueItems(t)
+			if len(items) != test.wantItems {
+				t.Fatalf("Done() got %d items, want %d", len(items), test.wantItems)
+			}
+			if len(items) != 0 && items[0].Status != test.wantStatus {
+				t.Fatalf("Done() got status %s, want %s", items[0].Status, test.wantStatus)
+			}
+		})
+	}
+}
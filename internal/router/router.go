@@ -55,6 +55,13 @@ func Init(ctx *common.Context) *gin.Engine {
 		// 收集集群大表信息
 		clu.GET("/bigtable", middlewares.AdminVerify(), new(ctl.ClusterController).GetClusterBigTables)
 
+		// 查询表结构快照
+		clu.GET("/catalog", new(ctl.CatalogController).QueryCatalogTable)
+		// 查询表结构变更历史
+		clu.GET("/catalog/change", new(ctl.CatalogController).QuerySchemaChange)
+		// 刷新表结构快照
+		clu.GET("/catalog/refresh", middlewares.AdminVerify(), new(ctl.CatalogController).RefreshCatalog)
+
 		// 查询集群对应的库列表
 		clu.GET("/:cluster_id/databases", new(ctl.ClusterController).GetClusterDatabases)
 		// 查询集群对应的表列表
@@ -70,6 +77,15 @@ func Init(ctx *common.Context) *gin.Engine {
 		clu.GET("/statistic/database", new(ctl.ClusterStatisticController).ClusterStatisticGroupByDatabase)
 		// 表维度统计信息
 		clu.GET("/statistic/table", new(ctl.ClusterStatisticController).ClusterStatisticGroupByTable)
+
+		// BU维度容量增长预测
+		clu.GET("/forecast/bu", new(ctl.ClusterStatisticController).ClusterForecastGroupByBu)
+		// 集群维度容量增长预测
+		clu.GET("/forecast/cluster", new(ctl.ClusterStatisticController).ClusterForecastGroupByCluster)
+		// 库维度容量增长预测
+		clu.GET("/forecast/database", new(ctl.ClusterStatisticController).ClusterForecastGroupByDatabase)
+		// 表维度容量增长预测
+		clu.GET("/forecast/table", new(ctl.ClusterStatisticController).ClusterForecastGroupByTable)
 	}
 
 	// 源端信息相关路由
@@ -123,6 +139,18 @@ func Init(ctx *common.Context) *gin.Engine {
 		policy.GET("/", new(ctl.PolicyController).QueryPolicy)
 		// 删除策略
 		policy.DELETE("/", new(ctl.PolicyController).DeletePolicy)
+		// 分析策略治理条件的执行计划
+		policy.POST("/explain", new(ctl.PolicyController).ExplainPolicy)
+		// 补录错过的执行周期
+		policy.POST("/backfill", new(ctl.PolicyController).BackfillPolicy)
+		// 查询策略推荐
+		policy.GET("/recommend", new(ctl.PolicyRecommendController).QueryPolicyRecommend)
+		// 刷新策略推荐
+		policy.GET("/recommend/refresh", middlewares.AdminVerify(), new(ctl.PolicyRecommendController).RefreshPolicyRecommend)
+		// 采纳策略推荐
+		policy.POST("/recommend/accept", new(ctl.PolicyRecommendController).AcceptPolicyRecommend)
+		// 忽略策略推荐
+		policy.POST("/recommend/dismiss", new(ctl.PolicyRecommendController).DismissPolicyRecommend)
 		// 查询策略修订记录
 		policy.GET("/revision", new(ctl.PolicyRevisionController).QueryPolicyRevision)
 		// BU维度统计信息
@@ -148,8 +176,12 @@ func Init(ctx *common.Context) *gin.Engine {
 		task.GET("/revision", new(ctl.TaskRevisionController).QueryPolicyRevision)
 		// 查询任务修订记录
 		task.GET("/changelog", new(ctl.TaskChangeLogController).QueryTaskChangeLog)
+		// 查询任务按库拆分的子任务
+		task.GET("/subtask", new(ctl.SubTaskController).QuerySubTask)
 		// 上报任务执行结果
 		task.PUT("/result", new(ctl.TaskController).UpdateTaskResult)
+		// 查询任务当前生效的限流参数
+		task.GET("/throttle", new(ctl.ThrottleController).QueryTaskThrottle)
 		// 查询任务状态统计
 		task.GET("/statistic/summary", new(ctl.TaskStatisticController).TaskStatisticSummary)
 		// BU维度统计信息
@@ -162,6 +194,23 @@ func Init(ctx *common.Context) *gin.Engine {
 		task.GET("/statistic/table", new(ctl.TaskStatisticController).TaskStatisticGroupByTable)
 		// 任务的执行计划
 		task.GET("/plan", new(ctl.TaskStatisticController).TaskExecPlan)
+		// 模拟调整配置或策略后的任务执行计划
+		task.POST("/plan/simulate", new(ctl.TaskStatisticController).TaskExecSimulate)
+	}
+
+	// 封网日历相关路由
+	blackout := public.Group("/blackout", middlewares.Jwt())
+	{
+		// 查询封网日历
+		blackout.GET("/", new(ctl.BlackoutController).QueryBlackout)
+		// 创建封网日历
+		blackout.POST("/", middlewares.AdminVerify(), new(ctl.BlackoutController).CreateBlackout)
+		// 修改封网日历
+		blackout.PUT("/", middlewares.AdminVerify(), new(ctl.BlackoutController).UpdateBlackout)
+		// 删除封网日历
+		blackout.DELETE("/", middlewares.AdminVerify(), new(ctl.BlackoutController).DeleteBlackout)
+		// 从iCal文件导入封网日历
+		blackout.POST("/import", middlewares.AdminVerify(), new(ctl.BlackoutController).ImportBlackout)
 	}
 
 	// 管理员相关路由
@@ -180,6 +229,41 @@ func Init(ctx *common.Context) *gin.Engine {
 		manage.PUT("/config", new(ctl.ConfigController).UpdateConfig)
 		// 通知测试
 		manage.GET("/notice/test", new(ctl.ConfigController).NoticeTest)
+		// 查询通知路由规则
+		manage.GET("/notice/route", new(ctl.NotifyRouteController).QueryNotifyRoute)
+		// 创建通知路由规则
+		manage.POST("/notice/route", new(ctl.NotifyRouteController).CreateNotifyRoute)
+		// 修改通知路由规则
+		manage.PUT("/notice/route", new(ctl.NotifyRouteController).UpdateNotifyRoute)
+		// 删除通知路由规则
+		manage.DELETE("/notice/route", new(ctl.NotifyRouteController).DeleteNotifyRoute)
+
+		// 查询并发配额
+		manage.GET("/quota", new(ctl.QuotaController).QueryQuota)
+		// 创建并发配额
+		manage.POST("/quota", new(ctl.QuotaController).CreateQuota)
+		// 修改并发配额
+		manage.PUT("/quota", new(ctl.QuotaController).UpdateQuota)
+		// 删除并发配额
+		manage.DELETE("/quota", new(ctl.QuotaController).DeleteQuota)
+
+		// 查询限流配置
+		manage.GET("/throttle", new(ctl.ThrottleController).QueryThrottle)
+		// 创建限流配置
+		manage.POST("/throttle", new(ctl.ThrottleController).CreateThrottle)
+		// 修改限流配置
+		manage.PUT("/throttle", new(ctl.ThrottleController).UpdateThrottle)
+		// 删除限流配置
+		manage.DELETE("/throttle", new(ctl.ThrottleController).DeleteThrottle)
+		// 调整任务的限流配置
+		manage.PUT("/throttle/task", new(ctl.ThrottleController).UpdateTaskThrottle)
+
+		// 查询队列中待处理的策略和任务
+		manage.GET("/queue", new(ctl.QueueController).QueryQueueItem)
+		// 重新入队列
+		manage.PUT("/queue/requeue", new(ctl.QueueController).RequeueQueueItem)
+		// 丢弃队列元素
+		manage.DELETE("/queue", new(ctl.QueueController).DropQueueItem)
 	}
 
 	// init swagger
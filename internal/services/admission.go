@@ -0,0 +1,210 @@
+package services
+
+import (
+	"fmt"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"time"
+)
+
+// 任务准入检查中与数据来源无关的判断，调度任务和模拟器都使用这里的判断，
+// 调用方负责查询执行中的任务、配额、封网等数据，now为任务所在时区的当前时间
+
+// ExecDateAdmission 达到执行日期
+func ExecDateAdmission(task *models.Task, now time.Time) (bool, common.ServiceCode, error) {
+	if task.ExecuteDate <= now.Format(time.DateOnly) {
+		return true, common.CodeOK, nil
+	}
+	return false, common.CodeTaskExecDateNotReached, fmt.Errorf("task.ExecuteDate(%s) > now(%v %s)",
+		task.ExecuteDate, now.Format(time.DateOnly), now.Location())
+}
+
+// ExecWindowPosition 判断时间与执行窗口的关系：-1在执行窗口之前，0在执行窗口内，1在执行窗口之后
+func ExecWindowPosition(windows []common.ExecWindow, execDate string, execTime time.Time) (int, error) {
+	// 没有到执行日期
+	if execDate > execTime.Format(time.DateOnly) {
+		return -1, nil
+	}
+
+	ranges, err := common.ActiveExecWindowRanges(windows, execDate, execTime)
+	if err != nil {
+		return 0, err
+	}
+
+	// 多个执行窗口时：处于任一窗口内即可执行，当天还有未开始的窗口时视为在执行窗口之前
+	res := 1
+	for _, r := range ranges {
+		if !execTime.Before(r.Start) && !execTime.After(r.End) {
+			// 刚好在执行窗口
+			return 0, nil
+		}
+		if execTime.Before(r.Start) {
+			// 在执行窗口之前
+			res = -1
+		}
+	}
+	return res, nil
+}
+
+// ExecWindowAdmission 处于执行窗口内
+func ExecWindowAdmission(windows []common.ExecWindow, task *models.Task, now time.Time) (bool, common.ServiceCode, error) {
+	inWin, err := ExecWindowPosition(windows, task.ExecuteDate, now)
+	if err != nil {
+		return false, common.CodeServerErr, fmt.Errorf("judge task(%v) in exec windows %s failed, %s", task.Name, string(task.ExecuteWindow), err)
+	}
+
+	switch inWin {
+	case -1:
+		// 在执行窗口之前
+		return false, common.CodeTaskNotReachedExecWin, fmt.Errorf("task has not yet reached the execution time window%s", string(task.ExecuteWindow))
+	case 1:
+		// 在执行窗口之后
+		return false, common.CodeTaskMissedExecWin, fmt.Errorf("task missed exec windows%s", string(task.ExecuteWindow))
+	}
+	return true, common.CodeOK, nil
+}
+
+// RemainingExecWindowEnd 返回当前所处执行窗口的结束时间，不在执行窗口内时只检查当前时间
+func RemainingExecWindowEnd(windows []common.ExecWindow, task *models.Task, now time.Time) (time.Time, error) {
+	ranges, err := common.ActiveExecWindowRanges(windows, task.ExecuteDate, now)
+	if err != nil {
+		return now, fmt.Errorf("parse task(%v) exec windows failed, %s", task.ID, err)
+	}
+
+	end := now.Add(time.Second)
+	for _, r := range ranges {
+		if !now.Before(r.Start) && !now.After(r.End) && r.End.After(end) {
+			end = r.End
+		}
+	}
+	return end, nil
+}
+
+// BlackoutAdmission 当前时间到本次执行窗口结束之间有封网时禁止执行，避免任务执行过程中进入封网期间。
+// 未通过时返回的时间为封网的结束时间，在此之前不会通过检查
+func BlackoutAdmission(blackouts []models.Blackout, windows []common.ExecWindow, task *models.Task, now time.Time) (bool, time.Time, common.ServiceCode, error) {
+	winEnd, err := RemainingExecWindowEnd(windows, task, now)
+	if err != nil {
+		return false, time.Time{}, common.CodeServerErr, err
+	}
+	if b, start, end, ok := MatchBlackout(blackouts, now, winEnd); ok {
+		return false, end, common.CodeTaskInBlackout, fmt.Errorf("task is in blackout(%s) from %s to %s before exec window ends at %s",
+			b.Name, start.Format(time.DateTime), end.Format(time.DateTime), winEnd.Format(time.DateTime))
+	}
+	return true, time.Time{}, common.CodeOK, nil
+}
+
+// ParallelAdmission 任务最大并发度检查
+func ParallelAdmission(executing, maxParallel int) (bool, common.ServiceCode, error) {
+	if executing >= maxParallel {
+		return false, common.CodeTaskParallelUpperLimit,
+			fmt.Errorf("current running task num(%v) has reached the maximum parallel limit(%v)", executing, maxParallel)
+	}
+	return true, common.CodeOK, nil
+}
+
+// QuotaAdmission BU或集群的并发配额检查，quota为nil或最大并发数小于等于0表示不限制
+func QuotaAdmission(quota *models.Quota, executing int) (bool, common.ServiceCode, error) {
+	if quota == nil || quota.MaxParallel <= 0 || executing < quota.MaxParallel {
+		return true, common.CodeOK, nil
+	}
+	code := common.CodeTaskBuQuotaLimit
+	if quota.Scope == common.QuotaScopeCluster {
+		code = common.CodeTaskClusterQuotaLimit
+	}
+	return false, code, fmt.Errorf("executing task num(%v) of %s(%s) has reached the quota(%s) limit(%v)",
+		executing, quota.Scope, quota.ScopeValue, quota.Name, quota.MaxParallel)
+}
+
+// FairShareAdmission 按BU权重公平分配任务并发数，BU的份额为 最大并发数*BU权重/有任务的BU权重之和(至少为1)，
+// 只有其他BU因并发不足在排队且未用满份额时，才限制超出份额的BU，避免空闲时浪费并发。
+// executing和waiting为各BU执行中和排队中的任务数，weights中没有的BU权重为1
+func FairShareAdmission(bu string, maxParallel int, weights, executing, waiting map[string]int) (bool, common.ServiceCode, error) {
+	active := map[string]struct{}{bu: {}}
+	for b := range executing {
+		active[b] = struct{}{}
+	}
+	for b, n := range waiting {
+		if n > 0 {
+			active[b] = struct{}{}
+		}
+	}
+
+	weight := func(b string) int {
+		if w, ok := weights[b]; ok {
+			return w
+		}
+		return 1
+	}
+	totalWeight := 0
+	for b := range active {
+		totalWeight += weight(b)
+	}
+	share := func(b string) int {
+		return max(maxParallel*weight(b)/totalWeight, 1)
+	}
+
+	if executing[bu] < share(bu) {
+		return true, common.CodeOK, nil
+	}
+	for b := range active {
+		if b == bu || waiting[b] == 0 {
+			continue
+		}
+		if executing[b] < share(b) {
+			return false, common.CodeTaskFairShareLimit,
+				fmt.Errorf("executing task num(%v) of bu(%s) has reached its fair share(%v), bu(%s) is waiting with %v executing task and share(%v)",
+					executing[bu], bu, share(bu), b, executing[b], share(b))
+		}
+	}
+	return true, common.CodeOK, nil
+}
+
+// ConflictAdmission 按任务冲突级别检查同一源端集群、库或表执行中的任务数是否达到上限
+func ConflictAdmission(level common.TaskConflictLevelType, conflictMax int, task *models.Task, executing []*models.Task) (bool, common.ServiceCode, error) {
+	code := common.CodeTaskSrcClusterConflict
+	switch level {
+	case common.TaskConflictLevelCluster:
+	case common.TaskConflictLevelDatabase:
+		code = common.CodeTaskSrcDatabaseConflict
+	case common.TaskConflictLevelTable:
+		code = common.CodeTaskSrcTableConflict
+	default:
+		return false, common.CodeConfigConflictLevelErr, fmt.Errorf("not support task conflict level: %s", level)
+	}
+
+	key, err := taskConflictKey(level, task)
+	if err != nil {
+		return false, common.CodeServerErr, err
+	}
+	count := 1
+	for _, e := range executing {
+		k, err := taskConflictKey(level, e)
+		if err != nil {
+			return false, common.CodeServerErr, err
+		}
+		if k == key {
+			count++
+		}
+	}
+	if count > conflictMax {
+		return false, code, fmt.Errorf("source %s(%v) reached the max num(%v) executing task", level, key, conflictMax)
+	}
+	return true, common.CodeOK, nil
+}
+
+// taskConflictKey 按任务冲突级别返回任务的源端集群、库或表(分表按同一张表计算)
+func taskConflictKey(level common.TaskConflictLevelType, task *models.Task) (string, error) {
+	switch level {
+	case common.TaskConflictLevelDatabase:
+		return task.SrcClusterID + "/" + task.SrcDatabaseName, nil
+	case common.TaskConflictLevelTable:
+		_, baseName, err := common.CheckSameShardingTables(task.SrcTablesName)
+		if err != nil {
+			return "", fmt.Errorf("check task(%v) same sharding tables failed, %s", task.Name, err)
+		}
+		return task.SrcClusterID + "/" + task.SrcDatabaseName + "/" + baseName, nil
+	default:
+		return task.SrcClusterID, nil
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+func TestExecWindowPosition(t *testing.T) {
+	windows := []common.ExecWindow{{Start: "01:00:00", End: "03:00:00"}, {Start: "22:00:00", End: "02:00:00"}}
+	tests := []struct {
+		name     string
+		execDate string
+		now      string
+		want     int
+	}{
+		{"exec date not reached", "2024-01-02", "2024-01-01 01:30:00", -1},
+		{"before first window", "2024-01-01", "2024-01-01 00:30:00", -1},
+		{"in window", "2024-01-01", "2024-01-01 01:30:00", 0},
+		{"window end", "2024-01-01", "2024-01-01 03:00:00", 0},
+		{"between windows", "2024-01-01", "2024-01-01 12:00:00", -1},
+		{"in cross day window", "2024-01-01", "2024-01-02 00:30:00", 0},
+		{"cross day window before exec date", "2024-01-02", "2024-01-02 00:30:00", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ExecWindowPosition(windows, test.execDate, parseLocalTime(test.now))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("ExecWindowPosition() got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExecWindowAdmission(t *testing.T) {
+	windows := []common.ExecWindow{{Start: "01:00:00", End: "03:00:00"}}
+	tests := []struct {
+		name string
+		now  string
+		want common.ServiceCode
+	}{
+		{"not reached", "2024-01-01 00:59:59", common.CodeTaskNotReachedExecWin},
+		{"in window", "2024-01-01 02:00:00", common.CodeOK},
+		{"missed", "2024-01-01 03:00:01", common.CodeTaskMissedExecWin},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := &models.Task{ExecuteDate: "2024-01-01"}
+			ok, code, _ := ExecWindowAdmission(windows, task, parseLocalTime(test.now))
+			if code != test.want || ok != (test.want == common.CodeOK) {
+				t.Fatalf("ExecWindowAdmission() got %v %s, want %s", ok, code.Message, test.want.Message)
+			}
+		})
+	}
+}
+
+func TestBlackoutAdmission(t *testing.T) {
+	windows := []common.ExecWindow{{Start: "01:00:00", End: "05:00:00"}}
+	blackouts := []models.Blackout{{
+		Name:      "release",
+		StartTime: parseLocalTime("2024-01-01 04:00:00"),
+		EndTime:   parseLocalTime("2024-01-01 06:00:00"),
+	}}
+	tests := []struct {
+		name    string
+		now     string
+		want    bool
+		wantEnd string
+	}{
+		{"blackout before window ends", "2024-01-01 02:00:00", false, "2024-01-01 06:00:00"},
+		{"in blackout", "2024-01-01 04:30:00", false, "2024-01-01 06:00:00"},
+		{"out of window only checks now", "2024-01-01 00:30:00", true, ""},
+		{"after blackout", "2024-01-02 01:00:00", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := &models.Task{ExecuteDate: "2024-01-01"}
+			ok, end, _, _ := BlackoutAdmission(blackouts, windows, task, parseLocalTime(test.now))
+			if ok != test.want {
+				t.Fatalf("BlackoutAdmission() got %v, want %v", ok, test.want)
+			}
+			if !ok && end.Format(time.DateTime) != test.wantEnd {
+				t.Fatalf("BlackoutAdmission() got end %v, want %s", end, test.wantEnd)
+			}
+		})
+	}
+}
+
+func TestQuotaAdmission(t *testing.T) {
+	tests := []struct {
+		name      string
+		quota     *models.Quota
+		executing int
+		want      common.ServiceCode
+	}{
+		{"no quota", nil, 10, common.CodeOK},
+		{"unlimited", &models.Quota{Scope: common.QuotaScopeBu, MaxParallel: 0}, 10, common.CodeOK},
+		{"under limit", &models.Quota{Scope: common.QuotaScopeBu, MaxParallel: 2}, 1, common.CodeOK},
+		{"bu limit", &models.Quota{Scope: common.QuotaScopeBu, MaxParallel: 2}, 2, common.CodeTaskBuQuotaLimit},
+		{"cluster limit", &models.Quota{Scope: common.QuotaScopeCluster, MaxParallel: 2}, 3, common.CodeTaskClusterQuotaLimit},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, code, _ := QuotaAdmission(test.quota, test.executing); code != test.want {
+				t.Fatalf("QuotaAdmission() got %s, want %s", code.Message, test.want.Message)
+			}
+		})
+	}
+}
+
+func TestFairShareAdmission(t *testing.T) {
+	tests := []struct {
+		name      string
+		bu        string
+		weights   map[string]int
+		executing map[string]int
+		waiting   map[string]int
+		want      bool
+	}{
+		{"under share", "a", nil, map[string]int{"a": 1, "b": 1}, map[string]int{"b": 1}, true},
+		{"over share and other bu waiting", "a", nil, map[string]int{"a": 2}, map[string]int{"b": 1}, false},
+		{"over share and nobody waiting", "a", nil, map[string]int{"a": 3}, nil, true},
+		{"other bu reached its share", "a", nil, map[string]int{"a": 2, "b": 2}, map[string]int{"b": 1}, true},
+		{"weighted share", "a", map[string]int{"a": 3}, map[string]int{"a": 2}, map[string]int{"b": 1}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if ok, _, err := FairShareAdmission(test.bu, 4, test.weights, test.executing, test.waiting); ok != test.want {
+				t.Fatalf("FairShareAdmission() got %v(%v), want %v", ok, err, test.want)
+			}
+		})
+	}
+}
+
+func TestConflictAdmission(t *testing.T) {
+	executing := []*models.Task{
+		{SrcClusterID: "c1", SrcDatabaseName: "db1", SrcTablesName: "order_1,order_2"},
+		{SrcClusterID: "c1", SrcDatabaseName: "db2", SrcTablesName: "user"},
+	}
+	tests := []struct {
+		name  string
+		level common.TaskConflictLevelType
+		max   int
+		task  *models.Task
+		want  common.ServiceCode
+	}{
+		{"cluster conflict", common.TaskConflictLevelCluster, 2, &models.Task{SrcClusterID: "c1"}, common.CodeTaskSrcClusterConflict},
+		{"cluster allowed", common.TaskConflictLevelCluster, 3, &models.Task{SrcClusterID: "c1"}, common.CodeOK},
+		{"other cluster", common.TaskConflictLevelCluster, 1, &models.Task{SrcClusterID: "c2"}, common.CodeOK},
+		{
+			name: "database conflict", level: common.TaskConflictLevelDatabase, max: 1,
+			task: &models.Task{SrcClusterID: "c1", SrcDatabaseName: "db2"}, want: common.CodeTaskSrcDatabaseConflict,
+		},
+		{
+			name: "single table not in sharding tables", level: common.TaskConflictLevelTable, max: 1,
+			task: &models.Task{SrcClusterID: "c1", SrcDatabaseName: "db1", SrcTablesName: "order_3"}, want: common.CodeOK,
+		},
+		{
+			name: "same sharding tables conflict", level: common.TaskConflictLevelTable, max: 1,
+			task: &models.Task{SrcClusterID: "c1", SrcDatabaseName: "db1", SrcTablesName: "order_3,order_4"}, want: common.CodeTaskSrcTableConflict,
+		},
+		{"unknown level", "unknown", 1, &models.Task{SrcClusterID: "c1"}, common.CodeConfigConflictLevelErr},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, code, _ := ConflictAdmission(test.level, test.max, test.task, executing); code != test.want {
+				t.Fatalf("ConflictAdmission() got %s, want %s", code.Message, test.want.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	. "github.com/sunkaimr/data-loom/internal/job/status"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	. "github.com/sunkaimr/data-loom/internal/pkg/queue"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// BackfillMaxPeriods 一次最多补录的周期数
+const BackfillMaxPeriods = 366
+
+// BackfillService 补录策略在指定日期范围内错过的执行周期
+type BackfillService struct {
+	PolicyID  uint          `json:"policy_id"`  // 策略ID
+	StartDate string        `json:"start_date"` // 补录的开始日期 "2024-01-02"
+	EndDate   string        `json:"end_date"`   // 补录的结束日期 "2024-03-02"，需早于今天
+	Collapse  bool          `json:"collapse"`   // 是否将错过的周期合并为一个任务执行，合并后的任务按最后一个周期的时间计算治理条件
+	Periods   []string      `json:"periods"`    // 错过的执行周期
+	Tasks     []TaskService `json:"tasks"`      // 生成的补录任务
+}
+
+// Backfill 为策略在日期范围内没有生成过任务的周期创建补录任务，补录任务与正常任务一样经过准入检查后执行
+func (c *BackfillService) Backfill(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	policy := &models.Policy{}
+	err := db.Model(policy).First(policy, "id =?", c.PolicyID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.CodePolicyNotExist, fmt.Errorf("models.Policy(id=%v) not exist", c.PolicyID)
+		}
+		return common.CodeServerErr, fmt.Errorf("query models.Policy(id=%v) from db failed, %s", c.PolicyID, err)
+	}
+	if !policy.Enable {
+		return common.CodePolicyBackfillDisabled, fmt.Errorf("policy(%v) is disabled", policy.ID)
+	}
+	if policy.Period == common.PeriodOnce {
+		return common.CodePolicyBackfillPeriodErr, fmt.Errorf("policy(%v) period is %s", policy.ID, policy.Period)
+	}
+
+	source := &models.Source{}
+	err = db.Model(source).First(source, "id =?", policy.SrcID).Error
+	if err != nil {
+		return common.CodeServerErr, fmt.Errorf("query models.Source(id=%v) from db failed, %s", policy.SrcID, err)
+	}
+
+	// 执行周期按源端集群所在时区计算
+	tz, err := ClusterTimezone(db, source.ClusterID)
+	if err != nil {
+		return common.CodeServerErr, err
+	}
+	loc := common.LoadLocation(tz)
+	now := common.NowIn(tz)
+
+	start, err1 := time.ParseInLocation(time.DateOnly, c.StartDate, loc)
+	end, err2 := time.ParseInLocation(time.DateOnly, c.EndDate, loc)
+	if err1 != nil || err2 != nil {
+		return common.CodePolicyBackfillDateErr, fmt.Errorf("parse start_date(%s) or end_date(%s) failed, it should like '%s'", c.StartDate, c.EndDate, time.DateOnly)
+	}
+	if start.After(end) || end.Format(time.DateOnly) >= now.Format(time.DateOnly) {
+		return common.CodePolicyBackfillDateErr, fmt.Errorf("start_date(%s) should not after end_date(%s) and end_date should before today", c.StartDate, c.EndDate)
+	}
+	if end.Sub(start) > time.Hour*24*BackfillMaxPeriods*2 {
+		return common.CodePolicyBackfillTooMany, fmt.Errorf("date range(%s - %s) too large", c.StartDate, c.EndDate)
+	}
+
+	c.Periods, err = c.missedPeriods(db, policy, start, end)
+	if err != nil {
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	if len(c.Periods) > BackfillMaxPeriods {
+		return common.CodePolicyBackfillTooMany, fmt.Errorf("missed %d periods, more than %d", len(c.Periods), BackfillMaxPeriods)
+	}
+	if len(c.Periods) == 0 {
+		return common.CodeOK, nil
+	}
+
+	executeDate, err := backfillExecuteDate(policy, now)
+	if err != nil {
+		return common.CodePolicyExecuteWindowErr, err
+	}
+
+	groups := make([][]string, 0, len(c.Periods))
+	if c.Collapse {
+		groups = append(groups, c.Periods)
+	} else {
+		for _, p := range c.Periods {
+			groups = append(groups, []string{p})
+		}
+	}
+
+	tasks := make([]*models.Task, 0, len(groups))
+	for _, periods := range groups {
+		task := GenerateTaskByPolicy(&models.Task{}, policy, source)
+		task.Creator = u.UserName
+		task.Name = fmt.Sprintf("%s(补录%s)", task.Name, utils.Ternary[string](len(periods) == 1, periods[0], periods[0]+"~"+periods[len(periods)-1]))
+		task.ExecuteDate = executeDate
+		task.Timezone = tz
+		task.ExecuteWindows = policy.ExecuteWindows
+		task.Backfill = true
+		task.BackfillPeriods = strings.Join(periods, ",")
+		task.Condition, err = BackfillCondition(policy, task)
+		if err != nil {
+			return common.CodeServerErr, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	err = db.Create(&tasks).Error
+	if err != nil {
+		err = fmt.Errorf("save models.Task to db failed, %s", err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	cCtx := common.NewContext().WithDB(db).WithLog(log)
+	for _, task := range tasks {
+		CreateTaskChangeLog(cCtx, task, u.RealName, fmt.Sprintf(common.TaskChangeLogBackfill, task.BackfillPeriods))
+		c.Tasks = append(c.Tasks, *new(TaskService).ModelToService(task))
+
+		if !common.JudgeTaskCouldCheckBeforeExec(task.ExecuteDate, task.Timezone) {
+			continue
+		}
+		handle := &TaskQueueHandle{ID: task.ID, PolicyID: task.PolicyID, HandleID: ctx.GetHeader(common.RequestID)}
+		if TaskQueue.Push(*handle) {
+			log.With(WithExtra[*TaskQueueHandle](handle, ProcQueued)...).Infof("task %s push queue sucess", task.Name)
+		} else {
+			log.With(WithExtra[*TaskQueueHandle](handle, ProcNotQueue)...).Infof("task %s not push queue", task.Name)
+		}
+	}
+	return common.CodeOK, nil
+}
+
+// missedPeriods 计算日期范围内应执行但没有生成过任务的周期，已生成过任务(无论执行结果)的周期不再补录
+func (c *BackfillService) missedPeriods(db *gorm.DB, policy *models.Policy, start, end time.Time) ([]string, error) {
+	var tasks []models.Task
+	err := db.Model(&models.Task{}).Select("execute_date", "backfill", "backfill_periods").
+		Where("policy_id =?", policy.ID).Order("execute_date").Find(&tasks).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.Task(policy_id=%v) from db failed, %s", policy.ID, err)
+	}
+	return missedPeriodsOf(policy, tasks, start, end)
+}
+
+// missedPeriodsOf 根据策略已生成的任务(按执行日期排序)计算日期范围内没有生成过任务的周期
+func missedPeriodsOf(policy *models.Policy, tasks []models.Task, start, end time.Time) ([]string, error) {
+	// 天和周级别的周期以策略第一次执行的日期为基准计算
+	anchor := policy.CreatedAt.In(start.Location()).Format(time.DateOnly)
+	covered := make(map[string]bool)
+	var execDates []string
+	for _, t := range tasks {
+		if t.Backfill {
+			for _, p := range strings.Split(t.BackfillPeriods, ",") {
+				covered[p] = true
+			}
+			continue
+		}
+		execDates = append(execDates, t.ExecuteDate)
+		anchor = min(anchor, t.ExecuteDate)
+	}
+	anchorDate, err := time.ParseInLocation(time.DateOnly, anchor, start.Location())
+	if err != nil {
+		return nil, fmt.Errorf("parse anchor date(%s) failed, %s", anchor, err)
+	}
+
+	var schedule *common.CronSchedule
+	if policy.Period == common.PeriodCron {
+		schedule, err = common.ParseCronExpr(policy.CronExpr)
+		if err != nil {
+			return nil, fmt.Errorf("parse policy(%v) cron_expr(%s) failed, %s", policy.ID, policy.CronExpr, err)
+		}
+	}
+
+	var periods []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if !periodScheduledOn(policy, schedule, anchorDate, d) {
+			continue
+		}
+		date := d.Format(time.DateOnly)
+		if covered[date] {
+			continue
+		}
+		// 正常任务的执行日期可能被顺延，落在本周期内的任务都算作本周期已执行
+		nextTime, err := PolicyNextScheduleTime(policy, d)
+		if err != nil {
+			return nil, err
+		}
+		next := FormatExecDate(policy, nextTime)
+		executed := false
+		for _, e := range execDates {
+			if e >= date && e < next {
+				executed = true
+				break
+			}
+		}
+		if !executed {
+			periods = append(periods, date)
+		}
+		if len(periods) > BackfillMaxPeriods {
+			break
+		}
+	}
+	return periods, nil
+}
+
+// periodScheduledOn 判断策略在指定日期是否应该执行
+func periodScheduledOn(policy *models.Policy, schedule *common.CronSchedule, anchor, d time.Time) bool {
+	days := int(d.Sub(anchor).Round(time.Hour*24) / (time.Hour * 24))
+	months := (d.Year()-anchor.Year())*12 + int(d.Month()) - int(anchor.Month())
+	monthly := func(step int) bool {
+		return d.Format(time.DateOnly) == FormatExecDate(policy, d) && ((months%step)+step)%step == 0
+	}
+
+	switch policy.Period {
+	case common.PeriodDay:
+		return true
+	case common.PeriodTwoDay:
+		return ((days%2)+2)%2 == 0
+	case common.PeriodWeekly:
+		return ((days%7)+7)%7 == 0
+	case common.PeriodTwoWeeks:
+		return ((days%14)+14)%14 == 0
+	case common.PeriodMonthly:
+		return monthly(1)
+	case common.PeriodQuarterly:
+		return monthly(3)
+	case common.PeriodSixMonths:
+		return monthly(6)
+	case common.PeriodYearly:
+		return monthly(12)
+	case common.PeriodCron:
+		return schedule != nil && schedule.Match(d)
+	default:
+		return false
+	}
+}
+
+// backfillExecuteDate 补录任务尽快执行：今天还有未结束的执行窗口时今天执行，否则在之后第一个有执行窗口的日期执行
+func backfillExecuteDate(policy *models.Policy, now time.Time) (string, error) {
+	windows, err := common.ParseExecWindows(policy.ExecuteWindow, policy.ExecuteWindows)
+	if err != nil {
+		return "", err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for i := 0; i < 8; i++ {
+		day := today.AddDate(0, 0, i)
+		ranges, err := common.ExecWindowRanges(windows, day)
+		if err != nil {
+			return "", err
+		}
+		for _, r := range ranges {
+			if r.End.After(now) {
+				return day.Format(time.DateOnly), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("policy(%v) has no execute window in next week", policy.ID)
+}
+
+// BackfillCondition 补录任务的治理条件按最后一个补录周期执行窗口开始的时间计算，条件中的NOW()等函数替换为该时间
+func BackfillCondition(policy *models.Policy, task *models.Task) (string, error) {
+	periods := strings.Split(task.BackfillPeriods, ",")
+	loc := common.LoadLocation(task.Timezone)
+	day, err := time.ParseInLocation(time.DateOnly, periods[len(periods)-1], loc)
+	if err != nil {
+		return "", fmt.Errorf("parse task(%v) backfill period(%s) failed, %s", task.ID, task.BackfillPeriods, err)
+	}
+
+	windows, err := common.ParseExecWindows(policy.ExecuteWindow, policy.ExecuteWindows)
+	if err != nil {
+		return "", err
+	}
+	execTime := day
+	if ranges, err := common.ExecWindowRanges(windows, day); err == nil && len(ranges) != 0 {
+		execTime = ranges[0].Start
+	} else if start, err := time.ParseInLocation(time.DateTime, day.Format("2006-01-02 ")+common.FirstExecWindow(windows).Start, loc); err == nil {
+		execTime = start
+	}
+	return utils.FreezeSQLTimeFunc(policy.Condition, execTime), nil
+}
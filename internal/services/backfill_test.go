@@ -0,0 +1,122 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+func TestPeriodScheduledOn(t *testing.T) {
+	anchor := parseLocalTime("2024-01-01 00:00:00")
+	lastSunday, err := common.ParseCronExpr("0 0 * * 0L")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		period   common.PeriodType
+		day      int
+		schedule *common.CronSchedule
+		date     string
+		want     bool
+	}{
+		{"day", common.PeriodDay, 0, nil, "2024-03-05", true},
+		{"two day", common.PeriodTwoDay, 0, nil, "2024-01-03", true},
+		{"two day skipped", common.PeriodTwoDay, 0, nil, "2024-01-04", false},
+		{"two day before anchor", common.PeriodTwoDay, 0, nil, "2023-12-30", true},
+		{"weekly", common.PeriodWeekly, 0, nil, "2024-01-08", true},
+		{"weekly skipped", common.PeriodWeekly, 0, nil, "2024-01-09", false},
+		{"two weeks", common.PeriodTwoWeeks, 0, nil, "2024-01-15", true},
+		{"two weeks skipped", common.PeriodTwoWeeks, 0, nil, "2024-01-08", false},
+		{"monthly", common.PeriodMonthly, 31, nil, "2024-01-31", true},
+		{"monthly last day of short month", common.PeriodMonthly, 31, nil, "2024-02-29", true},
+		{"monthly other day", common.PeriodMonthly, 31, nil, "2024-02-28", false},
+		{"quarterly", common.PeriodQuarterly, 1, nil, "2024-04-01", true},
+		{"quarterly skipped month", common.PeriodQuarterly, 1, nil, "2024-02-01", false},
+		{"quarterly before anchor", common.PeriodQuarterly, 1, nil, "2023-10-01", true},
+		{"six months", common.PeriodSixMonths, 1, nil, "2024-07-01", true},
+		{"yearly", common.PeriodYearly, 15, nil, "2025-01-15", true},
+		{"yearly skipped", common.PeriodYearly, 15, nil, "2024-02-15", false},
+		{"cron", common.PeriodCron, 0, lastSunday, "2024-01-28", true},
+		{"cron not match", common.PeriodCron, 0, lastSunday, "2024-01-21", false},
+		{"cron without schedule", common.PeriodCron, 0, nil, "2024-01-28", false},
+		{"once", common.PeriodOnce, 0, nil, "2024-01-01", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := &models.Policy{Period: test.period, Day: test.day}
+			d := parseLocalTime(test.date + " 00:00:00")
+			if got := periodScheduledOn(policy, test.schedule, anchor, d); got != test.want {
+				t.Fatalf("periodScheduledOn(%s) got %v, want %v", test.date, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMissedPeriodsOf(t *testing.T) {
+	normal := func(date string) models.Task { return models.Task{ExecuteDate: date} }
+	backfill := func(periods string) models.Task {
+		return models.Task{ExecuteDate: "2024-02-01", Backfill: true, BackfillPeriods: periods}
+	}
+	tests := []struct {
+		name      string
+		period    common.PeriodType
+		day       int
+		createdAt string
+		tasks     []models.Task
+		start     string
+		end       string
+		want      []string
+	}{
+		{
+			name:      "daily",
+			period:    common.PeriodDay,
+			createdAt: "2024-01-01",
+			tasks:     []models.Task{normal("2024-01-01"), normal("2024-01-03"), backfill("2024-01-02")},
+			start:     "2024-01-01",
+			end:       "2024-01-05",
+			want:      []string{"2024-01-04", "2024-01-05"},
+		},
+		{
+			name:      "weekly anchored on first task and shifted task counts",
+			period:    common.PeriodWeekly,
+			createdAt: "2024-01-10",
+			tasks:     []models.Task{normal("2024-01-03"), normal("2024-01-12")},
+			start:     "2024-01-01",
+			end:       "2024-01-31",
+			want:      []string{"2024-01-17", "2024-01-24", "2024-01-31"},
+		},
+		{
+			name:      "monthly on last day",
+			period:    common.PeriodMonthly,
+			day:       31,
+			createdAt: "2023-12-01",
+			start:     "2024-01-01",
+			end:       "2024-04-30",
+			want:      []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"},
+		},
+		{
+			name:      "all covered",
+			period:    common.PeriodTwoDay,
+			createdAt: "2024-01-01",
+			tasks:     []models.Task{normal("2024-01-01"), backfill("2024-01-03,2024-01-05")},
+			start:     "2024-01-01",
+			end:       "2024-01-06",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := &models.Policy{Period: test.period, Day: test.day}
+			policy.CreatedAt = parseLocalTime(test.createdAt + " 10:00:00")
+			got, err := missedPeriodsOf(policy, test.tasks, parseLocalTime(test.start+" 00:00:00"), parseLocalTime(test.end+" 00:00:00"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("missedPeriodsOf() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,668 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlackoutService 封网日历，封网期间禁止执行治理任务
+type BlackoutService struct {
+	Model
+	Name        string                        `json:"name"`        // 封网名称
+	Description string                        `json:"description"` // 说明
+	Enable      bool                          `json:"enable"`      // 是否生效
+	Scope       common.BlackoutScopeType      `json:"scope"`       // 生效范围 全局:global, 指定BU:bu, 指定集群:cluster
+	ScopeValue  string                        `json:"scope_value"` // 生效范围为bu时填写BU，为cluster时填写集群ID
+	StartTime   string                        `json:"start_time"`  // 开始时间 如: 2024-11-10 00:00:00
+	EndTime     string                        `json:"end_time"`    // 结束时间(不含) 如: 2024-11-12 00:00:00
+	Recurrence  common.BlackoutRecurrenceType `json:"recurrence"`  // 重复周期 不重复:none, 每天:daily, 每周:weekly, 每月:monthly, 每年:yearly
+	RecurUntil  string                        `json:"recur_until"` // 重复截止时间，为空时一直重复
+	ICalUID     string                        `json:"ical_uid"`    // 从iCal导入时的事件UID
+}
+
+// BlackoutImportService 从iCal文件导入封网日历的结果
+type BlackoutImportService struct {
+	Scope      common.BlackoutScopeType `json:"scope"`       // 生效范围
+	ScopeValue string                   `json:"scope_value"` // 生效的BU或集群ID
+	Created    int                      `json:"created"`     // 新增个数
+	Updated    int                      `json:"updated"`     // 更新个数
+	Skipped    []string                 `json:"skipped"`     // 不支持导入的事件及原因
+}
+
+func (c *BlackoutService) CheckParameters(ctx *gin.Context) (bool, common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if len(c.Name) == 0 || len(c.Name) >= 1024 {
+		return false, common.CodeBlackoutNameErr, fmt.Errorf("validate blackout name(%s) not pass", c.Name)
+	}
+
+	if c.Scope == "" {
+		c.Scope = common.BlackoutScopeGlobal
+	}
+	if !common.CheckBlackoutScope(c.Scope) {
+		return false, common.CodeBlackoutScopeErr, fmt.Errorf("validate scope(%s) not pass", c.Scope)
+	}
+	switch c.Scope {
+	case common.BlackoutScopeGlobal:
+		c.ScopeValue = ""
+	case common.BlackoutScopeBu:
+		if c.ScopeValue == "" {
+			return false, common.CodeBlackoutScopeErr, fmt.Errorf("scope_value(bu) can not be empty when scope is %s", c.Scope)
+		}
+	case common.BlackoutScopeCluster:
+		var count int64
+		err := db.Model(&models.Cluster{}).Where("cluster_id =?", c.ScopeValue).Count(&count).Error
+		if err != nil {
+			return false, common.CodeServerErr, fmt.Errorf("query models.Cluster(cluster_id=%s) from db failed, %s", c.ScopeValue, err)
+		}
+		if count == 0 {
+			return false, common.CodeClusterNotExist, fmt.Errorf("cluster(%s) not exist", c.ScopeValue)
+		}
+	}
+
+	start, err := time.ParseInLocation(time.DateTime, c.StartTime, time.Now().Location())
+	if err != nil {
+		return false, common.CodeBlackoutTimeErr, fmt.Errorf("parse start_time(%s) failed, %s", c.StartTime, err)
+	}
+	end, err := time.ParseInLocation(time.DateTime, c.EndTime, time.Now().Location())
+	if err != nil {
+		return false, common.CodeBlackoutTimeErr, fmt.Errorf("parse end_time(%s) failed, %s", c.EndTime, err)
+	}
+	if !end.After(start) {
+		return false, common.CodeBlackoutTimeErr, fmt.Errorf("end_time(%s) should after start_time(%s)", c.EndTime, c.StartTime)
+	}
+
+	if c.Recurrence == "" {
+		c.Recurrence = common.BlackoutRecurrenceNone
+	}
+	if !common.CheckBlackoutRecurrence(c.Recurrence) {
+		return false, common.CodeBlackoutRecurrenceErr, fmt.Errorf("validate recurrence(%s) not pass", c.Recurrence)
+	}
+	if c.Recurrence == common.BlackoutRecurrenceNone {
+		c.RecurUntil = ""
+	} else if c.RecurUntil != "" {
+		until, err := time.ParseInLocation(time.DateTime, c.RecurUntil, time.Now().Location())
+		if err != nil {
+			return false, common.CodeBlackoutRecurrenceErr, fmt.Errorf("parse recur_until(%s) failed, %s", c.RecurUntil, err)
+		}
+		if until.Before(start) {
+			return false, common.CodeBlackoutRecurrenceErr, fmt.Errorf("recur_until(%s) should after start_time(%s)", c.RecurUntil, c.StartTime)
+		}
+	}
+	// 每次封网的时长不能超过重复周期
+	if c.Recurrence != common.BlackoutRecurrenceNone && end.After(addBlackoutRecurrence(start, c.Recurrence, 1)) {
+		return false, common.CodeBlackoutRecurrenceErr,
+			fmt.Errorf("duration of blackout(%s - %s) exceeds the recurrence(%s)", c.StartTime, c.EndTime, c.Recurrence)
+	}
+
+	return true, common.CodeOK, nil
+}
+
+func (c *BlackoutService) CreateBlackout(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	blackout := c.ServiceToModel()
+	blackout.ID = 0
+	blackout.CreatedAt = time.Now()
+	blackout.Creator = u.UserName
+
+	var count int64
+	err := db.Model(blackout).Where("name =?", blackout.Name).Count(&count).Error
+	if err != nil {
+		log.Errorf("query models.Blackout(name=%s) from db failed, %s", blackout.Name, err)
+		return common.CodeServerErr, err
+	}
+	if count != 0 {
+		err = fmt.Errorf("models.Blackout(name=%s) exist", blackout.Name)
+		return common.CodeBlackoutNameConflict, err
+	}
+
+	err = db.Save(blackout).Error
+	if err != nil {
+		log.Errorf("save models.Blackout(%+v) to db failed, %s", blackout, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(blackout)
+	return common.CodeOK, nil
+}
+
+func (c *BlackoutService) UpdateBlackout(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	blackout := &models.Blackout{}
+	err := db.Model(blackout).First(blackout, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("query models.Blackout(id=%d) not exist", c.ID)
+			return common.CodeBlackoutNotExist, err
+		}
+		log.Errorf("query models.Blackout(id=%d) from db failed, %s", c.ID, err)
+		return common.CodeServerErr, err
+	}
+
+	var count int64
+	err = db.Model(blackout).Where("name =? AND id !=?", c.Name, c.ID).Count(&count).Error
+	if err != nil {
+		log.Errorf("query models.Blackout(name =%v AND id !=%v) from db failed, %s", c.Name, c.ID, err)
+		return common.CodeServerErr, err
+	}
+	if count != 0 {
+		err = fmt.Errorf("models.Blackout(name=%s) exist", c.Name)
+		log.Error(err)
+		return common.CodeBlackoutNameConflict, err
+	}
+
+	old := &BlackoutService{}
+	old.ModelToService(blackout)
+	c.Name = utils.Ternary[string](c.Name == "", old.Name, c.Name)
+	c.Scope = utils.Ternary[common.BlackoutScopeType](c.Scope == "", old.Scope, c.Scope)
+	c.ScopeValue = utils.Ternary[string](c.ScopeValue == "", old.ScopeValue, c.ScopeValue)
+	c.StartTime = utils.Ternary[string](c.StartTime == "", old.StartTime, c.StartTime)
+	c.EndTime = utils.Ternary[string](c.EndTime == "", old.EndTime, c.EndTime)
+	c.Recurrence = utils.Ternary[common.BlackoutRecurrenceType](c.Recurrence == "", old.Recurrence, c.Recurrence)
+	c.ICalUID = old.ICalUID
+
+	// 参数校验
+	if ok, res, err := c.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", c, err)
+		return res, err
+	}
+
+	newBlackout := c.ServiceToModel()
+	newBlackout.CreatedAt = blackout.CreatedAt
+	newBlackout.Creator = blackout.Creator
+	newBlackout.Editor = u.UserName
+	err = db.Save(newBlackout).Error
+	if err != nil {
+		log.Errorf("update models.Blackout(%+v) from db failed, %s", newBlackout, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(newBlackout)
+	return common.CodeOK, nil
+}
+
+func (c *BlackoutService) DeleteBlackout(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	blackout := &models.Blackout{}
+	err := db.Model(blackout).First(blackout, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("query models.Blackout(id=%d) not exist", c.ID)
+			log.Error(err)
+			return common.CodeBlackoutNotExist, err
+		}
+		err = fmt.Errorf("query models.Blackout(id=%d) from db failed, %s", c.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	db.Model(&models.Blackout{}).Where("id =?", blackout.ID).Update("editor", u.UserName)
+	err = db.Delete(&models.Blackout{}, "id =?", blackout.ID).Error
+	if err != nil {
+		err = fmt.Errorf("delete models.Blackout(id=%d) from db failed, %s", blackout.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	return common.CodeOK, nil
+}
+
+func (c *BlackoutService) QueryBlackout(ctx *gin.Context, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.Blackout](db).
+		QueryPaging(ctx).
+		Order("id desc").
+		Query(
+			common.FilterFuzzyStringMap(queryMap),
+			common.FilterID(c.ID),
+		)
+	if err != nil {
+		err = fmt.Errorf("query models.Blackout from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]BlackoutService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		b := &BlackoutService{}
+		b.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *b)
+	}
+	return ret, common.CodeOK, nil
+}
+
+// ImportICal 从iCal文件导入节假日等封网日历，UID相同的事件更新已有的封网日历
+func (c *BlackoutImportService) ImportICal(ctx *gin.Context, r io.Reader) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	if c.Scope == "" {
+		c.Scope = common.BlackoutScopeGlobal
+	}
+	if !common.CheckBlackoutScope(c.Scope) {
+		return common.CodeBlackoutScopeErr, fmt.Errorf("validate scope(%s) not pass", c.Scope)
+	}
+	if c.Scope == common.BlackoutScopeGlobal {
+		c.ScopeValue = ""
+	}
+
+	events, err := parseICalEvents(r)
+	if err != nil {
+		log.Error(err)
+		return common.CodeBlackoutICalErr, err
+	}
+
+	for _, e := range events {
+		if e.err != nil {
+			c.Skipped = append(c.Skipped, fmt.Sprintf("%s(%s): %s", e.summary, e.uid, e.err))
+			continue
+		}
+
+		b := &BlackoutService{
+			Name:        e.summary,
+			Description: e.description,
+			Enable:      true,
+			Scope:       c.Scope,
+			ScopeValue:  c.ScopeValue,
+			StartTime:   e.start.Format(time.DateTime),
+			EndTime:     e.end.Format(time.DateTime),
+			Recurrence:  e.recurrence,
+			ICalUID:     e.uid,
+		}
+		if !e.until.IsZero() {
+			b.RecurUntil = e.until.Format(time.DateTime)
+		}
+		if ok, _, err := b.CheckParameters(ctx); !ok {
+			c.Skipped = append(c.Skipped, fmt.Sprintf("%s(%s): %s", e.summary, e.uid, err))
+			continue
+		}
+
+		blackout := b.ServiceToModel()
+		existed := &models.Blackout{}
+		err = db.Model(existed).Where("ical_uid =? AND scope =? AND scope_value =?", e.uid, c.Scope, c.ScopeValue).
+			Limit(1).Find(existed).Error
+		if err != nil {
+			err = fmt.Errorf("query models.Blackout(ical_uid=%s) from db failed, %s", e.uid, err)
+			log.Error(err)
+			return common.CodeServerErr, err
+		}
+
+		// 名称重复时追加开始日期
+		var count int64
+		err = db.Model(&models.Blackout{}).Where("name =? AND id !=?", blackout.Name, existed.ID).Count(&count).Error
+		if err != nil {
+			err = fmt.Errorf("query models.Blackout(name=%s) from db failed, %s", blackout.Name, err)
+			log.Error(err)
+			return common.CodeServerErr, err
+		}
+		if count != 0 {
+			blackout.Name = fmt.Sprintf("%s(%s)", blackout.Name, e.start.Format(time.DateOnly))
+		}
+
+		if existed.ID != 0 {
+			blackout.ID = existed.ID
+			blackout.CreatedAt = existed.CreatedAt
+			blackout.Creator = existed.Creator
+			blackout.Enable = existed.Enable
+			blackout.Editor = u.UserName
+			c.Updated++
+		} else {
+			blackout.CreatedAt = time.Now()
+			blackout.Creator = u.UserName
+			c.Created++
+		}
+		err = db.Save(blackout).Error
+		if err != nil {
+			err = fmt.Errorf("save models.Blackout(%+v) to db failed, %s", blackout, err)
+			log.Error(err)
+			return common.CodeServerErr, err
+		}
+	}
+	return common.CodeOK, nil
+}
+
+// QueryEffectiveBlackouts 查询对指定BU和集群生效的封网日历
+func QueryEffectiveBlackouts(db *gorm.DB, bu, clusterID string) ([]models.Blackout, error) {
+	var blackouts []models.Blackout
+	err := db.Model(&models.Blackout{}).
+		Where("enable =? AND (scope =? OR (scope =? AND scope_value =?) OR (scope =? AND scope_value =?))", true,
+			common.BlackoutScopeGlobal, common.BlackoutScopeBu, bu, common.BlackoutScopeCluster, clusterID).
+		Find(&blackouts).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.Blackout(bu=%s, cluster_id=%s) from db failed, %s", bu, clusterID, err)
+	}
+	return blackouts, nil
+}
+
+// MatchBlackout 返回与时间段[start, end)有重叠的第一个封网日历及其重叠的那一次封网时间
+func MatchBlackout(blackouts []models.Blackout, start, end time.Time) (*models.Blackout, time.Time, time.Time, bool) {
+	for i := range blackouts {
+		if s, e, ok := BlackoutOccurrence(&blackouts[i], start, end); ok {
+			return &blackouts[i], s, e, true
+		}
+	}
+	return nil, time.Time{}, time.Time{}, false
+}
+
+// BlackoutOccurrence 计算封网日历与时间段[start, end)有重叠的那一次封网时间
+func BlackoutOccurrence(b *models.Blackout, start, end time.Time) (time.Time, time.Time, bool) {
+	if b.Recurrence == "" || b.Recurrence == common.BlackoutRecurrenceNone {
+		return b.StartTime, b.EndTime, b.StartTime.Before(end) && b.EndTime.After(start)
+	}
+
+	// 先估算重复的次数，再逐次向后查找
+	k := 0
+	switch b.Recurrence {
+	case common.BlackoutRecurrenceDaily:
+		k = int(start.Sub(b.EndTime).Hours() / 24)
+	case common.BlackoutRecurrenceWeekly:
+		k = int(start.Sub(b.EndTime).Hours() / (24 * 7))
+	case common.BlackoutRecurrenceMonthly:
+		k = (start.Year()-b.EndTime.Year())*12 + int(start.Month()) - int(b.EndTime.Month()) - 1
+	case common.BlackoutRecurrenceYearly:
+		k = start.Year() - b.EndTime.Year() - 1
+	}
+	k = max(k, 0)
+
+	for ; ; k++ {
+		s := addBlackoutRecurrence(b.StartTime, b.Recurrence, k)
+		e := s.Add(b.EndTime.Sub(b.StartTime))
+		if !s.Before(end) || (!b.RecurUntil.IsZero() && b.RecurUntil.After(b.StartTime) && s.After(b.RecurUntil)) {
+			return time.Time{}, time.Time{}, false
+		}
+		if e.After(start) {
+			return s, e, true
+		}
+	}
+}
+
+// addBlackoutRecurrence 返回第n次重复的时间，按月或按年重复时目标月份没有这一天则取该月最后一天，如：1月31日按月重复为2月28日(29日)
+func addBlackoutRecurrence(t time.Time, r common.BlackoutRecurrenceType, n int) time.Time {
+	switch r {
+	case common.BlackoutRecurrenceDaily:
+		return t.AddDate(0, 0, n)
+	case common.BlackoutRecurrenceWeekly:
+		return t.AddDate(0, 0, 7*n)
+	case common.BlackoutRecurrenceMonthly:
+		return addMonthsClamped(t, n)
+	case common.BlackoutRecurrenceYearly:
+		return addMonthsClamped(t, 12*n)
+	default:
+		return t
+	}
+}
+
+func addMonthsClamped(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, n, 0)
+	lastDay := time.Date(first.Year(), first.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	return first.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
+
+// icalEvent iCal文件中的VEVENT
+type icalEvent struct {
+	uid         string
+	summary     string
+	description string
+	start       time.Time
+	end         time.Time
+	recurrence  common.BlackoutRecurrenceType
+	until       time.Time
+	err         error // 不支持导入的原因
+}
+
+// parseICalEvents 解析iCal(RFC 5545)文件中的VEVENT，重复规则仅支持FREQ、UNTIL和COUNT
+func parseICalEvents(r io.Reader) ([]*icalEvent, error) {
+	// 展开折行：以空格或tab开头的行是上一行的延续
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) != 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read iCal file failed, %s", err)
+	}
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("invalid iCal file, should begin with BEGIN:VCALENDAR")
+	}
+
+	var (
+		events  []*icalEvent
+		e       *icalEvent
+		allDay  bool
+		hasEnd  bool
+		rrule   string
+		finishE = func() {
+			if e.err == nil && e.start.IsZero() {
+				e.err = fmt.Errorf("DTSTART is missing")
+			}
+			if e.err == nil && !hasEnd {
+				e.end = utils.Ternary[time.Time](allDay, e.start.AddDate(0, 0, 1), e.start)
+			}
+			if e.err == nil {
+				e.recurrence, e.until, e.err = parseICalRRule(rrule, e.start)
+			}
+			events = append(events, e)
+		}
+	)
+	for _, line := range lines {
+		name, params, value := splitICalLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			e, allDay, hasEnd, rrule = &icalEvent{recurrence: common.BlackoutRecurrenceNone}, false, false, ""
+		case name == "END" && strings.EqualFold(value, "VEVENT") && e != nil:
+			finishE()
+			e = nil
+		case e == nil:
+			continue
+		case name == "UID":
+			e.uid = value
+		case name == "SUMMARY":
+			e.summary = unescapeICalText(value)
+		case name == "DESCRIPTION":
+			e.description = unescapeICalText(value)
+		case name == "DTSTART":
+			var err error
+			e.start, allDay, err = parseICalTime(params, value)
+			if err != nil && e.err == nil {
+				e.err = err
+			}
+		case name == "DTEND":
+			var err error
+			e.end, _, err = parseICalTime(params, value)
+			if err != nil && e.err == nil {
+				e.err = err
+			}
+			hasEnd = true
+		case name == "RRULE":
+			rrule = value
+		}
+	}
+
+	for _, e := range events {
+		if e.summary == "" {
+			e.summary = e.uid
+		}
+	}
+	return events, nil
+}
+
+// splitICalLine 将 NAME;PARAM=VALUE:VALUE 格式的行拆分为属性名、参数和值
+func splitICalLine(line string) (string, map[string]string, string) {
+	idx := strings.Index(line, ":")
+	if idx < 0 {
+		return "", nil, ""
+	}
+	head, value := line[:idx], line[idx+1:]
+	parts := strings.Split(head, ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, strings.TrimSpace(value)
+}
+
+// parseICalTime 解析DTSTART/DTEND，返回时间及是否为全天事件
+func parseICalTime(params map[string]string, value string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, time.Now().Location())
+		if err != nil {
+			return time.Time{}, true, fmt.Errorf("parse date(%s) failed, %s", value, err)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("parse time(%s) failed, %s", value, err)
+		}
+		return t.In(time.Now().Location()), false, nil
+	}
+
+	loc := time.Now().Location()
+	if tz := params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("parse time(%s) failed, %s", value, err)
+	}
+	return t.In(time.Now().Location()), false, nil
+}
+
+// parseICalRRule 将RRULE转换为封网日历的重复周期和截止时间
+func parseICalRRule(rule string, start time.Time) (common.BlackoutRecurrenceType, time.Time, error) {
+	if rule == "" {
+		return common.BlackoutRecurrenceNone, time.Time{}, nil
+	}
+
+	var (
+		recurrence common.BlackoutRecurrenceType
+		until      time.Time
+		count      int
+		err        error
+	)
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			switch strings.ToUpper(kv[1]) {
+			case "DAILY":
+				recurrence = common.BlackoutRecurrenceDaily
+			case "WEEKLY":
+				recurrence = common.BlackoutRecurrenceWeekly
+			case "MONTHLY":
+				recurrence = common.BlackoutRecurrenceMonthly
+			case "YEARLY":
+				recurrence = common.BlackoutRecurrenceYearly
+			default:
+				return "", time.Time{}, fmt.Errorf("unsupported RRULE FREQ(%s)", kv[1])
+			}
+		case "UNTIL":
+			until, _, err = parseICalTime(nil, kv[1])
+			if err != nil {
+				return "", time.Time{}, err
+			}
+		case "COUNT":
+			count, err = strconv.Atoi(kv[1])
+			if err != nil || count <= 0 {
+				return "", time.Time{}, fmt.Errorf("invalid RRULE COUNT(%s)", kv[1])
+			}
+		case "INTERVAL":
+			if kv[1] != "1" {
+				return "", time.Time{}, fmt.Errorf("unsupported RRULE INTERVAL(%s)", kv[1])
+			}
+		case "WKST":
+		default:
+			return "", time.Time{}, fmt.Errorf("unsupported RRULE(%s)", rule)
+		}
+	}
+	if recurrence == "" {
+		return "", time.Time{}, fmt.Errorf("RRULE(%s) FREQ is missing", rule)
+	}
+	if count > 0 {
+		until = addBlackoutRecurrence(start, recurrence, count-1)
+	}
+	return recurrence, until, nil
+}
+
+func unescapeICalText(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+func (c *BlackoutService) ServiceToModel() *models.Blackout {
+	m := &models.Blackout{}
+	m.ID = c.ID
+	m.Creator = c.Creator
+	m.Editor = c.Editor
+	m.CreatedAt, _ = time.ParseInLocation(time.DateTime, c.CreatedAt, time.Now().Location())
+	m.UpdatedAt, _ = time.ParseInLocation(time.DateTime, c.UpdatedAt, time.Now().Location())
+	m.Name = c.Name
+	m.Description = c.Description
+	m.Enable = c.Enable
+	m.Scope = c.Scope
+	m.ScopeValue = c.ScopeValue
+	m.StartTime, _ = time.ParseInLocation(time.DateTime, c.StartTime, time.Now().Location())
+	m.EndTime, _ = time.ParseInLocation(time.DateTime, c.EndTime, time.Now().Location())
+	m.Recurrence = c.Recurrence
+	m.RecurUntil, _ = time.ParseInLocation(time.DateTime, c.RecurUntil, time.Now().Location())
+	if m.RecurUntil.IsZero() {
+		m.RecurUntil = time.UnixMilli(0)
+	}
+	m.ICalUID = c.ICalUID
+	return m
+}
+
+func (c *BlackoutService) ModelToService(m *models.Blackout) *BlackoutService {
+	c.ID = m.ID
+	c.Creator = m.Creator
+	c.Editor = m.Editor
+	c.CreatedAt = m.CreatedAt.Format(time.DateTime)
+	c.UpdatedAt = m.UpdatedAt.Format(time.DateTime)
+	c.Name = m.Name
+	c.Description = m.Description
+	c.Enable = m.Enable
+	c.Scope = m.Scope
+	c.ScopeValue = m.ScopeValue
+	c.StartTime = m.StartTime.Format(time.DateTime)
+	c.EndTime = m.EndTime.Format(time.DateTime)
+	c.Recurrence = m.Recurrence
+	c.RecurUntil = ""
+	if m.RecurUntil.After(m.StartTime) {
+		c.RecurUntil = m.RecurUntil.Format(time.DateTime)
+	}
+	c.ICalUID = m.ICalUID
+	return c
+}
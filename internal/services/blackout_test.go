@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+func parseLocalTime(s string) time.Time {
+	t, _ := time.ParseInLocation(time.DateTime, s, time.Now().Location())
+	return t
+}
+
+func TestAddBlackoutRecurrence(t *testing.T) {
+	tests := []struct {
+		name string
+		t    string
+		r    common.BlackoutRecurrenceType
+		n    int
+		want string
+	}{
+		{"daily", "2024-01-31 22:00:00", common.BlackoutRecurrenceDaily, 1, "2024-02-01 22:00:00"},
+		{"weekly", "2024-01-31 22:00:00", common.BlackoutRecurrenceWeekly, 2, "2024-02-14 22:00:00"},
+		{"monthly", "2024-01-15 22:00:00", common.BlackoutRecurrenceMonthly, 1, "2024-02-15 22:00:00"},
+		{"monthly clamp leap year", "2024-01-31 22:00:00", common.BlackoutRecurrenceMonthly, 1, "2024-02-29 22:00:00"},
+		{"monthly clamp", "2023-01-31 22:00:00", common.BlackoutRecurrenceMonthly, 1, "2023-02-28 22:00:00"},
+		{"monthly clamp 30 days", "2024-01-31 22:00:00", common.BlackoutRecurrenceMonthly, 3, "2024-04-30 22:00:00"},
+		{"monthly not accumulate", "2024-01-31 22:00:00", common.BlackoutRecurrenceMonthly, 2, "2024-03-31 22:00:00"},
+		{"monthly cross year", "2024-11-30 22:00:00", common.BlackoutRecurrenceMonthly, 3, "2025-02-28 22:00:00"},
+		{"yearly", "2024-06-01 00:00:00", common.BlackoutRecurrenceYearly, 1, "2025-06-01 00:00:00"},
+		{"yearly clamp", "2024-02-29 00:00:00", common.BlackoutRecurrenceYearly, 1, "2025-02-28 00:00:00"},
+		{"yearly leap year", "2024-02-29 00:00:00", common.BlackoutRecurrenceYearly, 4, "2028-02-29 00:00:00"},
+		{"none", "2024-01-31 22:00:00", common.BlackoutRecurrenceNone, 1, "2024-01-31 22:00:00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := addBlackoutRecurrence(parseLocalTime(test.t), test.r, test.n).Format(time.DateTime)
+			if got != test.want {
+				t.Fatalf("addBlackoutRecurrence(%s, %s, %d) got %s, want %s", test.t, test.r, test.n, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBlackoutOccurrence(t *testing.T) {
+	tests := []struct {
+		name      string
+		b         models.Blackout
+		start     string
+		end       string
+		wantOK    bool
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name:   "once not overlap",
+			b:      models.Blackout{StartTime: parseLocalTime("2024-01-01 00:00:00"), EndTime: parseLocalTime("2024-01-02 00:00:00")},
+			start:  "2024-01-02 00:00:00",
+			end:    "2024-01-03 00:00:00",
+			wantOK: false,
+		},
+		{
+			name:      "once overlap",
+			b:         models.Blackout{StartTime: parseLocalTime("2024-01-01 00:00:00"), EndTime: parseLocalTime("2024-01-02 00:00:00")},
+			start:     "2024-01-01 12:00:00",
+			end:       "2024-01-01 12:00:01",
+			wantOK:    true,
+			wantStart: "2024-01-01 00:00:00",
+			wantEnd:   "2024-01-02 00:00:00",
+		},
+		{
+			name: "daily",
+			b: models.Blackout{StartTime: parseLocalTime("2024-01-01 22:00:00"), EndTime: parseLocalTime("2024-01-02 02:00:00"),
+				Recurrence: common.BlackoutRecurrenceDaily},
+			start:     "2024-03-10 01:00:00",
+			end:       "2024-03-10 05:00:00",
+			wantOK:    true,
+			wantStart: "2024-03-09 22:00:00",
+			wantEnd:   "2024-03-10 02:00:00",
+		},
+		{
+			name: "monthly end of month",
+			b: models.Blackout{StartTime: parseLocalTime("2024-01-31 20:00:00"), EndTime: parseLocalTime("2024-02-01 08:00:00"),
+				Recurrence: common.BlackoutRecurrenceMonthly},
+			start:     "2024-02-29 21:00:00",
+			end:       "2024-02-29 21:00:01",
+			wantOK:    true,
+			wantStart: "2024-02-29 20:00:00",
+			wantEnd:   "2024-03-01 08:00:00",
+		},
+		{
+			name: "monthly not in march 3",
+			b: models.Blackout{StartTime: parseLocalTime("2024-01-31 20:00:00"), EndTime: parseLocalTime("2024-01-31 23:00:00"),
+				Recurrence: common.BlackoutRecurrenceMonthly},
+			start:  "2024-03-02 00:00:00",
+			end:    "2024-03-03 23:00:00",
+			wantOK: false,
+		},
+		{
+			name: "recur until",
+			b: models.Blackout{StartTime: parseLocalTime("2024-01-01 00:00:00"), EndTime: parseLocalTime("2024-01-01 06:00:00"),
+				Recurrence: common.BlackoutRecurrenceWeekly, RecurUntil: parseLocalTime("2024-01-31 00:00:00")},
+			start:  "2024-02-05 00:00:00",
+			end:    "2024-02-05 06:00:00",
+			wantOK: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, e, ok := BlackoutOccurrence(&test.b, parseLocalTime(test.start), parseLocalTime(test.end))
+			if ok != test.wantOK {
+				t.Fatalf("BlackoutOccurrence() got ok %v, want %v", ok, test.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if s.Format(time.DateTime) != test.wantStart || e.Format(time.DateTime) != test.wantEnd {
+				t.Fatalf("BlackoutOccurrence() got %s - %s, want %s - %s",
+					s.Format(time.DateTime), e.Format(time.DateTime), test.wantStart, test.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParseICalRRule(t *testing.T) {
+	start := parseLocalTime("2024-01-31 20:00:00")
+	tests := []struct {
+		name      string
+		rule      string
+		want      common.BlackoutRecurrenceType
+		wantUntil string
+		wantErr   bool
+	}{
+		{"empty", "", common.BlackoutRecurrenceNone, "", false},
+		{"daily", "FREQ=DAILY", common.BlackoutRecurrenceDaily, "", false},
+		{"weekly with wkst", "FREQ=WEEKLY;WKST=MO", common.BlackoutRecurrenceWeekly, "", false},
+		{"monthly count", "FREQ=MONTHLY;COUNT=2", common.BlackoutRecurrenceMonthly, "2024-02-29 20:00:00", false},
+		{"yearly until", "FREQ=YEARLY;UNTIL=20260101", common.BlackoutRecurrenceYearly, "2026-01-01 00:00:00", false},
+		{"interval 1", "FREQ=DAILY;INTERVAL=1", common.BlackoutRecurrenceDaily, "", false},
+		{"interval 2", "FREQ=DAILY;INTERVAL=2", "", "", true},
+		{"byday", "FREQ=WEEKLY;BYDAY=MO,TU", "", "", true},
+		{"hourly", "FREQ=HOURLY", "", "", true},
+		{"invalid count", "FREQ=DAILY;COUNT=0", "", "", true},
+		{"freq missing", "COUNT=3", "", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, until, err := parseICalRRule(test.rule, start)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseICalRRule(%s) got err %v, want err %v", test.rule, err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			gotUntil := ""
+			if !until.IsZero() {
+				gotUntil = until.Format(time.DateTime)
+			}
+			if r != test.want || gotUntil != test.wantUntil {
+				t.Fatalf("parseICalRRule(%s) got %s %s, want %s %s", test.rule, r, gotUntil, test.want, test.wantUntil)
+			}
+		})
+	}
+}
+
+func TestParseICalEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		ical    string
+		wantErr bool
+		want    []icalEvent
+	}{
+		{
+			name:    "not calendar",
+			ical:    "BEGIN:VEVENT\nEND:VEVENT\n",
+			wantErr: true,
+		},
+		{
+			name: "timed event with folded summary",
+			ical: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1@test\r\nSUMMARY:double 11\r\n  freeze\\, all\r\n" +
+				"DTSTART:20241110T000000\r\nDTEND:20241112T000000\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []icalEvent{{uid: "1@test", summary: "double 11 freeze, all", recurrence: common.BlackoutRecurrenceNone,
+				start: parseLocalTime("2024-11-10 00:00:00"), end: parseLocalTime("2024-11-12 00:00:00")}},
+		},
+		{
+			name: "all day event without end and summary",
+			ical: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:2@test\nDTSTART;VALUE=DATE:20240101\nEND:VEVENT\nEND:VCALENDAR\n",
+			want: []icalEvent{{uid: "2@test", summary: "2@test", recurrence: common.BlackoutRecurrenceNone,
+				start: parseLocalTime("2024-01-01 00:00:00"), end: parseLocalTime("2024-01-02 00:00:00")}},
+		},
+		{
+			name: "recurring event",
+			ical: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:3@test\nSUMMARY:month end\nDTSTART:20240131T200000\nDTEND:20240131T230000\n" +
+				"RRULE:FREQ=MONTHLY;COUNT=3\nEND:VEVENT\nEND:VCALENDAR\n",
+			want: []icalEvent{{uid: "3@test", summary: "month end", recurrence: common.BlackoutRecurrenceMonthly,
+				start: parseLocalTime("2024-01-31 20:00:00"), end: parseLocalTime("2024-01-31 23:00:00"), until: parseLocalTime("2024-03-31 20:00:00")}},
+		},
+		{
+			name: "unsupported rule",
+			ical: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:4@test\nSUMMARY:x\nDTSTART:20240101T000000\nRRULE:FREQ=WEEKLY;BYDAY=MO\nEND:VEVENT\nEND:VCALENDAR\n",
+			want: []icalEvent{{uid: "4@test", summary: "x", err: errors.New("unsupported")}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := parseICalEvents(strings.NewReader(test.ical))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseICalEvents() got err %v, want err %v", err, test.wantErr)
+			}
+			if len(events) != len(test.want) {
+				t.Fatalf("parseICalEvents() got %d events, want %d", len(events), len(test.want))
+			}
+			for i, want := range test.want {
+				got := events[i]
+				if (got.err != nil) != (want.err != nil) {
+					t.Fatalf("parseICalEvents() event %d got err %v, want err %v", i, got.err, want.err)
+				}
+				if want.err != nil {
+					continue
+				}
+				if got.uid != want.uid || got.summary != want.summary || got.recurrence != want.recurrence ||
+					!got.start.Equal(want.start) || !got.end.Equal(want.end) || !got.until.Equal(want.until) {
+					t.Fatalf("parseICalEvents() event %d got %+v, want %+v", i, *got, want)
+				}
+			}
+		})
+	}
+}
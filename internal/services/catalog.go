@@ -0,0 +1,578 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CatalogTableService 源端表结构元数据
+type CatalogTableService struct {
+	Model
+	Bu           string               `json:"bu"`            // bu
+	ClusterID    string               `json:"cluster_id"`    // 集群ID
+	ClusterName  string               `json:"cluster_name"`  // 集群名称
+	Database     string               `json:"database"`      // 库名
+	Table        string               `json:"table"`         // 表名
+	Engine       string               `json:"engine"`        // 存储引擎
+	TableRows    int64                `json:"table_rows"`    // 估算行数
+	DataLength   int64                `json:"data_length"`   // 数据大小(字节)
+	IndexLength  int64                `json:"index_length"`  // 索引大小(字节)
+	DataFree     int64                `json:"data_free"`     // 碎片空间大小(字节)
+	TableComment string               `json:"table_comment"` // 表注释
+	PrimaryKey   []string             `json:"primary_key"`   // 主键列
+	Columns      []mysql.ColumnSchema `json:"columns"`       // 列信息
+	Indexes      []mysql.IndexSchema  `json:"indexes"`       // 索引信息
+	SnapshotAt   string               `json:"snapshot_at"`   // 快照时间
+}
+
+// CatalogSchemaChangeService 源端表结构变更历史
+type CatalogSchemaChangeService struct {
+	ID         uint                    `json:"id"`
+	ClusterID  string                  `json:"cluster_id"`  // 集群ID
+	Database   string                  `json:"database"`    // 库名
+	Table      string                  `json:"table"`       // 表名
+	ChangeType common.SchemaChangeType `json:"change_type"` // 变更类型 新建表:create, 修改表:alter, 删除表:drop
+	Detail     string                  `json:"detail"`      // 变更详情
+	OldColumns []mysql.ColumnSchema    `json:"old_columns"` // 变更前的列信息
+	NewColumns []mysql.ColumnSchema    `json:"new_columns"` // 变更后的列信息
+	OldIndexes []mysql.IndexSchema     `json:"old_indexes"` // 变更前的索引信息
+	NewIndexes []mysql.IndexSchema     `json:"new_indexes"` // 变更后的索引信息
+	ChangedAt  string                  `json:"changed_at"`  // 发现变更的时间
+}
+
+var RefreshCatalogLock sync.Mutex
+
+// RefreshCatalog 对所有集群的表结构做快照，并记录表结构变更
+func (_ *CatalogTableService) RefreshCatalog(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	if !RefreshCatalogLock.TryLock() {
+		err := fmt.Errorf("there is already a task refreshing the catalog")
+		return common.CodeClusterCatalogTaskExisted, err
+	}
+	defer RefreshCatalogLock.Unlock()
+
+	var clusters []models.Cluster
+	err := db.Model(models.Cluster{}).Find(&clusters).Error
+	if err != nil {
+		err = fmt.Errorf("query models.Cluster failed, %s", err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	for _, c := range clusters {
+		clusterSvc := &ClusterService{}
+		clusterSvc.ModelToService(&c)
+		err = refreshClusterCatalog(ctx, clusterSvc)
+		if err != nil {
+			log.Errorf("refresh cluster(%s) catalog failed, %s", c.ClusterID, err)
+			continue
+		}
+	}
+	return common.CodeOK, nil
+}
+
+func refreshClusterCatalog(ctx *gin.Context, c *ClusterService) error {
+	log, db := common.ExtractContext(ctx)
+
+	schemas, _, err := NewClusterDriver(c).GetTablesSchema(ctx, strings.Split(Cfg.ClusterExcludeDatabase, ","))
+	if err != nil {
+		return err
+	}
+
+	var olds []models.CatalogTable
+	err = db.Model(models.CatalogTable{}).Where("cluster_id =?", c.ClusterID).Find(&olds).Error
+	if err != nil {
+		return fmt.Errorf("query models.CatalogTable(cluster_id=%s) failed, %s", c.ClusterID, err)
+	}
+	oldMap := make(map[string]*models.CatalogTable, len(olds))
+	for i := range olds {
+		oldMap[olds[i].Database+"."+olds[i].Table] = &olds[i]
+	}
+
+	// 首次快照时不记录新建表的变更
+	firstSnapshot := len(olds) == 0
+	now := time.Now()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, schema := range schemas {
+			table := schemaToCatalogTable(c, &schema, now)
+			old, exist := oldMap[schema.Database+"."+schema.Table]
+			delete(oldMap, schema.Database+"."+schema.Table)
+
+			var change *models.CatalogSchemaChange
+			switch {
+			case !exist && !firstSnapshot:
+				change = &models.CatalogSchemaChange{
+					ChangeType: common.SchemaChangeCreate,
+					Detail:     fmt.Sprintf("create table %s.%s", schema.Database, schema.Table),
+					NewColumns: table.Columns,
+					NewIndexes: table.Indexes,
+				}
+			case exist && old.SchemaHash != table.SchemaHash:
+				table.ID = old.ID
+				table.CreatedAt = old.CreatedAt
+				change = &models.CatalogSchemaChange{
+					ChangeType: common.SchemaChangeAlter,
+					Detail:     diffCatalogTable(old, table),
+					OldColumns: old.Columns,
+					NewColumns: table.Columns,
+					OldIndexes: old.Indexes,
+					NewIndexes: table.Indexes,
+				}
+			case exist:
+				table.ID = old.ID
+				table.CreatedAt = old.CreatedAt
+			}
+
+			err := tx.Save(table).Error
+			if err != nil {
+				return fmt.Errorf("save models.CatalogTable(%s.%s) failed, %s", schema.Database, schema.Table, err)
+			}
+
+			if change != nil {
+				change.Creator = common.SystemUser
+				change.ClusterID, change.Database, change.Table, change.ChangedAt = c.ClusterID, schema.Database, schema.Table, now
+				err = tx.Save(change).Error
+				if err != nil {
+					return fmt.Errorf("save models.CatalogSchemaChange(%s.%s) failed, %s", schema.Database, schema.Table, err)
+				}
+				log.Infof("cluster(%s) table(%s.%s) schema changed, %s", c.ClusterID, schema.Database, schema.Table, change.Detail)
+			}
+		}
+
+		// 剩下的表示表已被删除
+		for _, old := range oldMap {
+			err := tx.Unscoped().Delete(&models.CatalogTable{}, old.ID).Error
+			if err != nil {
+				return fmt.Errorf("delete models.CatalogTable(%s.%s) failed, %s", old.Database, old.Table, err)
+			}
+
+			err = tx.Save(&models.CatalogSchemaChange{
+				Model:      models.Model{Creator: common.SystemUser},
+				ClusterID:  c.ClusterID,
+				Database:   old.Database,
+				Table:      old.Table,
+				ChangeType: common.SchemaChangeDrop,
+				Detail:     fmt.Sprintf("drop table %s.%s", old.Database, old.Table),
+				OldColumns: old.Columns,
+				OldIndexes: old.Indexes,
+				ChangedAt:  now,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("save models.CatalogSchemaChange(%s.%s) failed, %s", old.Database, old.Table, err)
+			}
+		}
+		return nil
+	})
+}
+
+func schemaToCatalogTable(c *ClusterService, schema *mysql.TableSchema, now time.Time) *models.CatalogTable {
+	var primaryKey []string
+	for _, idx := range schema.Indexes {
+		if idx.Name == "PRIMARY" {
+			primaryKey = idx.Columns
+		}
+	}
+
+	columns, _ := json.Marshal(schema.Columns)
+	indexes, _ := json.Marshal(schema.Indexes)
+	hash := md5.New()
+	hash.Write(columns)
+	hash.Write(indexes)
+	hash.Write([]byte(schema.Comment))
+
+	return &models.CatalogTable{
+		Model:        models.Model{Creator: common.SystemUser},
+		Bu:           c.Bu,
+		ClusterID:    c.ClusterID,
+		ClusterName:  c.ClusterName,
+		Database:     schema.Database,
+		Table:        schema.Table,
+		Engine:       schema.Engine,
+		TableRows:    schema.TableRows,
+		DataLength:   schema.DataLength,
+		IndexLength:  schema.IndexLength,
+		DataFree:     schema.DataFree,
+		TableComment: schema.Comment,
+		PrimaryKey:   strings.Join(primaryKey, ","),
+		Columns:      columns,
+		Indexes:      indexes,
+		SchemaHash:   hex.EncodeToString(hash.Sum(nil)),
+		SnapshotAt:   now,
+	}
+}
+
+// diffCatalogTable 生成表结构变更的描述
+func diffCatalogTable(old, new *models.CatalogTable) string {
+	var oldCols, newCols []mysql.ColumnSchema
+	var oldIdx, newIdx []mysql.IndexSchema
+	_ = json.Unmarshal(old.Columns, &oldCols)
+	_ = json.Unmarshal(new.Columns, &newCols)
+	_ = json.Unmarshal(old.Indexes, &oldIdx)
+	_ = json.Unmarshal(new.Indexes, &newIdx)
+
+	var details []string
+	oldColMap := make(map[string]mysql.ColumnSchema, len(oldCols))
+	for _, col := range oldCols {
+		oldColMap[col.Name] = col
+	}
+	for _, col := range newCols {
+		o, ok := oldColMap[col.Name]
+		delete(oldColMap, col.Name)
+		switch {
+		case !ok:
+			details = append(details, fmt.Sprintf("add column %s %s", col.Name, col.Type))
+		case o != col:
+			details = append(details, fmt.Sprintf("modify column %s %s -> %s", col.Name, o.Type, col.Type))
+		}
+	}
+	for name := range oldColMap {
+		details = append(details, fmt.Sprintf("drop column %s", name))
+	}
+
+	oldIdxMap := make(map[string]mysql.IndexSchema, len(oldIdx))
+	for _, idx := range oldIdx {
+		oldIdxMap[idx.Name] = idx
+	}
+	for _, idx := range newIdx {
+		o, ok := oldIdxMap[idx.Name]
+		delete(oldIdxMap, idx.Name)
+		switch {
+		case !ok:
+			details = append(details, fmt.Sprintf("add index %s(%s)", idx.Name, strings.Join(idx.Columns, ",")))
+		case o.Unique != idx.Unique || !utils.IsSliceEqual(o.Columns, idx.Columns):
+			details = append(details, fmt.Sprintf("modify index %s(%s) -> (%s)", idx.Name, strings.Join(o.Columns, ","), strings.Join(idx.Columns, ",")))
+		}
+	}
+	for name := range oldIdxMap {
+		details = append(details, fmt.Sprintf("drop index %s", name))
+	}
+
+	if old.TableComment != new.TableComment {
+		details = append(details, fmt.Sprintf("modify comment '%s' -> '%s'", old.TableComment, new.TableComment))
+	}
+	return strings.Join(details, "; ")
+}
+
+// QueryCatalogTable 查询表结构元数据
+func (c *CatalogTableService) QueryCatalogTable(ctx *gin.Context, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.CatalogTable](db).
+		QueryPaging(ctx).
+		Order("id desc").
+		Query(
+			common.FilterFuzzyStringMap(queryMap),
+			common.FilterID(c.ID),
+		)
+	if err != nil {
+		log.Errorf("query models.CatalogTable from db faield, %s", err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]CatalogTableService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		s := &CatalogTableService{}
+		s.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *s)
+	}
+
+	return ret, common.CodeOK, nil
+}
+
+// QuerySchemaChange 查询表结构变更历史
+func (c *CatalogSchemaChangeService) QuerySchemaChange(ctx *gin.Context, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.CatalogSchemaChange](db).
+		QueryPaging(ctx).
+		Order("id desc").
+		Query(
+			common.FilterFuzzyStringMap(queryMap),
+			common.FilterID(c.ID),
+		)
+	if err != nil {
+		log.Errorf("query models.CatalogSchemaChange from db faield, %s", err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]CatalogSchemaChangeService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		s := &CatalogSchemaChangeService{}
+		s.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *s)
+	}
+
+	return ret, common.CodeOK, nil
+}
+
+func (c *CatalogTableService) ModelToService(m *models.CatalogTable) *CatalogTableService {
+	c.ID = m.ID
+	c.Creator = m.Creator
+	c.Editor = m.Editor
+	c.CreatedAt = m.CreatedAt.Format(time.DateTime)
+	c.UpdatedAt = m.UpdatedAt.Format(time.DateTime)
+	c.Bu = m.Bu
+	c.ClusterID = m.ClusterID
+	c.ClusterName = m.ClusterName
+	c.Database = m.Database
+	c.Table = m.Table
+	c.Engine = m.Engine
+	c.TableRows = m.TableRows
+	c.DataLength = m.DataLength
+	c.IndexLength = m.IndexLength
+	c.DataFree = m.DataFree
+	c.TableComment = m.TableComment
+	c.PrimaryKey = nil
+	if m.PrimaryKey != "" {
+		c.PrimaryKey = strings.Split(m.PrimaryKey, ",")
+	}
+	_ = json.Unmarshal(m.Columns, &c.Columns)
+	_ = json.Unmarshal(m.Indexes, &c.Indexes)
+	c.SnapshotAt = m.SnapshotAt.Format(time.DateTime)
+	return c
+}
+
+func (c *CatalogSchemaChangeService) ModelToService(m *models.CatalogSchemaChange) *CatalogSchemaChangeService {
+	c.ID = m.ID
+	c.ClusterID = m.ClusterID
+	c.Database = m.Database
+	c.Table = m.Table
+	c.ChangeType = m.ChangeType
+	c.Detail = m.Detail
+	_ = json.Unmarshal(m.OldColumns, &c.OldColumns)
+	_ = json.Unmarshal(m.NewColumns, &c.NewColumns)
+	_ = json.Unmarshal(m.OldIndexes, &c.OldIndexes)
+	_ = json.Unmarshal(m.NewIndexes, &c.NewIndexes)
+	c.ChangedAt = m.ChangedAt.Format(time.DateTime)
+	return c
+}
+
+// CatalogSnapshotted 集群是否已有表结构快照，没有快照时需要回退到直接查询集群
+func CatalogSnapshotted(ctx *gin.Context, clusterID string) bool {
+	_, db := common.ExtractContext(ctx)
+
+	var count int64
+	err := db.Model(models.CatalogTable{}).Where("cluster_id =?", clusterID).Count(&count).Error
+	return err == nil && count > 0
+}
+
+// CatalogDatabases 从快照中查询集群的库列表
+func CatalogDatabases(ctx *gin.Context, clusterID string) ([]string, error) {
+	_, db := common.ExtractContext(ctx)
+
+	var databases []string
+	err := db.Model(models.CatalogTable{}).Where("cluster_id =?", clusterID).
+		Distinct("`database`").Order("`database`").Pluck("database", &databases).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.CatalogTable(cluster_id=%s) databases failed, %s", clusterID, err)
+	}
+	return databases, nil
+}
+
+// CatalogTables 从快照中查询库的表列表
+func CatalogTables(ctx *gin.Context, clusterID, database string) ([]models.CatalogTable, error) {
+	_, db := common.ExtractContext(ctx)
+
+	var tables []models.CatalogTable
+	err := db.Model(models.CatalogTable{}).Where("cluster_id =? AND `database` =?", clusterID, database).
+		Order("`table`").Find(&tables).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.CatalogTable(cluster_id=%s, database=%s) failed, %s", clusterID, database, err)
+	}
+	return tables, nil
+}
+
+// ClusterDatabases 查询集群的库列表，优先从表结构快照中读取；快照中找不到want中的库时(如快照刷新后新建的库)以集群的实时信息为准
+func ClusterDatabases(ctx *gin.Context, c *ClusterService, want ...string) ([]string, common.ServiceCode, error) {
+	if !CatalogSnapshotted(ctx, c.ClusterID) {
+		return NewClusterDriver(c).GetDatabases(ctx)
+	}
+
+	databases, err := CatalogDatabases(ctx, c.ClusterID)
+	if err != nil {
+		return nil, common.CodeServerErr, err
+	}
+	if !utils.IsSubSlices(want, databases) {
+		return NewClusterDriver(c).GetDatabases(ctx)
+	}
+	return databases, common.CodeOK, nil
+}
+
+// ClusterTables 查询库的表列表，优先从表结构快照中读取；快照中找不到want中的表时(如快照刷新后新建的表)以集群的实时信息为准
+func ClusterTables(ctx *gin.Context, c *ClusterService, database string, want ...string) ([]string, common.ServiceCode, error) {
+	if !CatalogSnapshotted(ctx, c.ClusterID) {
+		return NewClusterDriver(c).GetTables(ctx, database)
+	}
+
+	catalogTables, err := CatalogTables(ctx, c.ClusterID, database)
+	if err != nil {
+		return nil, common.CodeServerErr, err
+	}
+	tables := make([]string, 0, len(catalogTables))
+	for _, t := range catalogTables {
+		tables = append(tables, t.Table)
+	}
+	if !utils.IsSubSlices(want, tables) {
+		return NewClusterDriver(c).GetTables(ctx, database)
+	}
+	return tables, common.CodeOK, nil
+}
+
+// ClusterTablesHasPrimaryKey 检查表是否都有主键，优先从表结构快照中读取，快照中没有的表从集群实时检查
+func ClusterTablesHasPrimaryKey(ctx *gin.Context, c *ClusterService, database string, tables []string) (bool, common.ServiceCode, error) {
+	if !CatalogSnapshotted(ctx, c.ClusterID) {
+		return NewClusterDriver(c).TablesHasPrimaryKey(ctx, database, tables)
+	}
+
+	catalogTables, err := CatalogTables(ctx, c.ClusterID, database)
+	if err != nil {
+		return false, common.CodeServerErr, err
+	}
+	hasPrimaryKey := make(map[string]bool, len(catalogTables))
+	for _, t := range catalogTables {
+		hasPrimaryKey[t.Table] = t.PrimaryKey != ""
+	}
+
+	var noPrimaryKeyTabs, notInCatalog []string
+	for _, t := range tables {
+		has, ok := hasPrimaryKey[t]
+		switch {
+		case !ok:
+			notInCatalog = append(notInCatalog, t)
+		case !has:
+			noPrimaryKeyTabs = append(noPrimaryKeyTabs, t)
+		}
+	}
+	if len(noPrimaryKeyTabs) != 0 {
+		return false, common.CodeSourceTableHasPrimaryKey, fmt.Errorf("tables %v has no PrimaryKey", noPrimaryKeyTabs)
+	}
+	if len(notInCatalog) != 0 {
+		return NewClusterDriver(c).TablesHasPrimaryKey(ctx, database, notInCatalog)
+	}
+	return true, common.CodeOK, nil
+}
+
+// CatalogCheckTableColumns 从表结构快照中检查表和列是否存在，快照中找不到表或列时以集群的实时表结构为准，集群没有快照时跳过检查
+func CatalogCheckTableColumns(ctx *gin.Context, c *ClusterService, database, table string, columns []string) (common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if !CatalogSnapshotted(ctx, c.ClusterID) {
+		return common.CodeOK, nil
+	}
+
+	var catalogTables []models.CatalogTable
+	err := db.Model(models.CatalogTable{}).
+		Where("cluster_id =? AND `database` =? AND `table` =?", c.ClusterID, database, table).
+		Find(&catalogTables).Error
+	if err != nil {
+		return common.CodeServerErr, fmt.Errorf("query models.CatalogTable(%s.%s) failed, %s", database, table, err)
+	}
+
+	var tableColumns []mysql.ColumnSchema
+	if len(catalogTables) != 0 {
+		_ = json.Unmarshal(catalogTables[0].Columns, &tableColumns)
+	}
+	notExist := notExistColumns(tableColumns, columns)
+	if len(catalogTables) == 0 || len(notExist) != 0 {
+		var code common.ServiceCode
+		tableColumns, code, err = NewClusterDriver(c).GetTableColumns(ctx, database, table)
+		if err != nil {
+			return code, err
+		}
+		if len(tableColumns) == 0 {
+			return common.CodeSourceTableNotExist, fmt.Errorf("table(%s.%s) not exist in cluster(%s)", database, table, c.ClusterID)
+		}
+		notExist = notExistColumns(tableColumns, columns)
+	}
+	if len(notExist) != 0 {
+		return common.CodePolicyConditionsErr, fmt.Errorf("columns %v not exist in table(%s.%s)", notExist, database, table)
+	}
+	return common.CodeOK, nil
+}
+
+// notExistColumns 返回表中不存在的列，忽略大小写和反引号
+func notExistColumns(tableColumns []mysql.ColumnSchema, columns []string) []string {
+	columnNames := make([]string, 0, len(tableColumns))
+	for _, col := range tableColumns {
+		columnNames = append(columnNames, strings.ToLower(col.Name))
+	}
+
+	var notExist []string
+	for _, col := range columns {
+		col = strings.Trim(strings.TrimSpace(col), "`")
+		if col == "" || col == "*" {
+			continue
+		}
+		if !utils.ElementExist(strings.ToLower(col), columnNames) {
+			notExist = append(notExist, col)
+		}
+	}
+	return notExist
+}
+
+// ClusterTableIndexes 查询表的索引，优先从表结构快照中读取
+func ClusterTableIndexes(ctx *gin.Context, c *ClusterService, database, table string) ([]mysql.IndexSchema, common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if !CatalogSnapshotted(ctx, c.ClusterID) {
+		return NewClusterDriver(c).GetTableIndexes(ctx, database, table)
+	}
+
+	var catalogTables []models.CatalogTable
+	err := db.Model(models.CatalogTable{}).
+		Where("cluster_id =? AND `database` =? AND `table` =?", c.ClusterID, database, table).
+		Find(&catalogTables).Error
+	if err != nil {
+		return nil, common.CodeServerErr, fmt.Errorf("query models.CatalogTable(%s.%s) failed, %s", database, table, err)
+	}
+	if len(catalogTables) == 0 {
+		return NewClusterDriver(c).GetTableIndexes(ctx, database, table)
+	}
+
+	var indexes []mysql.IndexSchema
+	_ = json.Unmarshal(catalogTables[0].Indexes, &indexes)
+	return indexes, common.CodeOK, nil
+}
+
+// ClusterTableColumns 查询表的列，优先从表结构快照中读取
+func ClusterTableColumns(ctx *gin.Context, c *ClusterService, database, table string) ([]mysql.ColumnSchema, common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if !CatalogSnapshotted(ctx, c.ClusterID) {
+		return NewClusterDriver(c).GetTableColumns(ctx, database, table)
+	}
+
+	var catalogTables []models.CatalogTable
+	err := db.Model(models.CatalogTable{}).
+		Where("cluster_id =? AND `database` =? AND `table` =?", c.ClusterID, database, table).
+		Find(&catalogTables).Error
+	if err != nil {
+		return nil, common.CodeServerErr, fmt.Errorf("query models.CatalogTable(%s.%s) failed, %s", database, table, err)
+	}
+	if len(catalogTables) == 0 {
+		return NewClusterDriver(c).GetTableColumns(ctx, database, table)
+	}
+
+	var columns []mysql.ColumnSchema
+	_ = json.Unmarshal(catalogTables[0].Columns, &columns)
+	return columns, common.CodeOK, nil
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sunkaimr/data-loom/internal/models"
-	"github.com/sunkaimr/data-loom/internal/notice"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
 	"time"
 )
@@ -20,7 +19,7 @@ type TaskChangeLogService struct {
 }
 
 func CreateTaskChangeLog(ctx *common.Context, task *models.Task, user, content string) {
-	log, db := ctx.Log, ctx.DB
+	db := ctx.DB
 
 	db.Save(&models.TaskChangeLog{
 		TaskID:     task.ID,
@@ -47,7 +46,8 @@ func CreateTaskChangeLog(ctx *common.Context, task *models.Task, user, content s
 		if task.TaskStatus == common.TaskStatusSupplementFailed ||
 			task.TaskStatus == common.TaskStatusExecCheckFailed ||
 			task.TaskStatus == common.TaskStatusExecFailed ||
-			task.TaskStatus == common.TaskStatusTimeout {
+			task.TaskStatus == common.TaskStatusTimeout ||
+			task.TaskStatus == common.TaskStatusSkipped {
 			needSend = true
 		}
 	case common.NotifyPolicyTypeAlways:
@@ -56,19 +56,14 @@ func CreateTaskChangeLog(ctx *common.Context, task *models.Task, user, content s
 			task.TaskStatus == common.TaskStatusExecCheckFailed ||
 			task.TaskStatus == common.TaskStatusExecFailed ||
 			task.TaskStatus == common.TaskStatusSuccess ||
-			task.TaskStatus == common.TaskStatusTimeout {
+			task.TaskStatus == common.TaskStatusTimeout ||
+			task.TaskStatus == common.TaskStatusSkipped {
 			needSend = true
 		}
 	}
 
 	if needSend {
-		msg := notice.GenerateMessage(ctx, task)
-		err := notice.NewDriver(Cfg.ServiceToModel()).Send(ctx, msg)
-		if err != nil {
-			log.Errorf("send notice(%+v) failed, %s", msg, err)
-		} else {
-			log.Debugf("send notice(%+v) success", msg)
-		}
+		SendTaskNotice(ctx, task)
 	}
 }
 
@@ -25,11 +25,16 @@ type ClusterService struct {
 	Description string                       `json:"description"`  // 说明
 	Bu          string                       `json:"bu"`           // bu
 	Env         string                       `json:"env"`          // 环境
+	Timezone    string                       `json:"timezone"`     // 时区(如Asia/Shanghai)，执行日期和执行窗口按此时区计算，为空时使用服务所在时区
 	ImportFrom  common.ClusterImportFromType `json:"import_from"`  // 添加方式：customized:自定义添加 ipaas:从资源中心导入
 	ClusterType common.ClusterType           `json:"cluster_type"` // 集群类型（mysql, 其他的）
 	ServiceAddr string                       `json:"service_addr"` // 服务地址(vip) ip:port
 	WriteAddr   string                       `json:"write_addr"`   // 写库地址 ip:port
 	ReadAddr    string                       `json:"read_addr"`    // 读库地址 ip:port
+	PrimaryUUID string                       `json:"primary_uuid"` // 自动发现的主库server_uuid（只读）
+	PrimaryAddr string                       `json:"primary_addr"` // 自动发现的主库地址（只读），不会覆盖配置的写库地址
+	Replicas    string                       `json:"replicas"`     // 自动发现的从库地址列表（只读）
+	TopologyAt  string                       `json:"topology_at"`  // 最近一次发现集群拓扑的时间
 	UserName    string                       `json:"user_name"`    // 用户名（若未提供用户名和密码则以全局的为准）
 	Password    string                       `json:"password"`     // 密码
 }
@@ -78,6 +83,10 @@ func (c *ClusterService) CheckParameters(ctx *gin.Context) (bool, common.Service
 		return false, common.CodeClusterIDErr, fmt.Errorf("ClusterID(%s) lenth should be 1-64", c.ClusterID)
 	}
 
+	if err := common.CheckTimezone(c.Timezone); err != nil {
+		return false, common.CodeClusterTimezoneErr, err
+	}
+
 	c.ImportFrom = common.ImportFromCustomized
 	if c.ServiceAddr == "" {
 		return false, common.CodeClusterServiceAddrErr, fmt.Errorf("ServiceAddr(%s) lenth should be 1-1024", c.ServiceAddr)
@@ -166,14 +175,27 @@ func (c *ClusterService) CheckUpdateParameters(ctx *gin.Context) (bool, common.S
 		return false, common.CodeServerErr, err
 	}
 
-	if clusterMod.ImportFrom != common.ImportFromCustomized {
-		err = fmt.Errorf("cluster(%v) import from %s is unchangeable", clusterMod.ClusterName, clusterMod.ImportFrom)
+	err = common.CheckTimezone(c.Timezone)
+	if err != nil {
 		log.Error(err)
-		return false, common.CodeClusterSyncImmutable, err
+		return false, common.CodeClusterTimezoneErr, err
+	}
+
+	if clusterMod.ImportFrom != common.ImportFromCustomized {
+		// 从外部同步的集群仅支持修改时区
+		if c.ClusterName != clusterMod.ClusterName || c.Description != clusterMod.Description ||
+			c.Bu != clusterMod.Bu || c.Env != clusterMod.Env || c.UserName != clusterMod.UserName ||
+			(c.Password != "" && c.Password != clusterMod.Password) {
+			err = fmt.Errorf("cluster(%v) import from %s is unchangeable", clusterMod.ClusterName, clusterMod.ImportFrom)
+			log.Error(err)
+			return false, common.CodeClusterSyncImmutable, err
+		}
+		c.Password = clusterMod.Password
+		return true, common.CodeOK, nil
 	}
 
 	// 可更新字段
-	// ClusterName, Description, Bu, Env, UserName, Password
+	// ClusterName, Description, Bu, Env, Timezone, UserName, Password
 	var count int64
 	err = db.Model(clusterMod).Where("id !=? AND cluster_name =?", c.ID, c.ClusterName).Count(&count).Error
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -224,11 +246,14 @@ func (c *ClusterService) UpdateCluster(ctx *gin.Context) (common.ServiceCode, er
 		return common.CodeServerErr, err
 	}
 
+	timezoneChanged := clusterMod.Timezone != c.Timezone
+
 	clusterMod.Editor = u.UserName
 	clusterMod.ClusterName = c.ClusterName
 	clusterMod.Description = c.Description
 	clusterMod.Bu = c.Bu
 	clusterMod.Env = c.Env
+	clusterMod.Timezone = c.Timezone
 	clusterMod.UserName = c.UserName
 	clusterMod.Password = c.Password
 
@@ -241,6 +266,18 @@ func (c *ClusterService) UpdateCluster(ctx *gin.Context) (common.ServiceCode, er
 		return common.CodeServerErr, err
 	}
 
+	// 时区变化后需要按新的时区重新计算未执行任务的执行日期
+	if timezoneChanged {
+		err = db.Model(&models.Task{}).
+			Where("src_cluster_id =? AND task_status IN(?)", clusterMod.ClusterID, common.TaskStatusCanUpdate).
+			Update("need_check_execute_date", true).Error
+		if err != nil {
+			err = fmt.Errorf("update models.Task(src_cluster_id=%v AND task_status IN(%v)) need_check_execute_date=true failed, %s", clusterMod.ClusterID, common.TaskStatusCanUpdate, err)
+			log.Error(err)
+			return common.CodeServerErr, err
+		}
+	}
+
 	return common.CodeOK, nil
 }
 
@@ -345,7 +382,7 @@ func (c *ClusterService) GetClusterDatabase(ctx *gin.Context) ([]string, common.
 		return nil, common.CodeServerErr, err
 	}
 	c.ModelToService(clusterMod)
-	databases, code, err := NewClusterDriver(c).GetDatabases(ctx)
+	databases, code, err := ClusterDatabases(ctx, c)
 	databases = utils.RemoveSubSlices(databases, strings.Split(Cfg.ClusterExcludeDatabase, ","))
 
 	return databases, code, err
@@ -368,7 +405,7 @@ func (c *ClusterService) GetClusterTables(ctx *gin.Context, database string) ([]
 		return nil, common.CodeServerErr, err
 	}
 	c.ModelToService(clusterMod)
-	tables, code, err := NewClusterDriver(c).GetTables(ctx, database)
+	tables, code, err := ClusterTables(ctx, c, database)
 	tables = common.ExcludeTablesFilter(database, tables, strings.Split(Cfg.ClusterExcludeTables, ","))
 	return tables, code, err
 }
@@ -385,11 +422,16 @@ func (c *ClusterService) ServiceToModel() *models.Cluster {
 	m.Description = c.Description
 	m.Bu = c.Bu
 	m.Env = c.Env
+	m.Timezone = c.Timezone
 	m.ImportFrom = c.ImportFrom
 	m.ClusterType = c.ClusterType
 	m.ServiceAddr = c.ServiceAddr
 	m.WriteAddr = c.WriteAddr
 	m.ReadAddr = c.ReadAddr
+	m.PrimaryUUID = c.PrimaryUUID
+	m.PrimaryAddr = c.PrimaryAddr
+	m.Replicas = c.Replicas
+	m.TopologyAt, _ = time.ParseInLocation(time.DateTime, c.TopologyAt, time.Now().Location())
 	m.UserName = c.UserName
 	m.Password = c.Password
 	return m
@@ -406,11 +448,16 @@ func (c *ClusterService) ModelToService(m *models.Cluster) *ClusterService {
 	c.Description = m.Description
 	c.Bu = m.Bu
 	c.Env = m.Env
+	c.Timezone = m.Timezone
 	c.ImportFrom = m.ImportFrom
 	c.ClusterType = m.ClusterType
 	c.ServiceAddr = m.ServiceAddr
 	c.WriteAddr = m.WriteAddr
 	c.ReadAddr = m.ReadAddr
+	c.PrimaryUUID = m.PrimaryUUID
+	c.PrimaryAddr = m.PrimaryAddr
+	c.Replicas = m.Replicas
+	c.TopologyAt = m.TopologyAt.Format(time.DateTime)
 	c.UserName = m.UserName
 	c.Password = m.Password
 	return c
@@ -447,6 +494,12 @@ func (c *ClusterService) SyncCluster(ctx *gin.Context) (common.ServiceCode, erro
 		} else {
 			// 2，库里存在&ipaas中也存在需更新
 			if !clusterServiceEqual(&clu, newCluster) {
+				// 时区由用户维护，拓扑由发现任务维护，同步时保留
+				newCluster.Timezone = clu.Timezone
+				newCluster.PrimaryUUID = clu.PrimaryUUID
+				newCluster.PrimaryAddr = clu.PrimaryAddr
+				newCluster.Replicas = clu.Replicas
+				newCluster.TopologyAt = clu.TopologyAt
 				//newCluster.ID = clu.ID
 				err = db.Model(&models.Cluster{}).Where("cluster_id =?", newCluster.ClusterID).Save(newCluster).Error
 				if err != nil {
@@ -502,6 +555,76 @@ func clusterServiceEqual(old, new *models.Cluster) bool {
 	return false
 }
 
+// RefreshClusterTopology 发现所有集群的主从拓扑
+func (_ *ClusterService) RefreshClusterTopology(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	var clusters []models.Cluster
+	err := db.Model(models.Cluster{}).Where("cluster_type =?", common.ClusterTypeMysql).Find(&clusters).Error
+	if err != nil {
+		err = fmt.Errorf("query models.Cluster(cluster_type=%s) failed, %s", common.ClusterTypeMysql, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	for _, c := range clusters {
+		clusterSvc := &ClusterService{}
+		clusterSvc.ModelToService(&c)
+		_, _, err = UpdateClusterTopology(ctx, clusterSvc)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+	}
+	return common.CodeOK, nil
+}
+
+// UpdateClusterTopology 发现集群的主从拓扑并记录到库中，返回当前主库的server_uuid和地址。
+// 发现的拓扑单独保存，不覆盖用户配置的写库和读库地址
+func UpdateClusterTopology(ctx *gin.Context, c *ClusterService) (string, string, error) {
+	log, db := common.ExtractContext(ctx)
+
+	uuid, primary, replicas, err := NewClusterDriver(c).DiscoverTopology(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("discover cluster(%s) topology failed, %s", c.ClusterID, err)
+	}
+
+	if (c.PrimaryUUID != "" || c.PrimaryAddr != "") && !SamePrimary(c.PrimaryUUID, c.PrimaryAddr, uuid, primary) {
+		log.Warnf("cluster(%s) primary changed from %s(%s) to %s(%s)", c.ClusterID, c.PrimaryAddr, c.PrimaryUUID, primary, uuid)
+	}
+
+	err = db.Model(&models.Cluster{}).Where("cluster_id =?", c.ClusterID).
+		Updates(map[string]any{
+			"primary_uuid": uuid,
+			"primary_addr": primary,
+			"replicas":     strings.Join(replicas, ","),
+			"topology_at":  time.Now(),
+		}).Error
+	if err != nil {
+		return uuid, primary, fmt.Errorf("update models.Cluster(%s) topology failed, %s", c.ClusterID, err)
+	}
+
+	c.PrimaryUUID, c.PrimaryAddr, c.Replicas = uuid, primary, strings.Join(replicas, ",")
+	return uuid, primary, nil
+}
+
+// SamePrimary 判断两次发现的主库是否为同一实例：都有server_uuid时按server_uuid比较，否则按规范化后的host:port比较
+func SamePrimary(uuidA, addrA, uuidB, addrB string) bool {
+	if uuidA != "" && uuidB != "" {
+		return strings.EqualFold(uuidA, uuidB)
+	}
+	return normalizeAddr(addrA) == normalizeAddr(addrB)
+}
+
+// normalizeAddr 规范化实例地址：主机名不区分大小写，缺省端口为3306
+func normalizeAddr(addr string) string {
+	host, port, err := utils.ParseAddr(addr, "", 3306)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(addr))
+	}
+	return fmt.Sprintf("%s:%d", strings.ToLower(host), port)
+}
+
 var RefreshClusterBigTablesLock sync.Mutex
 
 func (_ *ClusterService) RefreshClusterBigTables(ctx *gin.Context) (common.ServiceCode, error) {
@@ -540,17 +663,29 @@ func (_ *ClusterService) RefreshClusterBigTables(ctx *gin.Context) (common.Servi
 					Table    string
 					Size     int
 				}); ok {
-					// 查找该table使用的策略
-					var res []uint
+					// 查找该table使用的策略，源端包含多个分库时任一分库的表都属于该源端
+					var policies []struct {
+						ID            uint
+						SrcID         uint
+						DatabasesName string
+					}
 					err = db.Model(&models.Policy{}).
-						Select("policy.id").
+						Select("policy.id, policy.src_id, source.databases_name").
 						Joins("JOIN source ON policy.src_id = source.id").
-						Where("policy.enable =? AND policy.govern <> ? AND source.cluster_id =? AND source.database_name =? AND source.tables_name LIKE ?",
-							true, common.GovernTypeRebuild, c.ClusterID, v.Database, "%"+v.Table+"%").
-						Scan(&res).Error
+						Where("policy.enable =? AND policy.govern <> ? AND source.cluster_id =? AND (source.database_name =? OR FIND_IN_SET(?, source.databases_name)) AND source.tables_name LIKE ?",
+							true, common.GovernTypeRebuild, c.ClusterID, v.Database, v.Database, "%"+v.Table+"%").
+						Scan(&policies).Error
 					if err != nil {
 						log.Errorf("query models.Policy from db failed, %s", err)
 					}
+					var res []uint
+					var srcID uint
+					for _, p := range policies {
+						res = append(res, p.ID)
+						if srcID == 0 && p.DatabasesName != "" {
+							srcID = p.SrcID
+						}
+					}
 
 					clusterStatistics = append(clusterStatistics, models.ClusterStatistics{
 						Date:        date,
@@ -561,6 +696,7 @@ func (_ *ClusterService) RefreshClusterBigTables(ctx *gin.Context) (common.Servi
 						Table:       v.Table,
 						TableSize:   v.Size,
 						Policies:    utils.SliceToString(res, ","),
+						SrcID:       srcID,
 					})
 				}
 			}
@@ -0,0 +1,27 @@
+package services
+
+import "testing"
+
+func TestSamePrimary(t *testing.T) {
+	tests := []struct {
+		name         string
+		uuidA, addrA string
+		uuidB, addrB string
+		want         bool
+	}{
+		{"same uuid different addr format", "3e11fa47-71ca-11e1-9e33-c80aa9429562", "10.0.0.1:3306",
+			"3E11FA47-71CA-11E1-9E33-C80AA9429562", "db-01:3306", true},
+		{"uuid changed", "3e11fa47-71ca-11e1-9e33-c80aa9429562", "db-01:3306",
+			"8a94f357-aab4-11df-86ab-c80aa9429562", "db-01:3306", false},
+		{"addr case and default port", "", "DB-01", "", "db-01:3306", true},
+		{"addr changed", "", "db-01:3306", "", "db-02:3306", false},
+		{"one side without uuid", "", "db-01:3306", "3e11fa47-71ca-11e1-9e33-c80aa9429562", "db-01:3306", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := SamePrimary(test.uuidA, test.addrA, test.uuidB, test.addrB); got != test.want {
+				t.Fatalf("SamePrimary(%s, %s, %s, %s) got %v, want %v", test.uuidA, test.addrA, test.uuidB, test.addrB, got, test.want)
+			}
+		})
+	}
+}
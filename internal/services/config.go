@@ -7,6 +7,7 @@ import (
 	"github.com/sunkaimr/data-loom/internal/models"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
 	"github.com/sunkaimr/data-loom/pkg/utils"
+	"github.com/sunkaimr/data-loom/pkg/webhook"
 )
 
 var Cfg = &ConfigService{}
@@ -22,11 +23,21 @@ type ConfigService struct {
 	TaskConflictLevel     common.TaskConflictLevelType `json:"task_conflict_level"`     // 任务冲突级别：源端集群级别，库级别，表级别
 	TaskConflictMax       int                          `json:"task_conflict_max"`       // 最多允许运行几个任务
 	WorkflowRetentionDays int                          `json:"workflow_retention_days"` // 任务的工作流保留天数
+	TaskPriorityAging     int                          `json:"task_priority_aging"`     // 任务优先级老化周期，等待执行每超过多少分钟优先级提升1，0表示不提升
+	TaskFairShare         bool                         `json:"task_fair_share"`         // 开启按BU权重公平分配任务并发数
 	ThanosUrl             string                       `json:"thanos_url"`              // 监控指标地址
 
 	SourceStatusDetect          bool `json:"source_status_detect"`            // 源端库运行状态检测
 	SourceStatusDetectDiskUsage int  `json:"source_status_detect_disk_usage"` // 源端磁盘使用率
 
+	SourceStatusDetectThreadsRunning int `json:"source_status_detect_threads_running"` // 源端正在运行的线程数阈值,0表示不检测
+	SourceStatusDetectActiveTrx      int `json:"source_status_detect_active_trx"`      // 源端活跃事务数阈值,0表示不检测
+	SourceStatusDetectQPS            int `json:"source_status_detect_qps"`             // 源端QPS阈值,0表示不检测
+	SourceStatusDetectSamples        int `json:"source_status_detect_samples"`         // 源端负载连续超过阈值多少次后暂停执行(停止工作流并回到等待执行)
+
+	PolicyConditionMaxScanRows int  `json:"policy_condition_max_scan_rows"` // 策略治理条件预估扫描行数阈值,0表示不检测
+	PolicyConditionStrict      bool `json:"policy_condition_strict"`        // 治理条件无法使用索引或扫描行数超过阈值时拒绝保存策略,否则仅告警
+
 	// 通知相关
 	Notice                  common.NoticeType `json:"notice"`
 	EmailHost               string            `json:"email_host"`
@@ -34,6 +45,15 @@ type ConfigService struct {
 	EmailUsername           string            `json:"email_username"`
 	EmailPassword           string            `json:"email_password"`
 	EmailInsecureSkipVerify bool              `json:"email_insecure_skip_verify"`
+	WebhookURL              string            `json:"webhook_url"`      // Webhook通知地址
+	WebhookSecret           string            `json:"webhook_secret"`   // Webhook签名密钥，请求头X-Data-Loom-Signature为sha256=HMAC-SHA256(密钥, "时间戳.请求体")
+	WebhookTemplate         string            `json:"webhook_template"` // Webhook请求体模板(text/template)，为空时使用默认的JSON格式
+	WebhookRetries          int               `json:"webhook_retries"`  // Webhook发送失败后的重试次数
+	DingTalkWebhook         string            `json:"dingtalk_webhook"` // 钉钉群机器人地址
+	DingTalkSecret          string            `json:"dingtalk_secret"`  // 钉钉群机器人加签密钥，为空表示未开启加签
+	FeishuWebhook           string            `json:"feishu_webhook"`   // 飞书群机器人地址
+	FeishuSecret            string            `json:"feishu_secret"`    // 飞书群机器人签名校验密钥，为空表示未开启签名校验
+	WeComWebhook            string            `json:"wecom_webhook"`    // 企业微信群机器人地址
 }
 
 func (_ *ConfigService) ReloadConfig(ctx *gin.Context) {
@@ -103,6 +123,54 @@ func (c *ConfigService) UpdateConfig(ctx *gin.Context) (*ConfigService, common.S
 		c.WorkflowRetentionDays = cfg.WorkflowRetentionDays
 	}
 
+	if c.TaskPriorityAging < 0 {
+		return nil, common.CodeConfigPriorityAgingErr, fmt.Errorf("TaskPriorityAging(%d) should not less than 0", c.TaskPriorityAging)
+	}
+
+	if c.SourceStatusDetectThreadsRunning < 0 {
+		c.SourceStatusDetectThreadsRunning = cfg.SourceStatusDetectThreadsRunning
+	}
+
+	if c.SourceStatusDetectActiveTrx < 0 {
+		c.SourceStatusDetectActiveTrx = cfg.SourceStatusDetectActiveTrx
+	}
+
+	if c.SourceStatusDetectQPS < 0 {
+		c.SourceStatusDetectQPS = cfg.SourceStatusDetectQPS
+	}
+
+	if c.SourceStatusDetectSamples <= 0 {
+		c.SourceStatusDetectSamples = cfg.SourceStatusDetectSamples
+	}
+
+	if c.PolicyConditionMaxScanRows < 0 {
+		c.PolicyConditionMaxScanRows = cfg.PolicyConditionMaxScanRows
+	}
+
+	if c.Notice == "" {
+		c.Notice = utils.Ternary[common.NoticeType](cfg.Notice == "", common.NoticeTypeEmail, cfg.Notice)
+	} else if !common.CheckNoticeType(c.Notice) {
+		return nil, common.CodeConfigNoticeTypeErr, fmt.Errorf("notice(%s) not pass", c.Notice)
+	}
+
+	if c.Notice == common.NoticeTypeWebhook && c.WebhookURL == "" {
+		return nil, common.CodeConfigWebhookErr, fmt.Errorf("webhook_url can not be empty when notice is %s", c.Notice)
+	}
+	if (c.Notice == common.NoticeTypeDingTalk && c.DingTalkWebhook == "") ||
+		(c.Notice == common.NoticeTypeFeishu && c.FeishuWebhook == "") ||
+		(c.Notice == common.NoticeTypeWeCom && c.WeComWebhook == "") {
+		return nil, common.CodeConfigRobotErr, fmt.Errorf("robot webhook can not be empty when notice is %s", c.Notice)
+	}
+	if c.WebhookTemplate != "" {
+		if err = webhook.CheckTemplate(c.WebhookTemplate); err != nil {
+			return nil, common.CodeConfigWebhookErr, err
+		}
+	}
+	// 未提供重试次数时以库里为准，提供0表示不重试
+	if c.WebhookRetries == common.InvalidInt || c.WebhookRetries < 0 {
+		c.WebhookRetries = cfg.WebhookRetries
+	}
+
 	cfg = c.ServiceToModel()
 
 	err = db.Where("id=1").Save(cfg).Error
@@ -131,15 +199,32 @@ func (c *ConfigService) ServiceToModel() *models.Config {
 	m.TaskConflictLevel = c.TaskConflictLevel
 	m.TaskConflictMax = c.TaskConflictMax
 	m.WorkflowRetentionDays = c.WorkflowRetentionDays
+	m.TaskPriorityAging = c.TaskPriorityAging
+	m.TaskFairShare = c.TaskFairShare
 	m.Notice = c.Notice
 	m.EmailHost = c.EmailHost
 	m.EmailPort = c.EmailPort
 	m.EmailUsername = c.EmailUsername
 	m.EmailPassword = c.EmailPassword
 	m.EmailInsecureSkipVerify = c.EmailInsecureSkipVerify
+	m.WebhookURL = c.WebhookURL
+	m.WebhookSecret = c.WebhookSecret
+	m.WebhookTemplate = c.WebhookTemplate
+	m.WebhookRetries = c.WebhookRetries
+	m.DingTalkWebhook = c.DingTalkWebhook
+	m.DingTalkSecret = c.DingTalkSecret
+	m.FeishuWebhook = c.FeishuWebhook
+	m.FeishuSecret = c.FeishuSecret
+	m.WeComWebhook = c.WeComWebhook
 
 	m.SourceStatusDetect = c.SourceStatusDetect
 	m.SourceStatusDetectDiskUsage = c.SourceStatusDetectDiskUsage
+	m.SourceStatusDetectThreadsRunning = c.SourceStatusDetectThreadsRunning
+	m.SourceStatusDetectActiveTrx = c.SourceStatusDetectActiveTrx
+	m.SourceStatusDetectQPS = c.SourceStatusDetectQPS
+	m.SourceStatusDetectSamples = c.SourceStatusDetectSamples
+	m.PolicyConditionMaxScanRows = c.PolicyConditionMaxScanRows
+	m.PolicyConditionStrict = c.PolicyConditionStrict
 	return m
 }
 
@@ -154,13 +239,30 @@ func (c *ConfigService) ModelToService(m *models.Config) *ConfigService {
 	c.TaskConflictLevel = m.TaskConflictLevel
 	c.TaskConflictMax = m.TaskConflictMax
 	c.WorkflowRetentionDays = m.WorkflowRetentionDays
+	c.TaskPriorityAging = m.TaskPriorityAging
+	c.TaskFairShare = m.TaskFairShare
 	c.Notice = m.Notice
 	c.EmailHost = m.EmailHost
 	c.EmailPort = m.EmailPort
 	c.EmailUsername = m.EmailUsername
 	c.EmailPassword = m.EmailPassword
 	c.EmailInsecureSkipVerify = m.EmailInsecureSkipVerify
+	c.WebhookURL = m.WebhookURL
+	c.WebhookSecret = m.WebhookSecret
+	c.WebhookTemplate = m.WebhookTemplate
+	c.WebhookRetries = m.WebhookRetries
+	c.DingTalkWebhook = m.DingTalkWebhook
+	c.DingTalkSecret = m.DingTalkSecret
+	c.FeishuWebhook = m.FeishuWebhook
+	c.FeishuSecret = m.FeishuSecret
+	c.WeComWebhook = m.WeComWebhook
 	c.SourceStatusDetect = m.SourceStatusDetect
 	c.SourceStatusDetectDiskUsage = m.SourceStatusDetectDiskUsage
+	c.SourceStatusDetectThreadsRunning = m.SourceStatusDetectThreadsRunning
+	c.SourceStatusDetectActiveTrx = m.SourceStatusDetectActiveTrx
+	c.SourceStatusDetectQPS = m.SourceStatusDetectQPS
+	c.SourceStatusDetectSamples = m.SourceStatusDetectSamples
+	c.PolicyConditionMaxScanRows = m.PolicyConditionMaxScanRows
+	c.PolicyConditionStrict = m.PolicyConditionStrict
 	return c
 }
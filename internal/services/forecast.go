@@ -0,0 +1,312 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	forecastDefaultDays           = 90 // 默认预测未来90天
+	forecastDefaultFastGrowthRate = 10 // 默认月增长率超过10%视为快速增长
+)
+
+// ClusterForecast 集群大表的容量增长预测
+type ClusterForecast struct {
+	StartDate      string                  `json:"start_date"`       // 参与拟合的历史数据开始日期
+	EndDate        string                  `json:"end_date"`         // 参与拟合的历史数据结束日期
+	Days           string                  `json:"days"`             // 预测未来多少天的容量
+	Threshold      string                  `json:"threshold"`        // 容量阈值(GB)，预测何时达到该阈值
+	FastGrowthRate string                  `json:"fast_growth_rate"` // 月增长率超过该值(%)视为快速增长
+	Total          int                     `json:"total"`
+	Data           []ClusterForecastDetail `json:"data"`
+}
+
+// ClusterForecastDetail 容量增长预测信息
+type ClusterForecastDetail struct {
+	Bu               string  `json:"bu"`                // bu
+	ClusterID        string  `json:"cluster_id"`        // 集群ID
+	ClusterName      string  `json:"cluster_name"`      // 集群名称
+	Database         string  `json:"database"`          // 库名
+	Table            string  `json:"table"`             // 表名
+	Samples          int     `json:"samples"`           // 参与拟合的历史数据个数
+	FirstDate        string  `json:"first_date"`        // 第一个历史数据的日期
+	LastDate         string  `json:"last_date"`         // 最后一个历史数据的日期
+	FirstSize        int     `json:"first_size"`        // 第一个历史数据的大小(GB)
+	CurrentSize      int     `json:"current_size"`      // 最新的大小(GB)
+	DailyGrowth      float64 `json:"daily_growth"`      // 拟合的每天增长量(GB)
+	MonthlyGrowth    float64 `json:"monthly_growth"`    // 拟合的每月增长量(GB)
+	MonthlyRate      float64 `json:"monthly_rate"`      // 月增长率(%)
+	ForecastDate     string  `json:"forecast_date"`     // 预测日期
+	ForecastSize     int     `json:"forecast_size"`     // 预测日期的大小(GB)
+	Threshold        int     `json:"threshold"`         // 容量阈值(GB)
+	ThresholdDate    string  `json:"threshold_date"`    // 预计达到阈值的日期，为空表示不会达到
+	DaysToThreshold  int     `json:"days_to_threshold"` // 从今天起距离达到阈值的天数，-1表示不会达到
+	FastGrowing      bool    `json:"fast_growing"`      // 是否快速增长
+	Policies         string  `json:"policies"`          // 对应的策略（仅表维度）
+	TablesNum        int     `json:"tables_num"`        // 大表个数
+	UngovernedNum    int     `json:"ungoverned_num"`    // 快速增长且没有策略的表个数
+	UngovernedTables string  `json:"ungoverned_tables"` // 快速增长且没有策略的表
+	NeedPolicy       bool    `json:"need_policy"`       // 快速增长但没有配置策略（仅表维度）
+}
+
+// forecastSeries 某个维度下每个日期的容量
+type forecastSeries struct {
+	detail *ClusterForecastDetail
+	sizes  map[string]int      // date -> size
+	tables map[string][]string // date -> tables
+}
+
+// ClusterForecastDetail 按维度拟合历史容量的增长趋势，预测未来的容量及达到阈值的日期
+func (c *ClusterForecast) ClusterForecastDetail(ctx *gin.Context, groupBy string, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	_, err := time.ParseInLocation(time.DateOnly, c.StartDate, time.Now().Location())
+	if err != nil {
+		c.StartDate = "1970-01-01"
+	}
+
+	_, err = time.ParseInLocation(time.DateOnly, c.EndDate, time.Now().Location())
+	if err != nil {
+		c.EndDate = time.Now().Format(time.DateOnly)
+	}
+
+	days, err := strconv.Atoi(c.Days)
+	if err != nil || days <= 0 {
+		days = forecastDefaultDays
+	}
+	c.Days = strconv.Itoa(days)
+
+	threshold, err := strconv.Atoi(c.Threshold)
+	if err != nil || threshold <= 0 {
+		threshold = 0
+		c.Threshold = ""
+	}
+
+	fastGrowthRate, err := strconv.ParseFloat(c.FastGrowthRate, 64)
+	if err != nil || fastGrowthRate <= 0 {
+		fastGrowthRate = forecastDefaultFastGrowthRate
+	}
+	c.FastGrowthRate = strconv.FormatFloat(fastGrowthRate, 'f', -1, 64)
+
+	var res []models.ClusterStatistics
+	err = db.Model(models.ClusterStatistics{}).
+		Where("date >= ? AND date <= ?", c.StartDate, c.EndDate).
+		Scopes(common.FilterFuzzyStringMap(queryMap)).
+		Order("date").
+		Scan(&res).Error
+	if err != nil {
+		err = fmt.Errorf("query models.ClusterStatistics from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	genKey := func(d *models.ClusterStatistics) string { return "" }
+	switch groupBy {
+	case "bu":
+		genKey = func(d *models.ClusterStatistics) string {
+			return d.Bu
+		}
+	case "cluster":
+		genKey = func(d *models.ClusterStatistics) string {
+			return strings.Join([]string{d.Bu, d.ClusterName}, "::::")
+		}
+	case "database":
+		genKey = func(d *models.ClusterStatistics) string {
+			return strings.Join([]string{d.Bu, d.ClusterName, d.Database}, "::::")
+		}
+	case "table":
+		genKey = func(d *models.ClusterStatistics) string {
+			return strings.Join([]string{d.Bu, d.ClusterName, d.Database, d.Table}, "::::")
+		}
+	default:
+		return c, common.CodeOK, fmt.Errorf("unsupported query method %s", groupBy)
+	}
+
+	seriesMap := make(map[string]*forecastSeries, len(res))
+	// 每张表的历史数据，用于找出快速增长且没有策略的表
+	tableMap := make(map[string]*forecastSeries, len(res))
+	for _, r := range res {
+		key := genKey(&r)
+		s, ok := seriesMap[key]
+		if !ok {
+			s = newForecastSeries(&r, groupBy)
+			seriesMap[key] = s
+		}
+		s.add(&r)
+
+		tableKey := strings.Join([]string{r.ClusterID, r.Database, r.Table}, "::::")
+		t, ok := tableMap[tableKey]
+		if !ok {
+			t = newForecastSeries(&r, "table")
+			tableMap[tableKey] = t
+		}
+		t.add(&r)
+	}
+
+	// 先计算每张表是否快速增长且没有策略
+	now := time.Now()
+	fastTables := make(map[string]bool, len(tableMap))
+	for key, t := range tableMap {
+		t.fit(days, 0, now)
+		if t.detail.isFastGrowing(fastGrowthRate) && t.detail.Policies == "" {
+			fastTables[key] = true
+		}
+	}
+
+	c.Data = make([]ClusterForecastDetail, 0, len(seriesMap))
+	for _, s := range seriesMap {
+		s.fit(days, threshold, now)
+		d := s.detail
+		d.FastGrowing = d.isFastGrowing(fastGrowthRate)
+
+		// 统计该维度下最新一次统计中快速增长且没有策略的表
+		var ungoverned []string
+		for _, table := range s.tables[d.LastDate] {
+			if fastTables[table] {
+				ungoverned = append(ungoverned, strings.ReplaceAll(table, "::::", "."))
+			}
+		}
+		if groupBy != "table" {
+			d.UngovernedNum, d.UngovernedTables = len(ungoverned), strings.Join(ungoverned, ",")
+		} else {
+			d.NeedPolicy = len(ungoverned) != 0
+		}
+		c.Data = append(c.Data, *d)
+	}
+	c.Total = len(c.Data)
+
+	// 最早达到阈值的排在前面，其次按月增长量排序
+	sort.Slice(c.Data, func(i, j int) bool {
+		if c.Data[i].DaysToThreshold != c.Data[j].DaysToThreshold {
+			if c.Data[i].DaysToThreshold < 0 || c.Data[j].DaysToThreshold < 0 {
+				return c.Data[j].DaysToThreshold < 0
+			}
+			return c.Data[i].DaysToThreshold < c.Data[j].DaysToThreshold
+		}
+		return c.Data[i].MonthlyGrowth > c.Data[j].MonthlyGrowth
+	})
+
+	return c, common.CodeOK, nil
+}
+
+func newForecastSeries(r *models.ClusterStatistics, groupBy string) *forecastSeries {
+	d := &ClusterForecastDetail{
+		Bu:              r.Bu,
+		ClusterID:       r.ClusterID,
+		ClusterName:     r.ClusterName,
+		Database:        r.Database,
+		Table:           r.Table,
+		DaysToThreshold: -1,
+	}
+
+	switch groupBy {
+	case "bu":
+		d.ClusterName = ""
+		d.ClusterID = ""
+		fallthrough
+	case "cluster":
+		d.Database = ""
+		fallthrough
+	case "database":
+		d.Table = ""
+	}
+
+	return &forecastSeries{
+		detail: d,
+		sizes:  make(map[string]int),
+		tables: make(map[string][]string),
+	}
+}
+
+func (s *forecastSeries) add(r *models.ClusterStatistics) {
+	s.sizes[r.Date] += r.TableSize
+	s.tables[r.Date] = append(s.tables[r.Date], strings.Join([]string{r.ClusterID, r.Database, r.Table}, "::::"))
+	if s.detail.Table != "" {
+		s.detail.Policies = r.Policies
+	}
+}
+
+// fit 使用最小二乘法对历史容量做线性拟合，预测日期和达到阈值的日期都从now开始计算
+func (s *forecastSeries) fit(days, threshold int, now time.Time) {
+	d := s.detail
+
+	dates := make([]string, 0, len(s.sizes))
+	for date := range s.sizes {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	d.Samples = len(dates)
+	d.FirstDate, d.LastDate = dates[0], dates[len(dates)-1]
+	d.FirstSize, d.CurrentSize = s.sizes[d.FirstDate], s.sizes[d.LastDate]
+	d.TablesNum = len(s.tables[d.LastDate])
+
+	first, _ := time.ParseInLocation(time.DateOnly, d.FirstDate, time.Now().Location())
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, date := range dates {
+		t, _ := time.ParseInLocation(time.DateOnly, date, time.Now().Location())
+		x, y := t.Sub(first).Hours()/24, float64(s.sizes[date])
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	// 只有一个数据点时无法拟合趋势
+	if n > 1 && n*sumXX-sumX*sumX != 0 {
+		d.DailyGrowth = (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	}
+	d.MonthlyGrowth = round2(d.DailyGrowth * 30)
+	if d.CurrentSize > 0 {
+		d.MonthlyRate = round2(d.DailyGrowth * 30 * 100 / float64(d.CurrentSize))
+	}
+	d.DailyGrowth = round2(d.DailyGrowth)
+
+	// 最后一次统计之后到now的增长量按拟合的趋势估算
+	last, _ := time.ParseInLocation(time.DateOnly, d.LastDate, now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	nowSize := float64(d.CurrentSize)
+	if today.After(last) {
+		nowSize = math.Max(0, nowSize+d.DailyGrowth*today.Sub(last).Hours()/24)
+	}
+
+	forecast := today.AddDate(0, 0, days)
+	d.ForecastDate = forecast.Format(time.DateOnly)
+	d.ForecastSize = int(math.Max(0, nowSize+d.DailyGrowth*float64(days)))
+
+	if threshold > 0 {
+		d.Threshold = threshold
+		d.DaysToThreshold = daysToReach(nowSize, float64(threshold), d.DailyGrowth)
+		if d.DaysToThreshold >= 0 {
+			d.ThresholdDate = today.AddDate(0, 0, d.DaysToThreshold).Format(time.DateOnly)
+		}
+	}
+}
+
+// isFastGrowing 月增长率是否超过阈值
+func (d *ClusterForecastDetail) isFastGrowing(rate float64) bool {
+	return d.MonthlyGrowth > 0 && d.MonthlyRate >= rate
+}
+
+// daysToReach 按每天的增长量计算从current增长到target需要的天数，-1表示不会达到
+func daysToReach(current, target, daily float64) int {
+	if current >= target {
+		return 0
+	}
+	if daily <= 0 {
+		return -1
+	}
+	return int(math.Ceil((target - current) / daily))
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,136 @@
+package services
+
+import "testing"
+
+func TestForecastSeriesFit(t *testing.T) {
+	tests := []struct {
+		name              string
+		sizes             map[string]int
+		days              int
+		threshold         int
+		now               string
+		wantDaily         float64
+		wantMonthlyRate   float64
+		wantForecastDate  string
+		wantForecastSize  int
+		wantDaysThreshold int
+		wantThresholdDate string
+	}{
+		{
+			name:              "linear growth",
+			sizes:             map[string]int{"2024-01-01": 100, "2024-01-06": 105, "2024-01-11": 110},
+			days:              30,
+			threshold:         150,
+			now:               "2024-01-11 10:00:00",
+			wantDaily:         1,
+			wantMonthlyRate:   27.27,
+			wantForecastDate:  "2024-02-10",
+			wantForecastSize:  140,
+			wantDaysThreshold: 40,
+			wantThresholdDate: "2024-02-20",
+		},
+		{
+			name:              "stale statistics measured from today",
+			sizes:             map[string]int{"2024-01-01": 100, "2024-01-06": 105, "2024-01-11": 110},
+			days:              30,
+			threshold:         150,
+			now:               "2024-01-21 10:00:00",
+			wantDaily:         1,
+			wantMonthlyRate:   27.27,
+			wantForecastDate:  "2024-02-20",
+			wantForecastSize:  150,
+			wantDaysThreshold: 30,
+			wantThresholdDate: "2024-02-20",
+		},
+		{
+			name:              "single sample",
+			sizes:             map[string]int{"2024-01-01": 100},
+			days:              30,
+			threshold:         150,
+			now:               "2024-01-01 10:00:00",
+			wantForecastDate:  "2024-01-31",
+			wantForecastSize:  100,
+			wantDaysThreshold: -1,
+		},
+		{
+			name:              "already reached",
+			sizes:             map[string]int{"2024-01-01": 200, "2024-01-11": 210},
+			days:              10,
+			threshold:         150,
+			now:               "2024-01-11 10:00:00",
+			wantDaily:         1,
+			wantMonthlyRate:   14.29,
+			wantForecastDate:  "2024-01-21",
+			wantForecastSize:  220,
+			wantDaysThreshold: 0,
+			wantThresholdDate: "2024-01-11",
+		},
+		{
+			name:              "shrinking",
+			sizes:             map[string]int{"2024-01-01": 100, "2024-01-11": 90},
+			days:              200,
+			threshold:         150,
+			now:               "2024-01-11 10:00:00",
+			wantDaily:         -1,
+			wantMonthlyRate:   -33.33,
+			wantForecastDate:  "2024-07-29",
+			wantForecastSize:  0,
+			wantDaysThreshold: -1,
+		},
+		{
+			name:              "no threshold",
+			sizes:             map[string]int{"2024-01-01": 100, "2024-01-11": 110},
+			days:              30,
+			now:               "2024-01-11 10:00:00",
+			wantDaily:         1,
+			wantMonthlyRate:   27.27,
+			wantForecastDate:  "2024-02-10",
+			wantForecastSize:  140,
+			wantDaysThreshold: -1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &forecastSeries{
+				detail: &ClusterForecastDetail{DaysToThreshold: -1},
+				sizes:  test.sizes,
+				tables: make(map[string][]string),
+			}
+			s.fit(test.days, test.threshold, parseLocalTime(test.now))
+			d := s.detail
+			if d.DailyGrowth != test.wantDaily || d.MonthlyRate != test.wantMonthlyRate {
+				t.Fatalf("fit() got daily %v rate %v, want %v %v", d.DailyGrowth, d.MonthlyRate, test.wantDaily, test.wantMonthlyRate)
+			}
+			if d.ForecastDate != test.wantForecastDate || d.ForecastSize != test.wantForecastSize {
+				t.Fatalf("fit() got forecast %s %d, want %s %d", d.ForecastDate, d.ForecastSize, test.wantForecastDate, test.wantForecastSize)
+			}
+			if d.DaysToThreshold != test.wantDaysThreshold || d.ThresholdDate != test.wantThresholdDate {
+				t.Fatalf("fit() got threshold %d %s, want %d %s", d.DaysToThreshold, d.ThresholdDate, test.wantDaysThreshold, test.wantThresholdDate)
+			}
+		})
+	}
+}
+
+func TestDaysToReach(t *testing.T) {
+	tests := []struct {
+		name    string
+		current float64
+		target  float64
+		daily   float64
+		want    int
+	}{
+		{"reached", 150, 100, 1, 0},
+		{"exactly reached", 100, 100, 0, 0},
+		{"no growth", 50, 100, 0, -1},
+		{"shrinking", 50, 100, -1, -1},
+		{"round up", 50, 100, 3, 17},
+		{"whole days", 50, 100, 5, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := daysToReach(test.current, test.target, test.daily); got != test.want {
+				t.Fatalf("daysToReach(%v, %v, %v) got %d, want %d", test.current, test.target, test.daily, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/notice"
+	"github.com/sunkaimr/data-loom/internal/notice/message"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// NotifyRouteService 通知路由规则，任务按BU、集群、治理方式、任务状态匹配后发送到指定的通知渠道。
+// 所有匹配的规则的通知渠道合并去重后发送，没有规则匹配时使用全局配置的通知方式
+type NotifyRouteService struct {
+	Model
+	Name             string                  `json:"name"`              // 规则名称
+	Description      string                  `json:"description"`       // 说明
+	Enable           bool                    `json:"enable"`            // 是否生效
+	Bus              []string                `json:"bus"`               // 匹配的BU，为空表示不限
+	ClusterIDs       []string                `json:"cluster_ids"`       // 匹配的集群ID，为空表示不限
+	Governs          []common.GovernType     `json:"governs"`           // 匹配的治理方式，为空表示不限
+	TaskStatuses     []common.TaskStatusType `json:"task_statuses"`     // 匹配的任务状态，为空表示不限
+	Channels         []common.NoticeType     `json:"channels"`          // 通知渠道 email, webhook, dingtalk, feishu, wecom
+	EscalateHours    int                     `json:"escalate_hours"`    // 任务失败超过该小时数仍未处理时升级通知，0表示不升级
+	EscalateChannels []common.NoticeType     `json:"escalate_channels"` // 升级通知的渠道，为空时使用通知渠道
+	EscalateRelevant []string                `json:"escalate_relevant"` // 升级通知时额外通知的人(用户名或邮箱)
+}
+
+func (c *NotifyRouteService) CheckParameters(_ *gin.Context) (bool, common.ServiceCode, error) {
+	if len(c.Name) == 0 || len(c.Name) >= 1024 {
+		return false, common.CodeNotifyRouteNameErr, fmt.Errorf("validate notify route name(%s) not pass", c.Name)
+	}
+	for _, g := range c.Governs {
+		if !common.CheckGovernType(g) {
+			return false, common.CodeNotifyRouteMatchErr, fmt.Errorf("validate govern(%s) not pass", g)
+		}
+	}
+	for _, s := range c.TaskStatuses {
+		if !common.CheckTaskStatusType(s) {
+			return false, common.CodeNotifyRouteMatchErr, fmt.Errorf("validate task_status(%s) not pass", s)
+		}
+	}
+
+	c.Channels = utils.RemoveDupElement(c.Channels)
+	if len(c.Channels) == 0 {
+		return false, common.CodeNotifyRouteChannelErr, fmt.Errorf("channels can not be empty")
+	}
+	c.EscalateChannels = utils.RemoveDupElement(c.EscalateChannels)
+	for _, t := range append(append([]common.NoticeType{}, c.Channels...), c.EscalateChannels...) {
+		if !common.CheckNoticeType(t) {
+			return false, common.CodeNotifyRouteChannelErr, fmt.Errorf("validate channel(%s) not pass", t)
+		}
+	}
+
+	if c.EscalateHours < 0 {
+		return false, common.CodeNotifyRouteEscalateErr, fmt.Errorf("escalate_hours(%d) should not less than 0", c.EscalateHours)
+	}
+	if c.EscalateHours == 0 && (len(c.EscalateChannels) != 0 || len(c.EscalateRelevant) != 0) {
+		return false, common.CodeNotifyRouteEscalateErr, fmt.Errorf("escalate_hours should greater than 0 when escalate_channels or escalate_relevant is set")
+	}
+	return true, common.CodeOK, nil
+}
+
+func (c *NotifyRouteService) checkConflict(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	var count int64
+	err := db.Model(&models.NotifyRoute{}).Where("name =? AND id !=?", c.Name, c.ID).Count(&count).Error
+	if err != nil {
+		log.Errorf("query models.NotifyRoute(name =%v AND id !=%v) from db failed, %s", c.Name, c.ID, err)
+		return common.CodeServerErr, err
+	}
+	if count != 0 {
+		err = fmt.Errorf("models.NotifyRoute(name=%s) exist", c.Name)
+		log.Error(err)
+		return common.CodeNotifyRouteNameConflict, err
+	}
+	return common.CodeOK, nil
+}
+
+func (c *NotifyRouteService) CreateNotifyRoute(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	c.ID = 0
+	if res, err := c.checkConflict(ctx); res != common.CodeOK {
+		return res, err
+	}
+
+	route := c.ServiceToModel()
+	route.CreatedAt = time.Now()
+	route.Creator = u.UserName
+	err := db.Save(route).Error
+	if err != nil {
+		log.Errorf("save models.NotifyRoute(%+v) to db failed, %s", route, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(route)
+	return common.CodeOK, nil
+}
+
+// UpdateNotifyRoute 修改通知路由规则，未传的匹配条件和渠道保持不变，传空数组表示清空
+func (c *NotifyRouteService) UpdateNotifyRoute(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	route := &models.NotifyRoute{}
+	err := db.Model(route).First(route, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("query models.NotifyRoute(id=%d) not exist", c.ID)
+			return common.CodeNotifyRouteNotExist, err
+		}
+		log.Errorf("query models.NotifyRoute(id=%d) from db failed, %s", c.ID, err)
+		return common.CodeServerErr, err
+	}
+
+	old := &NotifyRouteService{}
+	old.ModelToService(route)
+	c.Name = utils.Ternary[string](c.Name == "", old.Name, c.Name)
+	c.Bus = utils.Ternary[[]string](c.Bus == nil, old.Bus, c.Bus)
+	c.ClusterIDs = utils.Ternary[[]string](c.ClusterIDs == nil, old.ClusterIDs, c.ClusterIDs)
+	c.Governs = utils.Ternary[[]common.GovernType](c.Governs == nil, old.Governs, c.Governs)
+	c.TaskStatuses = utils.Ternary[[]common.TaskStatusType](c.TaskStatuses == nil, old.TaskStatuses, c.TaskStatuses)
+	c.Channels = utils.Ternary[[]common.NoticeType](c.Channels == nil, old.Channels, c.Channels)
+	c.EscalateChannels = utils.Ternary[[]common.NoticeType](c.EscalateChannels == nil, old.EscalateChannels, c.EscalateChannels)
+	c.EscalateRelevant = utils.Ternary[[]string](c.EscalateRelevant == nil, old.EscalateRelevant, c.EscalateRelevant)
+
+	// 参数校验
+	if ok, res, err := c.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", c, err)
+		return res, err
+	}
+
+	if res, err := c.checkConflict(ctx); res != common.CodeOK {
+		return res, err
+	}
+
+	newRoute := c.ServiceToModel()
+	newRoute.CreatedAt = route.CreatedAt
+	newRoute.Creator = route.Creator
+	newRoute.Editor = u.UserName
+	err = db.Save(newRoute).Error
+	if err != nil {
+		log.Errorf("update models.NotifyRoute(%+v) from db failed, %s", newRoute, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(newRoute)
+	return common.CodeOK, nil
+}
+
+func (c *NotifyRouteService) DeleteNotifyRoute(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	route := &models.NotifyRoute{}
+	err := db.Model(route).First(route, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("query models.NotifyRoute(id=%d) not exist", c.ID)
+			log.Error(err)
+			return common.CodeNotifyRouteNotExist, err
+		}
+		err = fmt.Errorf("query models.NotifyRoute(id=%d) from db failed, %s", c.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	db.Model(&models.NotifyRoute{}).Where("id =?", route.ID).Update("editor", u.UserName)
+	err = db.Delete(&models.NotifyRoute{}, "id =?", route.ID).Error
+	if err != nil {
+		err = fmt.Errorf("delete models.NotifyRoute(id=%d) from db failed, %s", route.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	return common.CodeOK, nil
+}
+
+func (c *NotifyRouteService) QueryNotifyRoute(ctx *gin.Context, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.NotifyRoute](db).
+		QueryPaging(ctx).
+		Order("id desc").
+		Query(
+			common.FilterFuzzyStringMap(queryMap),
+			common.FilterID(c.ID),
+		)
+	if err != nil {
+		err = fmt.Errorf("query models.NotifyRoute from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]NotifyRouteService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		r := &NotifyRouteService{}
+		r.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *r)
+	}
+	return ret, common.CodeOK, nil
+}
+
+// Match 任务是否满足规则的匹配条件，未设置的条件不限
+func (c *NotifyRouteService) Match(task *models.Task) bool {
+	return (len(c.Bus) == 0 || utils.ElementExist(task.SrcBu, c.Bus)) &&
+		(len(c.ClusterIDs) == 0 || utils.ElementExist(task.SrcClusterID, c.ClusterIDs)) &&
+		(len(c.Governs) == 0 || utils.ElementExist(task.Govern, c.Governs)) &&
+		(len(c.TaskStatuses) == 0 || utils.ElementExist(task.TaskStatus, c.TaskStatuses))
+}
+
+// QueryEnabledNotifyRoutes 查询所有生效的通知路由规则
+func QueryEnabledNotifyRoutes(db *gorm.DB) ([]NotifyRouteService, error) {
+	var routes []models.NotifyRoute
+	err := db.Model(&models.NotifyRoute{}).Where("enable =?", true).Order("id").Find(&routes).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.NotifyRoute(enable=true) from db failed, %s", err)
+	}
+
+	ret := make([]NotifyRouteService, 0, len(routes))
+	for i := range routes {
+		r := &NotifyRouteService{}
+		ret = append(ret, *r.ModelToService(&routes[i]))
+	}
+	return ret, nil
+}
+
+// TaskNoticeChannels 按通知路由规则计算任务的通知渠道，没有规则匹配时使用全局配置的通知方式
+func TaskNoticeChannels(ctx *common.Context, task *models.Task) []common.NoticeType {
+	routes, err := QueryEnabledNotifyRoutes(ctx.DB)
+	if err != nil {
+		ctx.Log.Error(err)
+	}
+	return matchedNoticeChannels(routes, task, Cfg.Notice)
+}
+
+// matchedNoticeChannels 合并所有匹配的规则的通知渠道，没有规则匹配时使用def
+func matchedNoticeChannels(routes []NotifyRouteService, task *models.Task, def common.NoticeType) []common.NoticeType {
+	var channels []common.NoticeType
+	for i := range routes {
+		if routes[i].Match(task) {
+			channels = append(channels, routes[i].Channels...)
+		}
+	}
+	if len(channels) == 0 {
+		return []common.NoticeType{def}
+	}
+	return utils.RemoveDupElement(channels)
+}
+
+// SendTaskNotice 向任务的关注人发送通知，通知渠道由通知路由规则决定，任务的通知策略为不通知时不发送
+func SendTaskNotice(ctx *common.Context, task *models.Task) {
+	if task.NotifyPolicy == common.NotifyPolicyTypeSilence {
+		return
+	}
+
+	msg := notice.GenerateMessage(ctx, task)
+	sendNotice(ctx, TaskNoticeChannels(ctx, task), msg)
+}
+
+// sendNotice 异步向各个渠道发送通知，某个渠道发送慢或失败不影响其他渠道和调用方
+func sendNotice(ctx *common.Context, channels []common.NoticeType, msg *message.Message) {
+	log := ctx.Log
+
+	cfg := Cfg.ServiceToModel()
+	for _, t := range channels {
+		if ctx.Wg != nil {
+			ctx.Wg.Add(1)
+		}
+		go func(t common.NoticeType) {
+			if ctx.Wg != nil {
+				defer ctx.Wg.Done()
+			}
+			err := notice.NewDriverByType(cfg, t).Send(ctx, msg)
+			if err != nil {
+				log.Errorf("send %s notice(%+v) failed, %s", t, msg, err)
+			} else {
+				log.Debugf("send %s notice(%+v) success", t, msg)
+			}
+		}(t)
+	}
+}
+
+// EscalateTaskNotice 任务进入失败状态超过规则设置的时长仍未处理时升级通知，同一个任务的同一次失败每条规则只升级一次
+func EscalateTaskNotice(ctx *common.Context) {
+	log, db := ctx.Log, ctx.DB
+
+	routes, err := QueryEnabledNotifyRoutes(db)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	escalateRoutes := make([]*NotifyRouteService, 0, len(routes))
+	for i := range routes {
+		if routes[i].EscalateHours > 0 {
+			escalateRoutes = append(escalateRoutes, &routes[i])
+		}
+	}
+	if len(escalateRoutes) == 0 {
+		return
+	}
+
+	var tasks []models.Task
+	err = db.Model(&models.Task{}).
+		Where("task_status IN (?) AND notify_policy !=?", common.TaskStatusFailed, common.NotifyPolicyTypeSilence).
+		Find(&tasks).Error
+	if err != nil {
+		log.Errorf("query models.Task(task_status IN %v) from db failed, %s", common.TaskStatusFailed, err)
+		return
+	}
+
+	now := time.Now()
+	for i := range tasks {
+		task := &tasks[i]
+
+		var logs []models.TaskChangeLog
+		err = db.Model(&models.TaskChangeLog{}).Select("id, time, task_status").
+			Where("task_id =?", task.ID).Order("id").Find(&logs).Error
+		if err != nil {
+			log.Errorf("query models.TaskChangeLog(task_id=%v) from db failed, %s", task.ID, err)
+			continue
+		}
+		failedAt := taskFailedAt(logs, task.UpdatedAt)
+
+		for _, route := range escalateRoutes {
+			if !route.Match(task) || !escalationDue(failedAt, now, route.EscalateHours) {
+				continue
+			}
+
+			var count int64
+			err = db.Model(&models.NotifyEscalation{}).
+				Where("task_id =? AND route_id =? AND failed_at =?", task.ID, route.ID, failedAt).
+				Count(&count).Error
+			if err != nil {
+				log.Errorf("query models.NotifyEscalation(task_id=%v, route_id=%v) from db failed, %s", task.ID, route.ID, err)
+				continue
+			}
+			if count != 0 {
+				continue
+			}
+			err = db.Save(&models.NotifyEscalation{TaskID: task.ID, RouteID: route.ID, FailedAt: failedAt, Time: now}).Error
+			if err != nil {
+				log.Errorf("save models.NotifyEscalation(task_id=%v, route_id=%v) to db failed, %s", task.ID, route.ID, err)
+				continue
+			}
+
+			// 升级记录不能通过CreateTaskChangeLog写入，否则会按任务状态再次触发普通通知
+			content := fmt.Sprintf(common.TaskChangeLogNotifyEscalate, common.TaskStatusCN[task.TaskStatus], route.EscalateHours, route.Name)
+			err = db.Save(&models.TaskChangeLog{
+				TaskID:     task.ID,
+				TaskStatus: task.TaskStatus,
+				Time:       now,
+				UserName:   common.SystemUserName,
+				Content:    content,
+			}).Error
+			if err != nil {
+				log.Errorf("save models.TaskChangeLog(task_id=%v) to db failed, %s", task.ID, err)
+			}
+
+			escalateTask := *task
+			if len(route.EscalateRelevant) != 0 {
+				var relevant []string
+				_ = json.Unmarshal(task.Relevant, &relevant)
+				escalateTask.Relevant, _ = json.Marshal(utils.RemoveDupElement(append(relevant, route.EscalateRelevant...)))
+			}
+			escalateTask.TaskReason = content + utils.Ternary[string](task.TaskReason == "", "", "，"+task.TaskReason)
+
+			msg := notice.GenerateMessage(ctx, &escalateTask)
+			sendNotice(ctx, utils.Ternary[[]common.NoticeType](len(route.EscalateChannels) == 0, route.Channels, route.EscalateChannels), msg)
+		}
+	}
+}
+
+// taskFailedAt 根据按ID排序的变化记录计算任务最近一次进入失败状态的时间，
+// 失败后重试又失败的从最后一次失败开始计算，没有失败记录时使用def
+func taskFailedAt(logs []models.TaskChangeLog, def time.Time) time.Time {
+	failedAt := time.Time{}
+	for _, l := range logs {
+		if !utils.ElementExist(l.TaskStatus, common.TaskStatusFailed) {
+			failedAt = time.Time{}
+			continue
+		}
+		if failedAt.IsZero() {
+			failedAt = l.Time
+		}
+	}
+	if failedAt.IsZero() {
+		return def
+	}
+	return failedAt
+}
+
+// escalationDue 任务进入失败状态是否已超过hours小时
+func escalationDue(failedAt, now time.Time, hours int) bool {
+	return hours > 0 && !now.Before(failedAt.Add(time.Duration(hours)*time.Hour))
+}
+
+func (c *NotifyRouteService) ServiceToModel() *models.NotifyRoute {
+	m := &models.NotifyRoute{}
+	m.ID = c.ID
+	m.Creator = c.Creator
+	m.Editor = c.Editor
+	m.CreatedAt, _ = time.ParseInLocation(time.DateTime, c.CreatedAt, time.Now().Location())
+	m.UpdatedAt, _ = time.ParseInLocation(time.DateTime, c.UpdatedAt, time.Now().Location())
+	m.Name = c.Name
+	m.Description = c.Description
+	m.Enable = c.Enable
+	m.Bus, _ = json.Marshal(utils.Ternary[[]string](c.Bus == nil, []string{}, c.Bus))
+	m.ClusterIDs, _ = json.Marshal(utils.Ternary[[]string](c.ClusterIDs == nil, []string{}, c.ClusterIDs))
+	m.Governs, _ = json.Marshal(utils.Ternary[[]common.GovernType](c.Governs == nil, []common.GovernType{}, c.Governs))
+	m.TaskStatuses, _ = json.Marshal(utils.Ternary[[]common.TaskStatusType](c.TaskStatuses == nil, []common.TaskStatusType{}, c.TaskStatuses))
+	m.Channels, _ = json.Marshal(utils.Ternary[[]common.NoticeType](c.Channels == nil, []common.NoticeType{}, c.Channels))
+	m.EscalateHours = c.EscalateHours
+	m.EscalateChannels, _ = json.Marshal(utils.Ternary[[]common.NoticeType](c.EscalateChannels == nil, []common.NoticeType{}, c.EscalateChannels))
+	m.EscalateRelevant, _ = json.Marshal(utils.Ternary[[]string](c.EscalateRelevant == nil, []string{}, c.EscalateRelevant))
+	return m
+}
+
+func (c *NotifyRouteService) ModelToService(m *models.NotifyRoute) *NotifyRouteService {
+	c.ID = m.ID
+	c.Creator = m.Creator
+	c.Editor = m.Editor
+	c.CreatedAt = m.CreatedAt.Format(time.DateTime)
+	c.UpdatedAt = m.UpdatedAt.Format(time.DateTime)
+	c.Name = m.Name
+	c.Description = m.Description
+	c.Enable = m.Enable
+	_ = json.Unmarshal(m.Bus, &c.Bus)
+	_ = json.Unmarshal(m.ClusterIDs, &c.ClusterIDs)
+	_ = json.Unmarshal(m.Governs, &c.Governs)
+	_ = json.Unmarshal(m.TaskStatuses, &c.TaskStatuses)
+	_ = json.Unmarshal(m.Channels, &c.Channels)
+	c.EscalateHours = m.EscalateHours
+	_ = json.Unmarshal(m.EscalateChannels, &c.EscalateChannels)
+	_ = json.Unmarshal(m.EscalateRelevant, &c.EscalateRelevant)
+	return c
+}
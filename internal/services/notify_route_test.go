@@ -0,0 +1,161 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+func TestNotifyRouteMatch(t *testing.T) {
+	task := &models.Task{
+		SrcBu:        "pay",
+		SrcClusterID: "c1",
+		Govern:       common.GovernTypeDelete,
+		TaskStatus:   common.TaskStatusExecFailed,
+	}
+	tests := []struct {
+		name  string
+		route NotifyRouteService
+		want  bool
+	}{
+		{"no condition", NotifyRouteService{}, true},
+		{"bu match", NotifyRouteService{Bus: []string{"order", "pay"}}, true},
+		{"bu not match", NotifyRouteService{Bus: []string{"order"}}, false},
+		{"cluster not match", NotifyRouteService{ClusterIDs: []string{"c2"}}, false},
+		{"govern not match", NotifyRouteService{Governs: []common.GovernType{common.GovernTypeArchive}}, false},
+		{"status match", NotifyRouteService{TaskStatuses: []common.TaskStatusType{common.TaskStatusExecFailed}}, true},
+		{"status not match", NotifyRouteService{TaskStatuses: []common.TaskStatusType{common.TaskStatusSuccess}}, false},
+		{
+			name: "all match",
+			route: NotifyRouteService{Bus: []string{"pay"}, ClusterIDs: []string{"c1"},
+				Governs: []common.GovernType{common.GovernTypeDelete}, TaskStatuses: []common.TaskStatusType{common.TaskStatusExecFailed}},
+			want: true,
+		},
+		{
+			name:  "one condition not match",
+			route: NotifyRouteService{Bus: []string{"pay"}, ClusterIDs: []string{"c2"}},
+			want:  false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.route.Match(task); got != test.want {
+				t.Fatalf("Match() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchedNoticeChannels(t *testing.T) {
+	task := &models.Task{SrcBu: "pay", SrcClusterID: "c1"}
+	tests := []struct {
+		name   string
+		routes []NotifyRouteService
+		want   []common.NoticeType
+	}{
+		{"no route", nil, []common.NoticeType{common.NoticeTypeEmail}},
+		{
+			name:   "no route match",
+			routes: []NotifyRouteService{{Bus: []string{"order"}, Channels: []common.NoticeType{common.NoticeTypeWebhook}}},
+			want:   []common.NoticeType{common.NoticeTypeEmail},
+		},
+		{
+			name: "merge and remove duplicate",
+			routes: []NotifyRouteService{
+				{Bus: []string{"pay"}, Channels: []common.NoticeType{common.NoticeTypeDingTalk, common.NoticeTypeWebhook}},
+				{Bus: []string{"order"}, Channels: []common.NoticeType{common.NoticeTypeFeishu}},
+				{ClusterIDs: []string{"c1"}, Channels: []common.NoticeType{common.NoticeTypeWebhook, common.NoticeTypeWeCom}},
+			},
+			want: []common.NoticeType{common.NoticeTypeDingTalk, common.NoticeTypeWebhook, common.NoticeTypeWeCom},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := matchedNoticeChannels(test.routes, task, common.NoticeTypeEmail)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("matchedNoticeChannels() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTaskFailedAt(t *testing.T) {
+	def := parseLocalTime("2024-01-10 00:00:00")
+	log := func(status common.TaskStatusType, s string) models.TaskChangeLog {
+		return models.TaskChangeLog{TaskStatus: status, Time: parseLocalTime(s)}
+	}
+	tests := []struct {
+		name string
+		logs []models.TaskChangeLog
+		want string
+	}{
+		{"no log", nil, "2024-01-10 00:00:00"},
+		{"no failed log", []models.TaskChangeLog{log(common.TaskStatusWaiting, "2024-01-01 00:00:00")}, "2024-01-10 00:00:00"},
+		{
+			name: "failed",
+			logs: []models.TaskChangeLog{
+				log(common.TaskStatusExecuting, "2024-01-01 00:00:00"),
+				log(common.TaskStatusExecFailed, "2024-01-01 01:00:00"),
+				log(common.TaskStatusExecFailed, "2024-01-01 05:00:00"),
+			},
+			want: "2024-01-01 01:00:00",
+		},
+		{
+			name: "failed between failed statuses",
+			logs: []models.TaskChangeLog{
+				log(common.TaskStatusExecCheckFailed, "2024-01-01 01:00:00"),
+				log(common.TaskStatusTimeout, "2024-01-01 02:00:00"),
+			},
+			want: "2024-01-01 01:00:00",
+		},
+		{
+			name: "retry and failed again",
+			logs: []models.TaskChangeLog{
+				log(common.TaskStatusExecFailed, "2024-01-01 01:00:00"),
+				log(common.TaskStatusExecuting, "2024-01-02 00:00:00"),
+				log(common.TaskStatusExecFailed, "2024-01-02 03:00:00"),
+			},
+			want: "2024-01-02 03:00:00",
+		},
+		{
+			name: "not failed now",
+			logs: []models.TaskChangeLog{
+				log(common.TaskStatusExecFailed, "2024-01-01 01:00:00"),
+				log(common.TaskStatusExecuting, "2024-01-02 00:00:00"),
+			},
+			want: "2024-01-10 00:00:00",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := taskFailedAt(test.logs, def).Format(time.DateTime); got != test.want {
+				t.Fatalf("taskFailedAt() got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEscalationDue(t *testing.T) {
+	failedAt := parseLocalTime("2024-01-01 01:00:00")
+	tests := []struct {
+		name  string
+		now   string
+		hours int
+		want  bool
+	}{
+		{"not reached", "2024-01-01 02:59:59", 2, false},
+		{"just reached", "2024-01-01 03:00:00", 2, true},
+		{"exceeded", "2024-01-02 00:00:00", 2, true},
+		{"disabled", "2024-01-02 00:00:00", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := escalationDue(failedAt, parseLocalTime(test.now), test.hours); got != test.want {
+				t.Fatalf("escalationDue() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	partitionMethodRange        = "RANGE"
+	partitionMethodRangeColumns = "RANGE COLUMNS"
+	partitionMaxValue           = "MAXVALUE"
+
+	// toDaysOfUnixEpoch TO_DAYS('1970-01-01')
+	toDaysOfUnixEpoch = 719528
+)
+
+// 分区表达式的类型
+const (
+	partitionExprColumn        = "column"         // RANGE COLUMNS(col)
+	partitionExprToDays        = "to_days"        // RANGE(TO_DAYS(col))
+	partitionExprUnixTimestamp = "unix_timestamp" // RANGE(UNIX_TIMESTAMP(col))
+	partitionExprYear          = "year"           // RANGE(YEAR(col))
+)
+
+// PartitionPlan 分区治理的执行计划
+type PartitionPlan struct {
+	Table      string   `json:"table"`      // 表名
+	Expired    []string `json:"expired"`    // 过期需要删除或交换的分区
+	Created    []string `json:"created"`    // 需要预创建的分区
+	Statements []string `json:"statements"` // 需要执行的SQL
+}
+
+// partitionExprType 解析分区表达式，返回表达式类型，不是按指定列分区时返回错误
+func partitionExprType(p *mysql.PartitionSchema, column string) (string, error) {
+	expr := strings.ToLower(strings.NewReplacer("`", "", " ", "").Replace(p.Expression))
+	column = strings.ToLower(column)
+
+	switch strings.ToUpper(p.Method) {
+	case partitionMethodRangeColumns:
+		if expr == column {
+			return partitionExprColumn, nil
+		}
+	case partitionMethodRange:
+		for _, fn := range []string{partitionExprToDays, partitionExprUnixTimestamp, partitionExprYear} {
+			if expr == fmt.Sprintf("%s(%s)", fn, column) {
+				return fn, nil
+			}
+		}
+	default:
+		return "", fmt.Errorf("partition method(%s) is not RANGE or RANGE COLUMNS", p.Method)
+	}
+	return "", fmt.Errorf("partition expression(%s) is not on column(%s)", p.Expression, column)
+}
+
+// parsePartitionBound 将分区上界转换为时间，MAXVALUE返回true
+func parsePartitionBound(exprType, desc string) (time.Time, bool, error) {
+	desc = strings.TrimSpace(desc)
+	if strings.EqualFold(desc, partitionMaxValue) {
+		return time.Time{}, true, nil
+	}
+
+	switch exprType {
+	case partitionExprColumn:
+		s := strings.Trim(desc, "'")
+		if t, err := time.ParseInLocation(time.DateTime, s, time.Local); err == nil {
+			return t, false, nil
+		}
+		t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+		return t, false, err
+	case partitionExprToDays:
+		n, err := strconv.ParseInt(desc, 10, 64)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		t := time.Unix((n-toDaysOfUnixEpoch)*86400, 0).UTC()
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), false, nil
+	case partitionExprUnixTimestamp:
+		n, err := strconv.ParseInt(desc, 10, 64)
+		return time.Unix(n, 0), false, err
+	case partitionExprYear:
+		n, err := strconv.Atoi(desc)
+		return time.Date(n, 1, 1, 0, 0, 0, 0, time.Local), false, err
+	default:
+		return time.Time{}, false, fmt.Errorf("unsupported partition expression type(%s)", exprType)
+	}
+}
+
+// formatPartitionBound 将时间转换为分区上界，sample为已有分区的上界，用于保持日期格式一致
+func formatPartitionBound(exprType, sample string, t time.Time) string {
+	switch exprType {
+	case partitionExprColumn:
+		if strings.Contains(sample, ":") {
+			return "'" + t.Format(time.DateTime) + "'"
+		}
+		return "'" + t.Format(time.DateOnly) + "'"
+	case partitionExprToDays:
+		days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		return strconv.FormatInt(days+toDaysOfUnixEpoch, 10)
+	case partitionExprUnixTimestamp:
+		return strconv.FormatInt(t.Unix(), 10)
+	case partitionExprYear:
+		return strconv.Itoa(t.Year())
+	default:
+		return ""
+	}
+}
+
+// truncatePartitionInterval 取时间所在分区周期的开始时间
+func truncatePartitionInterval(t time.Time, interval common.PartitionIntervalType) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case common.PartitionIntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case common.PartitionIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case common.PartitionIntervalYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
+
+func addPartitionInterval(t time.Time, interval common.PartitionIntervalType, n int) time.Time {
+	switch interval {
+	case common.PartitionIntervalWeek:
+		return t.AddDate(0, 0, 7*n)
+	case common.PartitionIntervalMonth:
+		return t.AddDate(0, n, 0)
+	case common.PartitionIntervalYear:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+func partitionName(start time.Time, interval common.PartitionIntervalType) string {
+	switch interval {
+	case common.PartitionIntervalMonth:
+		return "p" + start.Format("200601")
+	case common.PartitionIntervalYear:
+		return "p" + start.Format("2006")
+	default:
+		return "p" + start.Format("20060102")
+	}
+}
+
+// PlanPartitions 根据分区元数据生成分区治理需要执行的SQL：
+// 上界早于保留期的分区删除或交换到归档表，并预创建未来precreate个周期的分区。
+// 交换分区时通过exchangeState查询分区和归档表的当前状态，任务重试时跳过上次已经完成的步骤，exchangeState为空时视为归档表不存在
+func PlanPartitions(table, column string, partitions []mysql.PartitionSchema, interval common.PartitionIntervalType,
+	retainDays, precreate int, action common.PartitionActionType, now time.Time,
+	exchangeState func(partition, archive string) (*mysql.ExchangeState, error)) (*PartitionPlan, error) {
+	plan := &PartitionPlan{Table: table}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("table(%s) is not partitioned", table)
+	}
+
+	exprType, err := partitionExprType(&partitions[0], column)
+	if err != nil {
+		return nil, fmt.Errorf("table(%s) %s", table, err)
+	}
+
+	var (
+		maxValuePartition string
+		lastBound         time.Time
+		sample            string
+		existed           = make(map[string]bool, len(partitions))
+		retainFrom        = truncatePartitionInterval(now, common.PartitionIntervalDay).AddDate(0, 0, -retainDays)
+	)
+	for _, p := range partitions {
+		existed[p.Name] = true
+		bound, isMax, err := parsePartitionBound(exprType, p.Description)
+		if err != nil {
+			return nil, fmt.Errorf("parse table(%s) partition(%s) bound(%s) failed, %s", table, p.Name, p.Description, err)
+		}
+		if isMax {
+			maxValuePartition = p.Name
+			continue
+		}
+		sample = p.Description
+		if bound.After(lastBound) {
+			lastBound = bound
+		}
+		// 分区中的数据都早于保留期才可以处理，至少保留一个分区
+		if !bound.After(retainFrom) && len(plan.Expired) < len(partitions)-1 {
+			plan.Expired = append(plan.Expired, p.Name)
+		}
+	}
+
+	for _, p := range plan.Expired {
+		if action == common.PartitionActionExchange {
+			archive := fmt.Sprintf("%s_%s", table, p)
+			state := &mysql.ExchangeState{PartitionHasRows: true}
+			if exchangeState != nil {
+				if state, err = exchangeState(p, archive); err != nil {
+					return nil, err
+				}
+			}
+			statements, err := exchangeStatements(table, p, archive, state)
+			if err != nil {
+				return nil, err
+			}
+			plan.Statements = append(plan.Statements, statements...)
+		}
+	}
+	if len(plan.Expired) != 0 {
+		plan.Statements = append(plan.Statements,
+			fmt.Sprintf("ALTER TABLE `%s` DROP PARTITION `%s`", table, strings.Join(plan.Expired, "`, `")))
+	}
+
+	// 预创建分区直到覆盖未来precreate个周期
+	if precreate > 0 && !lastBound.IsZero() {
+		target := addPartitionInterval(truncatePartitionInterval(now, interval), interval, precreate+1)
+		var defs []string
+		for start := lastBound; start.Before(target); {
+			end := addPartitionInterval(truncatePartitionInterval(start, interval), interval, 1)
+			name := partitionName(start, interval)
+			if !existed[name] {
+				existed[name] = true
+				plan.Created = append(plan.Created, name)
+				defs = append(defs, fmt.Sprintf("PARTITION `%s` VALUES LESS THAN (%s)", name, formatPartitionBound(exprType, sample, end)))
+			}
+			start = end
+		}
+
+		if len(defs) != 0 {
+			if maxValuePartition != "" {
+				defs = append(defs, fmt.Sprintf("PARTITION `%s` VALUES LESS THAN (MAXVALUE)", maxValuePartition))
+				plan.Statements = append(plan.Statements, fmt.Sprintf("ALTER TABLE `%s` REORGANIZE PARTITION `%s` INTO (%s)",
+					table, maxValuePartition, strings.Join(defs, ", ")))
+			} else {
+				plan.Statements = append(plan.Statements, fmt.Sprintf("ALTER TABLE `%s` ADD PARTITION (%s)",
+					table, strings.Join(defs, ", ")))
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// exchangeStatements 生成将分区交换到归档表的SQL，已经完成的步骤不再执行：
+// 归档表已存在时不再创建，归档表已不是分区表时不再移除分区，分区已经没有数据时说明已经交换过不再交换。
+// 再次交换会把归档表中的数据换回分区并随分区一起删除，因此分区和归档表都有数据时报错等待人工处理
+func exchangeStatements(table, partition, archive string, state *mysql.ExchangeState) ([]string, error) {
+	var statements []string
+	if !state.ArchiveExists {
+		statements = append(statements, fmt.Sprintf("CREATE TABLE `%s` LIKE `%s`", archive, table))
+	}
+	if !state.ArchiveExists || state.ArchivePartitioned {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE `%s` REMOVE PARTITIONING", archive))
+	}
+	if !state.PartitionHasRows {
+		return statements, nil
+	}
+	if state.ArchiveHasRows {
+		return nil, fmt.Errorf("table(%s) partition(%s) and archive table(%s) both have data, exchange again will lose the archived data",
+			table, partition, archive)
+	}
+	return append(statements, fmt.Sprintf("ALTER TABLE `%s` EXCHANGE PARTITION `%s` WITH TABLE `%s`", table, partition, archive)), nil
+}
+
+// CheckPartitionTables 检查源端表都是按指定列RANGE分区的表
+func CheckPartitionTables(ctx *gin.Context, c *ClusterService, database string, tables []string, column string) (common.ServiceCode, error) {
+	for _, table := range tables {
+		partitions, code, err := NewClusterDriver(c).GetTablePartitions(ctx, database, table)
+		if err != nil {
+			return code, err
+		}
+		if len(partitions) == 0 {
+			return common.CodePolicyNotPartitioned, fmt.Errorf("table(%s.%s) is not partitioned", database, table)
+		}
+
+		exprType, err := partitionExprType(&partitions[0], column)
+		if err != nil {
+			return common.CodePolicyNotPartitioned, fmt.Errorf("table(%s.%s) %s", database, table, err)
+		}
+		for _, p := range partitions {
+			if _, _, err = parsePartitionBound(exprType, p.Description); err != nil {
+				return common.CodePolicyNotPartitioned,
+					fmt.Errorf("parse table(%s.%s) partition(%s) bound(%s) failed, %s", database, table, p.Name, p.Description, err)
+			}
+		}
+	}
+	return common.CodeOK, nil
+}
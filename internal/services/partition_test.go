@@ -0,0 +1,175 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
+)
+
+func TestPlanPartitions(t *testing.T) {
+	monthly := []mysql.PartitionSchema{
+		{Name: "p202401", Method: "RANGE COLUMNS", Expression: "`dt`", Description: "'2024-02-01'"},
+		{Name: "p202402", Method: "RANGE COLUMNS", Expression: "`dt`", Description: "'2024-03-01'"},
+		{Name: "p202403", Method: "RANGE COLUMNS", Expression: "`dt`", Description: "'2024-04-01'"},
+		{Name: "pmax", Method: "RANGE COLUMNS", Expression: "`dt`", Description: "MAXVALUE"},
+	}
+	daily := []mysql.PartitionSchema{
+		{Name: "p20240312", Method: "RANGE", Expression: "to_days(`dt`)", Description: "739323"},
+		{Name: "p20240313", Method: "RANGE", Expression: "to_days(`dt`)", Description: "739324"},
+		{Name: "p20240314", Method: "RANGE", Expression: "to_days(`dt`)", Description: "739325"},
+	}
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local)
+	exchange := []string{
+		"CREATE TABLE `t_p202401` LIKE `t`",
+		"ALTER TABLE `t_p202401` REMOVE PARTITIONING",
+		"ALTER TABLE `t` EXCHANGE PARTITION `p202401` WITH TABLE `t_p202401`",
+		"ALTER TABLE `t` DROP PARTITION `p202401`",
+	}
+
+	tests := []struct {
+		name       string
+		column     string
+		partitions []mysql.PartitionSchema
+		interval   common.PartitionIntervalType
+		retainDays int
+		precreate  int
+		action     common.PartitionActionType
+		state      *mysql.ExchangeState
+		wantErr    bool
+		want       []string
+	}{
+		{
+			name:       "monthly drop and reorganize maxvalue",
+			column:     "dt",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 30,
+			precreate:  2,
+			action:     common.PartitionActionDrop,
+			want: []string{
+				"ALTER TABLE `t` DROP PARTITION `p202401`",
+				"ALTER TABLE `t` REORGANIZE PARTITION `pmax` INTO (PARTITION `p202404` VALUES LESS THAN ('2024-05-01'), " +
+					"PARTITION `p202405` VALUES LESS THAN ('2024-06-01'), PARTITION `pmax` VALUES LESS THAN (MAXVALUE))",
+			},
+		},
+		{
+			name:       "daily to_days drop and add",
+			column:     "dt",
+			partitions: daily,
+			interval:   common.PartitionIntervalDay,
+			retainDays: 1,
+			precreate:  2,
+			action:     common.PartitionActionDrop,
+			want: []string{
+				"ALTER TABLE `t` DROP PARTITION `p20240312`, `p20240313`",
+				"ALTER TABLE `t` ADD PARTITION (PARTITION `p20240315` VALUES LESS THAN (739326), " +
+					"PARTITION `p20240316` VALUES LESS THAN (739327), PARTITION `p20240317` VALUES LESS THAN (739328))",
+			},
+		},
+		{
+			name:       "keep at least one partition",
+			column:     "dt",
+			partitions: daily,
+			interval:   common.PartitionIntervalDay,
+			retainDays: 0,
+			action:     common.PartitionActionDrop,
+			want:       []string{"ALTER TABLE `t` DROP PARTITION `p20240312`, `p20240313`"},
+		},
+		{
+			name:       "nothing to do",
+			column:     "dt",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 365,
+			action:     common.PartitionActionDrop,
+		},
+		{
+			name:       "exchange",
+			column:     "dt",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 30,
+			action:     common.PartitionActionExchange,
+			want:       exchange,
+		},
+		{
+			name:       "exchange retry after create",
+			column:     "dt",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 30,
+			action:     common.PartitionActionExchange,
+			state:      &mysql.ExchangeState{ArchiveExists: true, ArchivePartitioned: true, PartitionHasRows: true},
+			want:       exchange[1:],
+		},
+		{
+			name:       "exchange retry after remove partitioning",
+			column:     "dt",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 30,
+			action:     common.PartitionActionExchange,
+			state:      &mysql.ExchangeState{ArchiveExists: true, PartitionHasRows: true},
+			want:       exchange[2:],
+		},
+		{
+			name:       "exchange retry after exchange",
+			column:     "dt",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 30,
+			action:     common.PartitionActionExchange,
+			state:      &mysql.ExchangeState{ArchiveExists: true, ArchiveHasRows: true},
+			want:       exchange[3:],
+		},
+		{
+			name:       "exchange both have data",
+			column:     "dt",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 30,
+			action:     common.PartitionActionExchange,
+			state:      &mysql.ExchangeState{ArchiveExists: true, ArchiveHasRows: true, PartitionHasRows: true},
+			wantErr:    true,
+		},
+		{
+			name:    "not partitioned",
+			column:  "dt",
+			action:  common.PartitionActionDrop,
+			wantErr: true,
+		},
+		{
+			name:       "other column",
+			column:     "created_at",
+			partitions: monthly,
+			interval:   common.PartitionIntervalMonth,
+			retainDays: 30,
+			action:     common.PartitionActionDrop,
+			wantErr:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var exchangeState func(partition, archive string) (*mysql.ExchangeState, error)
+			if test.state != nil {
+				exchangeState = func(_, _ string) (*mysql.ExchangeState, error) {
+					return test.state, nil
+				}
+			}
+			plan, err := PlanPartitions("t", test.column, test.partitions, test.interval,
+				test.retainDays, test.precreate, test.action, now, exchangeState)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("PlanPartitions() got err %v, want err %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(plan.Statements, test.want) {
+				t.Fatalf("PlanPartitions() got statements\n%q\nwant\n%q", plan.Statements, test.want)
+			}
+		})
+	}
+}
@@ -18,16 +18,20 @@ import (
 type PolicyService struct {
 	// 归档策略元数据信息
 	Model
-	Name          string                   `json:"name"`           // 策略名称
-	Description   string                   `json:"description"`    // 说明
-	Bu            string                   `json:"bu"`             // 资产BU
-	Enable        bool                     `json:"enable"`         // 是否生效
-	Period        common.PeriodType        `json:"period"`         // 执行周期 执行一次:once, 每月一次:monthly, 每季度一次:quarterly, 每半年一次:six-months, 每年一次:yearly
-	Day           int                      `json:"day"`            // 期望在每月的几号执行，对于执行周期小于一个月的任务不生效
-	ExecuteWindow []string                 `json:"execute_window"` // 执行时间窗口 如: [] 或者 ["03:00:00", "05:00:00"]
-	Pause         bool                     `json:"pause"`          // 执行窗口外是否需要暂停执行
-	RebuildFlag   bool                     `json:"rebuild_flag"`   // 执行窗口外是否重建表(仅在治理方式是删除时有效)。true:在执行窗口外仍然走重建流程; false:执行窗口外跳过重建流程
-	CleaningSpeed common.CleaningSpeedType `json:"cleaning_speed"` // 清理速度 稳定优先:steady, 速度适中:balanced, 速度优先:swift
+	Name           string                   `json:"name"`            // 策略名称
+	Description    string                   `json:"description"`     // 说明
+	Bu             string                   `json:"bu"`              // 资产BU
+	Enable         bool                     `json:"enable"`          // 是否生效
+	Period         common.PeriodType        `json:"period"`          // 执行周期 执行一次:once, 每月一次:monthly, 每季度一次:quarterly, 每半年一次:six-months, 每年一次:yearly, 按cron表达式:cron
+	Day            int                      `json:"day"`             // 期望在每月的几号执行，对于执行周期小于一个月的任务不生效
+	CronExpr       string                   `json:"cron_expr"`       // cron表达式(分 时 日 月 周)，仅精确到天，执行时间由执行窗口决定。日支持L(月末)，周支持nL(每月最后一个周n)，如: "0 0 * * 2,4"、"0 0 * * 0L"
+	ExecuteWindow  []string                 `json:"execute_window"`  // 执行时间窗口 如: [] 或者 ["03:00:00", "05:00:00"]
+	ExecuteWindows []common.ExecWindow      `json:"execute_windows"` // 多执行窗口，配置后以此为准，如: [{"start":"01:00:00","end":"05:00:00","weekdays":[1,2,3,4,5]},{"start":"00:00:00","end":"08:00:00","weekdays":[0,6]}]
+	Pause          bool                     `json:"pause"`           // 执行窗口外是否需要暂停执行
+	RebuildFlag    bool                     `json:"rebuild_flag"`    // 执行窗口外是否重建表(仅在治理方式是删除时有效)。true:在执行窗口外仍然走重建流程; false:执行窗口外跳过重建流程
+	CleaningSpeed  common.CleaningSpeedType `json:"cleaning_speed"`  // 清理速度 稳定优先:steady, 速度适中:balanced, 速度优先:swift
+	Priority       int                      `json:"priority"`        // 优先级(1-10)，数值越大越优先执行，默认5
+	ThrottleID     uint                     `json:"throttle_id"`     // 限流配置ID，为0时按清理速度使用内置的限流配置
 
 	// 源端信息
 	SrcID           uint   `json:"src_id"`            // 源端ID
@@ -43,13 +47,213 @@ type PolicyService struct {
 	ArchiveScope  string            `json:"archive_scope"`   // 数据归档范围，归档那些列（仅归档涉及）
 	RetainSrcData bool              `json:"retain_src_data"` // 归档时否保留源表数据默认不保留（仅归档涉及）
 
+	// 分区治理（仅分区治理涉及）
+	PartitionColumn     string                       `json:"partition_column"`      // 分区列，表需按该列RANGE分区
+	PartitionInterval   common.PartitionIntervalType `json:"partition_interval"`    // 分区粒度 按天:day, 按周:week, 按月:month, 按年:year
+	PartitionRetainDays int                          `json:"partition_retain_days"` // 保留最近多少天的分区，早于该时间的分区将被处理
+	PartitionPrecreate  int                          `json:"partition_precreate"`   // 预创建未来多少个周期的分区
+	PartitionAction     common.PartitionActionType   `json:"partition_action"`      // 过期分区处理方式 删除:drop, 交换到归档表后删除:exchange
+
+	// 增量治理（仅删除和归档涉及）
+	WatermarkColumn  string `json:"watermark_column"`  // 水位列(自增主键或时间列)，设置后每次只治理上次处理到的水位之后的数据，更新时为空表示不修改
+	Watermark        string `json:"watermark"`         // 已处理到的水位（只读）
+	ResetWatermark   bool   `json:"reset_watermark"`   // 是否重置水位，重置后下次从头开始治理（仅更新时有效）
+	DisableWatermark bool   `json:"disable_watermark"` // 是否关闭增量治理，关闭后每次按治理条件全量治理（仅更新时有效）
+
 	// 目标端信息
 	DestID       uint `json:"dest_id"`       // 目标端ID
 	ForceArchive bool `json:"force_archive"` // 当目标库、表都存在时强制向目标端归档
 
+	// 策略依赖
+	DependsOn        []uint                      `json:"depends_on"`         // 依赖的策略ID，依赖的策略在同一周期的任务执行成功后本策略的任务才会执行
+	DependFailAction common.DependFailActionType `json:"depend_fail_action"` // 依赖的策略任务执行失败时的处理方式 阻塞等待:block, 跳过本周期:skip, 通知后继续执行:notify
+
 	// 结果通知
 	Relevant     []string                `json:"relevant"`      // 关注人
 	NotifyPolicy common.NotifyPolicyType `json:"notify_policy"` // 通知策略 不通知:silence, 成功时通知:success, 失败时通知:failed, 成功或失败都通知:always
+
+	ConditionWarnings []string `json:"condition_warnings"` // 治理条件执行计划的告警及优化建议（不保存）
+}
+
+// CheckCronExpr 执行周期为cron时校验cron表达式，其他执行周期清空cron表达式
+func (c *PolicyService) CheckCronExpr() (common.ServiceCode, error) {
+	if c.Period != common.PeriodCron {
+		c.CronExpr = ""
+		return common.CodeOK, nil
+	}
+
+	c.CronExpr = strings.TrimSpace(c.CronExpr)
+	if _, err := common.ParseCronExpr(c.CronExpr); err != nil {
+		return common.CodePolicyCronExprErr, fmt.Errorf("validate cron_expr(%s) not pass, %s", c.CronExpr, err)
+	}
+	return common.CodeOK, nil
+}
+
+// CheckDependencies 校验依赖的策略都存在且依赖关系不存在环
+func (c *PolicyService) CheckDependencies(ctx *gin.Context) (common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if c.DependFailAction == "" {
+		c.DependFailAction = common.DependFailActionDefault
+	} else if !common.CheckDependFailAction(c.DependFailAction) {
+		return common.CodePolicyDependFailErr, fmt.Errorf("validate depend_fail_action(%s) not pass", c.DependFailAction)
+	}
+
+	c.DependsOn = utils.RemoveDupElement(c.DependsOn)
+	if len(c.DependsOn) == 0 {
+		return common.CodeOK, nil
+	}
+
+	graph, err := PolicyDependencyGraph(db)
+	if err != nil {
+		return common.CodeServerErr, err
+	}
+
+	for _, id := range c.DependsOn {
+		if id == c.ID {
+			return common.CodePolicyDependencyErr, fmt.Errorf("policy(%v) can not depend on itself", id)
+		}
+		if _, ok := graph[id]; !ok {
+			return common.CodePolicyDependencyErr, fmt.Errorf("depends_on policy(%v) not exist", id)
+		}
+	}
+
+	// 新建的策略不会被其他策略依赖，不会形成环
+	if common.InvalidUintID(c.ID) {
+		return common.CodeOK, nil
+	}
+
+	graph[c.ID] = c.DependsOn
+	if path := findDependencyPath(graph, c.DependsOn, c.ID); len(path) != 0 {
+		return common.CodePolicyDependencyCycle, fmt.Errorf("policy dependency cycle found, policy(%v) depends on %v", c.ID, path)
+	}
+	return common.CodeOK, nil
+}
+
+// PolicyDependencyGraph 查询所有策略的依赖关系: 策略ID -> 依赖的策略ID
+func PolicyDependencyGraph(db *gorm.DB) (map[uint][]uint, error) {
+	var policies []models.Policy
+	err := db.Model(&models.Policy{}).Select("id, depends_on").Find(&policies).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.Policy from db failed, %s", err)
+	}
+
+	graph := make(map[uint][]uint, len(policies))
+	for _, p := range policies {
+		graph[p.ID] = ParsePolicyDependsOn(p.DependsOn)
+	}
+	return graph, nil
+}
+
+// ParsePolicyDependsOn 解析策略依赖的策略ID
+func ParsePolicyDependsOn(dependsOn models.JSON) []uint {
+	var ids []uint
+	_ = json.Unmarshal(dependsOn, &ids)
+	return ids
+}
+
+// DependencyScheduledBefore 依赖的策略在execDate(含)之前是否还会生成任务，lastExecDate为依赖的策略上一个任务的执行日期。
+// 任务经队列异步生成，依赖的策略本周期的任务可能尚未生成，此时本策略的任务需等待
+func DependencyScheduledBefore(upstream *models.Policy, lastExecDate time.Time, execDate string) (bool, error) {
+	// 一次性策略执行过后不会再生成任务
+	if upstream.Period == common.PeriodOnce && !lastExecDate.IsZero() {
+		return false, nil
+	}
+	next, err := PolicyNextScheduleTime(upstream, lastExecDate)
+	if err != nil {
+		return false, err
+	}
+	return FormatExecDate(upstream, next) <= execDate, nil
+}
+
+// findDependencyPath 从from出发沿依赖关系查找到target的路径，找不到时返回nil
+func findDependencyPath(graph map[uint][]uint, from []uint, target uint) []uint {
+	visited := make(map[uint]bool)
+	var dfs func(id uint) []uint
+	dfs = func(id uint) []uint {
+		if id == target {
+			return []uint{id}
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		for _, next := range graph[id] {
+			if path := dfs(next); path != nil {
+				return append([]uint{id}, path...)
+			}
+		}
+		return nil
+	}
+
+	for _, id := range from {
+		if path := dfs(id); path != nil {
+			return path
+		}
+	}
+	return nil
+}
+
+// CheckCondition 分析治理条件的执行计划，不满足要求时根据配置拒绝或仅告警
+func (c *PolicyService) CheckCondition(ctx *gin.Context) (common.ServiceCode, error) {
+	explain := &PolicyExplainService{
+		SrcID:        c.SrcID,
+		Condition:    c.Condition,
+		ArchiveScope: c.ArchiveScope,
+	}
+	code, err := explain.Explain(ctx)
+	if err != nil {
+		return code, err
+	}
+
+	c.ConditionWarnings = make([]string, 0, len(explain.Warnings)+len(explain.Suggestions))
+	c.ConditionWarnings = append(append(c.ConditionWarnings, explain.Warnings...), explain.Suggestions...)
+	if explain.Level == common.ExplainResultReject {
+		return common.CodePolicyConditionReject,
+			fmt.Errorf("check policy condition not pass, %s", strings.Join(c.ConditionWarnings, "; "))
+	}
+	return common.CodeOK, nil
+}
+
+// CheckPartition 校验分区治理参数，并检查源端表都是按分区列RANGE分区的表
+func (c *PolicyService) CheckPartition(ctx *gin.Context) (common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if c.PartitionColumn == "" {
+		return common.CodePolicyPartitionErr, fmt.Errorf("policy.PartitionColumn can not be empty")
+	}
+	if c.PartitionInterval == "" {
+		c.PartitionInterval = common.PartitionIntervalMonth
+	} else if !common.CheckPartitionInterval(c.PartitionInterval) {
+		return common.CodePolicyPartitionErr, fmt.Errorf("validate partition_interval(%s) not pass", c.PartitionInterval)
+	}
+	if c.PartitionAction == "" {
+		c.PartitionAction = common.PartitionActionDrop
+	} else if !common.CheckPartitionAction(c.PartitionAction) {
+		return common.CodePolicyPartitionErr, fmt.Errorf("validate partition_action(%s) not pass", c.PartitionAction)
+	}
+	if c.PartitionRetainDays <= 0 {
+		return common.CodePolicyPartitionErr, fmt.Errorf("validate partition_retain_days(%d) not pass, should greater than 0", c.PartitionRetainDays)
+	}
+	if c.PartitionPrecreate < 0 {
+		return common.CodePolicyPartitionErr, fmt.Errorf("validate partition_precreate(%d) not pass, should not less than 0", c.PartitionPrecreate)
+	}
+
+	src := &models.Source{}
+	err := db.Model(src).Where("id =?", c.SrcID).First(src).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.CodeSourceNotExist, fmt.Errorf("models.Source(%v) not exist", c.SrcID)
+		}
+		return common.CodeServerErr, fmt.Errorf("query models.Source(%v) failed, %s", c.SrcID, err)
+	}
+
+	clusterSvc, err := GetClusterServiceByClusterID(ctx, src.ClusterID)
+	if err != nil {
+		return common.CodeServerErr, err
+	}
+
+	return CheckPartitionTables(ctx, clusterSvc, src.DatabaseName, strings.Split(src.TablesName, ","), c.PartitionColumn)
 }
 
 func (c *PolicyService) CheckParameters(ctx *gin.Context) (bool, common.ServiceCode, error) {
@@ -68,6 +272,10 @@ func (c *PolicyService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 			fmt.Errorf("validate day(%v) not pass, day should in (1-31) when policy.Period greater than one month", c.Day)
 	}
 
+	if code, err := c.CheckCronExpr(); err != nil {
+		return false, code, err
+	}
+
 	if !common.CheckGovernType(c.Govern) {
 		return false, common.CodePolicyGovernErr, fmt.Errorf("validate govern(%s) not pass", c.Govern)
 	}
@@ -78,17 +286,31 @@ func (c *PolicyService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 		return false, common.CodePolicyCleaningSpeedErr, fmt.Errorf("validate cleaning_speed(%s) not pass", c.CleaningSpeed)
 	}
 
+	if code, err := CheckThrottleExist(db, c.ThrottleID); err != nil {
+		return false, code, err
+	}
+
+	if c.Priority == 0 {
+		c.Priority = common.PriorityDefault
+	} else if !common.CheckPriority(c.Priority) {
+		return false, common.CodePolicyPriorityErr, fmt.Errorf("validate priority(%d) not pass", c.Priority)
+	}
+
 	if c.NotifyPolicy == "" {
 		c.NotifyPolicy = common.NotifyPolicyTypeDefault
 	} else if !common.CheckNotifyPolicyType(c.NotifyPolicy) {
 		return false, common.CodePolicyNotifyPolicyErr, fmt.Errorf("validate notify_policy(%s) not pass", c.NotifyPolicy)
 	}
 
-	_, execWin, err := checkExecWindow(c.ExecuteWindow)
+	var err error
+	c.ExecuteWindow, c.ExecuteWindows, err = checkExecWindows(c.ExecuteWindow, c.ExecuteWindows)
 	if err != nil {
 		return false, common.CodePolicyExecuteWindowErr, err
 	}
-	c.ExecuteWindow = execWin
+
+	if code, err := c.CheckDependencies(ctx); err != nil {
+		return false, code, err
+	}
 
 	src := &models.Source{}
 	err = db.Model(src).Where("id =?", c.SrcID).First(src).Error
@@ -150,16 +372,24 @@ func (c *PolicyService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 		}
 	}
 
-	// 判断Condition条件是否正确
-	clusterSvc, err := GetClusterServiceByClusterID(ctx, src.ClusterID)
-	if err != nil {
-		return false, common.CodeServerErr, err
+	if c.Govern == common.GovernTypePartition {
+		// 分区治理按分区整体处理数据，不需要治理条件
+		c.Condition = ""
+		res, err := c.CheckPartition(ctx)
+		if err != nil {
+			return false, res, err
+		}
+	} else {
+		// 判断Condition条件是否正确
+		res, err := c.CheckCondition(ctx)
+		if err != nil {
+			return false, res, err
+		}
 	}
 
-	tableNames := strings.Split(src.TablesName, ",")
-	_, res, err := NewClusterDriver(clusterSvc).SQLExplain(ctx, src.DatabaseName, tableNames[0], c.ArchiveScope, c.Condition)
+	res, err := c.CheckWatermark(ctx)
 	if err != nil {
-		return false, res, fmt.Errorf("check policy condition not pass, %s", err)
+		return false, res, err
 	}
 
 	if c.Name == "" {
@@ -251,20 +481,49 @@ func (c *PolicyService) CheckUpdateParameters(ctx *gin.Context) (bool, common.Se
 			fmt.Errorf("validate day(%v) not pass, day should in (1-31) when policy.Period greater than one month", c.Day)
 	}
 
+	if c.Period == common.PeriodCron && c.CronExpr == "" {
+		c.CronExpr = policy.CronExpr
+	}
+	if code, err := c.CheckCronExpr(); err != nil {
+		return false, code, err
+	}
+
 	if c.CleaningSpeed != "" && !common.CheckCleaningSpeed(c.CleaningSpeed) {
 		return false, common.CodePolicyCleaningSpeedErr, fmt.Errorf("validate cleaning_speed(%s) not pass", c.CleaningSpeed)
 	}
 
+	// 未提供限流配置时以库里为准，提供0表示按清理速度使用内置的限流配置
+	if c.ThrottleID == common.InvalidUint {
+		c.ThrottleID = policy.ThrottleID
+	}
+	if code, err := CheckThrottleExist(db, c.ThrottleID); err != nil {
+		return false, code, err
+	}
+
+	if c.Priority != 0 && !common.CheckPriority(c.Priority) {
+		return false, common.CodePolicyPriorityErr, fmt.Errorf("validate priority(%d) not pass", c.Priority)
+	}
+
 	if c.NotifyPolicy != "" && !common.CheckNotifyPolicyType(c.NotifyPolicy) {
 		return false, common.CodePolicyNotifyPolicyErr, fmt.Errorf("validate notify_policy(%s) not pass", c.NotifyPolicy)
 	}
 
-	if len(c.ExecuteWindow) != 0 {
-		_, execWin, err := checkExecWindow(c.ExecuteWindow)
+	if len(c.ExecuteWindow) != 0 || len(c.ExecuteWindows) != 0 {
+		c.ExecuteWindow, c.ExecuteWindows, err = checkExecWindows(c.ExecuteWindow, c.ExecuteWindows)
 		if err != nil {
 			return false, common.CodePolicyExecuteWindowErr, err
 		}
-		c.ExecuteWindow = execWin
+	}
+
+	// 未提供依赖时以库里为准，提供空列表表示解除依赖
+	if c.DependsOn == nil {
+		c.DependsOn = ParsePolicyDependsOn(policy.DependsOn)
+	}
+	if c.DependFailAction == "" {
+		c.DependFailAction = policy.DependFailAction
+	}
+	if code, err := c.CheckDependencies(ctx); err != nil {
+		return false, code, err
 	}
 
 	if !common.InvalidUintID(c.SrcID) && c.SrcID != policy.SrcID {
@@ -292,23 +551,36 @@ func (c *PolicyService) CheckUpdateParameters(ctx *gin.Context) (bool, common.Se
 		}
 	}
 
-	if c.Condition != policy.Condition {
-		// 判断Condition条件是否正确
-		src := &models.Source{}
-		err = db.Model(src).Where("id =?", c.SrcID).First(src).Error
+	if policy.Govern == common.GovernTypePartition {
+		c.Condition = ""
+		c.PartitionColumn = utils.Ternary[string](c.PartitionColumn == "", policy.PartitionColumn, c.PartitionColumn)
+		c.PartitionInterval = utils.Ternary[common.PartitionIntervalType](c.PartitionInterval == "", policy.PartitionInterval, c.PartitionInterval)
+		c.PartitionRetainDays = utils.Ternary[int](c.PartitionRetainDays == 0, policy.PartitionRetainDays, c.PartitionRetainDays)
+		c.PartitionPrecreate = utils.Ternary[int](c.PartitionPrecreate == common.InvalidInt, policy.PartitionPrecreate, c.PartitionPrecreate)
+		c.PartitionAction = utils.Ternary[common.PartitionActionType](c.PartitionAction == "", policy.PartitionAction, c.PartitionAction)
+		res, err := c.CheckPartition(ctx)
 		if err != nil {
-			return false, common.CodeServerErr, fmt.Errorf("query models.Source(%v) failed, %s", c.SrcID, err)
+			return false, res, err
 		}
-
-		clusterSvc, err := GetClusterServiceByClusterID(ctx, src.ClusterID)
+	} else if c.Condition != policy.Condition {
+		// 判断Condition条件是否正确
+		res, err := c.CheckCondition(ctx)
 		if err != nil {
-			return false, common.CodeServerErr, err
+			return false, res, err
 		}
+	}
 
-		tableNames := strings.Split(src.TablesName, ",")
-		_, res, err := NewClusterDriver(clusterSvc).SQLExplain(ctx, src.DatabaseName, tableNames[0], c.ArchiveScope, c.Condition)
+	// 未提供水位列时沿用原来的水位列，关闭增量治理需显式指定
+	if c.DisableWatermark {
+		c.WatermarkColumn = ""
+	} else if c.WatermarkColumn == "" {
+		c.WatermarkColumn = policy.WatermarkColumn
+	}
+	if c.WatermarkColumn != "" && c.WatermarkColumn != policy.WatermarkColumn {
+		c.Govern = policy.Govern
+		res, err := c.CheckWatermark(ctx)
 		if err != nil {
-			return false, res, fmt.Errorf("check policy condition not pass, %s", err)
+			return false, res, err
 		}
 	}
 
@@ -354,15 +626,40 @@ func (c *PolicyService) UpdatePolicy(ctx *gin.Context) (common.ServiceCode, erro
 	newPolicy.RetainSrcData = c.RetainSrcData
 	newPolicy.Period = utils.Ternary[common.PeriodType](c.Period == "", policy.Period, c.Period)
 	newPolicy.Day = utils.Ternary[int](c.Day == common.InvalidInt, policy.Day, c.Day)
+	newPolicy.CronExpr = utils.Ternary[string](newPolicy.Period == common.PeriodCron, c.CronExpr, "")
 	newPolicy.CleaningSpeed = utils.Ternary[common.CleaningSpeedType](c.CleaningSpeed == "", policy.CleaningSpeed, c.CleaningSpeed)
+	newPolicy.Priority = utils.Ternary[int](c.Priority == 0, policy.Priority, c.Priority)
+	newPolicy.ThrottleID = c.ThrottleID
 	newPolicy.Condition = utils.Ternary[string](c.Condition == "", policy.Condition, c.Condition)
 	newPolicy.NotifyPolicy = utils.Ternary[common.NotifyPolicyType](c.NotifyPolicy == "", policy.NotifyPolicy, c.NotifyPolicy)
+	if policy.Govern == common.GovernTypePartition {
+		newPolicy.Condition = ""
+		newPolicy.PartitionColumn = c.PartitionColumn
+		newPolicy.PartitionInterval = c.PartitionInterval
+		newPolicy.PartitionRetainDays = c.PartitionRetainDays
+		newPolicy.PartitionPrecreate = c.PartitionPrecreate
+		newPolicy.PartitionAction = c.PartitionAction
+	}
+	// 水位列变化或主动重置时从头开始治理
+	newPolicy.WatermarkColumn = c.WatermarkColumn
+	if c.WatermarkColumn != policy.WatermarkColumn || c.ResetWatermark {
+		newPolicy.Watermark = ""
+	}
 	if len(c.ExecuteWindow) != 0 {
 		newPolicy.ExecuteWindow, _ = json.Marshal(c.ExecuteWindow)
+		newPolicy.ExecuteWindows = nil
+	}
+	if len(c.ExecuteWindows) != 0 {
+		newPolicy.ExecuteWindows, _ = json.Marshal(c.ExecuteWindows)
 	}
 	if len(c.Relevant) != 0 {
 		newPolicy.Relevant, _ = json.Marshal(c.Relevant)
 	}
+	newPolicy.DependsOn = nil
+	if len(c.DependsOn) != 0 {
+		newPolicy.DependsOn, _ = json.Marshal(c.DependsOn)
+	}
+	newPolicy.DependFailAction = c.DependFailAction
 
 	err = db.Transaction(func(db *gorm.DB) error {
 		err = db.Save(&newPolicy).Error
@@ -510,6 +807,20 @@ func (c *PolicyService) DeletePolicy(ctx *gin.Context) (common.ServiceCode, erro
 		return common.CodePolicyUsingTask, err
 	}
 
+	// 被其他策略依赖，不可删除
+	graph, err := PolicyDependencyGraph(db)
+	if err != nil {
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	for id, deps := range graph {
+		if utils.ElementExist(policy.ID, deps) {
+			err = fmt.Errorf("policy(%s) is depended on by policy(%v)", policy.Name, id)
+			log.Error(err)
+			return common.CodePolicyDepended, err
+		}
+	}
+
 	err = db.Transaction(func(db *gorm.DB) error {
 		// 更新任务
 		db.Model(&models.Policy{}).Where("id =?", policy.ID).Update("editor", u.UserName)
@@ -551,6 +862,21 @@ func checkExecWindow(execWin []string) (bool, []string, error) {
 	return true, execWin, nil
 }
 
+// checkExecWindows 校验执行窗口，配置了多执行窗口时以多执行窗口为准，单个执行窗口取最早开始的窗口用于展示
+func checkExecWindows(execWin []string, execWindows []common.ExecWindow) ([]string, []common.ExecWindow, error) {
+	if len(execWindows) == 0 {
+		_, execWin, err := checkExecWindow(execWin)
+		return execWin, nil, err
+	}
+
+	execWindows, err := common.CheckExecWindows(execWindows)
+	if err != nil {
+		return nil, nil, err
+	}
+	first := common.FirstExecWindow(execWindows)
+	return []string{first.Start, first.End}, execWindows, nil
+}
+
 func (c *PolicyService) ServiceToModel() *models.Policy {
 	m := &models.Policy{}
 	m.ID = c.ID
@@ -563,18 +889,34 @@ func (c *PolicyService) ServiceToModel() *models.Policy {
 	m.Enable = c.Enable
 	m.Period = c.Period
 	m.Day = c.Day
+	m.CronExpr = c.CronExpr
 	m.Bu = c.Bu
 	m.Pause = c.Pause
 	m.RebuildFlag = c.RebuildFlag
 	m.CleaningSpeed = c.CleaningSpeed
+	m.Priority = c.Priority
+	m.ThrottleID = c.ThrottleID
 	m.SrcID = c.SrcID
 	m.Govern = c.Govern
 	m.Condition = c.Condition
 	m.ArchiveScope = c.ArchiveScope
 	m.RetainSrcData = c.RetainSrcData
+	m.PartitionColumn = c.PartitionColumn
+	m.PartitionInterval = c.PartitionInterval
+	m.PartitionRetainDays = c.PartitionRetainDays
+	m.PartitionPrecreate = c.PartitionPrecreate
+	m.PartitionAction = c.PartitionAction
+	m.WatermarkColumn = c.WatermarkColumn
 	m.DestID = c.DestID
+	if len(c.DependsOn) != 0 {
+		m.DependsOn, _ = json.Marshal(c.DependsOn)
+	}
+	m.DependFailAction = c.DependFailAction
 	m.NotifyPolicy = c.NotifyPolicy
 	m.ExecuteWindow, _ = json.Marshal(c.ExecuteWindow)
+	if len(c.ExecuteWindows) != 0 {
+		m.ExecuteWindows, _ = json.Marshal(c.ExecuteWindows)
+	}
 	m.Relevant, _ = json.Marshal(c.Relevant)
 	return m
 }
@@ -591,17 +933,30 @@ func (c *PolicyService) ModelToService(m *models.Policy) *PolicyService {
 	c.Enable = m.Enable
 	c.Period = m.Period
 	c.Day = m.Day
+	c.CronExpr = m.CronExpr
 	c.Pause = m.Pause
 	c.RebuildFlag = m.RebuildFlag
 	c.CleaningSpeed = m.CleaningSpeed
+	c.Priority = m.Priority
+	c.ThrottleID = m.ThrottleID
 	c.SrcID = m.SrcID
 	c.Govern = m.Govern
 	c.Condition = m.Condition
 	c.ArchiveScope = m.ArchiveScope
 	c.RetainSrcData = m.RetainSrcData
+	c.PartitionColumn = m.PartitionColumn
+	c.PartitionInterval = m.PartitionInterval
+	c.PartitionRetainDays = m.PartitionRetainDays
+	c.PartitionPrecreate = m.PartitionPrecreate
+	c.PartitionAction = m.PartitionAction
+	c.WatermarkColumn = m.WatermarkColumn
+	c.Watermark = m.Watermark
 	c.DestID = m.DestID
+	c.DependsOn = ParsePolicyDependsOn(m.DependsOn)
+	c.DependFailAction = m.DependFailAction
 	c.NotifyPolicy = m.NotifyPolicy
 	_ = json.Unmarshal(m.ExecuteWindow, &c.ExecuteWindow)
+	_ = json.Unmarshal(m.ExecuteWindows, &c.ExecuteWindows)
 	_ = json.Unmarshal(m.Relevant, &c.Relevant)
 	return c
 }
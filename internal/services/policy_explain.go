@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// PolicyExplainService 策略治理条件的执行计划分析
+type PolicyExplainService struct {
+	SrcID        uint   `json:"src_id"`        // 源端ID
+	Condition    string `json:"condition"`     // 数据治理条件, where条件
+	ArchiveScope string `json:"archive_scope"` // 数据归档范围，归档那些列（仅归档涉及）
+
+	Plan        *mysql.ExplainInfo       `json:"plan"`        // 执行计划
+	ScanRows    int64                    `json:"scan_rows"`   // 预估扫描行数
+	UseIndex    bool                     `json:"use_index"`   // 是否可以使用索引
+	Key         string                   `json:"key"`         // 使用的索引
+	Level       common.ExplainResultType `json:"level"`       // 检查结果 通过:pass, 告警:warn, 拒绝:reject
+	Warnings    []string                 `json:"warnings"`    // 告警信息
+	Suggestions []string                 `json:"suggestions"` // 优化建议
+}
+
+// Explain 分析治理条件的执行计划，判断能否使用索引以及扫描行数是否超过阈值
+func (c *PolicyExplainService) Explain(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	src := &models.Source{}
+	err := db.Model(src).Where("id =?", c.SrcID).First(src).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.CodeSourceNotExist, fmt.Errorf("models.Source(%v) not exist", c.SrcID)
+		}
+		return common.CodeServerErr, fmt.Errorf("query models.Source(%v) failed, %s", c.SrcID, err)
+	}
+
+	clusterSvc, err := GetClusterServiceByClusterID(ctx, src.ClusterID)
+	if err != nil {
+		return common.CodeServerErr, err
+	}
+
+	c.Condition = utils.TrimmingSQLConditionEnding(c.Condition)
+	table := strings.Split(src.TablesName, ",")[0]
+
+	code, err := CatalogCheckTableColumns(ctx, clusterSvc, src.DatabaseName, table, strings.Split(c.ArchiveScope, ","))
+	if err != nil {
+		return code, fmt.Errorf("check policy archive scope not pass, %s", err)
+	}
+
+	c.Plan, code, err = NewClusterDriver(clusterSvc).SQLExplain(ctx, src.DatabaseName, table, c.ArchiveScope, c.Condition)
+	if err != nil {
+		return code, fmt.Errorf("check policy condition not pass, %s", err)
+	}
+
+	c.Level = common.ExplainResultPass
+	c.Warnings, c.Suggestions = nil, nil
+
+	// 没有治理条件时治理的是全表数据，无需检查索引
+	if c.Condition == "" {
+		return common.CodeOK, nil
+	}
+
+	accessType := ""
+	for _, row := range c.Plan.ExplainRows {
+		if row.Rows > c.ScanRows {
+			c.ScanRows = row.Rows
+		}
+		if row.TableName == table || accessType == "" {
+			accessType, c.Key = row.AccessType, row.Key
+		}
+	}
+	if c.Key == "NULL" {
+		c.Key = ""
+	}
+	// ALL为全表扫描，index为全索引扫描，都需要扫描全表
+	c.UseIndex = c.Key != "" && accessType != "ALL" && accessType != "index"
+
+	if !c.UseIndex {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("condition can not use index, full table scan(type=%s)", accessType))
+	}
+	if Cfg.PolicyConditionMaxScanRows > 0 && c.ScanRows > int64(Cfg.PolicyConditionMaxScanRows) {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("estimated scan rows(%d) exceeds the threshold(%d)", c.ScanRows, Cfg.PolicyConditionMaxScanRows))
+	}
+
+	indexes, _, err := ClusterTableIndexes(ctx, clusterSvc, src.DatabaseName, table)
+	if err != nil {
+		log.Warnf("get table(%s.%s) indexes failed, %s", src.DatabaseName, table, err)
+	}
+	c.Suggestions = conditionSuggestions(c.Condition, c.UseIndex, indexes)
+
+	if len(c.Warnings) != 0 {
+		c.Level = common.ExplainResultWarn
+		if Cfg.PolicyConditionStrict {
+			c.Level = common.ExplainResultReject
+		}
+	}
+	return common.CodeOK, nil
+}
+
+// conditionSuggestions 根据条件中用到的列和表上已有的索引给出优化建议
+func conditionSuggestions(condition string, useIndex bool, indexes []mysql.IndexSchema) []string {
+	columns, wrapped, err := mysql.ConditionColumns(condition)
+	if err != nil {
+		return nil
+	}
+
+	// 索引的第一列才能用于范围查找
+	leading := make(map[string]mysql.IndexSchema, len(indexes))
+	for _, idx := range indexes {
+		if len(idx.Columns) != 0 {
+			if _, ok := leading[strings.ToLower(idx.Columns[0])]; !ok {
+				leading[strings.ToLower(idx.Columns[0])] = idx
+			}
+		}
+	}
+
+	var suggestions []string
+	for _, col := range wrapped {
+		if idx, ok := leading[strings.ToLower(col)]; ok {
+			suggestions = append(suggestions,
+				fmt.Sprintf("column %s is wrapped by function or expression and can not use index %s, compare column %s directly instead", col, idx.Name, col))
+		}
+	}
+
+	if useIndex {
+		return suggestions
+	}
+
+	var indexed []string
+	for _, col := range columns {
+		if idx, ok := leading[strings.ToLower(col)]; ok {
+			indexed = append(indexed, col)
+			if !utils.ElementExist(col, wrapped) {
+				suggestions = append(suggestions,
+					fmt.Sprintf("column %s has index %s(%s), use equality or range condition on it", col, idx.Name, strings.Join(idx.Columns, ",")))
+			}
+		}
+	}
+
+	if len(indexed) == 0 {
+		var available []string
+		for _, idx := range indexes {
+			available = append(available, fmt.Sprintf("%s(%s)", idx.Name, strings.Join(idx.Columns, ",")))
+		}
+		if len(available) != 0 {
+			suggestions = append(suggestions, fmt.Sprintf("existing indexes: %s, set the condition on the first column of these indexes", strings.Join(available, ", ")))
+		}
+		if len(columns) != 0 {
+			suggestions = append(suggestions, fmt.Sprintf("or add an index on column %s", columns[0]))
+		}
+	}
+	return suggestions
+}
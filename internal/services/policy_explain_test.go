@@ -0,0 +1,56 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sunkaimr/data-loom/pkg/mysql"
+)
+
+func TestConditionSuggestions(t *testing.T) {
+	indexes := []mysql.IndexSchema{
+		{Name: "PRIMARY", Unique: true, Columns: []string{"id"}},
+		{Name: "idx_created", Columns: []string{"created_at", "status"}},
+	}
+	tests := []struct {
+		name      string
+		condition string
+		useIndex  bool
+		indexes   []mysql.IndexSchema
+		want      []string
+	}{
+		{"use index", "created_at < '2024-01-01'", true, indexes, nil},
+		{
+			name:      "wrapped column",
+			condition: "DATE(created_at) < '2024-01-01'",
+			indexes:   indexes,
+			want: []string{
+				"column created_at is wrapped by function or expression and can not use index idx_created, compare column created_at directly instead",
+			},
+		},
+		{
+			name:      "indexed column not used",
+			condition: "status = 1 OR created_at < '2024-01-01'",
+			indexes:   indexes,
+			want:      []string{"column created_at has index idx_created(created_at,status), use equality or range condition on it"},
+		},
+		{
+			name:      "no indexed column",
+			condition: "status = 1",
+			indexes:   indexes,
+			want: []string{
+				"existing indexes: PRIMARY(id), idx_created(created_at,status), set the condition on the first column of these indexes",
+				"or add an index on column status",
+			},
+		},
+		{"no index", "status = 1", false, nil, []string{"or add an index on column status"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := conditionSuggestions(test.condition, test.useIndex, test.indexes)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("conditionSuggestions() got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/logger"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql/mysqltest"
+)
+
+func TestFindDependencyPath(t *testing.T) {
+	graph := map[uint][]uint{1: {2}, 2: {3}, 3: nil, 4: {1, 3}, 5: {5}}
+	tests := []struct {
+		name   string
+		from   []uint
+		target uint
+		want   []uint
+	}{
+		{"self dependency", []uint{5}, 5, []uint{5}},
+		{"direct", []uint{2}, 2, []uint{2}},
+		{"indirect", []uint{1}, 3, []uint{1, 2, 3}},
+		{"through second dependency", []uint{3, 1}, 2, []uint{1, 2}},
+		{"not reachable", []uint{1}, 4, nil},
+		{"missing upstream", []uint{9}, 1, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := findDependencyPath(graph, test.from, test.target); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("findDependencyPath(%v, %d) got %v, want %v", test.from, test.target, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	db := mysqltest.Open(t, &models.Policy{})
+	// 已有的依赖关系：1 -> 2 -> 3
+	for id, dependsOn := range map[uint][]uint{1: {2}, 2: {3}, 3: nil} {
+		p := &models.Policy{Name: "policy", Period: common.PeriodDay}
+		p.ID = id
+		p.Creator = common.SystemUserName
+		if len(dependsOn) != 0 {
+			p.DependsOn, _ = json.Marshal(dependsOn)
+		}
+		if err := db.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := &gin.Context{}
+	ctx.Set(common.LOGGER, logger.Log)
+	ctx.Set(common.DB, db)
+
+	tests := []struct {
+		name          string
+		id            uint
+		dependsOn     []uint
+		action        common.DependFailActionType
+		want          common.ServiceCode
+		wantDependsOn []uint
+	}{
+		{name: "new policy", dependsOn: []uint{1, 1}, want: common.CodeOK, wantDependsOn: []uint{1}},
+		{name: "no dependency", id: 3, dependsOn: nil, want: common.CodeOK},
+		{name: "self dependency", id: 2, dependsOn: []uint{2}, want: common.CodePolicyDependencyErr},
+		{name: "missing upstream", id: 2, dependsOn: []uint{9}, want: common.CodePolicyDependencyErr},
+		{name: "direct cycle", id: 2, dependsOn: []uint{1}, want: common.CodePolicyDependencyCycle},
+		{name: "indirect cycle", id: 3, dependsOn: []uint{1}, want: common.CodePolicyDependencyCycle},
+		{name: "update replaces stored dependencies", id: 1, dependsOn: []uint{3}, want: common.CodeOK, wantDependsOn: []uint{3}},
+		{name: "clear dependencies on update", id: 1, dependsOn: []uint{}, want: common.CodeOK, wantDependsOn: []uint{}},
+		{name: "invalid fail action", id: 3, dependsOn: []uint{1}, action: "retry", want: common.CodePolicyDependFailErr},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &PolicyService{DependsOn: test.dependsOn, DependFailAction: test.action}
+			c.ID = test.id
+			code, err := c.CheckDependencies(ctx)
+			if code != test.want {
+				t.Fatalf("CheckDependencies() got %s(%v), want %s", code.Message, err, test.want.Message)
+			}
+			if code == common.CodeOK && len(c.DependsOn)+len(test.wantDependsOn) != 0 && !reflect.DeepEqual(c.DependsOn, test.wantDependsOn) {
+				t.Fatalf("CheckDependencies() got depends_on %v, want %v", c.DependsOn, test.wantDependsOn)
+			}
+			if code == common.CodeOK && c.DependFailAction != common.DependFailActionDefault {
+				t.Fatalf("CheckDependencies() got depend_fail_action %s, want %s", c.DependFailAction, common.DependFailActionDefault)
+			}
+		})
+	}
+}
+
+func TestDependencyScheduledBefore(t *testing.T) {
+	tests := []struct {
+		name     string
+		period   common.PeriodType
+		day      int
+		last     string
+		execDate string
+		want     bool
+	}{
+		{"daily task not created yet", common.PeriodDay, 0, "2024-01-01", "2024-01-02", true},
+		{"daily next period after exec date", common.PeriodDay, 0, "2024-01-02", "2024-01-02", false},
+		{"weekly not scheduled in this period", common.PeriodWeekly, 0, "2024-01-01", "2024-01-05", false},
+		{"weekly scheduled on exec date", common.PeriodWeekly, 0, "2024-01-01", "2024-01-08", true},
+		{"monthly before day", common.PeriodMonthly, 15, "2024-01-15", "2024-02-14", false},
+		{"monthly on day", common.PeriodMonthly, 15, "2024-01-15", "2024-02-15", true},
+		{"once executed", common.PeriodOnce, 0, "2024-01-01", "2024-01-05", false},
+		{"never executed", common.PeriodDay, 0, "", "2099-01-01", true},
+		{"never executed before exec date", common.PeriodDay, 0, "", "2000-01-01", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upstream := &models.Policy{Period: test.period, Day: test.day}
+			var last time.Time
+			if test.last != "" {
+				last = parseLocalTime(test.last + " 00:00:00")
+			}
+			got, err := DependencyScheduledBefore(upstream, last, test.execDate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("DependencyScheduledBefore(%s, %s) got %v, want %v", test.last, test.execDate, got, test.want)
+			}
+		})
+	}
+}
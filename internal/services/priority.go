@@ -0,0 +1,144 @@
+package services
+
+import (
+	"fmt"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"sort"
+	"time"
+)
+
+// TaskOverdue 任务超过计划开始时间(执行日期当天第一个执行窗口的开始时间)的时长，未到计划开始时间时为0
+func TaskOverdue(task *models.Task, now time.Time) time.Duration {
+	planStart, err := time.ParseInLocation(time.DateOnly, task.ExecuteDate, common.LoadLocation(task.Timezone))
+	if err != nil {
+		return 0
+	}
+	if windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows); err == nil {
+		if ranges, err := common.ExecWindowRanges(windows, planStart); err == nil && len(ranges) != 0 {
+			planStart = ranges[0].Start
+		}
+	}
+
+	if now.Before(planStart) {
+		return 0
+	}
+	return now.Sub(planStart)
+}
+
+// TaskEffectivePriority 任务的有效优先级：基础优先级 + 超过计划开始时间的时长/老化周期，低优先级的任务等待足够久后也能得到执行
+func TaskEffectivePriority(task *models.Task, now time.Time) int {
+	priority := task.Priority
+	if !common.CheckPriority(priority) {
+		priority = common.PriorityDefault
+	}
+
+	if Cfg.TaskPriorityAging > 0 {
+		priority += int(TaskOverdue(task, now) / (time.Duration(Cfg.TaskPriorityAging) * time.Minute))
+	}
+	return priority
+}
+
+// CompareTaskAdmission 比较任务准入的先后顺序，小于0时task1先执行
+// 有效优先级高 > 超过计划开始时间久 > 执行窗口早 > 创建日期早 > 等待执行优先于检查失败 > ID小
+func CompareTaskAdmission(task1, task2 *models.Task, now time.Time) int {
+	// 优先级
+	p1, p2 := TaskEffectivePriority(task1, now), TaskEffectivePriority(task2, now)
+	if p1 != p2 {
+		return p2 - p1
+	}
+
+	// 超过计划开始时间的时长
+	overdue1, overdue2 := TaskOverdue(task1, now), TaskOverdue(task2, now)
+	if overdue1 != overdue2 {
+		if overdue1 > overdue2 {
+			return -1
+		}
+		return 1
+	}
+
+	// 执行窗口
+	if res, err := compareExecWindows(task1, task2); err == nil && res != 0 {
+		return res
+	}
+
+	// 任务创建时间
+	createTime1 := task1.CreatedAt.Format(time.DateOnly)
+	createTime2 := task2.CreatedAt.Format(time.DateOnly)
+	switch {
+	case createTime1 < createTime2:
+		return -1
+	case createTime1 > createTime2:
+		return 1
+	}
+
+	// 等待执行 > 检查失败
+	if task1.TaskStatus != task2.TaskStatus {
+		if task1.TaskStatus == common.TaskStatusWaiting {
+			return -1
+		}
+		if task2.TaskStatus == common.TaskStatusWaiting {
+			return 1
+		}
+	}
+	return int(task1.ID) - int(task2.ID)
+}
+
+// SortTasksByAdmission 按准入的先后顺序对任务排序
+func SortTasksByAdmission(tasks []models.Task, now time.Time) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return CompareTaskAdmission(&tasks[i], &tasks[j], now) < 0
+	})
+}
+
+func execWindowsToTime(execWindow, execWindows []byte) ([2]time.Time, error) {
+	var executeWinTime [2]time.Time
+
+	windows, err := common.ParseExecWindows(execWindow, execWindows)
+	if err != nil {
+		return executeWinTime, err
+	}
+	// 多个执行窗口时以最早开始的窗口为准
+	first := common.FirstExecWindow(windows)
+
+	startTime, err := time.ParseInLocation(time.TimeOnly, first.Start, time.Now().Location())
+	if err != nil {
+		return executeWinTime, fmt.Errorf("parse execWindow(%s - %s) failed, %s", first.Start, first.End, err)
+	}
+	endTime, err := time.ParseInLocation(time.TimeOnly, first.End, time.Now().Location())
+	if err != nil {
+		return executeWinTime, fmt.Errorf("parse execWindow(%s - %s) failed, %s", first.Start, first.End, err)
+	}
+
+	// 跨天了 endTime < startTime
+	if endTime.Before(startTime) {
+		endTime = endTime.Add(time.Hour * 24)
+	}
+	executeWinTime[0] = startTime
+	executeWinTime[1] = endTime
+	return executeWinTime, nil
+}
+
+// 比较task1和task2执行窗口的时间先后
+// -1：task1早于task2
+// 0：task1相同task2
+// 1：task1晚于task2
+func compareExecWindows(task1, task2 *models.Task) (int, error) {
+	execTime1, err1 := execWindowsToTime(task1.ExecuteWindow, task1.ExecuteWindows)
+	if err1 != nil {
+		return 0, fmt.Errorf("compare exec windows failed, %s", err1)
+	}
+	execTime2, err2 := execWindowsToTime(task2.ExecuteWindow, task2.ExecuteWindows)
+	if err2 != nil {
+		return 0, fmt.Errorf("compare exec windows failed, %s", err2)
+	}
+
+	if execTime1[0] == execTime2[0] {
+		return 0, nil
+	}
+
+	if execTime1[0].Before(execTime2[0]) {
+		return -1, nil
+	}
+	return 1, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+func TestTaskOverdue(t *testing.T) {
+	now := parseLocalTime("2024-01-02 02:00:00")
+	tests := []struct {
+		name    string
+		date    string
+		window  string
+		windows string
+		want    time.Duration
+	}{
+		{"not reached", "2024-01-03", `["01:00:00", "03:00:00"]`, "", 0},
+		{"before window start", "2024-01-02", `["03:00:00", "05:00:00"]`, "", 0},
+		{"in window", "2024-01-02", `["01:00:00", "03:00:00"]`, "", time.Hour},
+		{"days ago", "2024-01-01", `["01:00:00", "03:00:00"]`, "", 25 * time.Hour},
+		{"first of multiple windows", "2024-01-02", `["01:00:00", "03:00:00"]`,
+			`[{"start":"01:30:00","end":"02:30:00"},{"start":"00:30:00","end":"01:00:00"}]`, 90 * time.Minute},
+		{"invalid window uses exec date", "2024-01-02", `[]`, "", 2 * time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := &models.Task{ExecuteDate: test.date, ExecuteWindow: []byte(test.window), ExecuteWindows: []byte(test.windows)}
+			if got := TaskOverdue(task, now); got != test.want {
+				t.Fatalf("TaskOverdue() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTaskEffectivePriority(t *testing.T) {
+	aging := Cfg.TaskPriorityAging
+	defer func() { Cfg.TaskPriorityAging = aging }()
+
+	now := parseLocalTime("2024-01-02 02:00:00")
+	tests := []struct {
+		name     string
+		priority int
+		date     string
+		aging    int
+		want     int
+	}{
+		{"no aging", 3, "2024-01-01", 0, 3},
+		{"invalid priority uses default", 0, "2024-01-03", 0, common.PriorityDefault},
+		{"not overdue", 3, "2024-01-03", 30, 3},
+		{"aging", 3, "2024-01-02", 30, 5},
+		{"aging round down", 3, "2024-01-02", 40, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Cfg.TaskPriorityAging = test.aging
+			task := &models.Task{Priority: test.priority, ExecuteDate: test.date, ExecuteWindow: []byte(`["01:00:00", "03:00:00"]`)}
+			if got := TaskEffectivePriority(task, now); got != test.want {
+				t.Fatalf("TaskEffectivePriority() got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareTaskAdmission(t *testing.T) {
+	aging := Cfg.TaskPriorityAging
+	defer func() { Cfg.TaskPriorityAging = aging }()
+
+	now := parseLocalTime("2024-01-02 02:00:00")
+	task := func(id uint, priority int, date, start string, modify ...func(*models.Task)) *models.Task {
+		m := &models.Task{
+			Priority:      priority,
+			ExecuteDate:   date,
+			ExecuteWindow: []byte(`["` + start + `", "05:00:00"]`),
+			TaskStatus:    common.TaskStatusWaiting,
+			Model:         models.Model{ID: id, CreatedAt: parseLocalTime("2024-01-01 00:00:00")},
+		}
+		for _, f := range modify {
+			f(m)
+		}
+		return m
+	}
+	tests := []struct {
+		name  string
+		aging int
+		task1 *models.Task
+		task2 *models.Task
+		want  int
+	}{
+		{"higher priority first", 0, task(2, 8, "2024-01-02", "01:00:00"), task(1, 5, "2024-01-01", "01:00:00"), -1},
+		{"aging lifts waiting task", 30, task(2, 5, "2024-01-01", "01:00:00"), task(1, 8, "2024-01-02", "01:00:00"), -1},
+		{"more overdue first", 0, task(2, 5, "2024-01-02", "01:00:00"), task(1, 5, "2024-01-01", "01:00:00"), 1},
+		{"earlier window first", 0, task(2, 5, "2024-01-05", "01:00:00"), task(1, 5, "2024-01-05", "03:00:00"), -1},
+		{
+			name:  "earlier created first",
+			task1: task(2, 5, "2024-01-05", "01:00:00"),
+			task2: task(1, 5, "2024-01-05", "01:00:00", func(m *models.Task) { m.CreatedAt = parseLocalTime("2024-01-03 00:00:00") }),
+			want:  -1,
+		},
+		{
+			name:  "waiting before check failed",
+			task1: task(2, 5, "2024-01-05", "01:00:00"),
+			task2: task(1, 5, "2024-01-05", "01:00:00", func(m *models.Task) { m.TaskStatus = common.TaskStatusExecCheckFailed }),
+			want:  -1,
+		},
+		{"smaller id first", 0, task(1, 5, "2024-01-05", "01:00:00"), task(2, 5, "2024-01-05", "01:00:00"), -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Cfg.TaskPriorityAging = test.aging
+			got := CompareTaskAdmission(test.task1, test.task2, now)
+			if (got < 0 && test.want >= 0) || (got > 0 && test.want <= 0) || (got == 0 && test.want != 0) {
+				t.Fatalf("CompareTaskAdmission() got %d, want %d", got, test.want)
+			}
+			// 交换顺序后结果相反
+			if rev := CompareTaskAdmission(test.task2, test.task1, now); (rev < 0) == (got < 0) {
+				t.Fatalf("CompareTaskAdmission() reversed got %d, want opposite of %d", rev, got)
+			}
+		})
+	}
+}
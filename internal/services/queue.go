@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/queue"
+	"gorm.io/gorm"
+	"time"
+)
+
+// QueueItemService 持久化队列中待处理的策略或任务
+type QueueItemService struct {
+	Model
+	Queue       common.QueueNameType       `json:"queue"`        // 队列类型 策略:policy, 任务:task
+	ItemID      uint                       `json:"item_id"`      // 策略或任务ID
+	PolicyID    uint                       `json:"policy_id"`    // 策略ID
+	HandleID    string                     `json:"handle_id"`    // 处理ID
+	Status      common.QueueItemStatusType `json:"status"`       // 状态 等待处理:pending, 处理中:processing, 处理失败:failed
+	Attempts    int                        `json:"attempts"`     // 已处理次数
+	LeaseOwner  string                     `json:"lease_owner"`  // 租约持有者
+	LeaseExpiry string                     `json:"lease_expiry"` // 租约过期时间
+	LastError   string                     `json:"last_error"`   // 最近一次处理失败的原因
+}
+
+func (c *QueueItemService) QueryQueueItem(ctx *gin.Context) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	if c.Queue != "" && !common.CheckQueueName(c.Queue) {
+		return nil, common.CodeQueueNameErr, fmt.Errorf("validate queue(%s) not pass", c.Queue)
+	}
+	if c.Status != "" && !common.CheckQueueItemStatus(c.Status) {
+		return nil, common.CodeQueueStatusErr, fmt.Errorf("validate status(%s) not pass", c.Status)
+	}
+
+	res, err := common.NewPageList[[]models.QueueItem](db).
+		QueryPaging(ctx).
+		Order("id").
+		Query(
+			common.FilterFuzzyStringMap(map[string]string{"queue": string(c.Queue), "status": string(c.Status)}),
+			common.FilterID(c.ID),
+			common.FilterCustomUintID("item_id", c.ItemID),
+			common.FilterCustomUintID("policy_id", c.PolicyID),
+		)
+	if err != nil {
+		err = fmt.Errorf("query models.QueueItem from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]QueueItemService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		q := &QueueItemService{}
+		q.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *q)
+	}
+	return ret, common.CodeOK, nil
+}
+
+// RequeueQueueItem 将处理失败或租约未过期但已卡住的元素重新置为等待处理
+func (c *QueueItemService) RequeueQueueItem(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	err := queue.RequeueItem(db, c.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("models.QueueItem(id=%d) not exist", c.ID)
+			log.Error(err)
+			return common.CodeQueueItemNotExist, err
+		}
+		err = fmt.Errorf("requeue models.QueueItem(id=%d) failed, %s", c.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	log.Infof("requeue models.QueueItem(id=%d) by %s", c.ID, common.ExtractUserInfo(ctx).UserName)
+	return common.CodeOK, nil
+}
+
+// DropQueueItem 从队列中丢弃元素
+func (c *QueueItemService) DropQueueItem(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	err := queue.DropItem(db, c.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("models.QueueItem(id=%d) not exist", c.ID)
+			log.Error(err)
+			return common.CodeQueueItemNotExist, err
+		}
+		err = fmt.Errorf("drop models.QueueItem(id=%d) failed, %s", c.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	log.Infof("drop models.QueueItem(id=%d) by %s", c.ID, common.ExtractUserInfo(ctx).UserName)
+	return common.CodeOK, nil
+}
+
+func (c *QueueItemService) ModelToService(m *models.QueueItem) *QueueItemService {
+	c.ID = m.ID
+	c.Creator = m.Creator
+	c.Editor = m.Editor
+	c.CreatedAt = m.CreatedAt.Format(time.DateTime)
+	c.UpdatedAt = m.UpdatedAt.Format(time.DateTime)
+	c.Queue = m.Queue
+	c.ItemID = m.ItemID
+	c.PolicyID = m.PolicyID
+	c.HandleID = m.HandleID
+	c.Status = m.Status
+	c.Attempts = m.Attempts
+	c.LeaseOwner = m.LeaseOwner
+	c.LeaseExpiry = ""
+	if m.LeaseExpiry.After(time.UnixMilli(0)) {
+		c.LeaseExpiry = m.LeaseExpiry.Format(time.DateTime)
+	}
+	c.LastError = m.LastError
+	return c
+}
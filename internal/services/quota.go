@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// QuotaService 并发配额，限制同一BU或同一集群同时执行的任务数
+type QuotaService struct {
+	Model
+	Name        string                `json:"name"`         // 配额名称
+	Description string                `json:"description"`  // 说明
+	Enable      *bool                 `json:"enable"`       // 是否生效，更新时不填写表示不修改
+	Scope       common.QuotaScopeType `json:"scope"`        // 生效范围 指定BU:bu, 指定集群:cluster
+	ScopeValue  string                `json:"scope_value"`  // 生效范围为bu时填写BU，为cluster时填写集群ID
+	MaxParallel int                   `json:"max_parallel"` // 最多同时执行的任务数，0表示不限制
+	Weight      int                   `json:"weight"`       // 开启公平分配时BU的权重，仅对bu生效，默认为1
+}
+
+func (c *QuotaService) CheckParameters(ctx *gin.Context) (bool, common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if len(c.Name) == 0 || len(c.Name) >= 1024 {
+		return false, common.CodeQuotaNameErr, fmt.Errorf("validate quota name(%s) not pass", c.Name)
+	}
+
+	if !common.CheckQuotaScope(c.Scope) {
+		return false, common.CodeQuotaScopeErr, fmt.Errorf("validate scope(%s) not pass", c.Scope)
+	}
+	switch c.Scope {
+	case common.QuotaScopeBu:
+		if c.ScopeValue == "" {
+			return false, common.CodeQuotaScopeErr, fmt.Errorf("scope_value(bu) can not be empty when scope is %s", c.Scope)
+		}
+	case common.QuotaScopeCluster:
+		var count int64
+		err := db.Model(&models.Cluster{}).Where("cluster_id =?", c.ScopeValue).Count(&count).Error
+		if err != nil {
+			return false, common.CodeServerErr, fmt.Errorf("query models.Cluster(cluster_id=%s) from db failed, %s", c.ScopeValue, err)
+		}
+		if count == 0 {
+			return false, common.CodeClusterNotExist, fmt.Errorf("cluster(%s) not exist", c.ScopeValue)
+		}
+	}
+
+	if c.MaxParallel < 0 {
+		return false, common.CodeQuotaMaxParallelErr, fmt.Errorf("max_parallel(%d) should not less than 0", c.MaxParallel)
+	}
+
+	if c.Weight == 0 {
+		c.Weight = 1
+	}
+	if c.Weight < 0 {
+		return false, common.CodeQuotaWeightErr, fmt.Errorf("weight(%d) should greater than 0", c.Weight)
+	}
+
+	return true, common.CodeOK, nil
+}
+
+// checkConflict 名称不能重复，同一BU或集群只能有一个配额
+func (c *QuotaService) checkConflict(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	var count int64
+	err := db.Model(&models.Quota{}).Where("name =? AND id !=?", c.Name, c.ID).Count(&count).Error
+	if err != nil {
+		log.Errorf("query models.Quota(name =%v AND id !=%v) from db failed, %s", c.Name, c.ID, err)
+		return common.CodeServerErr, err
+	}
+	if count != 0 {
+		err = fmt.Errorf("models.Quota(name=%s) exist", c.Name)
+		log.Error(err)
+		return common.CodeQuotaNameConflict, err
+	}
+
+	err = db.Model(&models.Quota{}).Where("scope =? AND scope_value =? AND id !=?", c.Scope, c.ScopeValue, c.ID).Count(&count).Error
+	if err != nil {
+		log.Errorf("query models.Quota(scope=%s, scope_value=%s) from db failed, %s", c.Scope, c.ScopeValue, err)
+		return common.CodeServerErr, err
+	}
+	if count != 0 {
+		err = fmt.Errorf("models.Quota(scope=%s, scope_value=%s) exist", c.Scope, c.ScopeValue)
+		log.Error(err)
+		return common.CodeQuotaScopeConflict, err
+	}
+	return common.CodeOK, nil
+}
+
+func (c *QuotaService) CreateQuota(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	c.ID = 0
+	if res, err := c.checkConflict(ctx); res != common.CodeOK {
+		return res, err
+	}
+
+	quota := c.ServiceToModel()
+	quota.CreatedAt = time.Now()
+	quota.Creator = u.UserName
+	err := db.Save(quota).Error
+	if err != nil {
+		log.Errorf("save models.Quota(%+v) to db failed, %s", quota, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(quota)
+	return common.CodeOK, nil
+}
+
+func (c *QuotaService) UpdateQuota(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	quota := &models.Quota{}
+	err := db.Model(quota).First(quota, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("query models.Quota(id=%d) not exist", c.ID)
+			return common.CodeQuotaNotExist, err
+		}
+		log.Errorf("query models.Quota(id=%d) from db failed, %s", c.ID, err)
+		return common.CodeServerErr, err
+	}
+
+	old := &QuotaService{}
+	old.ModelToService(quota)
+	c.Name = utils.Ternary[string](c.Name == "", old.Name, c.Name)
+	c.Description = utils.Ternary[string](c.Description == "", old.Description, c.Description)
+	c.Enable = utils.Ternary[*bool](c.Enable == nil, old.Enable, c.Enable)
+	c.Scope = utils.Ternary[common.QuotaScopeType](c.Scope == "", old.Scope, c.Scope)
+	c.ScopeValue = utils.Ternary[string](c.ScopeValue == "", old.ScopeValue, c.ScopeValue)
+	c.MaxParallel = utils.Ternary[int](c.MaxParallel == common.InvalidInt, old.MaxParallel, c.MaxParallel)
+	c.Weight = utils.Ternary[int](c.Weight == 0, old.Weight, c.Weight)
+
+	// 参数校验
+	if ok, res, err := c.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", c, err)
+		return res, err
+	}
+
+	if res, err := c.checkConflict(ctx); res != common.CodeOK {
+		return res, err
+	}
+
+	newQuota := c.ServiceToModel()
+	newQuota.CreatedAt = quota.CreatedAt
+	newQuota.Creator = quota.Creator
+	newQuota.Editor = u.UserName
+	err = db.Save(newQuota).Error
+	if err != nil {
+		log.Errorf("update models.Quota(%+v) from db failed, %s", newQuota, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(newQuota)
+	return common.CodeOK, nil
+}
+
+func (c *QuotaService) DeleteQuota(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	quota := &models.Quota{}
+	err := db.Model(quota).First(quota, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("query models.Quota(id=%d) not exist", c.ID)
+			log.Error(err)
+			return common.CodeQuotaNotExist, err
+		}
+		err = fmt.Errorf("query models.Quota(id=%d) from db failed, %s", c.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	db.Model(&models.Quota{}).Where("id =?", quota.ID).Update("editor", u.UserName)
+	err = db.Delete(&models.Quota{}, "id =?", quota.ID).Error
+	if err != nil {
+		err = fmt.Errorf("delete models.Quota(id=%d) from db failed, %s", quota.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	return common.CodeOK, nil
+}
+
+func (c *QuotaService) QueryQuota(ctx *gin.Context, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.Quota](db).
+		QueryPaging(ctx).
+		Order("id desc").
+		Query(
+			common.FilterFuzzyStringMap(queryMap),
+			common.FilterID(c.ID),
+		)
+	if err != nil {
+		err = fmt.Errorf("query models.Quota from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]QuotaService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		q := &QuotaService{}
+		q.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *q)
+	}
+	return ret, common.CodeOK, nil
+}
+
+// EnabledQuota 查询BU或集群生效中的配额，不存在时返回nil
+func EnabledQuota(db *gorm.DB, scope common.QuotaScopeType, value string) (*models.Quota, error) {
+	var quotas []models.Quota
+	err := db.Model(&models.Quota{}).Where("enable =? AND scope =? AND scope_value =?", true, scope, value).
+		Limit(1).Find(&quotas).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.Quota(scope=%s, scope_value=%s) from db failed, %s", scope, value, err)
+	}
+	if len(quotas) == 0 {
+		return nil, nil
+	}
+	return &quotas[0], nil
+}
+
+// BuQuotaWeights 查询各BU生效中的公平分配权重，未配置的BU权重为1
+func BuQuotaWeights(db *gorm.DB) (map[string]int, error) {
+	var quotas []models.Quota
+	err := db.Model(&models.Quota{}).Where("enable =? AND scope =?", true, common.QuotaScopeBu).Find(&quotas).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.Quota(scope=%s) from db failed, %s", common.QuotaScopeBu, err)
+	}
+	weights := make(map[string]int, len(quotas))
+	for _, q := range quotas {
+		weights[q.ScopeValue] = max(q.Weight, 1)
+	}
+	return weights, nil
+}
+
+func (c *QuotaService) ServiceToModel() *models.Quota {
+	m := &models.Quota{}
+	m.ID = c.ID
+	m.Creator = c.Creator
+	m.Editor = c.Editor
+	m.CreatedAt, _ = time.ParseInLocation(time.DateTime, c.CreatedAt, time.Now().Location())
+	m.UpdatedAt, _ = time.ParseInLocation(time.DateTime, c.UpdatedAt, time.Now().Location())
+	m.Name = c.Name
+	m.Description = c.Description
+	m.Enable = c.Enable != nil && *c.Enable
+	m.Scope = c.Scope
+	m.ScopeValue = c.ScopeValue
+	m.MaxParallel = c.MaxParallel
+	m.Weight = c.Weight
+	return m
+}
+
+func (c *QuotaService) ModelToService(m *models.Quota) *QuotaService {
+	c.ID = m.ID
+	c.Creator = m.Creator
+	c.Editor = m.Editor
+	c.CreatedAt = m.CreatedAt.Format(time.DateTime)
+	c.UpdatedAt = m.UpdatedAt.Format(time.DateTime)
+	c.Name = m.Name
+	c.Description = m.Description
+	c.Enable = &m.Enable
+	c.Scope = m.Scope
+	c.ScopeValue = m.ScopeValue
+	c.MaxParallel = m.MaxParallel
+	c.Weight = m.Weight
+	return c
+}
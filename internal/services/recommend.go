@@ -0,0 +1,503 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/configs"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/mysql"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// recommendDataAgeDays 统计早于N天的数据量，从大到小选择第一个满足清理比例的作为保留天数
+	recommendDataAgeDays = []int{30, 90, 180, 365, 730}
+	// recommendMinCleanRatio 早于N天的数据占比超过该值才值得清理
+	recommendMinCleanRatio = 0.3
+	// recommendDefaultRetainDays 无法根据数据年龄判断时默认保留的天数
+	recommendDefaultRetainDays = 180
+	// recommendAutoIncRetainRatio 只有自增列时保留最近的数据比例
+	recommendAutoIncRetainRatio = 0.3
+	// recommendDefaultExecuteWindow 配置文件中没有配置推荐的执行窗口时使用的默认值
+	recommendDefaultExecuteWindow = []string{"01:00:00", "05:00:00"}
+)
+
+// PolicyRecommendService 策略推荐
+type PolicyRecommendService struct {
+	Model
+	Bu          string `json:"bu"`           // bu
+	ClusterID   string `json:"cluster_id"`   // 集群ID
+	ClusterName string `json:"cluster_name"` // 集群名称
+	Database    string `json:"database"`     // 库名
+	Table       string `json:"table"`        // 表名
+	TableSize   int    `json:"table_size"`   // 表大小(GB)
+
+	Column     string               `json:"column"`      // 治理条件使用的列
+	ColumnType string               `json:"column_type"` // 列类型
+	IndexName  string               `json:"index_name"`  // 列上的索引
+	DataAge    *mysql.ColumnDataAge `json:"data_age"`    // 数据年龄分布
+	Reason     string               `json:"reason"`      // 推荐理由
+
+	Govern        common.GovernType        `json:"govern"`         // 数据治理方式
+	Condition     string                   `json:"condition"`      // 数据治理条件
+	RetainDays    int                      `json:"retain_days"`    // 保留最近多少天的数据
+	Period        common.PeriodType        `json:"period"`         // 执行周期
+	Day           int                      `json:"day"`            // 期望在每月的几号执行
+	ExecuteWindow []string                 `json:"execute_window"` // 执行时间窗口
+	CleaningSpeed common.CleaningSpeedType `json:"cleaning_speed"` // 清理速度
+
+	Status        common.RecommendStatusType `json:"status"`         // 状态 待处理:pending, 已采纳:accepted, 已忽略:dismissed
+	DismissReason string                     `json:"dismiss_reason"` // 忽略原因
+	SrcID         uint                       `json:"src_id"`         // 采纳后对应的源端ID
+	PolicyID      uint                       `json:"policy_id"`      // 采纳后创建的策略ID
+}
+
+// PolicyRecommendAcceptService 采纳策略推荐，未填写的字段使用推荐的值
+type PolicyRecommendAcceptService struct {
+	ID            uint              `json:"id"`             // 策略推荐ID
+	Name          string            `json:"name"`           // 策略名称
+	Govern        common.GovernType `json:"govern"`         // 数据治理方式
+	Condition     string            `json:"condition"`      // 数据治理条件
+	DestID        uint              `json:"dest_id"`        // 目标端ID（仅归档涉及）
+	ExecuteWindow []string          `json:"execute_window"` // 执行时间窗口
+}
+
+// PolicyRecommendDismissService 忽略策略推荐
+type PolicyRecommendDismissService struct {
+	ID     uint   `json:"id"`     // 策略推荐ID
+	Reason string `json:"reason"` // 忽略原因
+}
+
+var RefreshPolicyRecommendLock sync.Mutex
+
+// RefreshPolicyRecommend 为最近一次统计中没有策略覆盖的大表生成策略推荐
+func (_ *PolicyRecommendService) RefreshPolicyRecommend(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	if !RefreshPolicyRecommendLock.TryLock() {
+		err := fmt.Errorf("there is already a task refreshing the policy recommend")
+		return common.CodePolicyRecommendExisted, err
+	}
+	defer RefreshPolicyRecommendLock.Unlock()
+
+	var date string
+	err := db.Model(models.ClusterStatistics{}).Select("IFNULL(MAX(date), '')").Scan(&date).Error
+	if err != nil {
+		err = fmt.Errorf("query models.ClusterStatistics latest date failed, %s", err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	if date == "" {
+		return common.CodeOK, nil
+	}
+
+	var bigTables []models.ClusterStatistics
+	err = db.Model(models.ClusterStatistics{}).Where("date =?", date).Find(&bigTables).Error
+	if err != nil {
+		err = fmt.Errorf("query models.ClusterStatistics(date=%s) failed, %s", date, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	var recommends []models.PolicyRecommend
+	err = db.Model(models.PolicyRecommend{}).Find(&recommends).Error
+	if err != nil {
+		err = fmt.Errorf("query models.PolicyRecommend failed, %s", err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	genKey := func(clusterID, database, table string) string {
+		return strings.Join([]string{clusterID, database, table}, "::::")
+	}
+	recommendMap := make(map[string]*models.PolicyRecommend, len(recommends))
+	for i, r := range recommends {
+		recommendMap[genKey(r.ClusterID, r.Database, r.Table)] = &recommends[i]
+	}
+
+	clusters := make(map[string]*ClusterService)
+	for _, t := range bigTables {
+		key := genKey(t.ClusterID, t.Database, t.Table)
+		old, exist := recommendMap[key]
+
+		// 已经有策略覆盖的表不再推荐，之前未处理的推荐一并删除
+		if t.Policies != "" {
+			if exist && old.Status == common.RecommendStatusPending {
+				_ = db.Delete(old).Error
+			}
+			continue
+		}
+
+		// 已采纳或已忽略的不再重复推荐
+		if exist && old.Status != common.RecommendStatusPending {
+			continue
+		}
+
+		clusterSvc, ok := clusters[t.ClusterID]
+		if !ok {
+			clusterSvc, err = GetClusterServiceByClusterID(ctx, t.ClusterID)
+			if err != nil {
+				log.Errorf("get cluster(%s) failed, %s", t.ClusterID, err)
+				continue
+			}
+			clusters[t.ClusterID] = clusterSvc
+		}
+
+		recommend, err := recommendPolicy(ctx, clusterSvc, &t)
+		if err != nil {
+			log.Warnf("recommend policy for table(%s.%s.%s) failed, %s", t.ClusterID, t.Database, t.Table, err)
+			continue
+		}
+		if recommend == nil {
+			continue
+		}
+
+		if exist {
+			recommend.ID = old.ID
+			recommend.CreatedAt = old.CreatedAt
+		}
+		recommend.Creator = common.SystemUser
+		err = db.Save(recommend).Error
+		if err != nil {
+			log.Errorf("save models.PolicyRecommend(%s.%s.%s) failed, %s", t.ClusterID, t.Database, t.Table, err)
+		}
+	}
+
+	return common.CodeOK, nil
+}
+
+// recommendPolicy 根据表上带索引的时间列或自增列以及数据年龄分布生成策略草稿，没有合适的列时返回nil
+func recommendPolicy(ctx *gin.Context, c *ClusterService, t *models.ClusterStatistics) (*models.PolicyRecommend, error) {
+	columns, _, err := ClusterTableColumns(ctx, c, t.Database, t.Table)
+	if err != nil {
+		return nil, err
+	}
+	indexes, _, err := ClusterTableIndexes(ctx, c, t.Database, t.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	// 索引的第一列才能用于范围查找
+	leading := make(map[string]string, len(indexes))
+	for _, idx := range indexes {
+		if len(idx.Columns) != 0 {
+			if _, ok := leading[idx.Columns[0]]; !ok {
+				leading[idx.Columns[0]] = idx.Name
+			}
+		}
+	}
+
+	var timeColumns, autoIncColumns []mysql.ColumnSchema
+	for _, col := range columns {
+		if _, ok := leading[col.Name]; !ok {
+			continue
+		}
+		if isTimeColumnType(col.Type) {
+			timeColumns = append(timeColumns, col)
+		} else if strings.Contains(strings.ToLower(col.Extra), "auto_increment") {
+			autoIncColumns = append(autoIncColumns, col)
+		}
+	}
+
+	r := &models.PolicyRecommend{
+		Bu:            t.Bu,
+		ClusterID:     t.ClusterID,
+		ClusterName:   t.ClusterName,
+		Database:      t.Database,
+		Table:         t.Table,
+		TableSize:     t.TableSize,
+		Govern:        common.GovernTypeDelete,
+		CleaningSpeed: common.CleaningSpeedSteady,
+		Status:        common.RecommendStatusPending,
+	}
+	r.ExecuteWindow, _ = json.Marshal(recommendExecuteWindow())
+
+	switch {
+	case len(timeColumns) != 0:
+		// 优先使用创建时间，更新时间会随数据修改而变化不适合作为治理条件
+		sort.SliceStable(timeColumns, func(i, j int) bool {
+			return timeColumnRank(timeColumns[i].Name) < timeColumnRank(timeColumns[j].Name)
+		})
+		col := timeColumns[0]
+
+		age, _, err := NewClusterDriver(c).GetColumnDataAge(ctx, t.Database, t.Table, col.Name, recommendDataAgeDays)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Column, r.ColumnType, r.IndexName = col.Name, col.Type, leading[col.Name]
+		r.DataAge, _ = json.Marshal(age)
+		r.RetainDays = recommendDefaultRetainDays
+		r.Reason = fmt.Sprintf("表大小%dGB且没有策略覆盖，列%s上有索引%s，", t.TableSize, col.Name, r.IndexName)
+
+		retain, ratio := retainDaysByDataAge(age)
+		if retain > 0 {
+			r.RetainDays = retain
+			r.Reason += fmt.Sprintf("早于%d天的数据约占%.0f%%，建议保留最近%d天的数据", retain, ratio*100, retain)
+		} else {
+			r.Reason += fmt.Sprintf("数据大多为近期写入，清理收益较低，默认保留最近%d天的数据", r.RetainDays)
+		}
+
+		r.Condition = fmt.Sprintf("%s < DATE_SUB(NOW(), INTERVAL %d DAY)", col.Name, r.RetainDays)
+		if r.RetainDays <= 90 {
+			r.Period = common.PeriodWeekly
+		} else {
+			r.Period, r.Day = common.PeriodMonthly, 1
+		}
+	case len(autoIncColumns) != 0:
+		col := autoIncColumns[0]
+
+		age, _, err := NewClusterDriver(c).GetColumnDataAge(ctx, t.Database, t.Table, col.Name, nil)
+		if err != nil {
+			return nil, err
+		}
+		minID, err1 := strconv.ParseInt(age.Min, 10, 64)
+		maxID, err2 := strconv.ParseInt(age.Max, 10, 64)
+		if err1 != nil || err2 != nil || maxID <= minID {
+			return nil, nil
+		}
+
+		r.Column, r.ColumnType, r.IndexName = col.Name, col.Type, leading[col.Name]
+		r.DataAge, _ = json.Marshal(age)
+		r.Condition = fmt.Sprintf("%s < %d", col.Name, maxID-int64(float64(maxID-minID)*recommendAutoIncRetainRatio))
+		r.Period = common.PeriodOnce
+		r.Reason = fmt.Sprintf("表大小%dGB且没有策略覆盖，表上没有带索引的时间列，按自增列%s保留最近%.0f%%的数据，请确认后再采纳",
+			t.TableSize, col.Name, recommendAutoIncRetainRatio*100)
+	default:
+		return nil, nil
+	}
+
+	return r, nil
+}
+
+// recommendExecuteWindow 推荐的执行窗口，优先使用配置文件中的配置
+func recommendExecuteWindow() []string {
+	if configs.C != nil && len(configs.C.Job.RecommendExecuteWindow) != 0 {
+		return configs.C.Job.RecommendExecuteWindow
+	}
+	return recommendDefaultExecuteWindow
+}
+
+// retainDaysByDataAge 从大到小找到第一个早于N天的数据占比超过清理比例的N
+func retainDaysByDataAge(age *mysql.ColumnDataAge) (int, float64) {
+	if age == nil || age.TableRows <= 0 {
+		return 0, 0
+	}
+	for i := len(age.Buckets) - 1; i >= 0; i-- {
+		ratio := float64(age.Buckets[i].Rows) / float64(age.TableRows)
+		if ratio >= recommendMinCleanRatio {
+			return age.Buckets[i].Days, min(ratio, 1)
+		}
+	}
+	return 0, 0
+}
+
+func isTimeColumnType(t string) bool {
+	t = strings.ToLower(t)
+	return strings.HasPrefix(t, "datetime") || strings.HasPrefix(t, "timestamp") || t == "date"
+}
+
+func timeColumnRank(name string) int {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "creat"), strings.Contains(name, "insert"), strings.Contains(name, "add"):
+		return 0
+	case strings.Contains(name, "updat"), strings.Contains(name, "modif"):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// QueryPolicyRecommend 查询策略推荐
+func (c *PolicyRecommendService) QueryPolicyRecommend(ctx *gin.Context, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.PolicyRecommend](db).
+		QueryPaging(ctx).
+		Order("table_size desc, id desc").
+		Query(
+			common.FilterFuzzyStringMap(queryMap),
+			common.FilterID(c.ID),
+		)
+	if err != nil {
+		log.Errorf("query models.PolicyRecommend from db faield, %s", err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]PolicyRecommendService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		s := &PolicyRecommendService{}
+		s.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *s)
+	}
+
+	return ret, common.CodeOK, nil
+}
+
+// AcceptPolicyRecommend 采纳策略推荐，在同一个事务中创建源端和策略，策略校验不通过时不会留下源端
+func (c *PolicyRecommendAcceptService) AcceptPolicyRecommend(ctx *gin.Context) (*PolicyService, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	recommend, code, err := getPendingPolicyRecommend(ctx, c.ID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	src := &SourceService{
+		ClusterID:    recommend.ClusterID,
+		DatabaseName: recommend.Database,
+		TablesName:   recommend.Table,
+	}
+	if ok, code, err := src.CheckParameters(ctx); !ok {
+		return nil, code, fmt.Errorf("check source parameters not pass, %s", err)
+	}
+
+	policy := &PolicyService{
+		Name:          c.Name,
+		Description:   recommend.Reason,
+		Enable:        true,
+		Period:        recommend.Period,
+		Day:           recommend.Day,
+		CleaningSpeed: recommend.CleaningSpeed,
+		Govern:        recommend.Govern,
+		Condition:     recommend.Condition,
+		DestID:        c.DestID,
+		ExecuteWindow: c.ExecuteWindow,
+	}
+	if len(policy.ExecuteWindow) == 0 {
+		_ = json.Unmarshal(recommend.ExecuteWindow, &policy.ExecuteWindow)
+	}
+	if c.Govern != "" {
+		policy.Govern = c.Govern
+	}
+	if c.Condition != "" {
+		policy.Condition = c.Condition
+	}
+
+	// 策略的校验依赖源端，源端和策略在同一个事务中创建，任何一步失败都回滚
+	code = common.CodeOK
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txCtx := ctx.Copy()
+		txCtx.Set(common.DB, tx)
+
+		if code, err = src.CreateSource(txCtx); err != nil {
+			return fmt.Errorf("create source failed, %s", err)
+		}
+		policy.SrcID = src.ID
+
+		var ok bool
+		if ok, code, err = policy.CheckParameters(txCtx); !ok {
+			return fmt.Errorf("check policy parameters not pass, %s", err)
+		}
+		if code, err = policy.CreatePolicy(txCtx); err != nil {
+			return fmt.Errorf("create policy failed, %s", err)
+		}
+
+		err = tx.Model(recommend).Updates(map[string]any{
+			"status":    common.RecommendStatusAccepted,
+			"src_id":    src.ID,
+			"policy_id": policy.ID,
+			"editor":    u.UserName,
+		}).Error
+		if err != nil {
+			code = common.CodeServerErr
+			err = fmt.Errorf("update models.PolicyRecommend(%v) failed, %s", c.ID, err)
+			log.Error(err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return policy, common.CodeOK, nil
+}
+
+// DismissPolicyRecommend 忽略策略推荐
+func (c *PolicyRecommendDismissService) DismissPolicyRecommend(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	c.Reason = strings.TrimSpace(c.Reason)
+	if c.Reason == "" {
+		return common.CodePolicyDismissReasonErr, fmt.Errorf("reason can not be empty")
+	}
+
+	recommend, code, err := getPendingPolicyRecommend(ctx, c.ID)
+	if err != nil {
+		return code, err
+	}
+
+	err = db.Model(recommend).Updates(map[string]any{
+		"status":         common.RecommendStatusDismissed,
+		"dismiss_reason": c.Reason,
+		"editor":         u.UserName,
+	}).Error
+	if err != nil {
+		err = fmt.Errorf("update models.PolicyRecommend(%v) failed, %s", c.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	return common.CodeOK, nil
+}
+
+func getPendingPolicyRecommend(ctx *gin.Context, id uint) (*models.PolicyRecommend, common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	recommend := &models.PolicyRecommend{}
+	err := db.Model(recommend).Where("id =?", id).First(recommend).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.CodePolicyRecommendNotExist, fmt.Errorf("models.PolicyRecommend(%v) not exist", id)
+		}
+		return nil, common.CodeServerErr, fmt.Errorf("query models.PolicyRecommend(%v) failed, %s", id, err)
+	}
+	if recommend.Status != common.RecommendStatusPending {
+		return nil, common.CodePolicyRecommendHandled, fmt.Errorf("models.PolicyRecommend(%v) has been %s", id, recommend.Status)
+	}
+	return recommend, common.CodeOK, nil
+}
+
+func (c *PolicyRecommendService) ModelToService(m *models.PolicyRecommend) *PolicyRecommendService {
+	c.ID = m.ID
+	c.Creator = m.Creator
+	c.Editor = m.Editor
+	c.CreatedAt = m.CreatedAt.Format(time.DateTime)
+	c.UpdatedAt = m.UpdatedAt.Format(time.DateTime)
+	c.Bu = m.Bu
+	c.ClusterID = m.ClusterID
+	c.ClusterName = m.ClusterName
+	c.Database = m.Database
+	c.Table = m.Table
+	c.TableSize = m.TableSize
+	c.Column = m.Column
+	c.ColumnType = m.ColumnType
+	c.IndexName = m.IndexName
+	c.Reason = m.Reason
+	c.Govern = m.Govern
+	c.Condition = m.Condition
+	c.RetainDays = m.RetainDays
+	c.Period = m.Period
+	c.Day = m.Day
+	c.CleaningSpeed = m.CleaningSpeed
+	c.Status = m.Status
+	c.DismissReason = m.DismissReason
+	c.SrcID = m.SrcID
+	c.PolicyID = m.PolicyID
+	_ = json.Unmarshal(m.DataAge, &c.DataAge)
+	_ = json.Unmarshal(m.ExecuteWindow, &c.ExecuteWindow)
+	return c
+}
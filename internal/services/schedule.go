@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// GenerateTaskByPolicy 根据策略和源端信息生成任务，执行日期需由调用方设置
+func GenerateTaskByPolicy(task *models.Task, policy *models.Policy, source *models.Source) *models.Task {
+	task.Creator = policy.Creator
+	task.Name = fmt.Sprintf("%s-%s", common.PeriodCN[policy.Period], policy.Name)
+	task.Enable = policy.Enable
+	task.PolicyID = policy.ID
+	task.ExecuteWindow = policy.ExecuteWindow
+
+	task.TaskStartTime = time.UnixMilli(0)
+	task.TaskEndTime = time.UnixMilli(0)
+	task.Pause = policy.Pause
+	task.RebuildFlag = policy.RebuildFlag
+	task.TaskStatus = common.TaskStatusScheduled
+	task.TaskReason = ""
+	task.TaskDetail = ""
+
+	// 源端信息
+	task.SrcID = policy.SrcID
+	task.SrcName = source.Name
+	task.SrcBu = source.Bu
+	task.SrcClusterID = source.ClusterID
+	task.SrcClusterName = source.ClusterName
+	task.SrcDatabaseName = source.DatabaseName
+	task.SrcTablesName = source.TablesName
+	task.SrcColumns = policy.ArchiveScope
+
+	// 数据清理方式
+	task.Govern = policy.Govern
+	task.Condition = policy.Condition
+	task.RetainSrcData = policy.RetainSrcData
+	task.CleaningSpeed = policy.CleaningSpeed
+	task.ThrottleID = policy.ThrottleID
+	task.PartitionColumn = policy.PartitionColumn
+	task.PartitionInterval = policy.PartitionInterval
+	task.PartitionRetainDays = policy.PartitionRetainDays
+	task.PartitionPrecreate = policy.PartitionPrecreate
+	task.PartitionAction = policy.PartitionAction
+	task.WatermarkColumn = policy.WatermarkColumn
+
+	task.Relevant = policy.Relevant
+	task.NotifyPolicy = policy.NotifyPolicy
+	task.Priority = policy.Priority
+	return task
+}
+
+// PolicyNextScheduleTime 根据上次的执行日期计算策略下一次的执行日期，cron表达式无法解析或之后不会再触发时返回错误
+func PolicyNextScheduleTime(policy *models.Policy, lastExecDate time.Time) (time.Time /*仅精确到天*/, error) {
+	if lastExecDate.IsZero() {
+		return time.Now(), nil
+	}
+	switch policy.Period {
+	case common.PeriodDay:
+		return lastExecDate.Add(time.Hour * 24), nil
+	case common.PeriodTwoDay:
+		return lastExecDate.Add(time.Hour * 24 * 2), nil
+	case common.PeriodWeekly:
+		return lastExecDate.Add(time.Hour * 24 * 7), nil
+	case common.PeriodTwoWeeks:
+		return lastExecDate.Add(time.Hour * 24 * 14), nil
+	case common.PeriodOnce:
+		return lastExecDate.Add(time.Hour * 24), nil
+	case common.PeriodMonthly:
+		return lastExecDate.AddDate(0, 1, 0), nil
+	case common.PeriodQuarterly:
+		return lastExecDate.AddDate(0, 3, 0), nil
+	case common.PeriodSixMonths:
+		return lastExecDate.AddDate(0, 6, 0), nil
+	case common.PeriodYearly:
+		return lastExecDate.AddDate(1, 0, 0), nil
+	case common.PeriodCron:
+		// 取上次执行日期之后第一个满足cron表达式的日期
+		schedule, err := common.ParseCronExpr(policy.CronExpr)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse policy(%v) cron_expr(%s) failed, %s", policy.ID, policy.CronExpr, err)
+		}
+		next := schedule.Next(lastExecDate)
+		if next.IsZero() {
+			return time.Time{}, fmt.Errorf("policy(%v) cron_expr(%s) will not be triggered after %s",
+				policy.ID, policy.CronExpr, lastExecDate.Format(time.DateOnly))
+		}
+		return next, nil
+	default:
+		return time.Now(), nil
+	}
+}
+
+// FormatExecDate 按策略的周期格式化执行日期，月度及以上周期取策略指定的day
+func FormatExecDate(policy *models.Policy, executeTime time.Time) string {
+	switch policy.Period {
+	case common.PeriodMonthly, common.PeriodQuarterly, common.PeriodSixMonths, common.PeriodYearly:
+		maxDay := utils.DaysInMonth(executeTime.Year(), int(executeTime.Month()))
+		if policy.Day > maxDay {
+			return fmt.Sprintf("%d-%02d-%02d", executeTime.Year(), executeTime.Month(), maxDay)
+		}
+		return fmt.Sprintf("%d-%02d-%02d", executeTime.Year(), executeTime.Month(), policy.Day)
+	default:
+		return executeTime.Format(time.DateOnly)
+	}
+}
+
+// ClusterTimezone 查询集群的时区，集群不存在或未配置时区时使用服务所在时区
+func ClusterTimezone(db *gorm.DB, clusterID string) (string, error) {
+	var clusters []models.Cluster
+	err := db.Model(models.Cluster{}).Select("timezone").Where("cluster_id =?", clusterID).Limit(1).Find(&clusters).Error
+	if err != nil {
+		return "", fmt.Errorf("query models.Cluster(cluster_id=%v) from db failed, %s", clusterID, err)
+	}
+	if len(clusters) == 0 {
+		return "", nil
+	}
+	return clusters[0].Timezone, nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+func TestPolicyNextScheduleTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		period  common.PeriodType
+		cron    string
+		last    string
+		want    string
+		wantErr bool
+	}{
+		{"day", common.PeriodDay, "", "2024-01-31 00:00:00", "2024-02-01", false},
+		{"two weeks", common.PeriodTwoWeeks, "", "2024-01-31 00:00:00", "2024-02-14", false},
+		{"monthly", common.PeriodMonthly, "", "2024-01-15 00:00:00", "2024-02-15", false},
+		{"yearly", common.PeriodYearly, "", "2024-01-15 00:00:00", "2025-01-15", false},
+		{"cron", common.PeriodCron, "0 0 * * 0L", "2024-01-15 00:00:00", "2024-01-28", false},
+		{"cron parse failed", common.PeriodCron, "0 0 x * *", "2024-01-15 00:00:00", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := &models.Policy{Period: test.period, CronExpr: test.cron}
+			got, err := PolicyNextScheduleTime(policy, parseLocalTime(test.last))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("PolicyNextScheduleTime() got err %v, want err %v", err, test.wantErr)
+			}
+			if err == nil && got.Format(time.DateOnly) != test.want {
+				t.Fatalf("PolicyNextScheduleTime() got %s, want %s", got.Format(time.DateOnly), test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,695 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+const (
+	SimulateDefaultDays     = 7         // 默认模拟未来7天
+	SimulateMaxDays         = 31        // 最多模拟未来31天
+	SimulateHistoryTasks    = 5         // 取策略最近几次执行成功的任务的平均时长作为预估执行时长
+	SimulateDefaultDuration = time.Hour // 策略没有历史执行记录时的预估执行时长
+
+	simulateStep      = time.Minute // 与调用工作流的定时任务周期一致
+	simulateMaxPeriod = 366         // 每个策略最多模拟的周期数
+)
+
+// SimulateService 模拟调整配置或策略后未来N天任务的执行情况，不会修改任何配置、策略和任务
+type SimulateService struct {
+	Days              int                          `json:"days"`                // 模拟未来多少天(1-31)，默认7天
+	TaskMaxParallel   int                          `json:"task_max_parallel"`   // 假设的任务最大并发数，0表示使用当前配置
+	TaskConflictLevel common.TaskConflictLevelType `json:"task_conflict_level"` // 假设的任务冲突级别，为空表示使用当前配置
+	TaskConflictMax   int                          `json:"task_conflict_max"`   // 假设的冲突级别下最多允许运行的任务数，0表示使用当前配置
+	Policies          []SimulatePolicy             `json:"policies"`            // 假设修改的策略，未生效的策略按生效模拟
+
+	StartTime string          `json:"start_time"` // 模拟的开始时间
+	EndTime   string          `json:"end_time"`   // 模拟的结束时间
+	Count     int             `json:"count"`      // 预测开始执行的任务个数
+	Tasks     []*SimulateTask `json:"tasks"`      // 预测开始执行的任务，按开始执行时间排序
+	Unfit     []*SimulateTask `json:"unfit"`      // 模拟期间无法开始执行的任务及原因
+}
+
+// SimulatePolicy 假设修改的策略字段，零值表示不修改
+type SimulatePolicy struct {
+	ID             uint                `json:"id"`              // 策略ID
+	Disable        bool                `json:"disable"`         // 假设停用该策略
+	Period         common.PeriodType   `json:"period"`          // 执行周期
+	Day            int                 `json:"day"`             // 期望在每月的几号执行
+	CronExpr       string              `json:"cron_expr"`       // cron表达式(执行周期为cron时有效)
+	ExecuteWindow  []string            `json:"execute_window"`  // 执行时间窗口 如: ["03:00:00", "05:00:00"]
+	ExecuteWindows []common.ExecWindow `json:"execute_windows"` // 多执行窗口
+	Priority       int                 `json:"priority"`        // 优先级(1-10)
+}
+
+// SimulateTask 模拟的任务
+type SimulateTask struct {
+	ID            uint                  `json:"id"`              // 任务ID，模拟生成的后续周期任务为0
+	Name          string                `json:"name"`            // 任务名称
+	PolicyID      uint                  `json:"policy_id"`       // 策略ID
+	Priority      int                   `json:"priority"`        // 优先级
+	ExecuteDate   string                `json:"execute_date"`    // 执行日期
+	Timezone      string                `json:"timezone"`        // 执行日期和执行窗口所在时区
+	TaskStatus    common.TaskStatusType `json:"task_status"`     // 任务当前的状态，模拟生成的任务为已排期
+	Duration      int                   `json:"duration"`        // 按历史执行时长预估的执行时长(秒)
+	HistoryCount  int                   `json:"history_count"`   // 用于预估执行时长的历史任务数，0表示按默认时长预估
+	TaskStartTime string                `json:"task_start_time"` // 预测开始执行时间
+	TaskEndTime   string                `json:"task_end_time"`   // 预测执行结束时间
+	OutOfWindow   bool                  `json:"out_of_window"`   // 预测在执行窗口内无法执行完(执行窗口外不暂停时会继续执行)
+	TaskReason    string                `json:"task_reason"`     // 最近一次未通过准入检查的原因
+	TaskDetail    string                `json:"task_detail"`     // 最近一次未通过准入检查的详情
+}
+
+// simTask 模拟过程中的任务
+type simTask struct {
+	out       *SimulateTask
+	task      models.Task
+	dependsOn []uint
+	windows   []common.ExecWindow
+	blackouts []models.Blackout
+	prev      *simTask // 同一策略上一个周期的任务
+	lastDate  string   // 同一策略上一个周期的执行日期，用于判断依赖的策略在本周期的任务
+
+	remaining time.Duration // 剩余的执行时长
+	notBefore time.Time     // 在此之前不会通过执行日期、执行窗口和封网检查
+	segStart  time.Time     // 本次开始执行的时间
+	segEnd    time.Time     // 本次执行结束或因超出执行窗口暂停的时间
+	started   bool
+	running   bool
+	finished  bool
+}
+
+// simulator 按模拟的配置回放任务准入过程
+type simulator struct {
+	maxParallel   int
+	conflictLevel common.TaskConflictLevelType
+	conflictMax   int
+	fairShare     bool
+	quotas        map[string]*models.Quota // scope::::value -> 配额
+	weights       map[string]int           // BU的公平分配权重
+	tasks         []*simTask
+	byPolicy      map[uint][]*simTask
+}
+
+// Simulate 按假设的配置和策略，回放执行日期、执行窗口、封网、依赖、最大并发、配额和冲突等准入检查，
+// 预测未来N天每个任务的开始和结束时间，执行时长取策略最近几次执行成功的任务的平均时长
+func (c *SimulateService) Simulate(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	if c.Days == 0 {
+		c.Days = SimulateDefaultDays
+	}
+	if c.Days < 1 || c.Days > SimulateMaxDays {
+		return common.CodeTaskSimulateDaysErr, fmt.Errorf("simulate days(%v) should in (1-%v)", c.Days, SimulateMaxDays)
+	}
+
+	sim := &simulator{
+		maxParallel:   utils.Ternary[int](c.TaskMaxParallel > 0, c.TaskMaxParallel, Cfg.TaskMaxParallel),
+		conflictLevel: utils.Ternary[common.TaskConflictLevelType](c.TaskConflictLevel != "", c.TaskConflictLevel, Cfg.TaskConflictLevel),
+		conflictMax:   utils.Ternary[int](c.TaskConflictMax > 0, c.TaskConflictMax, Cfg.TaskConflictMax),
+		fairShare:     Cfg.TaskFairShare,
+		byPolicy:      make(map[uint][]*simTask),
+	}
+	if !common.CheckTaskConflictLevel(sim.conflictLevel) {
+		return common.CodeConfigConflictLevelErr, fmt.Errorf("not support task conflict level: %s", sim.conflictLevel)
+	}
+
+	policies, code, err := c.simulatePolicies(db)
+	if err != nil {
+		return code, err
+	}
+
+	err = sim.loadQuotas(db)
+	if err != nil {
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	start := time.Now().Truncate(simulateStep)
+	end := start.AddDate(0, 0, c.Days)
+	c.StartTime, c.EndTime = start.Format(time.DateTime), end.Format(time.DateTime)
+
+	err = sim.loadTasks(db, policies, c.scheduleChanged, start, end)
+	if err != nil {
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	sim.run(start, end)
+
+	for _, s := range sim.tasks {
+		if s.started {
+			c.Tasks = append(c.Tasks, s.out)
+		} else {
+			c.Unfit = append(c.Unfit, s.out)
+		}
+	}
+	sort.SliceStable(c.Tasks, func(i, j int) bool { return c.Tasks[i].TaskStartTime < c.Tasks[j].TaskStartTime })
+	sort.SliceStable(c.Unfit, func(i, j int) bool { return c.Unfit[i].ExecuteDate < c.Unfit[j].ExecuteDate })
+	c.Count = len(c.Tasks)
+	return common.CodeOK, nil
+}
+
+// simulatePolicies 查询生效的策略并应用假设的修改
+func (c *SimulateService) simulatePolicies(db *gorm.DB) ([]*models.Policy, common.ServiceCode, error) {
+	overrides := make(map[uint]SimulatePolicy, len(c.Policies))
+	ids := make([]uint, 0, len(c.Policies))
+	for i, p := range c.Policies {
+		if p.Period != "" && !common.CheckPeriod(p.Period) {
+			return nil, common.CodePolicyPeriodErr, fmt.Errorf("validate policy(%v) period(%s) not pass", p.ID, p.Period)
+		}
+		if p.Priority != 0 && !common.CheckPriority(p.Priority) {
+			return nil, common.CodePolicyPriorityErr, fmt.Errorf("validate policy(%v) priority(%d) not pass", p.ID, p.Priority)
+		}
+		if p.CronExpr != "" && !common.CheckCronExpr(p.CronExpr) {
+			return nil, common.CodePolicyCronExprErr, fmt.Errorf("validate policy(%v) cron_expr(%s) not pass", p.ID, p.CronExpr)
+		}
+		if len(p.ExecuteWindow) != 0 || len(p.ExecuteWindows) != 0 {
+			var err error
+			c.Policies[i].ExecuteWindow, c.Policies[i].ExecuteWindows, err = checkExecWindows(p.ExecuteWindow, p.ExecuteWindows)
+			if err != nil {
+				return nil, common.CodePolicyExecuteWindowErr, err
+			}
+		}
+		overrides[p.ID] = c.Policies[i]
+		ids = append(ids, p.ID)
+	}
+
+	var policies []*models.Policy
+	err := db.Model(&models.Policy{}).Where("enable =? OR id IN (?)", true, append(ids, 0)).Find(&policies).Error
+	if err != nil {
+		return nil, common.CodeServerErr, fmt.Errorf("query models.Policy from db failed, %s", err)
+	}
+
+	res := make([]*models.Policy, 0, len(policies))
+	found := make(map[uint]bool, len(policies))
+	for _, policy := range policies {
+		found[policy.ID] = true
+		p, ok := overrides[policy.ID]
+		if !ok {
+			res = append(res, policy)
+			continue
+		}
+		if p.Disable {
+			continue
+		}
+
+		policy.Enable = true
+		if p.Period != "" {
+			policy.Period = p.Period
+		}
+		if p.Day != 0 {
+			policy.Day = p.Day
+		}
+		if p.CronExpr != "" {
+			policy.CronExpr = p.CronExpr
+		}
+		if p.Priority != 0 {
+			policy.Priority = p.Priority
+		}
+		if len(p.ExecuteWindow) != 0 {
+			policy.ExecuteWindow, _ = json.Marshal(p.ExecuteWindow)
+			policy.ExecuteWindows = nil
+			if len(p.ExecuteWindows) != 0 {
+				policy.ExecuteWindows, _ = json.Marshal(p.ExecuteWindows)
+			}
+		}
+
+		if !common.CheckPolicyDay(policy.Period, policy.Day) {
+			return nil, common.CodePolicyDayErr, fmt.Errorf("validate policy(%v) day(%v) not pass", policy.ID, policy.Day)
+		}
+		if policy.Period == common.PeriodCron && !common.CheckCronExpr(policy.CronExpr) {
+			return nil, common.CodePolicyCronExprErr, fmt.Errorf("validate policy(%v) cron_expr(%s) not pass", policy.ID, policy.CronExpr)
+		}
+		res = append(res, policy)
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			return nil, common.CodePolicyNotExist, fmt.Errorf("models.Policy(id=%v) not exist", id)
+		}
+	}
+	return res, common.CodeOK, nil
+}
+
+// scheduleChanged 假设的修改是否会改变策略的执行日期
+func (c *SimulateService) scheduleChanged(policyID uint) bool {
+	for _, p := range c.Policies {
+		if p.ID == policyID {
+			return p.Period != "" || p.Day != 0 || p.CronExpr != ""
+		}
+	}
+	return false
+}
+
+func (s *simulator) loadQuotas(db *gorm.DB) error {
+	var quotas []models.Quota
+	err := db.Model(&models.Quota{}).Where("enable =?", true).Find(&quotas).Error
+	if err != nil {
+		return fmt.Errorf("query models.Quota from db failed, %s", err)
+	}
+	s.quotas = make(map[string]*models.Quota, len(quotas))
+	for i, q := range quotas {
+		s.quotas[string(q.Scope)+"::::"+q.ScopeValue] = &quotas[i]
+	}
+
+	s.weights, err = BuQuotaWeights(db)
+	return err
+}
+
+// loadTasks 加载执行中的任务、待执行的任务，并按策略的执行周期生成模拟期间后续周期的任务
+func (s *simulator) loadTasks(db *gorm.DB, policies []*models.Policy, scheduleChanged func(uint) bool, start, end time.Time) error {
+	durations := make(map[uint][2]int)
+	duration := func(policyID uint) ([2]int, error) {
+		if d, ok := durations[policyID]; ok {
+			return d, nil
+		}
+		var history []int
+		err := db.Model(&models.Task{}).Where("policy_id =? AND task_status =? AND task_duration >?", policyID, common.TaskStatusSuccess, 0).
+			Order("id desc").Limit(SimulateHistoryTasks).Pluck("task_duration", &history).Error
+		if err != nil {
+			return [2]int{}, fmt.Errorf("query models.Task(policy_id=%v) duration from db failed, %s", policyID, err)
+		}
+		d := [2]int{int(SimulateDefaultDuration.Seconds()), len(history)}
+		if len(history) != 0 {
+			d[0] = 0
+			for _, v := range history {
+				d[0] += v
+			}
+			d[0] /= len(history)
+		}
+		durations[policyID] = d
+		return d, nil
+	}
+
+	blackoutCache := make(map[string][]models.Blackout)
+	newSimTask := func(task *models.Task) (*simTask, error) {
+		d, err := duration(task.PolicyID)
+		if err != nil {
+			return nil, err
+		}
+		windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows)
+		if err != nil {
+			return nil, fmt.Errorf("parse task(%v) exec windows failed, %s", task.Name, err)
+		}
+		key := task.SrcBu + "::::" + task.SrcClusterID
+		if _, ok := blackoutCache[key]; !ok {
+			blackoutCache[key], err = QueryEffectiveBlackouts(db, task.SrcBu, task.SrcClusterID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		st := &simTask{
+			task:      *task,
+			windows:   windows,
+			blackouts: blackoutCache[key],
+			remaining: time.Duration(d[0]) * time.Second,
+			out: &SimulateTask{
+				ID:           task.ID,
+				Name:         task.Name,
+				PolicyID:     task.PolicyID,
+				Priority:     task.Priority,
+				ExecuteDate:  task.ExecuteDate,
+				Timezone:     task.Timezone,
+				TaskStatus:   task.TaskStatus,
+				Duration:     d[0],
+				HistoryCount: d[1],
+			},
+		}
+		s.tasks = append(s.tasks, st)
+		return st, nil
+	}
+
+	// 执行中的任务占用并发直到预估的执行结束时间
+	var executing []models.Task
+	err := db.Model(&models.Task{}).Where("task_status IN (?)", common.TaskStatusExecuting).Find(&executing).Error
+	if err != nil {
+		return fmt.Errorf("query models.Task(task_status IN (%v)) from db failed, %s", common.TaskStatusExecuting, err)
+	}
+	lastExecuting := make(map[uint]*simTask, len(executing))
+	for i := range executing {
+		st, err := newSimTask(&executing[i])
+		if err != nil {
+			return err
+		}
+		st.started, st.running = true, true
+		st.segStart = executing[i].TaskStartTime
+		st.remaining = max(st.remaining-start.Sub(st.segStart), simulateStep)
+		st.segEnd = start.Add(st.remaining)
+		if executing[i].Pause {
+			if r, ok := st.windowAt(start); ok && r.End.Before(st.segEnd) {
+				st.segEnd = r.End
+			}
+		}
+		st.out.TaskStartTime = st.segStart.Format(time.DateTime)
+		if !executing[i].Backfill {
+			lastExecuting[executing[i].PolicyID] = st
+		}
+	}
+
+	sources := make(map[uint]*models.Source)
+	timezones := make(map[string]string)
+	for _, policy := range policies {
+		source, ok := sources[policy.SrcID]
+		if !ok {
+			source = &models.Source{}
+			err = db.Model(source).Where("id =?", policy.SrcID).First(source).Error
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return fmt.Errorf("query models.Source(id=%v) from db failed, %s", policy.SrcID, err)
+			}
+			sources[policy.SrcID] = source
+		}
+		tz, ok := timezones[source.ClusterID]
+		if !ok {
+			tz, err = ClusterTimezone(db, source.ClusterID)
+			if err != nil {
+				return err
+			}
+			timezones[source.ClusterID] = tz
+		}
+		loc := common.LoadLocation(tz)
+		endDate := end.In(loc).Format(time.DateOnly)
+
+		// 策略上一次任务的执行日期，补录任务不影响执行周期
+		var lastTasks []models.Task
+		err = db.Model(&models.Task{}).Select("execute_date").
+			Where("policy_id =? AND backfill =? AND task_status IN (?)", policy.ID, false, common.TaskStatusExecTimeImmutable).
+			Order("execute_date desc").Limit(1).Find(&lastTasks).Error
+		if err != nil {
+			return fmt.Errorf("query last models.Task(policy_id=%v) from db failed, %s", policy.ID, err)
+		}
+		lastDate := ""
+		if len(lastTasks) != 0 {
+			lastDate = lastTasks[0].ExecuteDate
+		}
+
+		var pending []models.Task
+		err = db.Model(&models.Task{}).Where("policy_id =? AND enable =? AND task_status IN (?)", policy.ID, true, common.TaskStatusCanUpdate).
+			Order("execute_date").Find(&pending).Error
+		if err != nil {
+			return fmt.Errorf("query models.Task(policy_id=%v, task_status IN (%v)) from db failed, %s", policy.ID, common.TaskStatusCanUpdate, err)
+		}
+
+		// 已生成的补录任务和正常任务一样参与准入
+		var next *models.Task
+		for i := range pending {
+			if !pending[i].Backfill {
+				if next == nil {
+					next = &pending[i]
+				}
+				continue
+			}
+			pending[i].Priority = policy.Priority
+			pending[i].ExecuteWindow, pending[i].ExecuteWindows = policy.ExecuteWindow, policy.ExecuteWindows
+			st, err := newSimTask(&pending[i])
+			if err != nil {
+				return err
+			}
+			st.dependsOn, st.lastDate = ParsePolicyDependsOn(policy.DependsOn), lastDate
+		}
+
+		// 第一个周期：优先使用已生成的任务，修改了执行周期或策略还没有生成任务时按执行周期重新计算
+		execDate := ""
+		switch {
+		case next != nil && !scheduleChanged(policy.ID):
+			execDate = next.ExecuteDate
+		case lastDate != "" && policy.Period == common.PeriodOnce && next == nil:
+			continue
+		case lastDate != "" && policy.Period != common.PeriodOnce:
+			last, err := time.ParseInLocation(time.DateOnly, lastDate, loc)
+			if err != nil {
+				return fmt.Errorf("parse policy(%v) last execute date(%s) failed, %s", policy.ID, lastDate, err)
+			}
+			nextTime, err := PolicyNextScheduleTime(policy, last)
+			if err != nil {
+				return err
+			}
+			execDate = FormatExecDate(policy, nextTime)
+		default:
+			execDate = start.In(loc).Format(time.DateOnly)
+		}
+
+		prev := lastExecuting[policy.ID]
+		for i := 0; i < simulateMaxPeriod && execDate <= endDate; i++ {
+			task := GenerateTaskByPolicy(&models.Task{}, policy, source)
+			if i == 0 && next != nil {
+				task.ID, task.Name, task.TaskStatus, task.CreatedAt = next.ID, next.Name, next.TaskStatus, next.CreatedAt
+				task.SrcTablesName, task.SrcDatabaseName = next.SrcTablesName, next.SrcDatabaseName
+			} else {
+				task.CreatedAt, _ = time.ParseInLocation(time.DateOnly, execDate, loc)
+			}
+			task.ExecuteDate = execDate
+			task.ExecuteWindows = policy.ExecuteWindows
+			task.Timezone = tz
+
+			st, err := newSimTask(task)
+			if err != nil {
+				return err
+			}
+			st.prev, st.dependsOn, st.lastDate = prev, ParsePolicyDependsOn(policy.DependsOn), lastDate
+			s.byPolicy[policy.ID] = append(s.byPolicy[policy.ID], st)
+			prev, lastDate = st, execDate
+
+			if policy.Period == common.PeriodOnce {
+				break
+			}
+			day, err := time.ParseInLocation(time.DateOnly, execDate, loc)
+			if err != nil {
+				return fmt.Errorf("parse policy(%v) execute date(%s) failed, %s", policy.ID, execDate, err)
+			}
+			nextTime, err := PolicyNextScheduleTime(policy, day)
+			if err != nil {
+				return err
+			}
+			nextDate := FormatExecDate(policy, nextTime)
+			if nextDate <= execDate {
+				break
+			}
+			execDate = nextDate
+		}
+	}
+	return nil
+}
+
+// run 按调用工作流的周期回放任务的准入和执行。两次事件(任务执行结束或暂停、到达执行窗口、封网结束)之间
+// 准入结果不会变化，因此直接跳到下一个事件所在的周期，而不是逐分钟回放
+func (s *simulator) run(start, end time.Time) {
+	for t := start; t.Before(end); t = s.nextEvent(start, end, t) {
+		// 执行结束或超出执行窗口需要暂停的任务释放并发
+		for _, st := range s.tasks {
+			if !st.running || t.Before(st.segEnd) {
+				continue
+			}
+			st.running = false
+			st.remaining -= st.segEnd.Sub(st.segStart)
+			if st.remaining <= 0 {
+				st.finished = true
+				st.out.TaskEndTime = st.segEnd.Format(time.DateTime)
+			}
+		}
+
+		var candidates []*simTask
+		for _, st := range s.tasks {
+			if st.running || st.finished || t.Before(st.notBefore) {
+				continue
+			}
+			if ok, code, err := s.preAdmission(st, t); !ok {
+				st.out.TaskReason, st.out.TaskDetail = code.Message, err.Error()
+				continue
+			}
+			candidates = append(candidates, st)
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return CompareTaskAdmission(&candidates[i].task, &candidates[j].task, t) < 0
+		})
+		for i, st := range candidates {
+			if ok, code, err := s.admission(st, candidates[i+1:]); !ok {
+				st.out.TaskReason, st.out.TaskDetail = code.Message, err.Error()
+				continue
+			}
+			s.start(st, t)
+		}
+	}
+
+	// 模拟结束时仍在执行的任务按剩余时长预估结束时间
+	for _, st := range s.tasks {
+		if st.running {
+			st.out.TaskEndTime = st.segStart.Add(st.remaining).Format(time.DateTime)
+		}
+	}
+}
+
+// nextEvent 返回t之后最近的事件所在的周期，没有事件时返回模拟的结束时间
+func (s *simulator) nextEvent(start, end, t time.Time) time.Time {
+	next := time.Time{}
+	for _, st := range s.tasks {
+		event := time.Time{}
+		switch {
+		case st.finished:
+			continue
+		case st.running:
+			event = st.segEnd
+		case st.notBefore.After(t):
+			event = st.notBefore
+		default:
+			continue
+		}
+		if next.IsZero() || event.Before(next) {
+			next = event
+		}
+	}
+	if next.IsZero() {
+		// 没有执行中的任务也没有等待到达的时间，后续的准入结果不会再变化
+		return end
+	}
+
+	// 对齐到调用工作流的周期，且至少前进一个周期
+	steps := (next.Sub(start) + simulateStep - 1) / simulateStep
+	if next = start.Add(steps * simulateStep); next.After(t) {
+		return next
+	}
+	return t.Add(simulateStep)
+}
+
+// start 开始执行任务，执行窗口外需要暂停的任务执行到执行窗口结束
+func (s *simulator) start(st *simTask, t time.Time) {
+	st.running = true
+	st.segStart, st.segEnd = t, t.Add(st.remaining)
+	st.out.TaskReason, st.out.TaskDetail = "", ""
+	if !st.started {
+		st.started = true
+		st.out.TaskStartTime = t.Format(time.DateTime)
+	}
+
+	r, ok := st.windowAt(t)
+	if !ok || !r.End.Before(st.segEnd) {
+		return
+	}
+	if st.task.Pause {
+		st.segEnd = r.End
+	} else {
+		st.out.OutOfWindow = true
+	}
+}
+
+// windowAt 返回t所在的执行窗口
+func (st *simTask) windowAt(t time.Time) (common.ExecWindowRange, bool) {
+	ranges, err := common.ActiveExecWindowRanges(st.windows, st.task.ExecuteDate, t.In(common.LoadLocation(st.task.Timezone)))
+	if err != nil {
+		return common.ExecWindowRange{}, false
+	}
+	for _, r := range ranges {
+		if !t.Before(r.Start) && t.Before(r.End) {
+			return r, true
+		}
+	}
+	return common.ExecWindowRange{}, false
+}
+
+// nextWindowStart 返回t之后任务最早可以开始执行的执行窗口的开始时间
+func (st *simTask) nextWindowStart(t time.Time) (time.Time, bool) {
+	loc := common.LoadLocation(st.task.Timezone)
+	day, err := time.ParseInLocation(time.DateOnly, st.task.ExecuteDate, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if lt := t.In(loc); day.Before(lt) {
+		day = time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, loc)
+	}
+	for i := 0; i < 8; i++ {
+		ranges, err := common.ExecWindowRanges(st.windows, day.AddDate(0, 0, i))
+		if err != nil {
+			return time.Time{}, false
+		}
+		for _, r := range ranges {
+			if r.Start.After(t) {
+				return r.Start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// preAdmission 与任务本身相关的准入检查：执行日期、执行窗口、封网、同一策略上一个周期的任务、依赖的策略
+func (s *simulator) preAdmission(st *simTask, t time.Time) (bool, common.ServiceCode, error) {
+	task := &st.task
+	now := t.In(common.LoadLocation(task.Timezone))
+	if ok, code, err := ExecDateAdmission(task, now); !ok {
+		st.notBefore, _ = st.nextWindowStart(t)
+		return ok, code, err
+	}
+
+	if ok, code, err := ExecWindowAdmission(st.windows, task, now); !ok {
+		st.notBefore, _ = st.nextWindowStart(t)
+		return ok, code, err
+	}
+
+	if ok, end, code, err := BlackoutAdmission(st.blackouts, st.windows, task, now); !ok {
+		st.notBefore = end
+		return ok, code, err
+	}
+
+	if st.prev != nil && !st.prev.finished {
+		return false, common.CodeTaskPrevPeriodNotDone, fmt.Errorf("previous task(%s) of policy(%v) with execute date %s has not finished",
+			st.prev.task.Name, task.PolicyID, st.prev.task.ExecuteDate)
+	}
+
+	// 依赖的策略在本周期的任务按执行成功模拟
+	for _, id := range st.dependsOn {
+		for _, up := range s.byPolicy[id] {
+			if up.task.ExecuteDate > st.lastDate && up.task.ExecuteDate <= task.ExecuteDate && !up.finished {
+				return false, common.CodeTaskDependencyNotReady,
+					fmt.Errorf("depends on policy(%v) task(%s) with execute date %s", id, up.task.Name, up.task.ExecuteDate)
+			}
+		}
+	}
+	return true, common.CodeOK, nil
+}
+
+// admission 与正在执行的任务相关的准入检查：最大并发、BU和集群的并发配额、公平分配、任务冲突
+func (s *simulator) admission(st *simTask, waiting []*simTask) (bool, common.ServiceCode, error) {
+	task := &st.task
+	executing := make([]*models.Task, 0, s.maxParallel)
+	buExecuting, clusterExecuting := make(map[string]int), make(map[string]int)
+	for _, r := range s.tasks {
+		if r.running {
+			executing = append(executing, &r.task)
+			buExecuting[r.task.SrcBu]++
+			clusterExecuting[r.task.SrcClusterID]++
+		}
+	}
+
+	if ok, code, err := ParallelAdmission(len(executing), s.maxParallel); !ok {
+		return ok, code, err
+	}
+
+	if ok, code, err := QuotaAdmission(s.quotas[string(common.QuotaScopeBu)+"::::"+task.SrcBu], buExecuting[task.SrcBu]); !ok {
+		return ok, code, err
+	}
+	if ok, code, err := QuotaAdmission(s.quotas[string(common.QuotaScopeCluster)+"::::"+task.SrcClusterID], clusterExecuting[task.SrcClusterID]); !ok {
+		return ok, code, err
+	}
+
+	if s.fairShare {
+		buWaiting := make(map[string]int)
+		for _, w := range waiting {
+			buWaiting[w.task.SrcBu]++
+		}
+		if ok, code, err := FairShareAdmission(task.SrcBu, s.maxParallel, s.weights, buExecuting, buWaiting); !ok {
+			return ok, code, err
+		}
+	}
+
+	return ConflictAdmission(s.conflictLevel, s.conflictMax, task, executing)
+}
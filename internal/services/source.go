@@ -8,8 +8,6 @@ import (
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
 	"github.com/sunkaimr/data-loom/pkg/utils"
 	"gorm.io/gorm"
-	"sort"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -23,7 +21,14 @@ type SourceService struct {
 	ClusterName  string `json:"cluster_name"`  // 集群名称
 	ClusterID    string `json:"cluster_id" `   // 集群ID
 	DatabaseName string `json:"database_name"` // 源库名
-	TablesName   string `json:"tables_name"`   // 源表名
+	TablesName   string `json:"tables_name"`   // 源表名，设置了表名匹配规则时为最近一次匹配到的表
+
+	TablesPattern     string                  `json:"tables_pattern"`      // 源表名匹配规则，如: order_* 或 ^order_\d+$，新增的分表会在补充任务信息时自动纳入治理
+	TablesPatternType common.TablePatternType `json:"tables_pattern_type"` // 源表名匹配规则类型 通配符:glob, 正则表达式:regex
+
+	DatabasePattern     string                  `json:"database_pattern"`      // 源库名匹配规则，如: db_*，不为空时源端包含多个分库，任务会按库拆分为子任务依次执行
+	DatabasePatternType common.TablePatternType `json:"database_pattern_type"` // 源库名匹配规则类型 通配符:glob, 正则表达式:regex
+	DatabasesName       string                  `json:"databases_name"`        // 最近一次匹配到的源库名，database_name为其中第一个库
 }
 
 func (c *SourceService) CreateSource(ctx *gin.Context) (common.ServiceCode, error) {
@@ -49,9 +54,22 @@ func (c *SourceService) CreateSource(ctx *gin.Context) (common.ServiceCode, erro
 
 	// 须确保同一个集群、库、表(ClusterID,DatabaseName,TablesName)只能有一个源，避免源滥用
 
+	// 设置了库名、表名匹配规则时匹配到的库、表会变化，按匹配规则判断是否为同一个源
+	sameSource := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("cluster_id =?", source.ClusterID)
+		if source.DatabasePattern != "" {
+			tx = tx.Where("database_pattern =? AND database_pattern_type =?", source.DatabasePattern, source.DatabasePatternType)
+		} else {
+			tx = tx.Where("database_name =? AND database_pattern =''", source.DatabaseName)
+		}
+		if source.TablesPattern != "" {
+			return tx.Where("tables_pattern =? AND tables_pattern_type =?", source.TablesPattern, source.TablesPatternType)
+		}
+		return tx.Where("tables_name =? AND tables_pattern =''", source.TablesName)
+	}
+
 	var existSources []models.Source
-	err := db.Model(source).Where("cluster_id =? AND database_name =? AND tables_name =?",
-		source.ClusterID, source.DatabaseName, source.TablesName).Find(&existSources).Error
+	err := db.Model(source).Scopes(sameSource).Find(&existSources).Error
 	if err != nil {
 		err = fmt.Errorf("query models.Source(cluster_id=%v AND database_name=%v AND tables_name=%v) from db failed, %s",
 			source.ClusterID, source.DatabaseName, source.TablesName, err)
@@ -72,8 +90,7 @@ func (c *SourceService) CreateSource(ctx *gin.Context) (common.ServiceCode, erro
 			return common.CodeServerErr, err
 		}
 
-		err = db.Model(source).Where("cluster_id =? AND database_name =? AND tables_name =?",
-			source.ClusterID, source.DatabaseName, source.TablesName).First(&source).Error
+		err = db.Model(source).Scopes(sameSource).First(&source).Error
 		if err != nil {
 			err = fmt.Errorf("query models.Source(cluster_id=%v AND database_name=%v AND tables_name=%v) from db failed, %s",
 				source.ClusterID, source.DatabaseName, source.TablesName, err)
@@ -215,12 +232,14 @@ func (c *SourceService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 
 	// 库名
 	c.DatabaseName = utils.TrimmingStringList(c.DatabaseName, ",")
-	if len(c.DatabaseName) == 0 {
+	c.DatabasePattern = strings.TrimSpace(c.DatabasePattern)
+	if len(c.DatabaseName) == 0 && len(c.DatabasePattern) == 0 {
 		return false, common.CodeSourceDatabaseNull, fmt.Errorf("database_name(%s) can not be empty", c.DatabaseName)
 	}
 
 	c.TablesName = utils.TrimmingStringList(c.TablesName, ",")
-	if len(c.TablesName) == 0 {
+	c.TablesPattern = strings.TrimSpace(c.TablesPattern)
+	if len(c.TablesName) == 0 && len(c.TablesPattern) == 0 {
 		return false, common.CodeSourceTableNameNull, fmt.Errorf("tables_name(%s) can not be empty", c.TablesName)
 	}
 
@@ -233,29 +252,54 @@ func (c *SourceService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 	c.ClusterName = clusterSvc.ClusterName
 	c.Bu = clusterSvc.Bu
 
+	// 设置了库名匹配规则时以匹配到的第一个库作为代表进行校验，其余的库须包含相同的表
+	shardDatabases := []string{c.DatabaseName}
+	if c.DatabasePattern != "" {
+		if c.DatabasePatternType == "" {
+			c.DatabasePatternType = common.TablePatternGlob
+		}
+		var res common.ServiceCode
+		shardDatabases, res, err = ResolveSourceDatabases(ctx, clusterSvc, c.DatabasePatternType, c.DatabasePattern)
+		if err != nil {
+			return false, res, err
+		}
+		c.DatabaseName = shardDatabases[0]
+		c.DatabasesName = strings.Join(shardDatabases, ",")
+	} else {
+		c.DatabasePatternType = ""
+		c.DatabasesName = ""
+	}
+
+	// 设置了表名匹配规则时以匹配到的表作为源表
+	if c.TablesPattern != "" {
+		if c.TablesPatternType == "" {
+			c.TablesPatternType = common.TablePatternGlob
+		}
+		tables, _, res, err := ResolveSourceTables(ctx, clusterSvc, c.DatabaseName, c.TablesPatternType, c.TablesPattern)
+		if err != nil {
+			return false, res, err
+		}
+		c.TablesName = strings.Join(tables, ",")
+	} else {
+		c.TablesPatternType = ""
+	}
+
 	// 表名支持一个表名，多个表时只支持同一个表的分表场景
 	_, baseName, err := common.CheckSameShardingTables(c.TablesName)
 	if err != nil {
 		return false, common.CodeSourceTableNameErr, fmt.Errorf("check source.table not pass, %s", err)
 	}
 
-	// 对表进行排序
+	// 对表进行排序，匹配规则匹配到的表保持匹配规则的顺序
 	reqTableList := strings.Split(c.TablesName, ",")
-	sort.Slice(reqTableList, func(i, j int) bool {
-		name1 := strings.TrimPrefix(reqTableList[i], baseName+"_")
-		name2 := strings.TrimPrefix(reqTableList[j], baseName+"_")
-
-		num1, _ := strconv.Atoi(name1)
-		num2, _ := strconv.Atoi(name2)
-
-		return num1 < num2
-	})
+	if c.TablesPattern == "" {
+		common.SortShardingTables(reqTableList, baseName)
+	}
 	c.TablesName = strings.Join(reqTableList, ",")
 
-	clusterDriver := NewClusterDriver(clusterSvc)
-
+	// 库、表和主键信息优先从表结构快照中读取，快照中找不到时以集群的实时信息为准
 	// 库名存在
-	databases, res, err := clusterDriver.GetDatabases(ctx)
+	databases, res, err := ClusterDatabases(ctx, clusterSvc, c.DatabaseName)
 	if err != nil {
 		return false, res, fmt.Errorf("get cluster(%s) databases failed, %s", clusterSvc.ClusterID, err)
 	}
@@ -266,7 +310,7 @@ func (c *SourceService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 	}
 
 	// 表存在
-	tables, res, err := clusterDriver.GetTables(ctx, c.DatabaseName)
+	tables, res, err := ClusterTables(ctx, clusterSvc, c.DatabaseName, reqTableList...)
 	if err != nil {
 		return false, res, fmt.Errorf("get cluster(%s) databases(%s) tabels failed", clusterSvc.ClusterID, c.DatabaseName)
 	}
@@ -278,14 +322,26 @@ func (c *SourceService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 			fmt.Errorf("got tables(%v) not in reality cluster(%s) tables(%v)", strings.Split(c.TablesName, ","), clusterSvc.ClusterID, tables)
 	}
 
+	// 其余分库也须包含相同的表
+	for _, database := range shardDatabases[1:] {
+		shardTables, res, err := ClusterTables(ctx, clusterSvc, database, reqTableList...)
+		if err != nil {
+			return false, res, fmt.Errorf("get cluster(%s) databases(%s) tabels failed", clusterSvc.ClusterID, database)
+		}
+		if !utils.IsSubSlices(reqTableList, shardTables) {
+			return false, common.CodeSourceTableNotExist,
+				fmt.Errorf("got tables(%v) not in reality cluster(%s) database(%s)", reqTableList, clusterSvc.ClusterID, database)
+		}
+	}
+
 	// 检查表是否都有主键
-	_, res, err = clusterDriver.TablesHasPrimaryKey(ctx, c.DatabaseName, reqTableList)
+	_, res, err = ClusterTablesHasPrimaryKey(ctx, clusterSvc, c.DatabaseName, reqTableList)
 	if err != nil {
 		return false, res, fmt.Errorf("check cluster(%s) databases(%s) primary key no pass, %s", clusterSvc.ClusterID, c.DatabaseName, err)
 	}
 
 	// 检查磁盘剩余空间
-	_, err = clusterDriver.GetFreeDisk(ctx)
+	_, err = NewClusterDriver(clusterSvc).GetFreeDisk(ctx)
 	if err != nil {
 		return false, common.CodeClusterFreeDiskErr, fmt.Errorf("check cluster(%s) freedisk no pass, %s", clusterSvc.ClusterID, err)
 	}
@@ -297,6 +353,58 @@ func (c *SourceService) CheckParameters(ctx *gin.Context) (bool, common.ServiceC
 	return true, common.CodeOK, nil
 }
 
+// ResolveSourceDatabases 按库名匹配规则从集群中实时获取匹配到的库
+func ResolveSourceDatabases(ctx *gin.Context, c *ClusterService, patternType common.TablePatternType, pattern string) ([]string, common.ServiceCode, error) {
+	if !common.CheckTablePatternType(patternType) {
+		return nil, common.CodeSourceDatabasePatternErr, fmt.Errorf("validate database_pattern_type(%s) not pass", patternType)
+	}
+
+	databases, code, err := NewClusterDriver(c).GetDatabases(ctx)
+	if err != nil {
+		return nil, code, fmt.Errorf("get cluster(%s) databases failed, %s", c.ClusterID, err)
+	}
+	databases = utils.RemoveSubSlices(databases, strings.Split(Cfg.ClusterExcludeDatabase, ","))
+
+	databases, err = common.MatchNamePattern(patternType, pattern, databases)
+	if err != nil {
+		return nil, common.CodeSourceDatabasePatternErr, err
+	}
+	if len(databases) == 0 {
+		return nil, common.CodeSourceDatabasePatternEmpty,
+			fmt.Errorf("database_pattern(%s) matched no database in cluster(%s)", pattern, c.ClusterID)
+	}
+	return databases, common.CodeOK, nil
+}
+
+// ResolveSourceTables 按表名匹配规则从集群中实时获取匹配到的表，按匹配规则的顺序返回。
+// 匹配到多张表时只保留同一个分表(表名_编号)的表，不符合分表命名规则的表作为跳过的表返回
+func ResolveSourceTables(ctx *gin.Context, c *ClusterService, database string, patternType common.TablePatternType, pattern string) ([]string, []string, common.ServiceCode, error) {
+	if !common.CheckTablePatternType(patternType) {
+		return nil, nil, common.CodeSourceTablePatternErr, fmt.Errorf("validate tables_pattern_type(%s) not pass", patternType)
+	}
+
+	tables, code, err := NewClusterDriver(c).GetTables(ctx, database)
+	if err != nil {
+		return nil, nil, code, fmt.Errorf("get cluster(%s) databases(%s) tabels failed, %s", c.ClusterID, database, err)
+	}
+	tables = common.ExcludeTablesFilter(database, tables, strings.Split(Cfg.ClusterExcludeTables, ","))
+
+	tables, err = common.MatchNamePattern(patternType, pattern, tables)
+	if err != nil {
+		return nil, nil, common.CodeSourceTablePatternErr, err
+	}
+	if len(tables) == 0 {
+		return nil, nil, common.CodeSourceTablePatternEmpty,
+			fmt.Errorf("tables_pattern(%s) matched no table in cluster(%s) database(%s)", pattern, c.ClusterID, database)
+	}
+
+	tables, skipped, err := common.PickShardingTables(tables)
+	if err != nil {
+		return nil, nil, common.CodeSourceTableNameErr, fmt.Errorf("tables matched by tables_pattern(%s) not pass, %s", pattern, err)
+	}
+	return tables, skipped, common.CodeOK, nil
+}
+
 func (c *SourceService) ServiceToModel() *models.Source {
 	m := &models.Source{}
 	m.ID = c.ID
@@ -311,6 +419,11 @@ func (c *SourceService) ServiceToModel() *models.Source {
 	m.ClusterID = c.ClusterID
 	m.DatabaseName = c.DatabaseName
 	m.TablesName = c.TablesName
+	m.TablesPattern = c.TablesPattern
+	m.TablesPatternType = c.TablesPatternType
+	m.DatabasePattern = c.DatabasePattern
+	m.DatabasePatternType = c.DatabasePatternType
+	m.DatabasesName = c.DatabasesName
 	return m
 }
 
@@ -327,5 +440,10 @@ func (c *SourceService) ModelToService(m *models.Source) *SourceService {
 	c.ClusterID = m.ClusterID
 	c.DatabaseName = m.DatabaseName
 	c.TablesName = m.TablesName
+	c.TablesPattern = m.TablesPattern
+	c.TablesPatternType = m.TablesPatternType
+	c.DatabasePattern = m.DatabasePattern
+	c.DatabasePatternType = m.DatabasePatternType
+	c.DatabasesName = m.DatabasesName
 	return c
 }
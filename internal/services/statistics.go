@@ -7,6 +7,7 @@ import (
 	"github.com/sunkaimr/data-loom/internal/models"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
 	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
 	"sort"
 	"strconv"
 	"strings"
@@ -34,11 +35,21 @@ type TaskPlanService struct {
 type Task struct {
 	ID                 uint                  `json:"id"` // ID
 	Name               string                `json:"name"`
-	TaskStartTime      string                `json:"task_start_time"`      // 开始执行时间
-	TaskEndTime        string                `json:"task_end_time"`        // 执行结束时间
-	TaskStatus         common.TaskStatusType `json:"task_status"`          // 任务状态
-	TaskResultQuantity int                   `json:"task_result_quantity"` // 治理数据量
-	TaskResultSize     int                   `json:"task_result_size"`     // 治理数据容量
+	PolicyID           uint                  `json:"policy_id"`                    // 策略ID
+	DependsOn          []uint                `json:"depends_on,omitempty"`         // 依赖的策略ID，与计划中同策略ID的任务构成依赖关系图
+	Priority           int                   `json:"priority"`                     // 优先级
+	EffectivePriority  int                   `json:"effective_priority,omitempty"` // 等待执行的任务老化后的有效优先级
+	QueueOrder         int                   `json:"queue_order,omitempty"`        // 等待执行的任务的准入顺序，1表示并发有空闲时最先执行
+	TaskStartTime      string                `json:"task_start_time"`              // 开始执行时间
+	TaskEndTime        string                `json:"task_end_time"`                // 执行结束时间
+	TaskStatus         common.TaskStatusType `json:"task_status"`                  // 任务状态
+	TaskResultQuantity int                   `json:"task_result_quantity"`         // 治理数据量
+	TaskResultSize     int                   `json:"task_result_size"`             // 治理数据容量
+	Blackout           string                `json:"blackout,omitempty"`           // 计划执行时间与封网日历冲突时的封网名称
+	ShiftedFrom        string                `json:"shifted_from,omitempty"`       // 因封网顺延时原计划的开始执行时间
+	Timezone           string                `json:"timezone"`                     // 任务所在时区，为空时为服务所在时区
+	ZoneTaskStartTime  string                `json:"zone_task_start_time"`         // 开始执行时间(任务所在时区)
+	ZoneTaskEndTime    string                `json:"zone_task_end_time"`           // 执行结束时间(任务所在时区)
 }
 
 // TaskStatisticDetail 任务统计信息
@@ -58,6 +69,8 @@ type StatisticDetail struct {
 	TotalQuantity int64  `json:"total_quantity"`
 	TotalSize     int64  `json:"total_size"`
 	Count         int64  `json:"count"`
+	SrcID         uint   `json:"-"` // 源端ID，源端包含多个分库时按源端汇总
+	DatabasesName string `json:"-"` // 源端包含的分库
 }
 
 // PolicyStatistic 策略统计信息
@@ -196,7 +209,7 @@ func (c *TaskPlanService) TaskExecPlan(ctx *gin.Context) (any, common.ServiceCod
 	// 已经执行完成的
 	var tasks1 []models.Task
 	err = db.Model(&models.Task{}).
-		Select("id, name, execute_date, task_start_time, task_end_time, task_status, task_result_quantity, task_result_size").
+		Select("id, name, policy_id, priority, execute_date, timezone, task_start_time, task_end_time, task_status, task_result_quantity, task_result_size").
 		Where("task_start_time >= ? AND task_end_time <= ? AND task_status IN (?)",
 			startTime, endTime, common.TaskStatusHasFinished).
 		Find(&tasks1).Error
@@ -209,7 +222,7 @@ func (c *TaskPlanService) TaskExecPlan(ctx *gin.Context) (any, common.ServiceCod
 	// 正在执行的
 	var tasks2 []models.Task
 	err = db.Model(&models.Task{}).
-		Select("id, name, execute_date, task_start_time, task_end_time, task_status, task_result_quantity, task_result_size, execute_window").
+		Select("id, name, policy_id, priority, execute_date, timezone, task_start_time, task_end_time, task_status, task_result_quantity, task_result_size, execute_window, execute_windows").
 		Where("execute_date >= ? AND execute_date <= ? AND task_status IN (?)", c.StartDate, c.EndDate, common.TaskStatusExecuting).
 		Find(&tasks2).Error
 	if err != nil {
@@ -221,7 +234,7 @@ func (c *TaskPlanService) TaskExecPlan(ctx *gin.Context) (any, common.ServiceCod
 	// 还未开始执行的
 	var tasks3 []models.Task
 	err = db.Model(&models.Task{}).
-		Select("id, name, execute_date, task_start_time, task_end_time, task_status, task_result_quantity, task_result_size, execute_window").
+		Select("id, name, policy_id, priority, execute_date, timezone, task_start_time, task_end_time, task_status, task_result_quantity, task_result_size, execute_window, execute_windows, src_bu, src_cluster_id").
 		Where("execute_date >= ? AND execute_date <= ? AND task_status IN (?)",
 			c.StartDate, c.EndDate, []common.TaskStatusType{common.TaskStatusScheduled, common.TaskStatusSupplementFailed, common.TaskStatusWaiting, common.TaskStatusExecCheckFailed}).
 		Find(&tasks3).Error
@@ -234,20 +247,86 @@ func (c *TaskPlanService) TaskExecPlan(ctx *gin.Context) (any, common.ServiceCod
 	tasks1 = append(tasks1, tasks2...)
 	tasks1 = append(tasks1, tasks3...)
 
+	// 已到执行日期等待执行的任务按准入顺序排序，展示调度的决策顺序
+	var candidates []models.Task
+	err = db.Model(&models.Task{}).
+		Select("id, created_at, priority, execute_date, timezone, task_status, execute_window, execute_windows").
+		Where("enable =? AND task_status IN (?)", true, common.TaskStatusCanExec).
+		Find(&candidates).Error
+	if err != nil {
+		err = fmt.Errorf("query models.Task from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+	now := time.Now()
+	queue := make([]models.Task, 0, len(candidates))
+	for _, task := range candidates {
+		if task.ExecuteDate <= common.NowIn(task.Timezone).Format(time.DateOnly) {
+			queue = append(queue, task)
+		}
+	}
+	SortTasksByAdmission(queue, now)
+	queueOrder := make(map[uint]int, len(queue))
+	effectivePriority := make(map[uint]int, len(queue))
+	for i := range queue {
+		queueOrder[queue[i].ID] = i + 1
+		effectivePriority[queue[i].ID] = TaskEffectivePriority(&queue[i], now)
+	}
+
+	// 策略之间的依赖关系
+	graph, err := PolicyDependencyGraph(db)
+	if err != nil {
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	// 按BU和集群缓存生效的封网日历
+	blackoutCache := make(map[string][]models.Blackout)
 	for _, task := range tasks1 {
 		start, end := "", ""
+		blackout, shiftedFrom := "", ""
 
 		switch task.TaskStatus {
 		case common.TaskStatusScheduled, common.TaskStatusSupplementFailed, common.TaskStatusWaiting, common.TaskStatusExecCheckFailed:
-			execWin := make([]string, 0, 2)
-			_ = json.Unmarshal(task.ExecuteWindow, &execWin)
-			start = task.ExecuteDate + " " + execWin[0]
-			end = task.ExecuteDate + " " + execWin[1]
+			start, end = planExecWindow(&task)
+			if start == "" {
+				continue
+			}
+
+			key := task.SrcBu + "::::" + task.SrcClusterID
+			if _, ok := blackoutCache[key]; !ok {
+				blackoutCache[key], err = QueryEffectiveBlackouts(db, task.SrcBu, task.SrcClusterID)
+				if err != nil {
+					log.Error(err)
+					return nil, common.CodeServerErr, err
+				}
+			}
+			if name, s, e, shifted := shiftPlanByBlackouts(&task, blackoutCache[key], start, end); name != "" {
+				blackout = name
+				if shifted {
+					shiftedFrom, start, end = start, s, e
+				}
+			}
 		case common.TaskStatusExecuting:
 			execWin := make([]string, 0, 2)
 			_ = json.Unmarshal(task.ExecuteWindow, &execWin)
+			if len(execWin) < 2 {
+				execWin = append(execWin, "", "")
+			}
 			start = task.TaskStartTime.Format(time.DateTime)
 			end = task.ExecuteDate + " " + execWin[1]
+
+			// 多个执行窗口时以开始执行时所在的执行窗口为准，执行窗口按任务所在时区计算
+			if windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows); err == nil {
+				startTime := task.TaskStartTime.In(common.LoadLocation(task.Timezone))
+				ranges, _ := common.ActiveExecWindowRanges(windows, task.ExecuteDate, startTime)
+				for _, r := range ranges {
+					if !startTime.Before(r.Start) && !startTime.After(r.End) {
+						end = r.End.In(time.Now().Location()).Format(time.DateTime)
+						break
+					}
+				}
+			}
 		default:
 			if task.TaskStartTime.IsZero() || task.TaskEndTime.IsZero() {
 				continue
@@ -259,11 +338,18 @@ func (c *TaskPlanService) TaskExecPlan(ctx *gin.Context) (any, common.ServiceCod
 		t := &Task{
 			ID:                 task.ID,
 			Name:               task.Name,
+			PolicyID:           task.PolicyID,
+			DependsOn:          graph[task.PolicyID],
+			Priority:           task.Priority,
+			EffectivePriority:  effectivePriority[task.ID],
+			QueueOrder:         queueOrder[task.ID],
 			TaskStartTime:      start,
 			TaskEndTime:        end,
 			TaskStatus:         task.TaskStatus,
 			TaskResultQuantity: task.TaskResultQuantity,
 			TaskResultSize:     task.TaskResultSize,
+			Blackout:           blackout,
+			ShiftedFrom:        shiftedFrom,
 		}
 
 		if t.TaskEndTime <= t.TaskStartTime {
@@ -272,12 +358,83 @@ func (c *TaskPlanService) TaskExecPlan(ctx *gin.Context) (any, common.ServiceCod
 			}
 		}
 
+		// 计划时间统一按服务所在时区返回，同时给出任务所在时区的时间
+		t.Timezone = task.Timezone
+		t.ZoneTaskStartTime = common.FormatInZone(t.TaskStartTime, task.Timezone)
+		t.ZoneTaskEndTime = common.FormatInZone(t.TaskEndTime, task.Timezone)
+
 		c.Tasks = append(c.Tasks, t)
 	}
 	c.Count = len(c.Tasks)
 	return c, common.CodeOK, nil
 }
 
+// planExecWindow 计算未开始执行任务的计划执行时间段：执行日期当天及之后第一个生效的执行窗口。
+// 执行窗口按任务所在时区计算，返回服务所在时区的时间
+func planExecWindow(task *models.Task) (string, string) {
+	windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows)
+	if err != nil {
+		return "", ""
+	}
+	day, err := time.ParseInLocation(time.DateOnly, task.ExecuteDate, common.LoadLocation(task.Timezone))
+	if err != nil {
+		return "", ""
+	}
+
+	for i := 0; i < 7; i++ {
+		ranges, err := common.ExecWindowRanges(windows, day.AddDate(0, 0, i))
+		if err != nil {
+			return "", ""
+		}
+		if len(ranges) != 0 {
+			return ranges[0].Start.In(time.Now().Location()).Format(time.DateTime), ranges[0].End.In(time.Now().Location()).Format(time.DateTime)
+		}
+	}
+	return "", ""
+}
+
+// shiftPlanByBlackouts 计划执行窗口与封网冲突时，按任务的执行窗口(含生效的星期和时区)顺延到第一个不与封网重叠的执行窗口，
+// 与执行准入检查剩余执行窗口内是否有封网的判断保持一致。返回冲突的封网名称、顺延后的执行时间，一年内无法避开时只标记不顺延
+func shiftPlanByBlackouts(task *models.Task, blackouts []models.Blackout, start, end string) (string, string, string, bool) {
+	if len(blackouts) == 0 {
+		return "", start, end, false
+	}
+
+	s, err1 := time.ParseInLocation(time.DateTime, start, time.Now().Location())
+	e, err2 := time.ParseInLocation(time.DateTime, end, time.Now().Location())
+	if err1 != nil || err2 != nil {
+		return "", start, end, false
+	}
+
+	b, _, _, ok := MatchBlackout(blackouts, s, e)
+	if !ok {
+		return "", start, end, false
+	}
+
+	windows, err := common.ParseExecWindows(task.ExecuteWindow, task.ExecuteWindows)
+	if err != nil {
+		return b.Name, start, end, false
+	}
+	loc := common.LoadLocation(task.Timezone)
+	day := s.In(loc)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i <= 366; i++ {
+		ranges, err := common.ExecWindowRanges(windows, day.AddDate(0, 0, i))
+		if err != nil {
+			return b.Name, start, end, false
+		}
+		for _, r := range ranges {
+			if !r.Start.After(s) {
+				continue
+			}
+			if _, _, _, ok = MatchBlackout(blackouts, r.Start, r.End); !ok {
+				return b.Name, r.Start.In(time.Now().Location()).Format(time.DateTime), r.End.In(time.Now().Location()).Format(time.DateTime), true
+			}
+		}
+	}
+	return b.Name, start, end, false
+}
+
 func (c *TaskStatisticDetail) TaskStatisticDetail(ctx *gin.Context, groupBy string, queryMap map[string]string) (any, common.ServiceCode, error) {
 	log, db := common.ExtractContext(ctx)
 	startTime, err := time.ParseInLocation(time.DateOnly, c.StartDate, time.Now().Location())
@@ -311,16 +468,16 @@ func (c *TaskStatisticDetail) TaskStatisticDetail(ctx *gin.Context, groupBy stri
 			return strings.Join([]string{d.Bu, d.ClusterName}, "::::")
 		}
 	case "database":
-		sel = "src_bu AS bu, src_cluster_name AS cluster_name, src_database_name AS `database`, govern, SUM(task_result_quantity) AS total_quantity, SUM(task_result_size) AS total_size, count(id) AS count"
-		group = "src_bu, src_cluster_name, src_database_name, govern"
+		sel = "src_bu AS bu, src_cluster_name AS cluster_name, src_database_name AS `database`, src_id, src_databases_name AS databases_name, govern, SUM(task_result_quantity) AS total_quantity, SUM(task_result_size) AS total_size, count(id) AS count"
+		group = "src_bu, src_cluster_name, src_database_name, src_id, src_databases_name, govern"
 		genKey = func(d *StatisticDetail) string {
-			return strings.Join([]string{d.Bu, d.ClusterName, d.Database}, "::::")
+			return strings.Join([]string{d.Bu, d.ClusterName, statisticDatabaseKey(d.Database, d.SrcID, d.DatabasesName)}, "::::")
 		}
 	case "table":
-		sel = "src_bu AS bu, src_cluster_name AS cluster_name, src_database_name AS `database`, src_tables_name AS `table`, govern, SUM(task_result_quantity) AS total_quantity, SUM(task_result_size) AS total_size, count(id) AS count"
-		group = "src_bu, src_cluster_name, src_database_name, src_tables_name, govern"
+		sel = "src_bu AS bu, src_cluster_name AS cluster_name, src_database_name AS `database`, src_id, src_databases_name AS databases_name, src_tables_name AS `table`, govern, SUM(task_result_quantity) AS total_quantity, SUM(task_result_size) AS total_size, count(id) AS count"
+		group = "src_bu, src_cluster_name, src_database_name, src_id, src_databases_name, src_tables_name, govern"
 		genKey = func(d *StatisticDetail) string {
-			return strings.Join([]string{d.Bu, d.ClusterName, d.Database, d.Table}, "::::")
+			return strings.Join([]string{d.Bu, d.ClusterName, statisticDatabaseKey(d.Database, d.SrcID, d.DatabasesName), d.Table}, "::::")
 		}
 	default:
 		return c, common.CodeOK, fmt.Errorf("unsupported query method %s", groupBy)
@@ -349,6 +506,10 @@ func (c *TaskStatisticDetail) TaskStatisticDetail(ctx *gin.Context, groupBy stri
 				res[i].Table = table
 			}
 		}
+		// 源端包含多个分库时各库的任务汇总到同一个源端上
+		if res[i].DatabasesName != "" {
+			res[i].Database = shardingDatabasesName(res[i].DatabasesName)
+		}
 
 		key := genKey(&res[i])
 		if val, ok := tableGovernMap[key]; ok {
@@ -568,6 +729,13 @@ func (c *ClusterStatistic) ClusterStatisticDetail(ctx *gin.Context, groupBy stri
 		return nil, common.CodeServerErr, err
 	}
 
+	// 属于多分库源端的表按源端汇总
+	srcDatabases, err := sourceDatabasesMap(db, res)
+	if err != nil {
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
 	clusterMap := make(map[string]*ClusterStatisticDetail, len(res))
 
 	genKey := func(d *models.ClusterStatistics) string { return "" }
@@ -586,11 +754,11 @@ func (c *ClusterStatistic) ClusterStatisticDetail(ctx *gin.Context, groupBy stri
 		}
 	case "database":
 		genKey = func(d *models.ClusterStatistics) string {
-			return strings.Join([]string{d.Date, d.Bu, d.ClusterName, d.Database}, "::::")
+			return strings.Join([]string{d.Date, d.Bu, d.ClusterName, statisticDatabaseKey(d.Database, d.SrcID, srcDatabases[d.SrcID])}, "::::")
 		}
 	case "table":
 		genKey = func(d *models.ClusterStatistics) string {
-			return strings.Join([]string{d.Date, d.Bu, d.ClusterName, d.Database, d.Table}, "::::")
+			return strings.Join([]string{d.Date, d.Bu, d.ClusterName, statisticDatabaseKey(d.Database, d.SrcID, srcDatabases[d.SrcID]), d.Table}, "::::")
 		}
 	default:
 		return c, common.CodeOK, fmt.Errorf("unsupported query method %s", groupBy)
@@ -608,7 +776,7 @@ func (c *ClusterStatistic) ClusterStatisticDetail(ctx *gin.Context, groupBy stri
 				Bu:              r.Bu,
 				ClusterID:       r.ClusterID,
 				ClusterName:     r.ClusterName,
-				Database:        r.Database,
+				Database:        utils.Ternary[string](srcDatabases[r.SrcID] != "", shardingDatabasesName(srcDatabases[r.SrcID]), r.Database),
 				Table:           r.Table,
 				TableSize:       r.TableSize,
 				TableSizeSum:    r.TableSize,
@@ -687,3 +855,47 @@ func (c *ClusterStatistic) ClusterStatisticDetail(ctx *gin.Context, groupBy stri
 
 	return c, common.CodeOK, nil
 }
+
+// statisticDatabaseKey 统计时库的汇总维度，源端包含多个分库时按源端ID汇总，否则按库名汇总
+func statisticDatabaseKey(database string, srcID uint, databasesName string) string {
+	if srcID != 0 && databasesName != "" {
+		return fmt.Sprintf("source:%d", srcID)
+	}
+	return database
+}
+
+// shardingDatabasesName 多个分库展示为分库的原始库名及分库个数
+func shardingDatabasesName(databasesName string) string {
+	databases := strings.Split(databasesName, ",")
+	if len(databases) == 1 {
+		return databases[0]
+	}
+	if b, database, err := common.CheckSameShardingTables(databasesName); err == nil && b {
+		return database + fmt.Sprintf("(%d个分库)", len(databases))
+	}
+	return databases[0] + fmt.Sprintf("等(%d个分库)", len(databases))
+}
+
+// sourceDatabasesMap 查询大表所属源端包含的分库
+func sourceDatabasesMap(db *gorm.DB, res []models.ClusterStatistics) (map[uint]string, error) {
+	var ids []uint
+	for _, r := range res {
+		if r.SrcID != 0 {
+			ids = append(ids, r.SrcID)
+		}
+	}
+	srcDatabases := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return srcDatabases, nil
+	}
+
+	var sources []models.Source
+	err := db.Model(&models.Source{}).Select("id, databases_name").Where("id IN (?)", utils.RemoveDupElement(ids)).Find(&sources).Error
+	if err != nil {
+		return nil, fmt.Errorf("query models.Source from db faield, %s", err)
+	}
+	for _, s := range sources {
+		srcDatabases[s.ID] = s.DatabasesName
+	}
+	return srcDatabases, nil
+}
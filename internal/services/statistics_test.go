@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+)
+
+func TestPlanExecWindow(t *testing.T) {
+	old := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = old }()
+
+	tests := []struct {
+		name      string
+		tz        string
+		window    string
+		windows   string
+		wantStart string
+		wantEnd   string
+	}{
+		{"server timezone", "", `["01:00:00", "03:00:00"]`, "", "2024-01-01 01:00:00", "2024-01-01 03:00:00"},
+		{"east timezone", "Asia/Shanghai", `["01:00:00", "03:00:00"]`, "", "2023-12-31 17:00:00", "2023-12-31 19:00:00"},
+		{"west timezone cross day", "America/New_York", `["22:00:00", "02:00:00"]`, "", "2024-01-02 03:00:00", "2024-01-02 07:00:00"},
+		{
+			name:      "first weekday window in timezone",
+			tz:        "Asia/Shanghai",
+			window:    `["01:00:00", "03:00:00"]`,
+			windows:   `[{"start":"02:00:00","end":"04:00:00","weekdays":[3]}]`,
+			wantStart: "2024-01-02 18:00:00",
+			wantEnd:   "2024-01-02 20:00:00",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := &models.Task{
+				ExecuteDate:    "2024-01-01",
+				Timezone:       test.tz,
+				ExecuteWindow:  []byte(test.window),
+				ExecuteWindows: []byte(test.windows),
+			}
+			start, end := planExecWindow(task)
+			if start != test.wantStart || end != test.wantEnd {
+				t.Fatalf("planExecWindow() got %s - %s, want %s - %s", start, end, test.wantStart, test.wantEnd)
+			}
+		})
+	}
+}
+
+func TestStatisticDatabaseKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		database      string
+		srcID         uint
+		databasesName string
+		wantKey       string
+		wantName      string
+	}{
+		{"single database", "order_db", 1, "", "order_db", "order_db"},
+		{"sharding databases", "order_db_1", 2, "order_db_0,order_db_1,order_db_2", "source:2", "order_db(3个分库)"},
+		{"other shard of same source", "order_db_2", 2, "order_db_0,order_db_1,order_db_2", "source:2", "order_db(3个分库)"},
+		{"databases not sharding named", "user", 3, "user,account", "source:3", "user等(2个分库)"},
+		{"only one matched database", "order_db_0", 4, "order_db_0", "source:4", "order_db_0"},
+		{"no source", "order_db_0", 0, "order_db_0,order_db_1", "order_db_0", "order_db(2个分库)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := statisticDatabaseKey(test.database, test.srcID, test.databasesName); got != test.wantKey {
+				t.Fatalf("statisticDatabaseKey() got %s, want %s", got, test.wantKey)
+			}
+			name := test.database
+			if test.databasesName != "" {
+				name = shardingDatabasesName(test.databasesName)
+			}
+			if name != test.wantName {
+				t.Fatalf("shardingDatabasesName(%s) got %s, want %s", test.databasesName, name, test.wantName)
+			}
+		})
+	}
+}
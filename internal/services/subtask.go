@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// SubTaskService 源端包含多个分库时，任务按库拆分的子任务
+type SubTaskService struct {
+	ID                 uint                  `json:"id"`
+	TaskID             uint                  `json:"task_id"`              // 任务ID
+	DatabaseName       string                `json:"database_name"`        // 源库名
+	TablesName         string                `json:"tables_name"`          // 源表名
+	TaskStatus         common.TaskStatusType `json:"task_status"`          // 子任务状态
+	TaskReason         string                `json:"task_reason"`          // 子任务失败原因
+	TaskDetail         string                `json:"task_detail"`          // 子任务失败详情
+	WorkFlow           string                `json:"workflow"`             // 工作流
+	TaskStartTime      string                `json:"task_start_time"`      // 开始执行时间
+	TaskEndTime        string                `json:"task_end_time"`        // 执行结束时间
+	TaskResultQuantity int                   `json:"task_result_quantity"` // 治理数据量
+	TaskResultSize     int                   `json:"task_result_size"`     // 治理数据容量(MB)
+}
+
+// CreateSubTasks 按库为任务生成子任务，subTasks为各库的校验结果，校验未通过的库标记为补充信息失败不参与执行。
+// 已执行成功的库保留执行结果不再重复执行，任务的源库和源表设置为第一个待执行的库
+func CreateSubTasks(ctx *common.Context, task *models.Task, subTasks []models.SubTask) error {
+	db := ctx.DB
+
+	var existed []models.SubTask
+	err := db.Model(&models.SubTask{}).Where("task_id =?", task.ID).Find(&existed).Error
+	if err != nil {
+		return fmt.Errorf("query models.SubTask(task_id=%v) from db failed, %s", task.ID, err)
+	}
+
+	succeeded := make(map[string]string, len(existed))
+	for _, s := range existed {
+		if s.TaskStatus == common.TaskStatusSuccess {
+			succeeded[s.DatabaseName] = s.TablesName
+		}
+	}
+
+	databases := make([]string, 0, len(subTasks))
+	var pending, failed []models.SubTask
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err = tx.Where("task_id =? AND task_status !=?", task.ID, common.TaskStatusSuccess).Delete(&models.SubTask{}).Error
+		if err != nil {
+			return fmt.Errorf("delete models.SubTask(task_id=%v) failed, %s", task.ID, err)
+		}
+		for _, subTask := range subTasks {
+			databases = append(databases, subTask.DatabaseName)
+			if tables, ok := succeeded[subTask.DatabaseName]; ok {
+				if tables == subTask.TablesName {
+					continue
+				}
+				// 源表发生变化的库需要重新执行
+				err = tx.Where("task_id =? AND database_name =?", task.ID, subTask.DatabaseName).Delete(&models.SubTask{}).Error
+				if err != nil {
+					return fmt.Errorf("delete models.SubTask(task_id=%v, database=%s) failed, %s", task.ID, subTask.DatabaseName, err)
+				}
+			}
+
+			subTask.ID = 0
+			subTask.TaskID = task.ID
+			if subTask.TaskStatus != common.TaskStatusSupplementFailed {
+				subTask.TaskStatus = common.TaskStatusWaiting
+				pending = append(pending, subTask)
+			} else {
+				failed = append(failed, subTask)
+			}
+			subTask.TaskStartTime = time.UnixMilli(0)
+			subTask.TaskEndTime = time.UnixMilli(0)
+			err = tx.Create(&subTask).Error
+			if err != nil {
+				return fmt.Errorf("create models.SubTask(task_id=%v, database=%s) failed, %s", task.ID, subTask.DatabaseName, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range failed {
+		CreateTaskChangeLog(ctx, task, common.SystemUserName,
+			fmt.Sprintf(common.TaskChangeLogSubTaskSupplementFailed, s.DatabaseName, s.TaskReason, s.TaskDetail))
+	}
+	if len(pending) == 0 {
+		return fmt.Errorf("no database(%s) of task(%v) need to be governed", strings.Join(databases, ","), task.ID)
+	}
+	names := make([]string, 0, len(pending))
+	for _, s := range pending {
+		names = append(names, s.DatabaseName)
+	}
+	task.SrcDatabasesName = strings.Join(databases, ",")
+	task.SrcDatabaseName = pending[0].DatabaseName
+	task.SrcTablesName = pending[0].TablesName
+	CreateTaskChangeLog(ctx, task, common.SystemUserName,
+		fmt.Sprintf(common.TaskChangeLogSubTaskCreate, len(pending), strings.Join(names, ",")))
+	return nil
+}
+
+// StartSubTask 标记当前库的子任务开始执行
+func StartSubTask(ctx *common.Context, task *models.Task) error {
+	if task.SrcDatabasesName == "" {
+		return nil
+	}
+
+	err := ctx.DB.Model(&models.SubTask{}).
+		Where("task_id =? AND database_name =?", task.ID, task.SrcDatabaseName).
+		Updates(map[string]any{
+			"task_status":     common.TaskStatusExecuting,
+			"work_flow":       task.WorkFlow,
+			"task_start_time": task.TaskStartTime,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("update models.SubTask(task_id=%v, database=%s) failed, %s", task.ID, task.SrcDatabaseName, err)
+	}
+	return nil
+}
+
+// FinishSubTask 记录当前库子任务的执行结果，并将各子任务的治理数据量汇总到任务上。
+// 当前库执行成功且还有待执行的库时，任务回到等待执行状态继续执行下一个库
+func FinishSubTask(ctx *common.Context, task *models.Task) (bool /* 是否继续执行下一个库 */, error) {
+	db := ctx.DB
+	if task.SrcDatabasesName == "" {
+		return false, nil
+	}
+
+	subTask := &models.SubTask{}
+	err := db.Model(subTask).Where("task_id =? AND database_name =?", task.ID, task.SrcDatabaseName).First(subTask).Error
+	if err != nil {
+		return false, fmt.Errorf("query models.SubTask(task_id=%v, database=%s) failed, %s", task.ID, task.SrcDatabaseName, err)
+	}
+	subTask.TaskStatus = task.TaskStatus
+	subTask.TaskReason = task.TaskReason
+	subTask.TaskDetail = task.TaskDetail
+	subTask.WorkFlow = task.WorkFlow
+	subTask.TaskStartTime = task.TaskStartTime
+	subTask.TaskEndTime = task.TaskEndTime
+	subTask.TaskResultQuantity = task.TaskResultQuantity
+	subTask.TaskResultSize = task.TaskResultSize
+	err = db.Save(subTask).Error
+	if err != nil {
+		return false, fmt.Errorf("update models.SubTask(%v) failed, %s", subTask.ID, err)
+	}
+
+	// 汇总各库的治理结果
+	var sum struct {
+		Quantity int
+		Size     int
+	}
+	err = db.Model(&models.SubTask{}).
+		Select("IFNULL(SUM(task_result_quantity),0) AS quantity, IFNULL(SUM(task_result_size),0) AS size").
+		Where("task_id =?", task.ID).Scan(&sum).Error
+	if err != nil {
+		return false, fmt.Errorf("sum models.SubTask(task_id=%v) result failed, %s", task.ID, err)
+	}
+	task.TaskResultQuantity = sum.Quantity
+	task.TaskResultSize = sum.Size
+
+	if task.TaskStatus != common.TaskStatusSuccess {
+		return false, nil
+	}
+
+	next := &models.SubTask{}
+	err = db.Model(next).Where("task_id =? AND task_status =?", task.ID, common.TaskStatusWaiting).Order("id").Limit(1).Find(next).Error
+	if err != nil {
+		return false, fmt.Errorf("query models.SubTask(task_id=%v) waiting failed, %s", task.ID, err)
+	}
+	if next.ID == 0 {
+		return false, nil
+	}
+
+	finished := task.SrcDatabaseName
+	task.SrcDatabaseName = next.DatabaseName
+	task.SrcTablesName = next.TablesName
+	task.TaskStatus = common.TaskStatusWaiting
+	task.TaskReason = ""
+	task.TaskDetail = ""
+	CreateTaskChangeLog(ctx, task, common.SystemUserName, fmt.Sprintf(common.TaskChangeLogSubTaskNext, finished, next.DatabaseName))
+	return true, nil
+}
+
+func (c *SubTaskService) QuerySubTask(ctx *gin.Context) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.SubTask](db).
+		QueryPaging(ctx).
+		Query(
+			common.FilterCustomUintID("task_id", c.TaskID),
+		)
+	if err != nil {
+		err = fmt.Errorf("query models.SubTask from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]SubTaskService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		s := &SubTaskService{}
+		s.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *s)
+	}
+
+	return ret, common.CodeOK, nil
+}
+
+func (c *SubTaskService) ModelToService(m *models.SubTask) *SubTaskService {
+	c.ID = m.ID
+	c.TaskID = m.TaskID
+	c.DatabaseName = m.DatabaseName
+	c.TablesName = m.TablesName
+	c.TaskStatus = m.TaskStatus
+	c.TaskReason = m.TaskReason
+	c.TaskDetail = m.TaskDetail
+	c.WorkFlow = m.WorkFlow
+	c.TaskStartTime = m.TaskStartTime.Format(time.DateTime)
+	c.TaskEndTime = m.TaskEndTime.Format(time.DateTime)
+	c.TaskResultQuantity = m.TaskResultQuantity
+	c.TaskResultSize = m.TaskResultSize
+	return c
+}
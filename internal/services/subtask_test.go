@@ -0,0 +1,172 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/pkg/logger"
+	"github.com/sunkaimr/data-loom/internal/pkg/mysql/mysqltest"
+)
+
+func newSubTaskContext(t *testing.T) (*common.Context, *models.Task) {
+	db := mysqltest.Open(t, &models.SubTask{})
+	// sqlite的索引名在库内唯一，子任务和变更日志使用了同名索引
+	if err := db.Migrator().DropIndex(&models.SubTask{}, "task_id_idx"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.TaskChangeLog{}); err != nil {
+		t.Fatal(err)
+	}
+	task := &models.Task{Name: "task-1", NotifyPolicy: common.NotifyPolicyTypeSilence}
+	task.ID = 1
+	return common.NewContext().WithLog(logger.Log).WithDB(db), task
+}
+
+func checkedSubTasks(failed ...string) []models.SubTask {
+	subTasks := []models.SubTask{
+		{DatabaseName: "db_0", TablesName: "t_0"},
+		{DatabaseName: "db_1", TablesName: "t_1"},
+		{DatabaseName: "db_2", TablesName: "t_2"},
+	}
+	for i := range subTasks {
+		for _, f := range failed {
+			if subTasks[i].DatabaseName == f {
+				subTasks[i].TaskStatus = common.TaskStatusSupplementFailed
+				subTasks[i].TaskReason = common.CodeSourceTableNotExist.Message
+			}
+		}
+	}
+	return subTasks
+}
+
+func subTaskStatus(t *testing.T, ctx *common.Context, taskID uint) map[string]common.TaskStatusType {
+	var subTasks []models.SubTask
+	if err := ctx.DB.Where("task_id =?", taskID).Find(&subTasks).Error; err != nil {
+		t.Fatal(err)
+	}
+	status := make(map[string]common.TaskStatusType, len(subTasks))
+	for _, s := range subTasks {
+		status[s.DatabaseName] = s.TaskStatus
+	}
+	return status
+}
+
+// runSubTask 模拟工作流执行当前库并返回是否继续执行下一个库
+func runSubTask(t *testing.T, ctx *common.Context, task *models.Task, status common.TaskStatusType, quantity int) bool {
+	task.TaskStatus = common.TaskStatusExecuting
+	if err := StartSubTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	task.TaskStatus = status
+	task.TaskResultQuantity = quantity
+	next, err := FinishSubTask(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return next
+}
+
+func TestCreateSubTasks(t *testing.T) {
+	ctx, task := newSubTaskContext(t)
+
+	if err := CreateSubTasks(ctx, task, checkedSubTasks("db_0")); err != nil {
+		t.Fatal(err)
+	}
+	if task.SrcDatabasesName != "db_0,db_1,db_2" || task.SrcDatabaseName != "db_1" || task.SrcTablesName != "t_1" {
+		t.Fatalf("CreateSubTasks() got task databases(%s) database(%s) tables(%s), want db_0,db_1,db_2 db_1 t_1",
+			task.SrcDatabasesName, task.SrcDatabaseName, task.SrcTablesName)
+	}
+	want := map[string]common.TaskStatusType{
+		"db_0": common.TaskStatusSupplementFailed,
+		"db_1": common.TaskStatusWaiting,
+		"db_2": common.TaskStatusWaiting,
+	}
+	if got := subTaskStatus(t, ctx, task.ID); !reflect.DeepEqual(got, want) {
+		t.Fatalf("CreateSubTasks() got sub tasks %v, want %v", got, want)
+	}
+
+	var count int64
+	ctx.DB.Model(&models.TaskChangeLog{}).Where("task_id =? AND content LIKE ?", task.ID, "库db_0校验未通过%").Count(&count)
+	if count != 1 {
+		t.Fatalf("CreateSubTasks() got %d change logs of failed database, want 1", count)
+	}
+
+	if err := CreateSubTasks(ctx, task, checkedSubTasks("db_0", "db_1", "db_2")); err == nil {
+		t.Fatal("CreateSubTasks() with all databases failed got nil error")
+	}
+}
+
+func TestFinishSubTask(t *testing.T) {
+	ctx, task := newSubTaskContext(t)
+
+	next, err := FinishSubTask(ctx, task)
+	if next || err != nil {
+		t.Fatalf("FinishSubTask() without sub tasks got %v %v, want false nil", next, err)
+	}
+
+	if err = CreateSubTasks(ctx, task, checkedSubTasks()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个库执行成功，继续执行下一个库
+	if !runSubTask(t, ctx, task, common.TaskStatusSuccess, 10) {
+		t.Fatal("FinishSubTask() db_0 success got false, want next database")
+	}
+	if task.SrcDatabaseName != "db_1" || task.SrcTablesName != "t_1" || task.TaskStatus != common.TaskStatusWaiting || task.TaskResultQuantity != 10 {
+		t.Fatalf("FinishSubTask() got database(%s) tables(%s) status(%s) quantity(%d), want db_1 t_1 waiting 10",
+			task.SrcDatabaseName, task.SrcTablesName, task.TaskStatus, task.TaskResultQuantity)
+	}
+
+	// 中间的库执行失败，任务结束且不再执行后面的库
+	if runSubTask(t, ctx, task, common.TaskStatusExecFailed, 5) {
+		t.Fatal("FinishSubTask() db_1 failed got true, want stop")
+	}
+	if task.TaskStatus != common.TaskStatusExecFailed || task.TaskResultQuantity != 15 {
+		t.Fatalf("FinishSubTask() got status(%s) quantity(%d), want exec_failed 15", task.TaskStatus, task.TaskResultQuantity)
+	}
+	want := map[string]common.TaskStatusType{
+		"db_0": common.TaskStatusSuccess,
+		"db_1": common.TaskStatusExecFailed,
+		"db_2": common.TaskStatusWaiting,
+	}
+	if got := subTaskStatus(t, ctx, task.ID); !reflect.DeepEqual(got, want) {
+		t.Fatalf("FinishSubTask() got sub tasks %v, want %v", got, want)
+	}
+
+	// 重新执行时保留已执行成功的库，从失败的库继续
+	if err = CreateSubTasks(ctx, task, checkedSubTasks()); err != nil {
+		t.Fatal(err)
+	}
+	if task.SrcDatabaseName != "db_1" {
+		t.Fatalf("CreateSubTasks() resume got database(%s), want db_1", task.SrcDatabaseName)
+	}
+	if !runSubTask(t, ctx, task, common.TaskStatusSuccess, 7) {
+		t.Fatal("FinishSubTask() db_1 success got false, want next database")
+	}
+	if task.SrcDatabaseName != "db_2" || task.TaskResultQuantity != 17 {
+		t.Fatalf("FinishSubTask() got database(%s) quantity(%d), want db_2 17", task.SrcDatabaseName, task.TaskResultQuantity)
+	}
+
+	// 最后一个库执行成功，任务结束
+	if runSubTask(t, ctx, task, common.TaskStatusSuccess, 3) {
+		t.Fatal("FinishSubTask() db_2 success got true, want finished")
+	}
+	if task.TaskStatus != common.TaskStatusSuccess || task.TaskResultQuantity != 20 {
+		t.Fatalf("FinishSubTask() got status(%s) quantity(%d), want success 20", task.TaskStatus, task.TaskResultQuantity)
+	}
+
+	// 所有库都已执行成功时无需再次执行，源表变化的库需要重新执行
+	if err = CreateSubTasks(ctx, task, checkedSubTasks()); err == nil {
+		t.Fatal("CreateSubTasks() with all databases succeeded got nil error")
+	}
+	subTasks := checkedSubTasks()
+	subTasks[2].TablesName = "t_2,t_3"
+	if err = CreateSubTasks(ctx, task, subTasks); err != nil {
+		t.Fatal(err)
+	}
+	if task.SrcDatabaseName != "db_2" || task.SrcTablesName != "t_2,t_3" {
+		t.Fatalf("CreateSubTasks() got database(%s) tables(%s), want db_2 t_2,t_3", task.SrcDatabaseName, task.SrcTablesName)
+	}
+}
@@ -26,9 +26,14 @@ type TaskService struct {
 	Enable             bool                  `json:"enable"`               // 是否生效
 	PolicyID           uint                  `json:"policy_id"`            // 策略ID
 	ExecuteWindow      []string              `json:"execute_window"`       // 执行窗口
+	ExecuteWindows     []common.ExecWindow   `json:"execute_windows"`      // 多执行窗口，配置后以此为准
 	ExecuteDate        string                `json:"execute_date"`         // 预计执行日期 "2024-01-02"
+	Timezone           string                `json:"timezone"`             // 执行日期和执行窗口所在时区(取自源端集群)，为空时为服务所在时区
+	Priority           int                   `json:"priority"`             // 优先级(1-10)，数值越大越优先执行
 	Pause              bool                  `json:"pause"`                // 执行窗口外是否需要暂停执行
 	RebuildFlag        bool                  `json:"rebuild_flag"`         // 执行窗口外是否重建表(仅在治理方式是删除时有效)。true:在执行窗口外仍然走重建流程; false:执行窗口外跳过重建流程
+	Backfill           bool                  `json:"backfill"`             // 是否为补录任务
+	BackfillPeriods    []string              `json:"backfill_periods"`     // 补录的执行周期
 	TaskStatus         common.TaskStatusType `json:"task_status"`          // 任务状态
 	TaskReason         string                `json:"task_reason"`          // 任务失败原因
 	TaskDetail         string                `json:"task_detail"`          // 任务失败详情
@@ -36,20 +41,23 @@ type TaskService struct {
 	TaskResultSize     int                   `json:"task_result_size"`     // 治理数据大小
 	TaskStartTime      string                `json:"task_start_time"`      // 开始执行时间
 	TaskEndTime        string                `json:"task_end_time"`        // 执行结束时间
+	ZoneTaskStartTime  string                `json:"zone_task_start_time"` // 开始执行时间(任务所在时区)
+	ZoneTaskEndTime    string                `json:"zone_task_end_time"`   // 执行结束时间(任务所在时区)
 	TaskDuration       int                   `json:"task_duration"`        // 执行时长(秒)
 	WorkFlow           string                `json:"workflow"`             // 工作流
 	WorkFlowURL        string                `json:"workflow_url"`         // 工作流地址
 
 	// 源端信息
-	SrcID           uint   `json:"src_id"`            // 任务ID
-	SrcName         string `json:"src_name"`          // 源端名称
-	SrcBu           string `json:"src_bu"`            // 资产BU
-	SrcClusterName  string `json:"src_cluster_name"`  // 集群名称
-	SrcClusterID    string `json:"src_cluster_id"`    // 集群ID
-	SrcAddr         string `json:"src_addr"`          // 源端地址
-	SrcDatabaseName string `json:"src_database_name"` // 源库名
-	SrcTablesName   string `json:"src_tables_name"`   // 源表名
-	SrcColumns      string `json:"src_columns"`       // 源列名
+	SrcID            uint   `json:"src_id"`             // 任务ID
+	SrcName          string `json:"src_name"`           // 源端名称
+	SrcBu            string `json:"src_bu"`             // 资产BU
+	SrcClusterName   string `json:"src_cluster_name"`   // 集群名称
+	SrcClusterID     string `json:"src_cluster_id"`     // 集群ID
+	SrcAddr          string `json:"src_addr"`           // 源端地址
+	SrcDatabaseName  string `json:"src_database_name"`  // 源库名，源端包含多个分库时为当前执行的库
+	SrcDatabasesName string `json:"src_databases_name"` // 源端包含多个分库时的全部库名
+	SrcTablesName    string `json:"src_tables_name"`    // 源表名
+	SrcColumns       string `json:"src_columns"`        // 源列名
 
 	// 目标端信息
 	DestID           uint               `json:"dest_id"`            // 目标端ID
@@ -65,6 +73,20 @@ type TaskService struct {
 	Condition     string                   `json:"condition"`       // 数据治理条件
 	RetainSrcData bool                     `json:"retain_src_data"` //归档时否保留源表数据
 	CleaningSpeed common.CleaningSpeedType `json:"cleaning_speed"`  // 清理速度 稳定优先:steady, 速度适中:balanced, 速度优先:swift
+	ThrottleID    uint                     `json:"throttle_id"`     // 限流配置ID，为0时按清理速度使用内置的限流配置
+
+	// 分区治理（仅分区治理涉及）
+	PartitionColumn     string                       `json:"partition_column"`      // 分区列
+	PartitionInterval   common.PartitionIntervalType `json:"partition_interval"`    // 分区粒度 按天:day, 按周:week, 按月:month, 按年:year
+	PartitionRetainDays int                          `json:"partition_retain_days"` // 保留最近多少天的分区
+	PartitionPrecreate  int                          `json:"partition_precreate"`   // 预创建未来多少个周期的分区
+	PartitionAction     common.PartitionActionType   `json:"partition_action"`      // 过期分区处理方式 删除:drop, 交换到归档表后删除:exchange
+
+	// 增量治理（仅删除和归档涉及）
+	WatermarkColumn string `json:"watermark_column"` // 水位列
+	WatermarkLow    string `json:"watermark_low"`    // 本次治理的水位下界(不含)
+	WatermarkHigh   string `json:"watermark_high"`   // 本次治理的水位上界(含)
+	Watermark       string `json:"watermark"`        // 工作流上报的已处理到的水位
 
 	// 结果通知
 	Relevant     []string                `json:"relevant"`      // 关注人
@@ -106,17 +128,20 @@ func (c *TaskService) CheckUpdateParameters(ctx *gin.Context) (bool, common.Serv
 		return false, common.CodeTaskNotifyPolicyErr, fmt.Errorf("validate notify_policy(%s) not pass", c.NotifyPolicy)
 	}
 
+	if c.Priority != 0 && !common.CheckPriority(c.Priority) {
+		return false, common.CodeTaskPriorityErr, fmt.Errorf("validate priority(%d) not pass", c.Priority)
+	}
+
 	executeDate, err := time.ParseInLocation(time.DateOnly, c.ExecuteDate, time.Now().Location())
 	if err != nil {
 		return false, common.CodeTaskExecDateErr, fmt.Errorf("parse execute date(%s) faield, it should like '%s', %s", c.ExecuteDate, time.DateOnly, err)
 	}
 	c.ExecuteDate = executeDate.Format(time.DateOnly)
 
-	_, execWin, err := checkExecWindow(c.ExecuteWindow)
+	c.ExecuteWindow, c.ExecuteWindows, err = checkExecWindows(c.ExecuteWindow, c.ExecuteWindows)
 	if err != nil {
 		return false, common.CodePolicyExecuteWindowErr, err
 	}
-	c.ExecuteWindow = execWin
 	task.TaskStatus = common.TaskStatusScheduled
 
 	return true, common.CodeOK, nil
@@ -145,9 +170,14 @@ func (c *TaskService) UpdateTask(ctx *gin.Context) (common.ServiceCode, error) {
 	newTask.RebuildFlag = c.RebuildFlag
 	newTask.ExecuteDate = c.ExecuteDate
 	newTask.ExecuteWindow, _ = json.Marshal(c.ExecuteWindow)
+	newTask.ExecuteWindows = nil
+	if len(c.ExecuteWindows) != 0 {
+		newTask.ExecuteWindows, _ = json.Marshal(c.ExecuteWindows)
+	}
 	newTask.Editor = u.UserName
 	newTask.Enable = c.Enable
 	newTask.NotifyPolicy = utils.Ternary[common.NotifyPolicyType](c.NotifyPolicy == "", task.NotifyPolicy, c.NotifyPolicy)
+	newTask.Priority = utils.Ternary[int](c.Priority == 0, task.Priority, c.Priority)
 	if len(c.Relevant) != 0 {
 		newTask.Relevant, _ = json.Marshal(c.Relevant)
 	}
@@ -170,7 +200,8 @@ func (c *TaskService) UpdateTask(ctx *gin.Context) (common.ServiceCode, error) {
 		return common.CodeServerErr, err
 	}
 
-	if task.ExecuteDate != newTask.ExecuteDate || string(task.ExecuteWindow) != string(newTask.ExecuteWindow) {
+	if task.ExecuteDate != newTask.ExecuteDate || string(task.ExecuteWindow) != string(newTask.ExecuteWindow) ||
+		string(task.ExecuteWindows) != string(newTask.ExecuteWindows) {
 		CreateTaskChangeLog(common.NewContext().WithDB(db).WithLog(log), task, u.RealName, fmt.Sprintf(common.TaskChangeLogUpdate, newTask.ExecuteDate, string(newTask.ExecuteWindow)))
 	}
 
@@ -179,7 +210,7 @@ func (c *TaskService) UpdateTask(ctx *gin.Context) (common.ServiceCode, error) {
 		// 如果距离执行时间不足1天直接进行调度前检查，否则修改任务状态为已排期走正常的检查流程
 
 		// 任务不是一天后执行先不用检查
-		if common.JudgeTaskCouldCheckBeforeExec(newTask.ExecuteDate) {
+		if common.JudgeTaskCouldCheckBeforeExec(newTask.ExecuteDate, newTask.Timezone) {
 			// 	因为任务将在一天后执行需通知TaskHandle立即进行调度前检查
 			handle := &TaskQueueHandle{ID: newTask.ID, PolicyID: newTask.PolicyID, HandleID: ctx.GetHeader(common.RequestID)}
 			log.With(WithExtra[*TaskQueueHandle](handle, ProcQueuing)...).Infof("task %s push queue...", newTask.Name)
@@ -302,6 +333,12 @@ func (c *TaskService) DeleteTask(ctx *gin.Context) (common.ServiceCode, error) {
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fmt.Errorf("delete models.TaskChangeLog(task_id=%v) from db failed, %s", task.ID, err)
 		}
+
+		// 删除分库子任务
+		err = db.Delete(&models.SubTask{}, "task_id =?", task.ID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("delete models.SubTask(task_id=%v) from db failed, %s", task.ID, err)
+		}
 		return nil
 	})
 	if err != nil {
@@ -337,6 +374,14 @@ func UpdateTaskResult(ctx *gin.Context, c *types.TaskResultService) (*TaskServic
 		return nil, common.CodeTaskStatusErr, err
 	}
 
+	// 超出执行窗口暂停的任务会在下一个执行窗口继续执行，忽略被停止的工作流上报的结果
+	if task.TaskStatus == common.TaskStatusWaiting && task.TaskReason == common.CodeTaskPausedOutOfExecWin.Message {
+		log.Infof("task(%v) has been paused out of exec windows, ignore result(%v)", task.ID, c.TaskStatus)
+		taskSvc := &TaskService{}
+		taskSvc.ModelToService(task)
+		return taskSvc, common.CodeOK, nil
+	}
+
 	if c.TaskStartTime != "" && c.TaskEndTime != "" {
 		s, err1 := time.ParseInLocation(time.DateTime, c.TaskStartTime, time.Now().Location())
 		e, err2 := time.ParseInLocation(time.DateTime, c.TaskEndTime, time.Now().Location())
@@ -357,6 +402,7 @@ func UpdateTaskResult(ctx *gin.Context, c *types.TaskResultService) (*TaskServic
 	task.TaskReason = c.TaskReason
 	task.TaskDetail = c.TaskDetail
 	task.TaskResultQuantity = utils.Ternary[int](c.TaskResultQuantity == common.InvalidInt, task.TaskResultQuantity, c.TaskResultQuantity)
+	task.Watermark = utils.Ternary[string](c.Watermark == "", task.Watermark, c.Watermark)
 
 	// 	清理后表大小, 用户提供了则以用户的为准
 	if c.TaskResultSize == common.InvalidInt {
@@ -378,12 +424,31 @@ func UpdateTaskResult(ctx *gin.Context, c *types.TaskResultService) (*TaskServic
 
 	task.TaskResultSize = utils.Ternary[int](task.TaskResultSize > 0, task.TaskResultSize, 0)
 
+	// 源端包含多个分库时记录当前库的执行结果，还有未执行的库时继续执行下一个库
+	newCtx := common.NewContext().WithDB(db).WithLog(log)
+	next, err := FinishSubTask(newCtx, task)
+	if err != nil {
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
 	err = db.Save(&task).Error
 	if err != nil {
 		log.Errorf("update models.Task(%+v) from db failed, %s", task, err)
 		return nil, common.CodeServerErr, err
 	}
-	CreateTaskChangeLog(common.NewContext().WithDB(db).WithLog(log), task, user.RealName, common.TaskChangeLogWorkFlowFinished)
+	CreateTaskChangeLog(newCtx, task, user.RealName, common.TaskChangeLogWorkFlowFinished)
+
+	if next {
+		taskSvc := &TaskService{}
+		taskSvc.ModelToService(task)
+		return taskSvc, common.CodeOK, nil
+	}
+
+	// 增量治理推进策略的水位
+	if err = AdvancePolicyWatermark(newCtx, task); err != nil {
+		log.Error(err)
+	}
 
 	status := ProcExecSuccess
 	switch task.TaskStatus {
@@ -422,8 +487,13 @@ func (c *TaskService) ServiceToModel() *models.Task {
 	m.Enable = c.Enable
 	m.PolicyID = c.PolicyID
 	m.ExecuteWindow, _ = json.Marshal(c.ExecuteWindow)
+	if len(c.ExecuteWindows) != 0 {
+		m.ExecuteWindows, _ = json.Marshal(c.ExecuteWindows)
+	}
 	m.Pause = c.Pause
 	m.RebuildFlag = c.RebuildFlag
+	m.Backfill = c.Backfill
+	m.BackfillPeriods = strings.Join(c.BackfillPeriods, ",")
 	m.TaskResultQuantity = c.TaskResultQuantity
 	m.TaskResultSize = c.TaskResultSize
 	m.TaskDuration = c.TaskDuration
@@ -434,6 +504,7 @@ func (c *TaskService) ServiceToModel() *models.Task {
 	m.SrcClusterName = c.SrcClusterName
 	m.SrcClusterID = c.SrcClusterID
 	m.SrcDatabaseName = c.SrcDatabaseName
+	m.SrcDatabasesName = c.SrcDatabasesName
 	m.SrcTablesName = c.SrcTablesName
 	m.SrcColumns = c.SrcColumns
 	m.DestID = c.DestID
@@ -446,9 +517,21 @@ func (c *TaskService) ServiceToModel() *models.Task {
 	m.Govern = c.Govern
 	m.Condition = c.Condition
 	m.RetainSrcData = c.RetainSrcData
+	m.PartitionColumn = c.PartitionColumn
+	m.PartitionInterval = c.PartitionInterval
+	m.PartitionRetainDays = c.PartitionRetainDays
+	m.PartitionPrecreate = c.PartitionPrecreate
+	m.PartitionAction = c.PartitionAction
+	m.WatermarkColumn = c.WatermarkColumn
+	m.WatermarkLow = c.WatermarkLow
+	m.WatermarkHigh = c.WatermarkHigh
+	m.Watermark = c.Watermark
 	m.CleaningSpeed = c.CleaningSpeed
+	m.ThrottleID = c.ThrottleID
 	m.NotifyPolicy = c.NotifyPolicy
 	m.ExecuteDate = c.ExecuteDate
+	m.Timezone = c.Timezone
+	m.Priority = c.Priority
 	m.TaskStartTime, _ = time.ParseInLocation(time.DateTime, c.TaskStartTime, time.Now().Location())
 	m.TaskEndTime, _ = time.ParseInLocation(time.DateTime, c.TaskEndTime, time.Now().Location())
 	m.Relevant, _ = json.Marshal(c.Relevant)
@@ -468,8 +551,12 @@ func (c *TaskService) ModelToService(m *models.Task) *TaskService {
 	c.Enable = m.Enable
 	c.Pause = m.Pause
 	c.RebuildFlag = m.RebuildFlag
+	c.Backfill = m.Backfill
+	c.BackfillPeriods = utils.Ternary[[]string](m.BackfillPeriods == "", nil, strings.Split(m.BackfillPeriods, ","))
 	c.PolicyID = m.PolicyID
 	c.ExecuteDate = m.ExecuteDate
+	c.Timezone = m.Timezone
+	c.Priority = m.Priority
 	c.TaskStatus = m.TaskStatus
 	c.TaskReason = m.TaskReason
 	c.TaskDetail = m.TaskDetail
@@ -483,6 +570,7 @@ func (c *TaskService) ModelToService(m *models.Task) *TaskService {
 	c.SrcClusterName = m.SrcClusterName
 	c.SrcClusterID = m.SrcClusterID
 	c.SrcDatabaseName = m.SrcDatabaseName
+	c.SrcDatabasesName = m.SrcDatabasesName
 	c.SrcTablesName = m.SrcTablesName
 	c.SrcColumns = m.SrcColumns
 	c.DestID = m.DestID
@@ -495,11 +583,24 @@ func (c *TaskService) ModelToService(m *models.Task) *TaskService {
 	c.Govern = m.Govern
 	c.Condition = m.Condition
 	c.RetainSrcData = m.RetainSrcData
+	c.PartitionColumn = m.PartitionColumn
+	c.PartitionInterval = m.PartitionInterval
+	c.PartitionRetainDays = m.PartitionRetainDays
+	c.PartitionPrecreate = m.PartitionPrecreate
+	c.PartitionAction = m.PartitionAction
+	c.WatermarkColumn = m.WatermarkColumn
+	c.WatermarkLow = m.WatermarkLow
+	c.WatermarkHigh = m.WatermarkHigh
+	c.Watermark = m.Watermark
 	c.CleaningSpeed = m.CleaningSpeed
+	c.ThrottleID = m.ThrottleID
 	c.NotifyPolicy = m.NotifyPolicy
 	_ = json.Unmarshal(m.Relevant, &c.Relevant)
 	_ = json.Unmarshal(m.ExecuteWindow, &c.ExecuteWindow)
+	_ = json.Unmarshal(m.ExecuteWindows, &c.ExecuteWindows)
 	c.TaskStartTime = utils.Ternary[string](m.TaskStartTime == time.UnixMilli(0), "", m.TaskStartTime.Format(time.DateTime))
 	c.TaskEndTime = utils.Ternary[string](m.TaskEndTime == time.UnixMilli(0), "", m.TaskEndTime.Format(time.DateTime))
+	c.ZoneTaskStartTime = utils.Ternary[string](c.TaskStartTime == "", "", common.FormatInZone(c.TaskStartTime, m.Timezone))
+	c.ZoneTaskEndTime = utils.Ternary[string](c.TaskEndTime == "", "", common.FormatInZone(c.TaskEndTime, m.Timezone))
 	return c
 }
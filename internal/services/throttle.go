@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/internal/workflow/types"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+// presetThrottles 未指定限流配置时按清理速度使用的内置限流参数，默认的清理速度与引入限流配置之前的执行参数一致(每批2000行，批次间隔1秒)
+var presetThrottles = map[common.CleaningSpeedType]types.Throttle{
+	common.CleaningSpeedSteady:   {Name: string(common.CleaningSpeedSteady), ChunkSize: 1000, SleepMs: 1000, MaxRowsPerSecond: 1000, MaxTxnBytes: 16 << 20},
+	common.CleaningSpeedBalanced: {Name: string(common.CleaningSpeedBalanced), ChunkSize: 2000, SleepMs: 1000},
+	common.CleaningSpeed:         {Name: string(common.CleaningSpeed), ChunkSize: 5000, SleepMs: 1000},
+}
+
+// ThrottleService 限流配置，控制删除和归档时每批处理的行数、批次间隔、每秒处理的行数及单个事务的大小
+type ThrottleService struct {
+	Model
+	Name             string `json:"name"`                // 限流配置名称
+	Description      string `json:"description"`         // 说明
+	ChunkSize        int    `json:"chunk_size"`          // 每批处理的行数
+	SleepMs          int    `json:"sleep_ms"`            // 批次之间休眠的毫秒数，pt-archiver只支持整秒休眠，执行时四舍五入到秒
+	MaxRowsPerSecond int    `json:"max_rows_per_second"` // 每秒最多处理的行数，0表示不限制，执行时折算为整秒的批次间隔
+	MaxTxnBytes      int64  `json:"max_txn_bytes"`       // 单个事务最多处理的字节数，0表示不限制
+}
+
+// TaskThrottleService 调整任务的限流配置
+type TaskThrottleService struct {
+	TaskID     uint `json:"task_id"`     // 任务ID
+	ThrottleID uint `json:"throttle_id"` // 限流配置ID，为0时按清理速度使用内置的限流配置
+}
+
+func (c *ThrottleService) CheckParameters(_ *gin.Context) (bool, common.ServiceCode, error) {
+	if len(c.Name) == 0 || len(c.Name) >= 1024 {
+		return false, common.CodeThrottleNameErr, fmt.Errorf("validate throttle name(%s) not pass", c.Name)
+	}
+	if _, ok := presetThrottles[common.CleaningSpeedType(c.Name)]; ok {
+		return false, common.CodeThrottleNameErr, fmt.Errorf("throttle name(%s) is reserved for cleaning speed", c.Name)
+	}
+	if c.ChunkSize <= 0 {
+		return false, common.CodeThrottleValueErr, fmt.Errorf("chunk_size(%d) should greater than 0", c.ChunkSize)
+	}
+	if c.SleepMs < 0 || c.MaxRowsPerSecond < 0 || c.MaxTxnBytes < 0 {
+		return false, common.CodeThrottleValueErr, fmt.Errorf("sleep_ms(%d), max_rows_per_second(%d), max_txn_bytes(%d) should not less than 0",
+			c.SleepMs, c.MaxRowsPerSecond, c.MaxTxnBytes)
+	}
+	return true, common.CodeOK, nil
+}
+
+func (c *ThrottleService) checkConflict(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	var count int64
+	err := db.Model(&models.Throttle{}).Where("name =? AND id !=?", c.Name, c.ID).Count(&count).Error
+	if err != nil {
+		log.Errorf("query models.Throttle(name =%v AND id !=%v) from db failed, %s", c.Name, c.ID, err)
+		return common.CodeServerErr, err
+	}
+	if count != 0 {
+		err = fmt.Errorf("models.Throttle(name=%s) exist", c.Name)
+		log.Error(err)
+		return common.CodeThrottleNameConflict, err
+	}
+	return common.CodeOK, nil
+}
+
+func (c *ThrottleService) CreateThrottle(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	c.ID = 0
+	if res, err := c.checkConflict(ctx); res != common.CodeOK {
+		return res, err
+	}
+
+	throttle := c.ServiceToModel()
+	throttle.CreatedAt = time.Now()
+	throttle.Creator = u.UserName
+	err := db.Save(throttle).Error
+	if err != nil {
+		log.Errorf("save models.Throttle(%+v) to db failed, %s", throttle, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(throttle)
+	return common.CodeOK, nil
+}
+
+// UpdateThrottle 修改限流配置，引用该配置执行中的任务会在下次查询限流参数时生效
+func (c *ThrottleService) UpdateThrottle(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	throttle := &models.Throttle{}
+	err := db.Model(throttle).First(throttle, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("query models.Throttle(id=%d) not exist", c.ID)
+			return common.CodeThrottleNotExist, err
+		}
+		log.Errorf("query models.Throttle(id=%d) from db failed, %s", c.ID, err)
+		return common.CodeServerErr, err
+	}
+
+	old := &ThrottleService{}
+	old.ModelToService(throttle)
+	c.Name = utils.Ternary[string](c.Name == "", old.Name, c.Name)
+	c.Description = utils.Ternary[string](c.Description == "", old.Description, c.Description)
+	c.ChunkSize = utils.Ternary[int](c.ChunkSize == 0, old.ChunkSize, c.ChunkSize)
+	c.SleepMs = utils.Ternary[int](c.SleepMs == common.InvalidInt, old.SleepMs, c.SleepMs)
+	c.MaxRowsPerSecond = utils.Ternary[int](c.MaxRowsPerSecond == common.InvalidInt, old.MaxRowsPerSecond, c.MaxRowsPerSecond)
+	c.MaxTxnBytes = utils.Ternary[int64](c.MaxTxnBytes == common.InvalidInt64, old.MaxTxnBytes, c.MaxTxnBytes)
+
+	// 参数校验
+	if ok, res, err := c.CheckParameters(ctx); !ok {
+		log.Errorf("check parameters(%+v) not pass, %s", c, err)
+		return res, err
+	}
+
+	if res, err := c.checkConflict(ctx); res != common.CodeOK {
+		return res, err
+	}
+
+	newThrottle := c.ServiceToModel()
+	newThrottle.CreatedAt = throttle.CreatedAt
+	newThrottle.Creator = throttle.Creator
+	newThrottle.Editor = u.UserName
+	err = db.Save(newThrottle).Error
+	if err != nil {
+		log.Errorf("update models.Throttle(%+v) from db failed, %s", newThrottle, err)
+		return common.CodeServerErr, err
+	}
+
+	c.ModelToService(newThrottle)
+	return common.CodeOK, nil
+}
+
+// DeleteThrottle 删除限流配置，被策略或未结束的任务引用时不能删除
+func (c *ThrottleService) DeleteThrottle(ctx *gin.Context) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	throttle := &models.Throttle{}
+	err := db.Model(throttle).First(throttle, "id = ?", c.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("query models.Throttle(id=%d) not exist", c.ID)
+			log.Error(err)
+			return common.CodeThrottleNotExist, err
+		}
+		err = fmt.Errorf("query models.Throttle(id=%d) from db failed, %s", c.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+
+	var policyCount, taskCount int64
+	err = db.Model(&models.Policy{}).Where("throttle_id =?", throttle.ID).Count(&policyCount).Error
+	if err == nil {
+		err = db.Model(&models.Task{}).Where("throttle_id =? AND task_status IN (?)", throttle.ID, common.TaskStatusNotFinish).Count(&taskCount).Error
+	}
+	if err != nil {
+		err = fmt.Errorf("query models.Policy or models.Task(throttle_id=%d) from db failed, %s", throttle.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	if policyCount+taskCount != 0 {
+		err = fmt.Errorf("models.Throttle(%d) is used by %d policies and %d tasks", throttle.ID, policyCount, taskCount)
+		log.Error(err)
+		return common.CodeThrottleUsing, err
+	}
+
+	db.Model(&models.Throttle{}).Where("id =?", throttle.ID).Update("editor", u.UserName)
+	err = db.Delete(&models.Throttle{}, "id =?", throttle.ID).Error
+	if err != nil {
+		err = fmt.Errorf("delete models.Throttle(id=%d) from db failed, %s", throttle.ID, err)
+		log.Error(err)
+		return common.CodeServerErr, err
+	}
+	return common.CodeOK, nil
+}
+
+func (c *ThrottleService) QueryThrottle(ctx *gin.Context, queryMap map[string]string) (any, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	res, err := common.NewPageList[[]models.Throttle](db).
+		QueryPaging(ctx).
+		Order("id desc").
+		Query(
+			common.FilterFuzzyStringMap(queryMap),
+			common.FilterID(c.ID),
+		)
+	if err != nil {
+		err = fmt.Errorf("query models.Throttle from db faield, %s", err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	ret := common.NewPageList[[]ThrottleService](db)
+	ret.Page = res.Page
+	ret.PageSize = res.PageSize
+	ret.Total = res.Total
+	for i := range res.Items {
+		t := &ThrottleService{}
+		t.ModelToService(&res.Items[i])
+		ret.Items = append(ret.Items, *t)
+	}
+	return ret, common.CodeOK, nil
+}
+
+// CheckThrottleExist 校验策略引用的限流配置存在，0表示按清理速度使用内置的限流配置
+func CheckThrottleExist(db *gorm.DB, id uint) (common.ServiceCode, error) {
+	if id == 0 {
+		return common.CodeOK, nil
+	}
+	var count int64
+	err := db.Model(&models.Throttle{}).Where("id =?", id).Count(&count).Error
+	if err != nil {
+		return common.CodeServerErr, fmt.Errorf("query models.Throttle(id=%d) from db failed, %s", id, err)
+	}
+	if count == 0 {
+		return common.CodePolicyThrottleErr, fmt.Errorf("models.Throttle(id=%d) not exist", id)
+	}
+	return common.CodeOK, nil
+}
+
+// TaskThrottle 查询任务当前生效的限流参数，未指定限流配置或配置已被删除时按清理速度使用内置的限流参数
+func TaskThrottle(db *gorm.DB, task *models.Task) (types.Throttle, error) {
+	if task.ThrottleID != 0 {
+		var throttles []models.Throttle
+		err := db.Model(&models.Throttle{}).Where("id =?", task.ThrottleID).Limit(1).Find(&throttles).Error
+		if err != nil {
+			return types.Throttle{}, fmt.Errorf("query models.Throttle(id=%d) from db failed, %s", task.ThrottleID, err)
+		}
+		if len(throttles) != 0 {
+			t := throttles[0]
+			return types.Throttle{
+				Name:             t.Name,
+				ChunkSize:        t.ChunkSize,
+				SleepMs:          t.SleepMs,
+				MaxRowsPerSecond: t.MaxRowsPerSecond,
+				MaxTxnBytes:      t.MaxTxnBytes,
+			}, nil
+		}
+	}
+
+	if t, ok := presetThrottles[task.CleaningSpeed]; ok {
+		return t, nil
+	}
+	return presetThrottles[common.CleaningSpeedDefault], nil
+}
+
+// QueryTaskThrottle 查询任务当前生效的限流参数，工作流执行过程中定时查询以便调整后及时生效
+func QueryTaskThrottle(ctx *gin.Context, taskID uint) (*types.Throttle, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+
+	task := &models.Task{}
+	err := db.Model(task).Select("id, throttle_id, cleaning_speed").First(task, "id =?", taskID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.CodeTaskNotExist, fmt.Errorf("query models.Task(id=%d) not exist", taskID)
+		}
+		err = fmt.Errorf("query models.Task(id=%d) from db failed, %s", taskID, err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+
+	throttle, err := TaskThrottle(db, task)
+	if err != nil {
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+	return &throttle, common.CodeOK, nil
+}
+
+// UpdateTaskThrottle 调整未结束任务的限流配置，执行中的任务由工作流查询后生效
+func (c *TaskThrottleService) UpdateTaskThrottle(ctx *gin.Context) (*types.Throttle, common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	u := common.ExtractUserInfo(ctx)
+
+	task := &models.Task{}
+	err := db.Model(task).First(task, "id =?", c.TaskID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.CodeTaskNotExist, fmt.Errorf("query models.Task(id=%d) not exist", c.TaskID)
+		}
+		err = fmt.Errorf("query models.Task(id=%d) from db failed, %s", c.TaskID, err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+	if utils.ElementExist(task.TaskStatus, common.TaskStatusHasFinished) {
+		return nil, common.CodeThrottleTaskFinished, fmt.Errorf("task(%d) status(%s) has finished", task.ID, task.TaskStatus)
+	}
+	if code, err := CheckThrottleExist(db, c.ThrottleID); err != nil {
+		return nil, code, err
+	}
+
+	err = db.Model(&models.Task{}).Where("id =?", task.ID).Updates(map[string]any{
+		"throttle_id": c.ThrottleID,
+		"editor":      u.UserName,
+	}).Error
+	if err != nil {
+		err = fmt.Errorf("update models.Task(id=%d) throttle_id(%d) failed, %s", task.ID, c.ThrottleID, err)
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+	task.ThrottleID = c.ThrottleID
+
+	throttle, err := TaskThrottle(db, task)
+	if err != nil {
+		log.Error(err)
+		return nil, common.CodeServerErr, err
+	}
+	CreateTaskChangeLog(common.NewContext().WithDB(db).WithLog(log), task, u.RealName, fmt.Sprintf(common.TaskChangeLogThrottle, throttle.Name))
+	return &throttle, common.CodeOK, nil
+}
+
+func (c *ThrottleService) ServiceToModel() *models.Throttle {
+	m := &models.Throttle{}
+	m.ID = c.ID
+	m.Creator = c.Creator
+	m.Editor = c.Editor
+	m.CreatedAt, _ = time.ParseInLocation(time.DateTime, c.CreatedAt, time.Now().Location())
+	m.UpdatedAt, _ = time.ParseInLocation(time.DateTime, c.UpdatedAt, time.Now().Location())
+	m.Name = c.Name
+	m.Description = c.Description
+	m.ChunkSize = c.ChunkSize
+	m.SleepMs = c.SleepMs
+	m.MaxRowsPerSecond = c.MaxRowsPerSecond
+	m.MaxTxnBytes = c.MaxTxnBytes
+	return m
+}
+
+func (c *ThrottleService) ModelToService(m *models.Throttle) *ThrottleService {
+	c.ID = m.ID
+	c.Creator = m.Creator
+	c.Editor = m.Editor
+	c.CreatedAt = m.CreatedAt.Format(time.DateTime)
+	c.UpdatedAt = m.UpdatedAt.Format(time.DateTime)
+	c.Name = m.Name
+	c.Description = m.Description
+	c.ChunkSize = m.ChunkSize
+	c.SleepMs = m.SleepMs
+	c.MaxRowsPerSecond = m.MaxRowsPerSecond
+	c.MaxTxnBytes = m.MaxTxnBytes
+	return c
+}
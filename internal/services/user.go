@@ -17,6 +17,7 @@ type UserService struct {
 	Password  string `json:"password"`   // 密码
 	RealName  string `json:"real_name"`  // 姓名
 	Email     string `json:"email"`      // 邮箱
+	ChatID    string `json:"chat_id"`    // 聊天工具(钉钉、飞书、企业微信)中的用户ID，群机器人通知时用于@提醒
 	IsLdap    uint   `json:"is_ldap"`    // 是否是LDAP用户
 	LastLogin string `json:"last_login"` // 上次登录时间
 	Token     string `json:"token"`      // token，登录成功后返回
@@ -101,16 +102,19 @@ func (c *UserService) ModifyUserInfo(ctx *gin.Context) (*UserService, common.Ser
 		return nil, common.CodeServerErr, err
 	}
 
-	// AD用户信息不能修改
+	// AD用户信息不能修改，仅可以修改聊天工具中的用户ID
 	if user.IsLdap == 1 || c.IsLdap == 1 {
-		err = fmt.Errorf("ldap user(%s) cannot modify user info", user.Username)
-		log.Error(err)
-		return nil, common.CodeUserDeniedLdap, err
+		if c.ChatID == "" || c.ChatID == user.ChatID {
+			err = fmt.Errorf("ldap user(%s) cannot modify user info", user.Username)
+			log.Error(err)
+			return nil, common.CodeUserDeniedLdap, err
+		}
+	} else {
+		user.RealName = c.RealName
+		user.Email = c.Email
 	}
-
-	user.RealName = c.RealName
-	user.Email = c.Email
-	if c.Password != "" {
+	user.ChatID = utils.Ternary[string](c.ChatID == "", user.ChatID, c.ChatID)
+	if c.Password != "" && user.IsLdap != 1 {
 		hashedPassword, err := utils.HashPassword(c.Password)
 		if err != nil {
 			err = fmt.Errorf("hash password failed, %s", err)
@@ -163,6 +167,7 @@ func (c *UserService) RegisterUser(ctx *gin.Context) (*UserService, common.Servi
 	user.Username = c.Username
 	user.RealName = c.RealName
 	user.Email = c.Email
+	user.ChatID = c.ChatID
 	user.IsLdap = 0
 	user.Password = hashedPassword
 	user.LastLogin = time.UnixMicro(0)
@@ -264,6 +269,7 @@ func (c *UserService) ServiceToModel() *models.User {
 	m.Password = c.Password
 	m.RealName = c.RealName
 	m.Email = c.Email
+	m.ChatID = c.ChatID
 	m.IsLdap = c.IsLdap
 	m.LastLogin, _ = time.ParseInLocation(time.DateTime, c.LastLogin, time.Now().Location())
 	return m
@@ -279,6 +285,7 @@ func (c *UserService) ModelToService(m *models.User) *UserService {
 	c.Password = m.Password
 	c.RealName = m.RealName
 	c.Email = m.Email
+	c.ChatID = m.ChatID
 	c.IsLdap = m.IsLdap
 	c.LastLogin = m.LastLogin.Format(time.DateTime)
 	return c
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"github.com/sunkaimr/data-loom/pkg/utils"
+)
+
+// CheckWatermark 校验增量治理的水位列，仅删除和归档支持增量治理，水位列需存在于源端所有表中
+func (c *PolicyService) CheckWatermark(ctx *gin.Context) (common.ServiceCode, error) {
+	_, db := common.ExtractContext(ctx)
+
+	if c.WatermarkColumn == "" {
+		return common.CodeOK, nil
+	}
+	if c.Govern != common.GovernTypeDelete && c.Govern != common.GovernTypeArchive {
+		return common.CodePolicyWatermarkErr, fmt.Errorf("policy govern(%s) not support watermark", c.Govern)
+	}
+
+	src := &models.Source{}
+	err := db.Model(src).Where("id =?", c.SrcID).First(src).Error
+	if err != nil {
+		return common.CodeSourceNotExist, fmt.Errorf("query models.Source(%v) failed, %s", c.SrcID, err)
+	}
+
+	clusterSvc, err := GetClusterServiceByClusterID(ctx, src.ClusterID)
+	if err != nil {
+		return common.CodeServerErr, err
+	}
+
+	for _, table := range strings.Split(src.TablesName, ",") {
+		columns, code, err := ClusterTableColumns(ctx, clusterSvc, src.DatabaseName, table)
+		if err != nil {
+			return code, err
+		}
+		exist := false
+		for _, col := range columns {
+			if strings.EqualFold(col.Name, c.WatermarkColumn) {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			return common.CodePolicyWatermarkErr,
+				fmt.Errorf("watermark column(%s) not exist in table(%s.%s)", c.WatermarkColumn, src.DatabaseName, table)
+		}
+	}
+	return common.CodeOK, nil
+}
+
+var numericWatermarkRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// CompareWatermark 比较两个水位值，都是数字时按数值精确比较(bigint、雪花ID超过2^53也不会丢失精度)，
+// 否则按字符串比较(时间列格式一致可直接比较)
+func CompareWatermark(a, b string) int {
+	if numericWatermarkRegexp.MatchString(a) && numericWatermarkRegexp.MatchString(b) {
+		ra, _ := new(big.Rat).SetString(a)
+		rb, _ := new(big.Rat).SetString(b)
+		return ra.Cmp(rb)
+	}
+	return strings.Compare(a, b)
+}
+
+func quoteWatermark(v string) string {
+	if numericWatermarkRegexp.MatchString(v) {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
+
+// watermarkLowerCondition 在治理条件的基础上限定水位列大于下界
+func watermarkLowerCondition(condition, column, low string) string {
+	if low == "" {
+		return condition
+	}
+	bound := fmt.Sprintf("`%s` > %s", column, quoteWatermark(low))
+	if condition == "" {
+		return bound
+	}
+	return fmt.Sprintf("(%s) AND %s", condition, bound)
+}
+
+// WatermarkCondition 增量治理时在治理条件的基础上限定本次治理的水位范围(low, high]
+func WatermarkCondition(task *models.Task) string {
+	if task.WatermarkColumn == "" || task.WatermarkHigh == "" {
+		return task.Condition
+	}
+	condition := watermarkLowerCondition(task.Condition, task.WatermarkColumn, task.WatermarkLow)
+	bound := fmt.Sprintf("`%s` <= %s", task.WatermarkColumn, quoteWatermark(task.WatermarkHigh))
+	if condition == "" {
+		return bound
+	}
+	return fmt.Sprintf("%s AND %s", condition, bound)
+}
+
+// BoundTaskWatermark 以策略已处理到的水位为下界，查询满足治理条件的数据中水位列的最大值作为本次治理的上界。
+// 已计算过上界的任务(暂停后继续、分库继续执行)沿用原来的范围
+func BoundTaskWatermark(ctx *gin.Context, clusterSvc *ClusterService, task *models.Task) (common.ServiceCode, error) {
+	log, db := common.ExtractContext(ctx)
+	if task.WatermarkColumn == "" || task.WatermarkHigh != "" {
+		return common.CodeOK, nil
+	}
+
+	policy := &models.Policy{}
+	err := db.Model(policy).Select("id, watermark_column, watermark").First(policy, "id =?", task.PolicyID).Error
+	if err != nil {
+		return common.CodeServerErr, fmt.Errorf("query models.Policy(%v) failed, %s", task.PolicyID, err)
+	}
+	low := ""
+	if policy.WatermarkColumn == task.WatermarkColumn {
+		low = policy.Watermark
+	}
+
+	databases := []string{task.SrcDatabaseName}
+	if task.SrcDatabasesName != "" {
+		databases = strings.Split(task.SrcDatabasesName, ",")
+	}
+
+	condition := watermarkLowerCondition(task.Condition, task.WatermarkColumn, low)
+	high := ""
+	for _, database := range databases {
+		for _, table := range strings.Split(task.SrcTablesName, ",") {
+			v, _, err := NewClusterDriver(clusterSvc).GetColumnMax(ctx, database, table, task.WatermarkColumn, condition)
+			if err != nil {
+				return common.CodeTaskWatermarkErr, err
+			}
+			if v != "" && (high == "" || CompareWatermark(v, high) > 0) {
+				high = v
+			}
+		}
+	}
+
+	// 没有新数据时上界等于下界，本次治理不会处理任何数据
+	task.WatermarkLow = low
+	task.WatermarkHigh = utils.Ternary[string](high == "", low, high)
+	if task.WatermarkHigh != "" {
+		CreateTaskChangeLog(common.NewContext().WithDB(db).WithLog(log), task, common.SystemUserName, fmt.Sprintf(common.TaskChangeLogWatermarkBound,
+			task.WatermarkColumn, utils.Ternary[string](low == "", "-", low), task.WatermarkColumn, task.WatermarkHigh))
+	}
+	return common.CodeOK, nil
+}
+
+// AdvancePolicyWatermark 任务执行结束后推进策略的水位：执行成功时推进到本次治理的上界，
+// 执行失败时推进到工作流上报的已处理到的水位，下次从该水位继续
+func AdvancePolicyWatermark(ctx *common.Context, task *models.Task) error {
+	if task.WatermarkColumn == "" || task.WatermarkHigh == "" {
+		return nil
+	}
+
+	watermark := ""
+	switch task.TaskStatus {
+	case common.TaskStatusSuccess:
+		watermark = task.WatermarkHigh
+	case common.TaskStatusExecFailed, common.TaskStatusTimeout:
+		if task.Watermark == "" {
+			return nil
+		}
+		watermark = utils.Ternary[string](CompareWatermark(task.Watermark, task.WatermarkHigh) > 0, task.WatermarkHigh, task.Watermark)
+	default:
+		return nil
+	}
+	if task.WatermarkLow != "" && CompareWatermark(watermark, task.WatermarkLow) <= 0 {
+		return nil
+	}
+
+	err := ctx.DB.Model(&models.Policy{}).
+		Where("id =? AND watermark_column =?", task.PolicyID, task.WatermarkColumn).
+		Update("watermark", watermark).Error
+	if err != nil {
+		return fmt.Errorf("update models.Policy(%v) watermark(%s) failed, %s", task.PolicyID, watermark, err)
+	}
+	CreateTaskChangeLog(ctx, task, common.SystemUserName, fmt.Sprintf(common.TaskChangeLogWatermarkAdvance, watermark))
+	return nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/sunkaimr/data-loom/internal/models"
+)
+
+func TestCompareWatermark(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"int less", "9", "10", -1},
+		{"int equal", "100", "100", 0},
+		{"negative", "-5", "3", -1},
+		{"decimal", "1.50", "1.5", 0},
+		{"bigint above 2^53", "9007199254740993", "9007199254740992", 1},
+		{"snowflake", "1844674407370955161", "1844674407370955160", 1},
+		{"unsigned bigint max", "18446744073709551615", "18446744073709551614", 1},
+		{"datetime", "2024-01-02 00:00:00", "2024-01-01 23:59:59", 1},
+		{"datetime equal", "2024-01-01 00:00:00", "2024-01-01 00:00:00", 0},
+		{"not number", "NaN", "1", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CompareWatermark(test.a, test.b); got != test.want {
+				t.Fatalf("CompareWatermark(%s, %s) got %d, want %d", test.a, test.b, got, test.want)
+			}
+			if got := CompareWatermark(test.b, test.a); got != -test.want {
+				t.Fatalf("CompareWatermark(%s, %s) got %d, want %d", test.b, test.a, got, -test.want)
+			}
+		})
+	}
+}
+
+func TestWatermarkCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		task models.Task
+		want string
+	}{
+		{"no watermark", models.Task{Condition: "id < 100"}, "id < 100"},
+		{"first run", models.Task{Condition: "id < 100", WatermarkColumn: "id", WatermarkHigh: "90"}, "id < 100 AND `id` <= 90"},
+		{"incremental", models.Task{Condition: "id < 100", WatermarkColumn: "id", WatermarkLow: "50", WatermarkHigh: "90"}, "(id < 100) AND `id` > 50 AND `id` <= 90"},
+		{"datetime", models.Task{WatermarkColumn: "ts", WatermarkLow: "2024-01-01 00:00:00", WatermarkHigh: "2024-01-02 00:00:00"},
+			"`ts` > '2024-01-01 00:00:00' AND `ts` <= '2024-01-02 00:00:00'"},
+		{"quote", models.Task{WatermarkColumn: "name", WatermarkHigh: "o'neil"}, "`name` <= 'o''neil'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := WatermarkCondition(&test.task); got != test.want {
+				t.Fatalf("WatermarkCondition() got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -25,6 +25,9 @@ type Workflow interface {
 	// RebuildTables 重建表
 	RebuildTables(*common.Context, *types.RebuildParaStruct) (string, error)
 
+	// PartitionData 分区治理，删除或交换过期分区并预创建分区
+	PartitionData(*common.Context, *types.PartitionParaStruct) (string, error)
+
 	// WorkFlowStatus 查看工作流状态
 	WorkFlowStatus(*common.Context, string) (types.WorkFlowStatusType, error)
 
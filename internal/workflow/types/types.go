@@ -26,6 +26,7 @@ type TaskResultService struct {
 	TaskResultSize     int                   `json:"task_result_size"`     // 治理数据容量
 	TaskStartTime      string                `json:"task_start_time"`      // 开始执行时间 "2006-01-02 15:04:05"
 	TaskEndTime        string                `json:"task_end_time"`        // 执行结束时间 "2006-01-02 15:04:05"
+	Watermark          string                `json:"watermark"`            // 增量治理时已处理到的水位，部分失败时下次从该水位继续
 }
 
 type TruncateParaStruct struct {
@@ -41,36 +42,42 @@ type TruncateParaStruct struct {
 }
 
 type DeleteParaStruct struct {
-	TaskID         uint                     `json:"task_id"`
-	StartTime      string                   `json:"start_time"`
-	EndTime        string                   `json:"end_time"`
-	Host           string                   `json:"host"`
-	Port           string                   `json:"port"`
-	User           string                   `json:"user"`
-	Password       string                   `json:"password"`
-	Database       string                   `json:"database"`
-	Tables         string                   `json:"tables"`
-	Condition      string                   `json:"condition"`
-	FreeDisk       string                   `json:"free_disk"`
-	RebuildFlag    bool                     `json:"rebuild_flag"`
-	CleaningSpeed  common.CleaningSpeedType `json:"cleaning_speed"`
-	Callback       Callback                 `json:"callback"`
-	CallbackResult TaskResultService        `json:"callback_result"`
+	TaskID          uint                     `json:"task_id"`
+	StartTime       string                   `json:"start_time"`
+	EndTime         string                   `json:"end_time"`
+	Host            string                   `json:"host"`
+	Port            string                   `json:"port"`
+	User            string                   `json:"user"`
+	Password        string                   `json:"password"`
+	Database        string                   `json:"database"`
+	Tables          string                   `json:"tables"`
+	Condition       string                   `json:"condition"`
+	FreeDisk        string                   `json:"free_disk"`
+	RebuildFlag     bool                     `json:"rebuild_flag"`
+	CleaningSpeed   common.CleaningSpeedType `json:"cleaning_speed"`
+	Throttle        Throttle                 `json:"throttle"`
+	WatermarkColumn string                   `json:"watermark_column"`
+	WatermarkHigh   string                   `json:"watermark_high"`
+	Callback        Callback                 `json:"callback"`
+	CallbackResult  TaskResultService        `json:"callback_result"`
 }
 
 type ArchiveParaStruct struct {
-	TaskID         uint                     `json:"task_id"`
-	Host           string                   `json:"host"`
-	Port           string                   `json:"port"`
-	User           string                   `json:"user"`
-	Password       string                   `json:"password"`
-	Database       string                   `json:"database"`
-	Tables         string                   `json:"tables"`
-	Condition      string                   `json:"condition"`
-	CleaningSpeed  common.CleaningSpeedType `json:"cleaning_speed"`
-	FreeDisk       string                   `json:"free_disk"`
-	Callback       Callback                 `json:"callback"`
-	CallbackResult TaskResultService        `json:"callback_result"`
+	TaskID          uint                     `json:"task_id"`
+	Host            string                   `json:"host"`
+	Port            string                   `json:"port"`
+	User            string                   `json:"user"`
+	Password        string                   `json:"password"`
+	Database        string                   `json:"database"`
+	Tables          string                   `json:"tables"`
+	Condition       string                   `json:"condition"`
+	CleaningSpeed   common.CleaningSpeedType `json:"cleaning_speed"`
+	Throttle        Throttle                 `json:"throttle"`
+	FreeDisk        string                   `json:"free_disk"`
+	WatermarkColumn string                   `json:"watermark_column"`
+	WatermarkHigh   string                   `json:"watermark_high"`
+	Callback        Callback                 `json:"callback"`
+	CallbackResult  TaskResultService        `json:"callback_result"`
 }
 
 type RebuildParaStruct struct {
@@ -86,9 +93,32 @@ type RebuildParaStruct struct {
 	CallbackResult TaskResultService `json:"callback_result"`
 }
 
+type PartitionParaStruct struct {
+	TaskID         uint              `json:"task_id"`
+	Host           string            `json:"host"`
+	Port           string            `json:"port"`
+	User           string            `json:"user"`
+	Password       string            `json:"password"`
+	Database       string            `json:"database"`
+	Tables         string            `json:"tables"`
+	Statements     string            `json:"statements"`
+	Callback       Callback          `json:"callback"`
+	CallbackResult TaskResultService `json:"callback_result"`
+}
+
 type Callback struct {
-	URL   string `json:"url"`
-	Token string `json:"token"`
+	URL         string `json:"url"`
+	Token       string `json:"token"`
+	ThrottleURL string `json:"throttle_url"` // 执行过程中定时查询最新的限流参数，管理员可以随时调整执行中任务的限流配置
+}
+
+// Throttle 删除和归档时的限流参数
+type Throttle struct {
+	Name             string `json:"name"`                // 限流配置名称
+	ChunkSize        int    `json:"chunk_size"`          // 每批处理的行数
+	SleepMs          int    `json:"sleep_ms"`            // 批次之间休眠的毫秒数，pt-archiver只支持整秒休眠，执行时四舍五入到秒
+	MaxRowsPerSecond int    `json:"max_rows_per_second"` // 每秒最多处理的行数，0表示不限制
+	MaxTxnBytes      int64  `json:"max_txn_bytes"`       // 单个事务最多处理的字节数，0表示不限制
 }
 
 type Extra struct {
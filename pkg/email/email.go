@@ -3,8 +3,6 @@ package email
 import (
 	"bytes"
 	"crypto/tls"
-	"fmt"
-	"github.com/sunkaimr/data-loom/configs"
 	. "github.com/sunkaimr/data-loom/internal/notice/message"
 	"github.com/sunkaimr/data-loom/internal/pkg/common"
 	"gopkg.in/gomail.v2"
@@ -48,25 +46,7 @@ func (c *Email) Test(_ *common.Context, testUser string) error {
 		return err
 	}
 
-	msg := Message{
-		TaskID:             123,
-		TaskName:           "这是一个任务通知示例",
-		SrcClusterName:     "cluster-name",
-		SrcDatabaseName:    "database",
-		SrcTablesName:      "table",
-		Govern:             "删除数据",
-		Condition:          "id < 10000",
-		TaskStartTime:      "2024-01-01 00:00:00",
-		TaskEndTime:        "2024-01-01 01:00:00",
-		TaskDuration:       "01:00:00",
-		TaskStatus:         "执行成功",
-		TaskResultQuantity: 123,
-		TaskResultSize:     456,
-		TaskReason:         "",
-
-		HomeURL: configs.C.Server.ExternalAddr,
-		TaskURL: fmt.Sprintf("%s/#/task/detail?task_id=", configs.C.Server.ExternalAddr),
-	}
+	msg := Example(testUser)
 
 	b := bytes.Buffer{}
 	err = tmpl.Execute(&b, msg)
@@ -131,6 +111,10 @@ const emailTemplate = `
 							<td style="text-align: right; padding-right: 20px; font-weight: bolder;">任务结束时间</td>
 							<td style="text-align: left; padding-left: 20px;">{{ .TaskEndTime }}</td>
 						</tr>
+						<tr>
+							<td style="text-align: right; padding-right: 20px; font-weight: bolder;">时区</td>
+							<td style="text-align: left; padding-left: 20px;">{{ .Timezone }}</td>
+						</tr>
 						<tr>
 							<td style="text-align: right; padding-right: 20px; font-weight: bolder;">任务执行时长</td>
 							<td style="text-align: left; padding-left: 20px;">{{ .TaskDuration }}</td>
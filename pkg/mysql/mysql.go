@@ -7,6 +7,8 @@ import (
 	"github.com/pingcap/tidb/pkg/parser"
 	"github.com/pingcap/tidb/pkg/parser/ast"
 	_ "github.com/pingcap/tidb/pkg/parser/test_driver"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -61,34 +63,34 @@ type QueryResult struct {
 
 // ExplainInfo 用于存放Explain信息
 type ExplainInfo struct {
-	SQL         string
-	ExplainRows []ExplainRow
-	Warnings    []ExplainWarning
+	SQL         string           `json:"sql"`
+	ExplainRows []ExplainRow     `json:"explain_rows"`
+	Warnings    []ExplainWarning `json:"warnings"`
 	//QueryCost     float64
 }
 
 // ExplainRow 单行Explain
 type ExplainRow struct {
-	ID           int
-	SelectType   string
-	TableName    string
-	Partitions   string // explain partitions
-	AccessType   string
-	PossibleKeys []string
-	Key          string
-	KeyLen       string // 索引长度，如果发生了index_merge， KeyLen 格式为 N,N，所以不能定义为整型
-	Ref          []string
-	Rows         int64
-	Filtered     float64 // 5.6 JSON, 5.7+, 5.5 EXTENDED
-	Scalability  string  // O(1), O(n), O(log n), O(log n)+
-	Extra        string
+	ID           int      `json:"id"`
+	SelectType   string   `json:"select_type"`
+	TableName    string   `json:"table"`
+	Partitions   string   `json:"partitions"` // explain partitions
+	AccessType   string   `json:"type"`
+	PossibleKeys []string `json:"possible_keys"`
+	Key          string   `json:"key"`
+	KeyLen       string   `json:"key_len"` // 索引长度，如果发生了index_merge， KeyLen 格式为 N,N，所以不能定义为整型
+	Ref          []string `json:"ref"`
+	Rows         int64    `json:"rows"`
+	Filtered     float64  `json:"filtered"`    // 5.6 JSON, 5.7+, 5.5 EXTENDED
+	Scalability  string   `json:"scalability"` // O(1), O(n), O(log n), O(log n)+
+	Extra        string   `json:"extra"`
 }
 
 // ExplainWarning explain extended 后 SHOW WARNINGS 输出的结果
 type ExplainWarning struct {
-	Level   string
-	Code    int
-	Message string
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
 }
 
 // Query 执行SQL
@@ -324,6 +326,481 @@ func (db *Connect) TableSize(database, table string) (int, error) {
 	return size, err
 }
 
+// GlobalStatus 查询全局状态变量的值，如：Threads_running、Questions
+func (db *Connect) GlobalStatus(name string) (int64, error) {
+	res, err := db.Query(fmt.Sprintf("SHOW GLOBAL STATUS LIKE '%s'", name))
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	varName, value := "", int64(0)
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&varName, &value)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	if varName == "" {
+		return 0, fmt.Errorf("global status(%s) not found", name)
+	}
+	return value, nil
+}
+
+// ActiveTrxNum 查询正在运行的事务数
+func (db *Connect) ActiveTrxNum() (int, error) {
+	res, err := db.Query("SELECT COUNT(*) FROM information_schema.INNODB_TRX WHERE trx_state = 'RUNNING'")
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	num := 0
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&num)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return num, nil
+}
+
+// QueryMaps 执行SQL并将每行结果转换为 列名->值 的形式
+func (db *Connect) QueryMaps(sql string) ([]map[string]string, error) {
+	res, err := db.Query(sql)
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	columns, err := res.Rows.Columns()
+	if err != nil {
+		_ = res.Rows.Close()
+		return nil, fmt.Errorf("get columns failed, %s", err)
+	}
+
+	var rows []map[string]string
+	for res.Rows.Next() {
+		values := make([][]byte, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = res.Rows.Scan(dest...)
+		if err != nil {
+			_ = res.Rows.Close()
+			return nil, fmt.Errorf("scan rows failed, %s", err)
+		}
+
+		row := make(map[string]string, len(columns))
+		for i, col := range columns {
+			row[col] = string(values[i])
+		}
+		rows = append(rows, row)
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return rows, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return rows, nil
+}
+
+// ReadOnly 查询实例是否只读
+func (db *Connect) ReadOnly() (bool, error) {
+	rows, err := db.QueryMaps("SELECT @@global.read_only AS read_only")
+	if err != nil {
+		return false, err
+	}
+	if len(rows) == 0 {
+		return false, fmt.Errorf("query read_only got empty result")
+	}
+	return rows[0]["read_only"] == "1", nil
+}
+
+// InstanceAddr 查询实例自身的地址，优先使用report_host
+func (db *Connect) InstanceAddr() (string, error) {
+	rows, err := db.QueryMaps("SELECT @@report_host AS report_host, @@hostname AS hostname, @@port AS port")
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("query instance addr got empty result")
+	}
+
+	host := rows[0]["report_host"]
+	if host == "" {
+		host = rows[0]["hostname"]
+	}
+	return fmt.Sprintf("%s:%s", host, rows[0]["port"]), nil
+}
+
+// ServerUUID 查询实例的server_uuid，主从切换后主库的server_uuid一定会变化
+func (db *Connect) ServerUUID() (string, error) {
+	rows, err := db.QueryMaps("SELECT @@server_uuid AS server_uuid")
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("query server_uuid got empty result")
+	}
+	return rows[0]["server_uuid"], nil
+}
+
+// ReplicaSource 查询从库对应的主库地址，非从库时返回空
+func (db *Connect) ReplicaSource() (string, error) {
+	hostKey, portKey := "Source_Host", "Source_Port"
+	rows, err := db.QueryMaps("SHOW REPLICA STATUS")
+	if err != nil {
+		// 兼容8.0.22之前的版本
+		hostKey, portKey = "Master_Host", "Master_Port"
+		rows, err = db.QueryMaps("SHOW SLAVE STATUS")
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%s:%s", rows[0][hostKey], rows[0][portKey]), nil
+}
+
+// ShowReplicas 查询主库下注册的从库地址
+func (db *Connect) ShowReplicas() ([]string, error) {
+	rows, err := db.QueryMaps("SHOW REPLICAS")
+	if err != nil {
+		// 兼容8.0.22之前的版本
+		rows, err = db.QueryMaps("SHOW SLAVE HOSTS")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	replicas := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row["Host"] == "" {
+			continue
+		}
+		replicas = append(replicas, fmt.Sprintf("%s:%s", row["Host"], row["Port"]))
+	}
+	sort.Strings(replicas)
+	return replicas, nil
+}
+
+// TableSchema 表结构元数据
+type TableSchema struct {
+	Database    string
+	Table       string
+	Engine      string
+	TableRows   int64 // 估算行数
+	DataLength  int64
+	IndexLength int64
+	DataFree    int64
+	Comment     string
+	Columns     []ColumnSchema
+	Indexes     []IndexSchema
+}
+
+// ColumnSchema 列元数据
+type ColumnSchema struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default"`
+	Extra    string `json:"extra"`
+	Comment  string `json:"comment"`
+}
+
+// IndexSchema 索引元数据
+type IndexSchema struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+}
+
+// TablesSchema 查询实例上所有表的结构元数据，excludeDatabases中的库不查询
+func (db *Connect) TablesSchema(excludeDatabases []string) ([]TableSchema, error) {
+	exclude := "''"
+	if len(excludeDatabases) != 0 {
+		exclude = "'" + strings.Join(excludeDatabases, "','") + "'"
+	}
+
+	tables, err := db.QueryMaps(fmt.Sprintf("SELECT "+
+		"TABLE_SCHEMA, TABLE_NAME, ENGINE, TABLE_ROWS, DATA_LENGTH, INDEX_LENGTH, DATA_FREE, TABLE_COMMENT "+
+		"FROM information_schema.TABLES "+
+		"WHERE TABLE_TYPE = 'BASE TABLE' AND TABLE_SCHEMA NOT IN (%s) "+
+		"ORDER BY TABLE_SCHEMA, TABLE_NAME", exclude))
+	if err != nil {
+		return nil, fmt.Errorf("query tables failed, %s", err)
+	}
+
+	schemas := make([]TableSchema, 0, len(tables))
+	index := make(map[string]int, len(tables))
+	for _, t := range tables {
+		rows, _ := strconv.ParseInt(t["TABLE_ROWS"], 10, 64)
+		dataLength, _ := strconv.ParseInt(t["DATA_LENGTH"], 10, 64)
+		indexLength, _ := strconv.ParseInt(t["INDEX_LENGTH"], 10, 64)
+		dataFree, _ := strconv.ParseInt(t["DATA_FREE"], 10, 64)
+		index[t["TABLE_SCHEMA"]+"."+t["TABLE_NAME"]] = len(schemas)
+		schemas = append(schemas, TableSchema{
+			Database:    t["TABLE_SCHEMA"],
+			Table:       t["TABLE_NAME"],
+			Engine:      t["ENGINE"],
+			TableRows:   rows,
+			DataLength:  dataLength,
+			IndexLength: indexLength,
+			DataFree:    dataFree,
+			Comment:     t["TABLE_COMMENT"],
+		})
+	}
+
+	columns, err := db.QueryMaps(fmt.Sprintf("SELECT "+
+		"TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT "+
+		"FROM information_schema.COLUMNS "+
+		"WHERE TABLE_SCHEMA NOT IN (%s) "+
+		"ORDER BY TABLE_SCHEMA, TABLE_NAME, ORDINAL_POSITION", exclude))
+	if err != nil {
+		return nil, fmt.Errorf("query columns failed, %s", err)
+	}
+	for _, c := range columns {
+		i, ok := index[c["TABLE_SCHEMA"]+"."+c["TABLE_NAME"]]
+		if !ok {
+			continue
+		}
+		schemas[i].Columns = append(schemas[i].Columns, ColumnSchema{
+			Name:     c["COLUMN_NAME"],
+			Type:     c["COLUMN_TYPE"],
+			Nullable: c["IS_NULLABLE"] == "YES",
+			Default:  c["COLUMN_DEFAULT"],
+			Extra:    c["EXTRA"],
+			Comment:  c["COLUMN_COMMENT"],
+		})
+	}
+
+	indexes, err := db.QueryMaps(fmt.Sprintf("SELECT "+
+		"TABLE_SCHEMA, TABLE_NAME, INDEX_NAME, NON_UNIQUE, COLUMN_NAME "+
+		"FROM information_schema.STATISTICS "+
+		"WHERE TABLE_SCHEMA NOT IN (%s) "+
+		"ORDER BY TABLE_SCHEMA, TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX", exclude))
+	if err != nil {
+		return nil, fmt.Errorf("query indexes failed, %s", err)
+	}
+	for _, idx := range indexes {
+		i, ok := index[idx["TABLE_SCHEMA"]+"."+idx["TABLE_NAME"]]
+		if !ok {
+			continue
+		}
+		n := len(schemas[i].Indexes)
+		if n == 0 || schemas[i].Indexes[n-1].Name != idx["INDEX_NAME"] {
+			schemas[i].Indexes = append(schemas[i].Indexes, IndexSchema{
+				Name:   idx["INDEX_NAME"],
+				Unique: idx["NON_UNIQUE"] == "0",
+			})
+			n++
+		}
+		schemas[i].Indexes[n-1].Columns = append(schemas[i].Indexes[n-1].Columns, idx["COLUMN_NAME"])
+	}
+
+	return schemas, nil
+}
+
+// TableIndexes 查询表的索引
+func (db *Connect) TableIndexes(database, table string) ([]IndexSchema, error) {
+	rows, err := db.QueryMaps(fmt.Sprintf("SELECT "+
+		"INDEX_NAME, NON_UNIQUE, COLUMN_NAME "+
+		"FROM information_schema.STATISTICS "+
+		"WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s' "+
+		"ORDER BY INDEX_NAME, SEQ_IN_INDEX", database, table))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []IndexSchema
+	for _, row := range rows {
+		n := len(indexes)
+		if n == 0 || indexes[n-1].Name != row["INDEX_NAME"] {
+			indexes = append(indexes, IndexSchema{Name: row["INDEX_NAME"], Unique: row["NON_UNIQUE"] == "0"})
+			n++
+		}
+		indexes[n-1].Columns = append(indexes[n-1].Columns, row["COLUMN_NAME"])
+	}
+	return indexes, nil
+}
+
+// TableColumns 查询表的列
+func (db *Connect) TableColumns(database, table string) ([]ColumnSchema, error) {
+	rows, err := db.QueryMaps(fmt.Sprintf("SELECT "+
+		"COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT "+
+		"FROM information_schema.COLUMNS "+
+		"WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s' "+
+		"ORDER BY ORDINAL_POSITION", database, table))
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]ColumnSchema, 0, len(rows))
+	for _, row := range rows {
+		columns = append(columns, ColumnSchema{
+			Name:     row["COLUMN_NAME"],
+			Type:     row["COLUMN_TYPE"],
+			Nullable: row["IS_NULLABLE"] == "YES",
+			Default:  row["COLUMN_DEFAULT"],
+			Extra:    row["EXTRA"],
+			Comment:  row["COLUMN_COMMENT"],
+		})
+	}
+	return columns, nil
+}
+
+// PartitionSchema 分区元数据
+type PartitionSchema struct {
+	Name        string `json:"name"`        // 分区名
+	Method      string `json:"method"`      // 分区方式 RANGE, RANGE COLUMNS, LIST...
+	Expression  string `json:"expression"`  // 分区表达式
+	Description string `json:"description"` // 分区上界 VALUES LESS THAN
+	Ordinal     int    `json:"ordinal"`     // 分区序号
+	TableRows   int64  `json:"table_rows"`  // 估算行数
+	DataLength  int64  `json:"data_length"` // 数据大小(字节)
+}
+
+// TablePartitions 查询表的分区，非分区表返回空
+func (db *Connect) TablePartitions(database, table string) ([]PartitionSchema, error) {
+	rows, err := db.QueryMaps(fmt.Sprintf("SELECT "+
+		"PARTITION_NAME, PARTITION_METHOD, PARTITION_EXPRESSION, PARTITION_DESCRIPTION, "+
+		"PARTITION_ORDINAL_POSITION, TABLE_ROWS, DATA_LENGTH "+
+		"FROM information_schema.PARTITIONS "+
+		"WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s' AND PARTITION_NAME IS NOT NULL "+
+		"ORDER BY PARTITION_ORDINAL_POSITION", database, table))
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]PartitionSchema, 0, len(rows))
+	for _, row := range rows {
+		ordinal, _ := strconv.Atoi(row["PARTITION_ORDINAL_POSITION"])
+		tableRows, _ := strconv.ParseInt(row["TABLE_ROWS"], 10, 64)
+		dataLength, _ := strconv.ParseInt(row["DATA_LENGTH"], 10, 64)
+		partitions = append(partitions, PartitionSchema{
+			Name:        row["PARTITION_NAME"],
+			Method:      row["PARTITION_METHOD"],
+			Expression:  row["PARTITION_EXPRESSION"],
+			Description: row["PARTITION_DESCRIPTION"],
+			Ordinal:     ordinal,
+			TableRows:   tableRows,
+			DataLength:  dataLength,
+		})
+	}
+	return partitions, nil
+}
+
+// ExchangeState 分区交换到归档表前双方的当前状态，用于重试时跳过已经完成的步骤
+type ExchangeState struct {
+	ArchiveExists      bool `json:"archive_exists"`      // 归档表已存在
+	ArchivePartitioned bool `json:"archive_partitioned"` // 归档表仍是分区表
+	ArchiveHasRows     bool `json:"archive_has_rows"`    // 归档表有数据
+	PartitionHasRows   bool `json:"partition_has_rows"`  // 待交换的分区有数据
+}
+
+// ExchangeState 查询分区和归档表的当前状态，是否有数据按实际数据判断而不是统计信息
+func (db *Connect) ExchangeState(database, table, partition, archive string) (*ExchangeState, error) {
+	state := &ExchangeState{}
+
+	rows, err := db.QueryMaps(fmt.Sprintf("SELECT CREATE_OPTIONS FROM information_schema.TABLES "+
+		"WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'", database, archive))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 0 {
+		state.ArchiveExists = true
+		state.ArchivePartitioned = strings.Contains(strings.ToLower(rows[0]["CREATE_OPTIONS"]), "partitioned")
+		rows, err = db.QueryMaps(fmt.Sprintf("SELECT 1 AS R FROM `%s`.`%s` LIMIT 1", database, archive))
+		if err != nil {
+			return nil, err
+		}
+		state.ArchiveHasRows = len(rows) != 0
+	}
+
+	rows, err = db.QueryMaps(fmt.Sprintf("SELECT 1 AS R FROM `%s`.`%s` PARTITION (`%s`) LIMIT 1", database, table, partition))
+	if err != nil {
+		return nil, err
+	}
+	state.PartitionHasRows = len(rows) != 0
+	return state, nil
+}
+
+// ColumnDataAge 按时间列统计的数据年龄分布
+type ColumnDataAge struct {
+	Column    string          `json:"column"`     // 时间列
+	Min       string          `json:"min"`        // 最早的数据
+	Max       string          `json:"max"`        // 最新的数据
+	TableRows int64           `json:"table_rows"` // 表的估算行数
+	Buckets   []DataAgeBucket `json:"buckets"`    // 早于N天的数据行数
+}
+
+// DataAgeBucket 早于N天的估算行数
+type DataAgeBucket struct {
+	Days int   `json:"days"`
+	Rows int64 `json:"rows"`
+}
+
+// ColumnDataAge 统计时间列的数据年龄分布，行数来自执行计划的估算值，避免扫描大表
+func (db *Connect) ColumnDataAge(database, table, column string, days []int) (*ColumnDataAge, error) {
+	age := &ColumnDataAge{Column: column}
+
+	rows, err := db.QueryMaps(fmt.Sprintf("SELECT MIN(`%s`) AS MIN_VALUE, MAX(`%s`) AS MAX_VALUE FROM `%s`.`%s`",
+		column, column, database, table))
+	if err != nil {
+		return nil, fmt.Errorf("query column(%s) range failed, %s", column, err)
+	}
+	if len(rows) != 0 {
+		age.Min, age.Max = rows[0]["MIN_VALUE"], rows[0]["MAX_VALUE"]
+	}
+
+	rows, err = db.QueryMaps(fmt.Sprintf("SELECT TABLE_ROWS FROM information_schema.TABLES "+
+		"WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'", database, table))
+	if err != nil {
+		return nil, fmt.Errorf("query table rows failed, %s", err)
+	}
+	if len(rows) != 0 {
+		age.TableRows, _ = strconv.ParseInt(rows[0]["TABLE_ROWS"], 10, 64)
+	}
+
+	for _, d := range days {
+		exp, err := db.Explain(fmt.Sprintf("SELECT * FROM `%s`.`%s` WHERE `%s` < DATE_SUB(NOW(), INTERVAL %d DAY)",
+			database, table, column, d))
+		if err != nil {
+			return nil, fmt.Errorf("explain column(%s) data age failed, %s", column, err)
+		}
+
+		bucket := DataAgeBucket{Days: d}
+		for _, row := range exp.ExplainRows {
+			bucket.Rows = max(bucket.Rows, row.Rows)
+		}
+		age.Buckets = append(age.Buckets, bucket)
+	}
+	return age, nil
+}
+
+// ColumnMax 查询满足条件的数据中列的最大值，没有数据时返回空
+func (db *Connect) ColumnMax(database, table, column, conditions string) (string, error) {
+	sqlText := fmt.Sprintf("SELECT MAX(`%s`) AS MAX_VALUE FROM `%s`.`%s`", column, database, table)
+	if conditions = strings.TrimSpace(conditions); conditions != "" {
+		sqlText += fmt.Sprintf(" WHERE %s", conditions)
+	}
+	rows, err := db.QueryMaps(sqlText)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return rows[0]["MAX_VALUE"], nil
+}
+
 // Explain 获取 SQL 的 explain 信息
 func (db *Connect) Explain(sql string) (exp *ExplainInfo, err error) {
 	res, err := db.Query(fmt.Sprintf("explain %s", sql))
@@ -333,7 +810,7 @@ func (db *Connect) Explain(sql string) (exp *ExplainInfo, err error) {
 
 	// 解析mysql结果，输出ExplainInfo
 	exp, err = parseExplainResult(res)
-	if err != nil {
+	if err == nil {
 		exp.SQL = sql
 	}
 	return exp, err
@@ -352,7 +829,7 @@ func parseExplainResult(res QueryResult) (exp *ExplainInfo, err error) {
 		"select_type":   &selectType,
 		"table":         &table,
 		"partitions":    &partitions,
-		"types":         &accessType,
+		"type":          &accessType,
 		"possible_keys": &possibleKeys,
 		"key":           &key,
 		"key_len":       &keyLen,
@@ -500,3 +977,72 @@ func BuildSelectSQL(table string, columns []string, conditions string) (string,
 	}
 	return "", nil
 }
+
+// ConditionColumns 解析where条件中用到的列，返回所有列以及被函数或表达式包裹的列(无法使用索引)
+func ConditionColumns(conditions string) ([]string, []string, error) {
+	conditions = strings.TrimSpace(conditions)
+	if conditions == "" {
+		return nil, nil, nil
+	}
+
+	stmt, err := parser.New().ParseOneStmt(fmt.Sprintf("SELECT * FROM t WHERE %s", conditions), "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok || sel.Where == nil {
+		return nil, nil, nil
+	}
+
+	v := &conditionVisitor{}
+	sel.Where.Accept(v)
+	return v.columns, v.wrapped, nil
+}
+
+type conditionVisitor struct {
+	depth   int // 处于函数调用或运算表达式中的层数
+	columns []string
+	wrapped []string
+}
+
+func (v *conditionVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.FuncCallExpr:
+		v.depth++
+	case *ast.BinaryOperationExpr:
+		// 算术运算中的列无法使用索引，比较和逻辑运算不影响
+		if !isCompareOrLogicOp(node.Op.String()) {
+			v.depth++
+		}
+	case *ast.ColumnNameExpr:
+		name := node.Name.Name.O
+		if !slices.Contains(v.columns, name) {
+			v.columns = append(v.columns, name)
+		}
+		if v.depth > 0 && !slices.Contains(v.wrapped, name) {
+			v.wrapped = append(v.wrapped, name)
+		}
+	}
+	return n, false
+}
+
+func (v *conditionVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.FuncCallExpr:
+		v.depth--
+	case *ast.BinaryOperationExpr:
+		if !isCompareOrLogicOp(node.Op.String()) {
+			v.depth--
+		}
+	}
+	return n, true
+}
+
+func isCompareOrLogicOp(op string) bool {
+	switch strings.ToLower(op) {
+	case "eq", "ne", "lt", "le", "gt", "ge", "nulleq", "and", "or", "xor":
+		return true
+	}
+	return false
+}
@@ -24,3 +24,26 @@ func TestBuildSelectSQL(t *testing.T) {
 		})
 	}
 }
+
+func TestConditionColumns(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		columns    []string
+		wrapped    []string
+	}{
+		{"test001", "id > 100", []string{"id"}, nil},
+		{"test002", "DATE(created_at) < '2024-01-01' or id+1 > 5", []string{"created_at", "id"}, []string{"created_at", "id"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			columns, wrapped, err := ConditionColumns(test.conditions)
+			if err != nil {
+				t.Fatalf("ConditionColumns failed, got error: %s", err)
+			}
+			if fmt.Sprint(columns) != fmt.Sprint(test.columns) || fmt.Sprint(wrapped) != fmt.Sprint(test.wrapped) {
+				t.Fatalf("ConditionColumns(%s) got %v %v, want %v %v", test.conditions, columns, wrapped, test.columns, test.wrapped)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package robot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	. "github.com/sunkaimr/data-loom/internal/notice/message"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DingTalk 钉钉群机器人，配置了加签密钥时在地址上附加timestamp和sign
+type DingTalk struct {
+	Webhook string
+	Secret  string
+}
+
+func (c *DingTalk) Send(_ *common.Context, msg *Message) error {
+	return c.send(msg)
+}
+
+func (c *DingTalk) Test(_ *common.Context, testUser string) error {
+	return c.send(Example(testUser))
+}
+
+// DingTalkSign 钉钉加签：Base64(HmacSHA256(secret, timestamp+"\n"+secret))
+func DingTalkSign(secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *DingTalk) send(msg *Message) error {
+	if c.Webhook == "" {
+		return fmt.Errorf("dingtalk webhook is empty")
+	}
+
+	text, err := renderMarkdown(msg, func(id string) string { return "@" + id })
+	if err != nil {
+		return err
+	}
+	// 钉钉的markdown需要在行尾加两个空格才会换行
+	text = "### " + title + "\n\n" + strings.ReplaceAll(text, "\n", "  \n")
+
+	addr := c.Webhook
+	if c.Secret != "" {
+		ts := time.Now().UnixMilli()
+		sep := "?"
+		if strings.Contains(addr, "?") {
+			sep = "&"
+		}
+		addr += sep + "timestamp=" + strconv.FormatInt(ts, 10) + "&sign=" + url.QueryEscape(DingTalkSign(c.Secret, ts))
+	}
+
+	body := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]any{
+			"title": title,
+			"text":  text,
+		},
+		"at": map[string]any{
+			"atUserIds": msg.Mentions,
+			"isAtAll":   false,
+		},
+	}
+	respBody, err := post(addr, body)
+	if err != nil {
+		return err
+	}
+
+	resp := struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}{}
+	if err = json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("unmarshal dingtalk response(%s) failed, %s", string(respBody), err)
+	}
+	if resp.ErrCode != 0 {
+		return fmt.Errorf("send dingtalk message failed, errcode: %d, errmsg: %s", resp.ErrCode, resp.ErrMsg)
+	}
+	return nil
+}
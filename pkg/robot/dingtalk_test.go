@@ -0,0 +1,23 @@
+package robot
+
+import "testing"
+
+func TestDingTalkSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		want      string
+	}{
+		{"sign", "SEC123", 1700000000000, "lkcPI1uoxBY1gUnCnnPH1Kkru0Hqjo7rFpA3haIVhEQ="},
+		{"timestamp changed", "SEC123", 1700000000001, "4n/tO3+YGYUoZJiPWGS0p8ad4S5Hqf6XK8x1jgF00YI="},
+		{"empty secret", "", 1700000000000, "wQEehkxzIwBWYeCJy+ICeNfgZqNPmpFiMFDT6Qxm81Y="},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := DingTalkSign(test.secret, test.timestamp); got != test.want {
+				t.Fatalf("DingTalkSign(%s, %d) got %s, want %s", test.secret, test.timestamp, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package robot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	. "github.com/sunkaimr/data-loom/internal/notice/message"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"strconv"
+	"time"
+)
+
+// Feishu 飞书(Lark)群机器人，以消息卡片的形式发送，配置了签名校验时在请求体中附加timestamp和sign
+type Feishu struct {
+	Webhook string
+	Secret  string
+}
+
+func (c *Feishu) Send(_ *common.Context, msg *Message) error {
+	return c.send(msg)
+}
+
+func (c *Feishu) Test(_ *common.Context, testUser string) error {
+	return c.send(Example(testUser))
+}
+
+// FeishuSign 飞书签名：以timestamp+"\n"+secret为密钥对空字符串做HmacSHA256后Base64
+func FeishuSign(secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Feishu) send(msg *Message) error {
+	if c.Webhook == "" {
+		return fmt.Errorf("feishu webhook is empty")
+	}
+
+	content, err := renderMarkdown(msg, func(id string) string { return fmt.Sprintf("<at id=%s></at>", id) })
+	if err != nil {
+		return err
+	}
+
+	headerColor := "green"
+	if failed(msg) {
+		headerColor = "red"
+	}
+	body := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title":    map[string]any{"tag": "plain_text", "content": title},
+				"template": headerColor,
+			},
+			"elements": []any{
+				map[string]any{
+					"tag":  "div",
+					"text": map[string]any{"tag": "lark_md", "content": content},
+				},
+				map[string]any{
+					"tag": "action",
+					"actions": []any{
+						map[string]any{
+							"tag":  "button",
+							"text": map[string]any{"tag": "plain_text", "content": "查看任务"},
+							"url":  msg.TaskURL,
+							"type": "primary",
+						},
+					},
+				},
+			},
+		},
+	}
+	if c.Secret != "" {
+		ts := time.Now().Unix()
+		body["timestamp"] = strconv.FormatInt(ts, 10)
+		body["sign"] = FeishuSign(c.Secret, ts)
+	}
+
+	respBody, err := post(c.Webhook, body)
+	if err != nil {
+		return err
+	}
+
+	resp := struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}{}
+	if err = json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("unmarshal feishu response(%s) failed, %s", string(respBody), err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("send feishu message failed, code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
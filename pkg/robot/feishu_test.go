@@ -0,0 +1,23 @@
+package robot
+
+import "testing"
+
+func TestFeishuSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		want      string
+	}{
+		{"sign", "secret", 1700000000, "fiWS2+gh28DOydAv7hzONH/mDn9+b1Y4Y5ivXWXy8vA="},
+		{"timestamp changed", "secret", 1700000001, "L7WFVJhjlaJ0axV69YT6Sj7JAVUXF++HCm8h2ACxEcg="},
+		{"empty secret", "", 1700000000, "DaBQacIHB6FCKocfPme7o5BIrwne87ibBLj7EYGUds0="},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FeishuSign(test.secret, test.timestamp); got != test.want {
+				t.Fatalf("FeishuSign(%s, %d) got %s, want %s", test.secret, test.timestamp, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package robot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	. "github.com/sunkaimr/data-loom/internal/notice/message"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	title          = "MySQL数据治理平台 - 任务通知"
+	requestTimeout = 10 * time.Second
+)
+
+// markdownTemplate 群机器人的markdown消息内容，{{ .Mention }}替换为各平台@提醒的写法
+const markdownTemplate = `**任务ID**：[{{ .TaskID }}]({{ .TaskURL }})
+**任务名称**：{{ .TaskName }}
+**集群名称**：{{ .SrcClusterName }}
+**库名**：{{ .SrcDatabaseName }}
+**表名**：{{ .SrcTablesName }}
+**治理方式**：{{ .Govern }}
+**治理条件**：{{ .Condition }}
+**任务开始时间**：{{ .TaskStartTime }}
+**任务结束时间**：{{ .TaskEndTime }}
+**时区**：{{ .Timezone }}
+**任务执行时长**：{{ .TaskDuration }}
+**任务状态**：{{ .TaskStatus }}
+**治理数据行数**：{{ .TaskResultQuantity }}
+**治理数据大小(MB)**：{{ .TaskResultSize }}
+{{- if .TaskReason }}
+**原因**：{{ .TaskReason }}
+{{- end }}
+{{- if .Mention }}
+
+{{ .Mention }}
+{{- end }}`
+
+// renderMarkdown 将通知渲染为markdown，mention为各平台@提醒用户的写法
+func renderMarkdown(msg *Message, mention func(string) string) (string, error) {
+	tmpl, err := template.New("robot").Parse(markdownTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var mentions []string
+	for _, id := range msg.Mentions {
+		mentions = append(mentions, mention(id))
+	}
+
+	b := bytes.Buffer{}
+	err = tmpl.Execute(&b, struct {
+		*Message
+		Mention string
+	}{Message: msg, Mention: strings.Join(mentions, " ")})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// failed 通知的任务是否为失败状态
+func failed(msg *Message) bool {
+	return msg.TaskStatusColor != ""
+}
+
+// post 发送群机器人消息，各平台都以HTTP 200返回，需按返回体中的错误码判断是否成功
+func post(url string, body any) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := (&http.Client{Timeout: requestTimeout}).Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("post robot message failed, %s", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("post robot message got status %d, %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,290 @@
+package robot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/sunkaimr/data-loom/internal/notice/message"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+// request 群机器人服务端收到的请求
+type request struct {
+	query url.Values
+	body  map[string]any
+}
+
+// newServer 模拟群机器人服务端，记录收到的请求并返回resp
+func newServer(t *testing.T, resp string) (*httptest.Server, *request) {
+	t.Helper()
+	got := &request{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got.query = r.URL.Query()
+		if err := json.Unmarshal(b, &got.body); err != nil {
+			t.Errorf("unmarshal request body(%s) failed, %s", string(b), err)
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+// field 按路径取出请求体中的字段，数组用下标表示
+func field(body any, path ...any) any {
+	for _, p := range path {
+		switch k := p.(type) {
+		case string:
+			m, _ := body.(map[string]any)
+			body = m[k]
+		case int:
+			a, _ := body.([]any)
+			if k >= len(a) {
+				return nil
+			}
+			body = a[k]
+		}
+	}
+	return body
+}
+
+func testMessage(failed bool, reason string, mentions ...string) *Message {
+	msg := &Message{
+		TaskID:          1,
+		TaskName:        "task-1",
+		SrcClusterName:  "cluster-1",
+		SrcDatabaseName: "db1",
+		SrcTablesName:   "order【共2张分表】",
+		Govern:          "删除数据",
+		Condition:       "id < 100",
+		TaskStatus:      "执行成功",
+		TaskReason:      reason,
+		Mentions:        mentions,
+		TaskURL:         "http://loom/#/task/detail?task_id=1",
+	}
+	if failed {
+		msg.TaskStatus = "执行失败"
+		msg.TaskStatusColor = "#F33"
+	}
+	return msg
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      *Message
+		want     []string
+		notWant  []string
+		lastLine string
+	}{
+		{
+			name:     "fields",
+			msg:      testMessage(false, ""),
+			want:     []string{"**任务ID**：[1](http://loom/#/task/detail?task_id=1)", "**任务名称**：task-1", "**表名**：order【共2张分表】", "**治理条件**：id < 100"},
+			notWant:  []string{"**原因**"},
+			lastLine: "**治理数据大小(MB)**：0",
+		},
+		{
+			name:     "reason",
+			msg:      testMessage(true, "timeout"),
+			want:     []string{"**任务状态**：执行失败"},
+			lastLine: "**原因**：timeout",
+		},
+		{
+			name:     "mentions",
+			msg:      testMessage(false, "", "u1", "u2"),
+			lastLine: "[u1] [u2]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := renderMarkdown(test.msg, func(id string) string { return "[" + id + "]" })
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range test.want {
+				if !strings.Contains(got, s) {
+					t.Fatalf("renderMarkdown() got %s, want contains %s", got, s)
+				}
+			}
+			for _, s := range test.notWant {
+				if strings.Contains(got, s) {
+					t.Fatalf("renderMarkdown() got %s, want not contains %s", got, s)
+				}
+			}
+			if lines := strings.Split(got, "\n"); lines[len(lines)-1] != test.lastLine {
+				t.Fatalf("renderMarkdown() got last line %s, want %s", lines[len(lines)-1], test.lastLine)
+			}
+		})
+	}
+}
+
+func TestDingTalkSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		msg      *Message
+		resp     string
+		mentions []any
+		wantErr  bool
+	}{
+		{"success", "", testMessage(false, ""), `{"errcode":0,"errmsg":"ok"}`, nil, false},
+		{"mentions and sign", "SEC123", testMessage(true, "timeout", "u1", "u2"), `{"errcode":0,"errmsg":"ok"}`, []any{"u1", "u2"}, false},
+		{"error code", "", testMessage(false, ""), `{"errcode":310000,"errmsg":"sign not match"}`, nil, true},
+		{"invalid response", "", testMessage(false, ""), `not json`, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, got := newServer(t, test.resp)
+			err := (&DingTalk{Webhook: srv.URL + "/robot/send?access_token=x", Secret: test.secret}).Send(common.NewContext(), test.msg)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Send() got err %v, want err %v", err, test.wantErr)
+			}
+
+			if got.query.Get("access_token") != "x" {
+				t.Fatalf("Send() got query %v, want access_token kept", got.query)
+			}
+			if test.secret != "" {
+				ts, sign := got.query.Get("timestamp"), got.query.Get("sign")
+				n, _ := strconv.ParseInt(ts, 10, 64)
+				if sign == "" || sign != DingTalkSign(test.secret, n) {
+					t.Fatalf("Send() got timestamp %s sign %s, want signed", ts, sign)
+				}
+			} else if got.query.Has("sign") {
+				t.Fatalf("Send() got sign %s, want no sign without secret", got.query.Get("sign"))
+			}
+
+			text, _ := field(got.body, "markdown", "text").(string)
+			if field(got.body, "msgtype") != "markdown" || !strings.HasPrefix(text, "### "+title+"\n\n") {
+				t.Fatalf("Send() got body %v, want markdown with title", got.body)
+			}
+			if !strings.Contains(text, "**任务名称**：task-1  \n") {
+				t.Fatalf("Send() got text %s, want lines end with two spaces", text)
+			}
+			mentions, _ := field(got.body, "at", "atUserIds").([]any)
+			if len(mentions) != len(test.mentions) {
+				t.Fatalf("Send() got atUserIds %v, want %v", mentions, test.mentions)
+			}
+			for _, m := range test.mentions {
+				if !strings.Contains(text, "@"+m.(string)) {
+					t.Fatalf("Send() got text %s, want mention @%s", text, m)
+				}
+			}
+		})
+	}
+}
+
+func TestFeishuSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		msg       *Message
+		resp      string
+		wantColor string
+		mentions  []string
+		wantErr   bool
+	}{
+		{"success", "", testMessage(false, ""), `{"code":0,"msg":"success"}`, "green", nil, false},
+		{"failed task with mentions and sign", "SEC123", testMessage(true, "timeout", "ou_1", "ou_2"), `{"code":0,"msg":"success"}`, "red", []string{"ou_1", "ou_2"}, false},
+		{"error code", "", testMessage(false, ""), `{"code":19021,"msg":"sign match fail"}`, "green", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, got := newServer(t, test.resp)
+			err := (&Feishu{Webhook: srv.URL, Secret: test.secret}).Send(common.NewContext(), test.msg)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Send() got err %v, want err %v", err, test.wantErr)
+			}
+
+			if field(got.body, "msg_type") != "interactive" || field(got.body, "card", "header", "template") != test.wantColor {
+				t.Fatalf("Send() got body %v, want interactive card with %s header", got.body, test.wantColor)
+			}
+			if u := field(got.body, "card", "elements", 1, "actions", 0, "url"); u != test.msg.TaskURL {
+				t.Fatalf("Send() got button url %v, want %s", u, test.msg.TaskURL)
+			}
+			content, _ := field(got.body, "card", "elements", 0, "text", "content").(string)
+			if !strings.Contains(content, "**任务名称**：task-1\n") {
+				t.Fatalf("Send() got content %s, want rendered message", content)
+			}
+			for _, m := range test.mentions {
+				if !strings.Contains(content, "<at id="+m+"></at>") {
+					t.Fatalf("Send() got content %s, want mention %s", content, m)
+				}
+			}
+			if test.secret != "" {
+				ts, _ := field(got.body, "timestamp").(string)
+				n, _ := strconv.ParseInt(ts, 10, 64)
+				if sign := field(got.body, "sign"); sign != FeishuSign(test.secret, n) {
+					t.Fatalf("Send() got timestamp %s sign %v, want signed", ts, sign)
+				}
+			} else if field(got.body, "sign") != nil {
+				t.Fatalf("Send() got sign %v, want no sign without secret", field(got.body, "sign"))
+			}
+		})
+	}
+}
+
+func TestWeComSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       *Message
+		resp      string
+		wantColor string
+		mentions  []string
+		wantErr   bool
+	}{
+		{"success", testMessage(false, ""), `{"errcode":0,"errmsg":"ok"}`, "info", nil, false},
+		{"failed task with mentions", testMessage(true, "timeout", "zhangsan", "lisi"), `{"errcode":0,"errmsg":"ok"}`, "warning", []string{"zhangsan", "lisi"}, false},
+		{"error code", testMessage(false, ""), `{"errcode":93000,"errmsg":"invalid webhook url"}`, "info", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, got := newServer(t, test.resp)
+			err := (&WeCom{Webhook: srv.URL + "/cgi-bin/webhook/send?key=x"}).Send(common.NewContext(), test.msg)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Send() got err %v, want err %v", err, test.wantErr)
+			}
+
+			if got.query.Get("key") != "x" {
+				t.Fatalf("Send() got query %v, want key kept", got.query)
+			}
+			content, _ := field(got.body, "markdown", "content").(string)
+			header := `### <font color="` + test.wantColor + `">` + title + "</font>\n"
+			if field(got.body, "msgtype") != "markdown" || !strings.HasPrefix(content, header) {
+				t.Fatalf("Send() got body %v, want markdown with header %s", got.body, header)
+			}
+			if !strings.Contains(content, "**任务名称**：task-1\n") {
+				t.Fatalf("Send() got content %s, want rendered message", content)
+			}
+			if want := "<@" + strings.Join(test.mentions, "> <@") + ">"; len(test.mentions) != 0 && !strings.HasSuffix(content, want) {
+				t.Fatalf("Send() got content %s, want mentions %s", content, want)
+			}
+		})
+	}
+}
+
+func TestSendEmptyWebhook(t *testing.T) {
+	tests := []struct {
+		name  string
+		robot interface {
+			Send(*common.Context, *Message) error
+		}
+	}{
+		{"dingtalk", &DingTalk{}},
+		{"feishu", &Feishu{}},
+		{"wecom", &WeCom{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.robot.Send(common.NewContext(), testMessage(false, "")); err == nil {
+				t.Fatal("Send() got nil err, want webhook empty err")
+			}
+		})
+	}
+}
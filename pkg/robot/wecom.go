@@ -0,0 +1,58 @@
+package robot
+
+import (
+	"encoding/json"
+	"fmt"
+	. "github.com/sunkaimr/data-loom/internal/notice/message"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+)
+
+// WeCom 企业微信群机器人，企业微信不支持加签，通过地址中的key鉴权
+type WeCom struct {
+	Webhook string
+}
+
+func (c *WeCom) Send(_ *common.Context, msg *Message) error {
+	return c.send(msg)
+}
+
+func (c *WeCom) Test(_ *common.Context, testUser string) error {
+	return c.send(Example(testUser))
+}
+
+func (c *WeCom) send(msg *Message) error {
+	if c.Webhook == "" {
+		return fmt.Errorf("wecom webhook is empty")
+	}
+
+	content, err := renderMarkdown(msg, func(id string) string { return fmt.Sprintf("<@%s>", id) })
+	if err != nil {
+		return err
+	}
+	color := "info"
+	if failed(msg) {
+		color = "warning"
+	}
+	content = fmt.Sprintf("### <font color=\"%s\">%s</font>\n%s", color, title, content)
+
+	body := map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]any{"content": content},
+	}
+	respBody, err := post(c.Webhook, body)
+	if err != nil {
+		return err
+	}
+
+	resp := struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}{}
+	if err = json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("unmarshal wecom response(%s) failed, %s", string(respBody), err)
+	}
+	if resp.ErrCode != 0 {
+		return fmt.Errorf("send wecom message failed, errcode: %d, errmsg: %s", resp.ErrCode, resp.ErrMsg)
+	}
+	return nil
+}
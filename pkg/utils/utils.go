@@ -278,6 +278,46 @@ func TrimmingSQLConditionEnding(condition string) string {
 	}
 }
 
+var (
+	sqlDateTimeFuncRegexp = regexp.MustCompile(`(?i)\b(?:(?:NOW|SYSDATE)\s*\(\s*\d*\s*\)|(?:CURRENT_TIMESTAMP|LOCALTIMESTAMP|LOCALTIME)\b(?:\s*\(\s*\d*\s*\))?)`)
+	sqlDateFuncRegexp     = regexp.MustCompile(`(?i)\b(?:CURDATE\s*\(\s*\)|CURRENT_DATE\b(?:\s*\(\s*\))?)`)
+	sqlTimeFuncRegexp     = regexp.MustCompile(`(?i)\b(?:CURTIME\s*\(\s*\d*\s*\)|CURRENT_TIME\b(?:\s*\(\s*\d*\s*\))?)`)
+	sqlUnixFuncRegexp     = regexp.MustCompile(`(?i)\bUNIX_TIMESTAMP\s*\(\s*\)`)
+)
+
+// FreezeSQLTimeFunc 将where条件中取当前时间的函数(NOW()、CURDATE()等)替换为指定时间的常量，引号内的内容不做替换
+func FreezeSQLTimeFunc(condition string, t time.Time) string {
+	replace := func(s string) string {
+		s = sqlUnixFuncRegexp.ReplaceAllString(s, strconv.FormatInt(t.Unix(), 10))
+		s = sqlDateTimeFuncRegexp.ReplaceAllString(s, "'"+t.Format(time.DateTime)+"'")
+		s = sqlDateFuncRegexp.ReplaceAllString(s, "'"+t.Format(time.DateOnly)+"'")
+		return sqlTimeFuncRegexp.ReplaceAllString(s, "'"+t.Format(time.TimeOnly)+"'")
+	}
+
+	var b strings.Builder
+	start := 0
+	var quote byte
+	for i := 0; i < len(condition); i++ {
+		c := condition[i]
+		switch {
+		case quote == 0 && (c == '\'' || c == '"' || c == '`'):
+			b.WriteString(replace(condition[start:i]))
+			quote, start = c, i
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			b.WriteString(condition[start : i+1])
+			quote, start = 0, i+1
+		}
+	}
+	if quote != 0 {
+		b.WriteString(condition[start:])
+	} else {
+		b.WriteString(replace(condition[start:]))
+	}
+	return b.String()
+}
+
 func HttpDo(method, url string, headers, query map[string]string, b string) (*http.Response, []byte, error) {
 	client := &http.Client{
 		Transport: &http.Transport{
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	. "github.com/sunkaimr/data-loom/internal/notice/message"
+	"github.com/sunkaimr/data-loom/internal/pkg/common"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+const (
+	HeaderTimestamp = "X-Data-Loom-Timestamp"
+	HeaderSignature = "X-Data-Loom-Signature"
+	HeaderEvent     = "X-Data-Loom-Event"
+	HeaderDelivery  = "X-Data-Loom-Delivery" // 每次通知的唯一ID，重试时不变，接收方可据此去重
+
+	EventTaskNotice = "task.notice"
+	EventTest       = "test"
+
+	DefaultRetries  = 3
+	backoffBase     = time.Second
+	requestTimeout  = 10 * time.Second
+	deliveryTimeout = time.Minute // 包含重试在内发送一次通知的最长时间
+)
+
+// Webhook 以JSON的格式将通知POST到指定地址，配置了密钥时使用HMAC-SHA256签名
+type Webhook struct {
+	URL      string
+	Secret   string // 签名密钥，签名内容为 "时间戳.请求体"
+	Template string // 请求体模板(text/template)，为空时使用默认的JSON格式
+	Retries  int    // 发送失败后的重试次数，按1s,2s,4s...退避
+}
+
+// Payload 渲染请求体的数据
+type Payload struct {
+	Event     string `json:"event"`
+	Timestamp int64  `json:"timestamp"`
+	*Message
+}
+
+// delivery 一次通知的请求，重试时使用相同的ID、时间戳和请求体
+type delivery struct {
+	id    string
+	event string
+	ts    int64
+	body  []byte
+}
+
+// Send 在后台异步发送通知，不阻塞调度和回调，失败时在deliveryTimeout内按退避重试
+func (c *Webhook) Send(ctx *common.Context, msg *Message) error {
+	d, err := c.newDelivery(EventTaskNotice, msg)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := c.deliver(ctx, d, c.Retries); err != nil && ctx != nil && ctx.Log != nil {
+			ctx.Log.Errorf("post webhook(%s) delivery(%s) failed, %s", c.URL, d.id, err)
+		}
+	}()
+	return nil
+}
+
+// Test 同步发送测试通知且不重试，及时返回结果
+func (c *Webhook) Test(ctx *common.Context, testUser string) error {
+	d, err := c.newDelivery(EventTest, Example(testUser))
+	if err != nil {
+		return err
+	}
+	return c.deliver(ctx, d, 0)
+}
+
+// CheckTemplate 校验请求体模板可以渲染出合法的JSON
+func CheckTemplate(tmpl string) error {
+	_, err := (&Webhook{Template: tmpl}).render(&Payload{Event: EventTest, Timestamp: time.Now().Unix(), Message: Example("")})
+	return err
+}
+
+func (c *Webhook) render(p *Payload) ([]byte, error) {
+	if c.Template == "" {
+		return json.Marshal(p)
+	}
+
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(c.Template)
+	if err != nil {
+		return nil, fmt.Errorf("parse webhook template failed, %s", err)
+	}
+
+	b := bytes.Buffer{}
+	err = tmpl.Execute(&b, p)
+	if err != nil {
+		return nil, fmt.Errorf("execute webhook template failed, %s", err)
+	}
+	if !json.Valid(b.Bytes()) {
+		return nil, fmt.Errorf("webhook template rendered invalid json: %s", b.String())
+	}
+	return b.Bytes(), nil
+}
+
+// Sign 计算请求的签名，接收方按相同的方式计算后比较即可校验请求来源
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Webhook) newDelivery(event string, msg *Message) (*delivery, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("webhook url is empty")
+	}
+
+	ts := time.Now().Unix()
+	body, err := c.render(&Payload{Event: event, Timestamp: ts, Message: msg})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery{id: newDeliveryID(), event: event, ts: ts, body: body}, nil
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// deliver 发送请求，失败后按1s,2s,4s...退避重试，包含重试在内不超过deliveryTimeout
+func (c *Webhook) deliver(ctx *common.Context, d *delivery, retries int) error {
+	timeout, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	var err error
+	for i := 0; ; i++ {
+		err = c.do(timeout, d)
+		if err == nil || i >= retries {
+			return err
+		}
+		backoff := backoffBase << i
+		if ctx != nil && ctx.Log != nil {
+			ctx.Log.Warnf("post webhook(%s) delivery(%s) failed, retry after %s, %s", c.URL, d.id, backoff, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-timeout.Done():
+			return fmt.Errorf("%s, give up after %s", err, deliveryTimeout)
+		}
+	}
+}
+
+func (c *Webhook) do(ctx context.Context, d *delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return fmt.Errorf("new webhook request failed, %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.event)
+	req.Header.Set(HeaderDelivery, d.id)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(d.ts, 10))
+	if c.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(c.Secret, d.ts, d.body))
+	}
+
+	resp, err := (&http.Client{Timeout: requestTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook(%s) failed, %s", c.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post webhook(%s) got status %d, %s", c.URL, resp.StatusCode, string(b))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/sunkaimr/data-loom/internal/notice/message"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		ts     int64
+		body   string
+		want   string
+	}{
+		{"json body", "secret", 1700000000, `{"a":1}`, "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"},
+		{"empty secret and body", "", 1700000000, "", "sha256=c1da1b6c6b8e9da7f4bbb90f7cab0820f271ad19ccbf80c88479c4e14f37d1c6"},
+		{"zero timestamp", "k", 0, "hello", "sha256=219a8453b42a28e66f1b837e89bd58fd0caa50b9c7f09f682ebe5267c0eb826d"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Sign(test.secret, test.ts, []byte(test.body)); got != test.want {
+				t.Fatalf("Sign() got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	p := &Payload{Event: EventTaskNotice, Timestamp: 1700000000, Message: &Message{TaskID: 1, TaskName: `a "quoted" name`}}
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+		want     map[string]any
+	}{
+		{
+			name: "default",
+			want: map[string]any{"event": EventTaskNotice, "timestamp": float64(1700000000), "task_id": float64(1), "task_name": `a "quoted" name`},
+		},
+		{
+			name:     "custom with json func",
+			template: `{"msgtype":"text","text":{"content":{{json .TaskName}}},"id":{{.TaskID}}}`,
+			want:     map[string]any{"msgtype": "text", "text": map[string]any{"content": `a "quoted" name`}, "id": float64(1)},
+		},
+		{
+			name:     "invalid json",
+			template: `{"content":"{{.TaskName}}"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "parse error",
+			template: `{"content":{{json .TaskName}`,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field",
+			template: `{"content":{{json .NotExist}}}`,
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := (&Webhook{Template: test.template}).render(p)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("render() got err %v, want err %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			got := map[string]any{}
+			if err = json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("render() got invalid json %s, %s", string(b), err)
+			}
+			for k, v := range test.want {
+				if !reflect.DeepEqual(got[k], v) {
+					t.Fatalf("render() got %s=%v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestSendRetryKeepsDelivery(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []http.Header
+		done     = make(chan struct{})
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Header.Clone())
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		close(done)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	err := (&Webhook{URL: server.URL, Secret: "secret", Retries: 1}).Send(nil, &Message{TaskID: 1})
+	if err != nil {
+		t.Fatalf("Send() got err %v", err)
+	}
+	if time.Since(start) > backoffBase/2 {
+		t.Fatalf("Send() should not block on delivery")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("webhook was not retried")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	first, second := requests[0], requests[1]
+	if first.Get(HeaderDelivery) == "" || first.Get(HeaderDelivery) != second.Get(HeaderDelivery) {
+		t.Fatalf("delivery id should be set and kept on retry, got %q and %q", first.Get(HeaderDelivery), second.Get(HeaderDelivery))
+	}
+	if first.Get(HeaderSignature) != second.Get(HeaderSignature) {
+		t.Fatalf("signature should be kept on retry")
+	}
+}
@@ -220,6 +220,63 @@ func (c *Argo) ArchiveData(ctx *common.Context, para *types.ArchiveParaStruct) (
 	return "", nil
 }
 
+func (c *Argo) PartitionData(ctx *common.Context, para *types.PartitionParaStruct) (string /* workflow id*/, error) {
+	log := ctx.Log
+	log.Debugf("sending PartitionData request to argo, %+v", para)
+
+	workflowNameSpace, workflowName := getWorkFlowAndNameSpace(configs.C.WorkFlow.Argo.Templates[Partition])
+	if workflowName == "" {
+		return "", fmt.Errorf("config.workflow.argo.templates.partition is null")
+	}
+
+	tempPara := WorkflowParas[Partition]
+	tmpl, err := template.New("template").Parse(tempPara)
+	if err != nil {
+		return "", err
+	}
+
+	// SQL中包含很多特殊字符这里通过base64编码，在使用时需要先解码
+	para.Statements = base64.StdEncoding.EncodeToString([]byte(para.Statements))
+	tmpPara := struct {
+		Namespace string
+		WorkFlow  string
+		types.PartitionParaStruct
+	}{
+		Namespace:           workflowNameSpace,
+		WorkFlow:            workflowName,
+		PartitionParaStruct: *para,
+	}
+	b := bytes.Buffer{}
+	err = tmpl.Execute(&b, tmpPara)
+	if err != nil {
+		return "", fmt.Errorf("execute template(%s) failed, %s", b.String(), err)
+	}
+
+	url := fmt.Sprintf(c.URL+SubmitWorkFlowURL, workflowNameSpace)
+	headers := make(map[string]string, 1)
+	headers["Authorization"] = "Bearer " + c.Auth
+	log.Debugf("call workflow[%s/%s] url: %s, parament: %s", workflowNameSpace, workflowName, url, b.String())
+
+	resp, body, err := utils.HttpDo(http.MethodPost, url, headers, nil, b.String())
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("got workflow[%s/%s] response code %v", workflowNameSpace, workflowName, resp.StatusCode)
+
+	wfResp := &WorkFlowResp{}
+	err = json.Unmarshal(body, wfResp)
+	if err != nil {
+		return "", fmt.Errorf("unmarshal failed, %s", err)
+	}
+
+	if wfResp.Code != 0 {
+		return "", fmt.Errorf("fail to submit workflow(%s/%s) %s", workflowNameSpace, workflowName, wfResp.Message)
+	}
+
+	return wfResp.Metadata.Namespace + "/" + wfResp.Metadata.Name, nil
+}
+
 func (c *Argo) WorkFlowStatus(ctx *common.Context, workflow string) (types.WorkFlowStatusType, error) {
 	log := ctx.Log
 
@@ -4,6 +4,7 @@ const Truncate = "truncate"
 const Delete = "delete"
 const Rebuild = "rebuild"
 const Archive = "archive"
+const Partition = "partition"
 
 var WorkflowParas = map[string]string{
 	Truncate: `
@@ -48,9 +49,16 @@ var WorkflowParas = map[string]string{
       "condition={{.Condition}}", 
       "rebuild_flag={{.RebuildFlag}}",
       "cleaning_speed={{.CleaningSpeed}}",
+      "chunk_size={{.Throttle.ChunkSize}}",
+      "sleep_ms={{.Throttle.SleepMs}}",
+      "max_rows_per_second={{.Throttle.MaxRowsPerSecond}}",
+      "max_txn_bytes={{.Throttle.MaxTxnBytes}}",
       "free_disk={{.FreeDisk}}", 
+      "watermark_column={{.WatermarkColumn}}",
+      "watermark_high={{.WatermarkHigh}}",
       "callback_url={{.Callback.URL}}", 
-      "callback_token={{.Callback.Token}}"
+      "callback_token={{.Callback.Token}}",
+      "throttle_url={{.Callback.ThrottleURL}}"
     ]
   }
 }
@@ -79,4 +87,27 @@ var WorkflowParas = map[string]string{
 }
 `,
 	Archive: ``,
+	Partition: `
+{
+  "namespace": "{{.Namespace}}",
+  "resourceKind": "WorkflowTemplate",
+  "resourceName": "{{.WorkFlow}}",
+  "labels": "task={{.TaskID}}",
+  "submitOptions": {
+    "entryPoint": null,
+    "parameters": [
+      "task_id={{.TaskID}}",
+      "mysql_host={{.Host}}",
+      "mysql_port={{.Port}}",
+      "mysql_user={{.User}}",
+      "mysql_password={{.Password}}",
+      "database_name={{.Database}}",
+      "table_name={{.Tables}}",
+      "statements={{.Statements}}",
+      "callback_url={{.Callback.URL}}", 
+      "callback_token={{.Callback.Token}}"
+    ]
+  }
+}
+`,
 }
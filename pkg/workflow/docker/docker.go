@@ -28,6 +28,11 @@ func (c *Docker) RebuildTables(ctx *common.Context, para *types.RebuildParaStruc
 	return "", nil
 }
 
+func (c *Docker) PartitionData(ctx *common.Context, para *types.PartitionParaStruct) (string, error) {
+	fmt.Printf("docker recv PartitionData request, %+v", para)
+	return "", nil
+}
+
 func (c *Docker) WorkFlowStatus(ctx *common.Context, id string) (types.WorkFlowStatusType, error) {
 	fmt.Printf("docker recv WorkFlowStatus request, %+v", id)
 	return types.WorkFlowStatusRunning, nil
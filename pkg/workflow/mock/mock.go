@@ -58,6 +58,7 @@ func (c *Mock) DeleteData(ctx *common.Context, para *types.DeleteParaStruct) (st
 		para.CallbackResult.TaskStatus = common.TaskStatusSuccess
 		para.CallbackResult.TaskReason = "模拟调用删除数据工作流"
 		para.CallbackResult.TaskResultQuantity = 222222
+		para.CallbackResult.Watermark = para.WatermarkHigh
 		b, _ := json.Marshal(para.CallbackResult)
 
 		_, _, err := utils.HttpDo(http.MethodPut, url, headers, nil, string(b))
@@ -88,6 +89,7 @@ func (c *Mock) ArchiveData(ctx *common.Context, para *types.ArchiveParaStruct) (
 		para.CallbackResult.TaskStatus = common.TaskStatusSuccess
 		para.CallbackResult.TaskReason = "模拟调用归档工作流"
 		para.CallbackResult.TaskResultQuantity = 222222
+		para.CallbackResult.Watermark = para.WatermarkHigh
 		b, _ := json.Marshal(para.CallbackResult)
 
 		_, _, err := utils.HttpDo(http.MethodPut, url, headers, nil, string(b))
@@ -132,6 +134,36 @@ func (c *Mock) RebuildTables(ctx *common.Context, para *types.RebuildParaStruct)
 	return "mock_rebuild", nil
 }
 
+func (c *Mock) PartitionData(ctx *common.Context, para *types.PartitionParaStruct) (string, error) {
+	log := ctx.Log
+	log.Debugf("sending PartitionData request to mock, %+v", para)
+
+	go func() {
+		// 模拟工作流执行
+		time.Sleep(80 * time.Second)
+
+		url := para.Callback.URL
+		headers := make(map[string]string, 1)
+		headers["Authorization"] = "Bearer " + para.Callback.Token
+
+		para.CallbackResult.ID = para.TaskID
+		para.CallbackResult.TaskStatus = common.TaskStatusSuccess
+		para.CallbackResult.TaskReason = "模拟调用分区治理工作流"
+		para.CallbackResult.TaskResultQuantity = 333333
+		b, _ := json.Marshal(para.CallbackResult)
+
+		_, _, err := utils.HttpDo(http.MethodPut, url, headers, nil, string(b))
+		if err != nil {
+			log.Error("report PartitionData result failed, %+v", err)
+			return
+		}
+
+		log.Infof("report PartitionData result success")
+	}()
+
+	return "mock_partition", nil
+}
+
 func (c *Mock) WorkFlowStatus(_ *common.Context, id string) (types.WorkFlowStatusType, error) {
 	fmt.Printf("mock recv WorkFlowStatus request, %+v", id)
 	return types.WorkFlowStatusRunning, nil